@@ -5,8 +5,10 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"sync"
@@ -21,19 +23,30 @@ import (
 const (
 	defaultNeededMem = 50 * 1024 // 50M
 
+	// the seconds to wait after startup before sampling the steady memory
+	memSteadyWait = 60
+	// the seconds between the checks whether the app exited while waiting
+	// the steady state
+	memExitCheckInterval = 5
+	// the max number of the apps whose memory is sampled at the same time
+	maxMemSamplers = 4
+
 	envMemQueryWait = "DDE_MEM_QUERY_WAIT"
 )
 
 var (
-	_memTicker     *time.Ticker
-	_tickerStopped chan struct{}
-
-	_curNeededMem = uint64(0)
-
 	_memQueryWait = 15
+
+	// the names of the apps whose memory is being sampled
+	_memSamplers   = make(map[string]bool)
+	_memSamplersMu sync.Mutex
 )
 
 func init() {
+	config := memchecker.GetConfig()
+	memanalyzer.SetModel(config.NeededMemPercentile,
+		time.Duration(config.SampleHalfLife)*24*time.Hour)
+
 	s := os.Getenv(envMemQueryWait)
 	if s == "" {
 		return
@@ -55,21 +68,58 @@ func (m *StartManager) IsMemSufficient() (bool, *dbus.Error) {
 	return memchecker.IsSufficient(), nil
 }
 
-// TryAgain launch the action which blocked with the memory insufficient
+// TryAgain launch all the pending launches which blocked with the memory
+// insufficient if launch is true, or cancel them
 func (m *StartManager) TryAgain(launch bool) *dbus.Error {
-	action := getCurAction()
-	logger.Info("Try Action:", action, launch)
-	setCurAction("")
-	stopMemTicker()
-	if !launch || action == "" {
+	logger.Info("Try pending launches:", launch)
+	if !launch {
+		_launchQueue.clear()
+		updateNeededMemory()
 		return nil
 	}
 
-	err := handleCurAction(action)
-	return dbusutil.ToError(err)
+	if _gSettingsConfig.memcheckerEnabled && !memchecker.IsSufficient() {
+		items := _launchQueue.list()
+		if len(items) != 0 {
+			updateNeededMemory()
+			showWarningDialog(items[len(items)-1].name)
+		}
+		return nil
+	}
+
+	var errs []string
+	for _, item := range _launchQueue.clear() {
+		err := m.launchPending(item)
+		if err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	updateNeededMemory()
+	if len(errs) != 0 {
+		return dbusutil.ToError(errors.New(strings.Join(errs, "; ")))
+	}
+	return nil
+}
+
+// GetMemPressure get the memory pressure stall information, the avg
+// values are the percentages of the time tasks stalled on memory
+func (m *StartManager) GetMemPressure() (map[string]float64, *dbus.Error) {
+	info, err := memchecker.GetPressure()
+	if err != nil {
+		return nil, dbusutil.ToError(err)
+	}
+
+	return map[string]float64{
+		"some-avg10":  info.SomeAvg10,
+		"some-avg60":  info.SomeAvg60,
+		"some-avg300": info.SomeAvg300,
+		"full-avg10":  info.FullAvg10,
+		"full-avg60":  info.FullAvg60,
+		"full-avg300": info.FullAvg300,
+	}, nil
 }
 
-// DumpMemRecord dump the process needed memory record
+// DumpMemRecord dump the memory samples and the estimated needed memory of the apps as json
 func (m *StartManager) DumpMemRecord() (string, *dbus.Error) {
 	return memanalyzer.DumpDB(), nil
 }
@@ -85,53 +135,105 @@ func (m *StartManager) setPropNeededMemory(v uint64) {
 	}
 }
 
-func handleMemInsufficient(v string) error {
+// deferLaunchIfMemInsufficient queue the launch if the memory insufficient,
+// returns true if queued
+func deferLaunchIfMemInsufficient(item *pendingLaunch) bool {
 	if !_gSettingsConfig.memcheckerEnabled {
-		return nil
+		return false
 	}
 	if memchecker.IsSufficient() {
-		return nil
-	}
-
-	action := getCurAction()
-	if action != "" {
-		logger.Info("The prev action is executing:", action)
-		showWarningDialog(getActionName(action))
-		return fmt.Errorf("the prev action(%s) is executing", action)
+		return false
 	}
 
 	logger.Info("Notice: current memory insufficient, please free.....")
-	_curNeededMem = getNeededMemory(v)
+	item.neededMem = getNeededMemory(item.name)
+	if _launchQueue.add(item) {
+		go startMemTicker()
+	}
 	updateNeededMemory()
-	go startMemTicker()
-	showWarningDialog(v)
-	return fmt.Errorf("memory has insufficient, please free")
+	showWarningDialog(item.name)
+	return true
 }
 
+// startMemTicker update the needed memory, and drain the launch queue once
+// the memory is sufficient for all of them. It stops after the queue empty.
 func startMemTicker() {
-	_memTicker = time.NewTicker(time.Second * 1)
-	_tickerStopped = make(chan struct{})
+	ticker := time.NewTicker(time.Second * 1)
+	defer ticker.Stop()
 	logger.Info("Start memory ticker")
+
+	// the pressure trigger notifies the stall spikes between the ticks
+	var pressureEvents <-chan struct{}
+	if memchecker.GetMode() == memchecker.ModePressure {
+		watcher, err := memchecker.WatchPressure()
+		if err != nil {
+			logger.Warning("Failed to watch memory pressure:", err)
+		} else {
+			defer watcher.Stop()
+			pressureEvents = watcher.Events()
+		}
+	}
+
 	for {
 		select {
-		case <-_tickerStopped:
+		case <-ticker.C:
+		case _, ok := <-pressureEvents:
+			if !ok {
+				pressureEvents = nil
+				continue
+			}
+			logger.Debug("Memory pressure triggered")
+		}
+
+		for _, item := range _launchQueue.removeExpired(time.Now()) {
+			logger.Info("The pending launch expired:", item.id, item.name)
+		}
+		if _launchQueue.stopTickerIfEmpty() {
 			logger.Info("Ticker has stopped")
 			_startManager.setPropNeededMemory(0)
 			return
-		case <-_memTicker.C:
-			updateNeededMemory()
+		}
+		checkPendingLaunches()
+	}
+}
+
+// checkPendingLaunches launch all the pending launches if the memory is
+// sufficient for them
+func checkPendingLaunches() {
+	updateNeededMemory()
+	if _startManager.NeededMemory != 0 || !memchecker.IsSufficient() {
+		return
+	}
+
+	for _, item := range _launchQueue.clear() {
+		logger.Info("Launch the pending:", item.id, item.name)
+		err := _startManager.launchPending(item)
+		if err != nil {
+			logger.Warning("Failed to launch the pending:", err)
 		}
 	}
 }
 
 func updateNeededMemory() {
+	if _launchQueue.len() == 0 {
+		_startManager.setPropNeededMemory(0)
+		return
+	}
+
 	info, err := memchecker.GetMemInfo()
 	if err != nil {
 		logger.Warning("Failed to get memory info:", err)
 		return
 	}
-	logger.Debug("Memory info:", _curNeededMem, info.MemAvailable, info.MinAvailable, info.MaxSwapUsed)
-	v := int64(_curNeededMem) + int64(info.MinAvailable) - int64(info.MemAvailable)
+
+	neededMem := _launchQueue.neededMemory()
+	if memchecker.GetMode() == memchecker.ModePressure {
+		updateNeededMemoryByPressure(neededMem, info)
+		return
+	}
+
+	logger.Debug("Memory info:", neededMem, info.MemAvailable, info.MinAvailable, info.MaxSwapUsed)
+	v := int64(neededMem) + int64(info.MinAvailable) - int64(info.MemAvailable)
 	if v < 0 {
 		v = 0
 	}
@@ -153,17 +255,20 @@ func updateNeededMemory() {
 	_startManager.setPropNeededMemory(uint64(v))
 }
 
-func stopMemTicker() {
-	if _memTicker == nil {
-		return
+// updateNeededMemoryByPressure the apps need their memory to be available,
+// and at least their memory to be freed while the memory under pressure.
+func updateNeededMemoryByPressure(neededMem uint64, info *memchecker.MemoryInfo) {
+	v := int64(neededMem) - int64(info.MemAvailable)
+	if v < 0 {
+		v = 0
 	}
-	_memTicker.Stop()
-	_memTicker = nil
 
-	if _tickerStopped != nil {
-		close(_tickerStopped)
+	if !memchecker.IsSufficient() && v < int64(neededMem) {
+		v = int64(neededMem)
 	}
-	_tickerStopped = nil
+
+	logger.Debug("Update needed memory by pressure:", _startManager.NeededMemory, v)
+	_startManager.setPropNeededMemory(uint64(v))
 }
 
 func showWarningDialog(action string) {
@@ -182,107 +287,107 @@ func showWarningDialog(action string) {
 	}
 }
 
-var (
-	_curAction    = ""
-	_actionLocker sync.Mutex
-	_app          = struct {
-		desktop   string
-		timestamp uint32
-		files     []string
-		options   map[string]dbus.Variant
-	}{}
-	_appAction = struct {
-		desktop   string
-		action    string
-		timestamp uint32
-	}{}
-	_cmd = struct {
-		exe     string
-		args    []string
-		options map[string]dbus.Variant
-	}{}
-)
-
-func setCurAction(action string) {
-	_actionLocker.Lock()
-	_curAction = action
-	_actionLocker.Unlock()
+func getNeededMemory(name string) uint64 {
+	v, err := memanalyzer.GetProcessMemory(name)
+	logger.Info("[getNeededMemory] result:", name, v, err)
+	if err != nil {
+		return defaultNeededMem
+	}
+	return v
 }
 
-func getCurAction() string {
-	_actionLocker.Lock()
-	defer _actionLocker.Unlock()
-	return _curAction
-}
+// startSaveNeededMemory sample the memory of the app in background, at most
+// one sampling for each app and maxMemSamplers in total.
+func startSaveNeededMemory(name, unitName string, pid uint32) {
+	if !acquireMemSampler(name) {
+		logger.Debug("Skip sampling the memory:", name)
+		return
+	}
 
-func getActionName(action string) string {
-	switch action {
-	case "LaunchApp":
-		return _app.desktop
-	case "LaunchAppAction":
-		return _appAction.desktop
-	case "RunCommand":
-		var _name = _cmd.exe
-		if len(_cmd.args) != 0 {
-			_name += " " + strings.Join(_cmd.args, " ")
+	go func() {
+		defer releaseMemSampler(name)
+		err := saveNeededMemory(name, unitName, pid)
+		if err != nil {
+			logger.Warning("Failed to save needed memory:", err)
 		}
-		return _name
-	}
-	return ""
+	}()
 }
 
-func handleCurAction(action string) error {
-	if action == "" {
-		return nil
+func acquireMemSampler(name string) bool {
+	_memSamplersMu.Lock()
+	defer _memSamplersMu.Unlock()
+	if _memSamplers[name] || len(_memSamplers) >= maxMemSamplers {
+		return false
 	}
+	_memSamplers[name] = true
+	return true
+}
 
-	var err error
-	switch action {
-	case "LaunchApp":
-		err = _startManager.launchAppWithOptions(_app.desktop, _app.timestamp,
-			_app.files, _app.options)
-	case "LaunchAppAction":
-		err = _startManager.launchAppAction(_appAction.desktop,
-			_appAction.action, _appAction.timestamp)
-	case "RunCommand":
-		err = _startManager.runCommandWithOptions(_cmd.exe, _cmd.args, _cmd.options)
-	}
-	if err != nil {
-		logger.Warning("Failed to launch action:", err)
-	}
-	return err
+func releaseMemSampler(name string) {
+	_memSamplersMu.Lock()
+	delete(_memSamplers, name)
+	_memSamplersMu.Unlock()
 }
 
-func getNeededMemory(name string) uint64 {
-	v, err := memanalyzer.GetProcessMemory(name)
-	logger.Info("[getNeededMemory] result:", name, v, err)
+// saveNeededMemory sample the memory of the app unit, the peak in the
+// startup and the steady state after that. The cgroup of the unit is
+// resolved by the pid once the process moved into it.
+func saveNeededMemory(name, unitName string, pid uint32) error {
+	var dir string
+	getMemory := func(unitName string) (uint64, error) {
+		if dir == "" {
+			v, err := memanalyzer.GetAppCGroupDir(pid)
+			if err != nil {
+				return 0, err
+			}
+			if filepath.Base(v) != unitName {
+				return 0, fmt.Errorf("the process %v not in %s yet", pid, unitName)
+			}
+			dir = v
+		}
+		return memanalyzer.GetCGroupMemory(dir)
+	}
+
+	peak, steady, err := sampleCGroupMemory(unitName, getMemory, time.Sleep)
 	if err != nil {
-		return defaultNeededMem
+		return fmt.Errorf("no memory sampled for %s: %v", name, err)
 	}
-	return v
+	logger.Info("process memory:", name, unitName, peak, steady)
+	return memanalyzer.SaveProcessSample(name, peak, steady)
 }
 
-func saveNeededMemory(name, cgroupName string) error {
-	tmp, _ := memanalyzer.GetProcessMemory(name)
-	if tmp > 0 {
-		logger.Debug("Process exists:", name, tmp)
-		return nil
+// sampleCGroupMemory returns the peak in the first _memQueryWait seconds and
+// the memory after memSteadyWait seconds more. The sampling stops once the
+// cgroup is gone, which means the app has exited, the steady is 0 then.
+func sampleCGroupMemory(cgroupName string, getMemory func(string) (uint64, error),
+	sleep func(time.Duration)) (peak, steady uint64, err error) {
+	var seen bool
+	for i := 0; i < _memQueryWait; i++ {
+		sleep(time.Second)
+		size, err := getMemory(cgroupName)
+		if err != nil {
+			if seen {
+				return peak, 0, nil
+			}
+			// the unit may be not created yet
+			logger.Debug("Failed to get cgroup memory:", cgroupName, err)
+			continue
+		}
+		seen = true
+		if size > peak {
+			peak = size
+		}
 	}
-
-	var (
-		size uint64
-		err  error
-	)
-	time.Sleep(time.Second * time.Duration(_memQueryWait))
-	size, err = memanalyzer.GetCGroupMemory(cgroupName)
-	logger.Info("process memory:", name, cgroupName, size, err)
-	if err != nil || size == 0 {
-		return err
+	if peak == 0 {
+		return 0, 0, errors.New("the cgroup is empty or not found")
 	}
 
-	if err != nil {
-		return err
+	for waited := 0; waited < memSteadyWait; waited += memExitCheckInterval {
+		sleep(memExitCheckInterval * time.Second)
+		steady, err = getMemory(cgroupName)
+		if err != nil {
+			return peak, 0, nil
+		}
 	}
-
-	return memanalyzer.SaveProcessMemory(name, size)
+	return peak, steady, nil
 }
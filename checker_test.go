@@ -0,0 +1,77 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_sampleCGroupMemory(t *testing.T) {
+	var elapsed time.Duration
+	sleep := func(d time.Duration) {
+		elapsed += d
+	}
+	// the memory by the elapsed seconds, not found after the end
+	newGetMemory := func(sizes map[int]uint64, end int) func(string) (uint64, error) {
+		return func(string) (uint64, error) {
+			sec := int(elapsed / time.Second)
+			if sec > end {
+				return 0, errors.New("not found")
+			}
+			return sizes[sec], nil
+		}
+	}
+
+	// the app keeps running
+	peak, steady, err := sampleCGroupMemory("app-a.scope",
+		newGetMemory(map[int]uint64{3: 300, 5: 500, _memQueryWait + memSteadyWait: 200}, 1000), sleep)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(500), peak)
+	assert.Equal(t, uint64(200), steady)
+	assert.Equal(t, time.Duration(_memQueryWait+memSteadyWait)*time.Second, elapsed)
+
+	// the app exits in the startup, stop sampling at once
+	elapsed = 0
+	peak, steady, err = sampleCGroupMemory("app-a.scope",
+		newGetMemory(map[int]uint64{1: 100, 2: 200}, 2), sleep)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(200), peak)
+	assert.Equal(t, uint64(0), steady)
+	assert.Equal(t, 3*time.Second, elapsed)
+
+	// the app exits before the steady state
+	elapsed = 0
+	_, steady, err = sampleCGroupMemory("app-a.scope",
+		newGetMemory(map[int]uint64{1: 100}, _memQueryWait+memExitCheckInterval), sleep)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(0), steady)
+	assert.Equal(t, time.Duration(_memQueryWait+2*memExitCheckInterval)*time.Second, elapsed)
+
+	// the cgroup never found
+	elapsed = 0
+	_, _, err = sampleCGroupMemory("app-a.scope", newGetMemory(nil, -1), sleep)
+	assert.Error(t, err)
+	assert.Equal(t, time.Duration(_memQueryWait)*time.Second, elapsed)
+}
+
+func Test_acquireMemSampler(t *testing.T) {
+	assert.True(t, acquireMemSampler("a"))
+	assert.False(t, acquireMemSampler("a"))
+	for i := 1; i < maxMemSamplers; i++ {
+		assert.True(t, acquireMemSampler(string(rune('a'+i))))
+	}
+	assert.False(t, acquireMemSampler("z"))
+
+	releaseMemSampler("a")
+	assert.True(t, acquireMemSampler("z"))
+	for name := range _memSamplers {
+		releaseMemSampler(name)
+	}
+	assert.Empty(t, _memSamplers)
+}
@@ -0,0 +1,217 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package main
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/godbus/dbus"
+)
+
+const (
+	dbusServiceName      = "com.deepin.daemon.Display"
+	dbusPath             = "/com/deepin/daemon/Display"
+	dbusInterface        = "com.deepin.daemon.Display"
+	dbusInterfaceMonitor = dbusInterface + ".Monitor"
+	dbusPropsInterface   = "org.freedesktop.DBus.Properties"
+)
+
+// ModeInfo 与 Display 服务中的 ModeInfo 对应
+type ModeInfo struct {
+	Id     uint32
+	Width  uint16
+	Height uint16
+	Rate   float64
+}
+
+func (mode ModeInfo) String() string {
+	return fmt.Sprintf("%dx%d@%.2f", mode.Width, mode.Height, mode.Rate)
+}
+
+// monitorInfo 显示器的状态，从 Monitor 对象的属性中读取
+type monitorInfo struct {
+	path         dbus.ObjectPath
+	Name         string
+	Manufacturer string
+	Model        string
+	Connected    bool
+	Enabled      bool
+	Primary      bool
+	X            int16
+	Y            int16
+	Width        uint16
+	Height       uint16
+	// 旋转角度，0、90、180 或 270
+	Rotation    int
+	RefreshRate float64
+	Brightness  float64
+	CurrentMode ModeInfo
+	BestMode    ModeInfo
+	Modes       []ModeInfo
+}
+
+// displayInfo Display 服务的状态
+type displayInfo struct {
+	DisplayMode string
+	Primary     string
+	Monitors    []*monitorInfo
+}
+
+type client struct {
+	conn *dbus.Conn
+	obj  dbus.BusObject
+}
+
+func newClient() (*client, error) {
+	conn, err := dbus.SessionBus()
+	if err != nil {
+		return nil, err
+	}
+	return &client{
+		conn: conn,
+		obj:  conn.Object(dbusServiceName, dbusPath),
+	}, nil
+}
+
+// call 调用 Display 的方法
+func (c *client) call(method string, args ...interface{}) error {
+	return c.obj.Call(dbusInterface+"."+method, 0, args...).Err
+}
+
+// callMonitor 调用显示器 monitor 的方法
+func (c *client) callMonitor(monitor *monitorInfo, method string, args ...interface{}) error {
+	obj := c.conn.Object(dbusServiceName, monitor.path)
+	err := obj.Call(dbusInterfaceMonitor+"."+method, 0, args...).Err
+	if err != nil {
+		return fmt.Errorf("%s %s: %v", monitor.Name, method, err)
+	}
+	return nil
+}
+
+func getAllProps(obj dbus.BusObject, iface string) (map[string]dbus.Variant, error) {
+	var props map[string]dbus.Variant
+	err := obj.Call(dbusPropsInterface+".GetAll", 0, iface).Store(&props)
+	return props, err
+}
+
+// storeProps 把 props 中的属性保存到 dest 中，key 是属性名，value 是指针
+func storeProps(props map[string]dbus.Variant, dest map[string]interface{}) error {
+	for name, ptr := range dest {
+		value, ok := props[name]
+		if !ok {
+			return fmt.Errorf("property %s not found", name)
+		}
+		err := dbus.Store([]interface{}{value.Value()}, ptr)
+		if err != nil {
+			return fmt.Errorf("store property %s failed: %v", name, err)
+		}
+	}
+	return nil
+}
+
+func (c *client) getDisplayInfo() (*displayInfo, error) {
+	props, err := getAllProps(c.obj, dbusInterface)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		displayMode byte
+		primary     string
+		paths       []dbus.ObjectPath
+		brightness  map[string]float64
+	)
+	err = storeProps(props, map[string]interface{}{
+		"DisplayMode": &displayMode,
+		"Primary":     &primary,
+		"Monitors":    &paths,
+		"Brightness":  &brightness,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	info := &displayInfo{
+		DisplayMode: displayModeName(displayMode),
+		Primary:     primary,
+	}
+	for _, path := range paths {
+		monitor, err := c.getMonitorInfo(path)
+		if err != nil {
+			return nil, err
+		}
+		monitor.Primary = monitor.Name == primary
+		monitor.Brightness = brightness[monitor.Name]
+		info.Monitors = append(info.Monitors, monitor)
+	}
+	sort.Slice(info.Monitors, func(i, j int) bool {
+		return info.Monitors[i].Name < info.Monitors[j].Name
+	})
+	return info, nil
+}
+
+func (c *client) getMonitorInfo(path dbus.ObjectPath) (*monitorInfo, error) {
+	props, err := getAllProps(c.conn.Object(dbusServiceName, path), dbusInterfaceMonitor)
+	if err != nil {
+		return nil, err
+	}
+	monitor := &monitorInfo{path: path}
+	var rotation uint16
+	err = storeProps(props, map[string]interface{}{
+		"Name":         &monitor.Name,
+		"Manufacturer": &monitor.Manufacturer,
+		"Model":        &monitor.Model,
+		"Connected":    &monitor.Connected,
+		"Enabled":      &monitor.Enabled,
+		"X":            &monitor.X,
+		"Y":            &monitor.Y,
+		"Width":        &monitor.Width,
+		"Height":       &monitor.Height,
+		"Rotation":     &rotation,
+		"RefreshRate":  &monitor.RefreshRate,
+		"CurrentMode":  &monitor.CurrentMode,
+		"BestMode":     &monitor.BestMode,
+		"Modes":        &monitor.Modes,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("get monitor %s properties failed: %v", path, err)
+	}
+	monitor.Rotation = rotationDegrees(rotation)
+	return monitor, nil
+}
+
+func (info *displayInfo) getMonitor(name string) (*monitorInfo, error) {
+	for _, monitor := range info.Monitors {
+		if monitor.Name == name {
+			return monitor, nil
+		}
+	}
+	return nil, fmt.Errorf("monitor %q not found", name)
+}
+
+// findMode 在显示器的模式中查找大小为 width x height 的模式，rate 为 0 时选择刷新率最高的
+func (monitor *monitorInfo) findMode(width, height uint16, rate float64) (ModeInfo, error) {
+	var result ModeInfo
+	found := false
+	for _, mode := range monitor.Modes {
+		if mode.Width != width || mode.Height != height {
+			continue
+		}
+		if rate != 0 {
+			if int(mode.Rate*100+0.5) == int(rate*100+0.5) {
+				return mode, nil
+			}
+			continue
+		}
+		if !found || mode.Rate > result.Rate {
+			result = mode
+			found = true
+		}
+	}
+	if !found {
+		return ModeInfo{}, fmt.Errorf("monitor %s has no mode %dx%d@%v", monitor.Name, width, height, rate)
+	}
+	return result, nil
+}
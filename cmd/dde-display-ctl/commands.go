@@ -0,0 +1,203 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+)
+
+func cmdList(c *client, args []string) error {
+	err := checkArgs(args, 0, 0, "list")
+	if err != nil {
+		return err
+	}
+	info, err := c.getDisplayInfo()
+	if err != nil {
+		return err
+	}
+	if optJSON {
+		return printJSON(info)
+	}
+	printDisplayInfo(os.Stdout, info)
+	return nil
+}
+
+func cmdSwitch(c *client, args []string) error {
+	fs := newFlagSet("switch")
+	optConfirm := fs.Uint("confirm", 0, "revert if not confirmed in seconds")
+	_ = fs.Parse(args)
+	args = fs.Args()
+	err := checkArgs(args, 1, 2, "switch [-confirm seconds] mirror|extend|only-one [name]")
+	if err != nil {
+		return err
+	}
+	mode, err := parseDisplayMode(args[0])
+	if err != nil {
+		return err
+	}
+	var name string
+	if len(args) == 2 {
+		name = args[1]
+	}
+	if mode == displayModeOnlyOne && name == "" {
+		return errors.New("only-one mode requires a monitor name")
+	}
+	return c.switchMode(mode, name, *optConfirm)
+}
+
+func cmdSet(c *client, args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: dde-display-ctl set <monitor> [options]")
+	}
+	name := args[0]
+	fs := newFlagSet("set " + name)
+	optMode := fs.String("mode", "", "mode WIDTHxHEIGHT[@RATE]")
+	optPos := fs.String("pos", "", "position X,Y")
+	optRotation := fs.Int("rotation", -1, "rotation 0, 90, 180 or 270")
+	optEnable := fs.Bool("enable", false, "enable the monitor")
+	optDisable := fs.Bool("disable", false, "disable the monitor")
+	optNoApply := fs.Bool("no-apply", false, "only set, apply later with the apply command")
+	optConfirm := fs.Uint("confirm", 0, "revert if not confirmed in seconds")
+	_ = fs.Parse(args[1:])
+	if fs.NArg() != 0 {
+		return fmt.Errorf("unexpected arguments %v", fs.Args())
+	}
+	if *optEnable && *optDisable {
+		return errors.New("-enable and -disable are mutually exclusive")
+	}
+
+	info, err := c.getDisplayInfo()
+	if err != nil {
+		return err
+	}
+	monitor, err := info.getMonitor(name)
+	if err != nil {
+		return err
+	}
+
+	changes := monitorConfig{
+		Name:     monitor.Name,
+		Enabled:  monitor.Enabled,
+		X:        monitor.X,
+		Y:        monitor.Y,
+		Rotation: monitor.Rotation,
+	}
+	if *optEnable {
+		changes.Enabled = true
+	} else if *optDisable {
+		changes.Enabled = false
+	}
+	if *optMode != "" {
+		changes.Width, changes.Height, changes.RefreshRate, err = parseMode(*optMode)
+		if err != nil {
+			return err
+		}
+	}
+	if *optPos != "" {
+		changes.X, changes.Y, err = parsePosition(*optPos)
+		if err != nil {
+			return err
+		}
+	}
+	if *optRotation != -1 {
+		changes.Rotation = *optRotation
+	}
+
+	err = c.setMonitor(monitor, &changes)
+	if err != nil {
+		return err
+	}
+	if *optNoApply {
+		return nil
+	}
+	return c.apply(*optConfirm)
+}
+
+func cmdPrimary(c *client, args []string) error {
+	err := checkArgs(args, 1, 1, "primary <monitor>")
+	if err != nil {
+		return err
+	}
+	return c.call("SetPrimary", args[0])
+}
+
+func cmdBrightness(c *client, args []string) error {
+	err := checkArgs(args, 2, 2, "brightness <monitor> <value>")
+	if err != nil {
+		return err
+	}
+	value, err := parseBrightness(args[1])
+	if err != nil {
+		return err
+	}
+	return c.call("SetAndSaveBrightness", args[0], value)
+}
+
+func cmdApply(c *client, args []string) error {
+	fs := newFlagSet("apply")
+	optConfirm := fs.Uint("confirm", 0, "revert if not confirmed in seconds")
+	_ = fs.Parse(args)
+	if fs.NArg() != 0 {
+		return fmt.Errorf("unexpected arguments %v", fs.Args())
+	}
+	return c.apply(*optConfirm)
+}
+
+func cmdDump(c *client, args []string) error {
+	fs := newFlagSet("dump")
+	optOutput := fs.String("o", "", "write to file instead of stdout")
+	_ = fs.Parse(args)
+	if fs.NArg() != 0 {
+		return fmt.Errorf("unexpected arguments %v", fs.Args())
+	}
+
+	info, err := c.getDisplayInfo()
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(toDisplayConfig(info), "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+	if *optOutput == "" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	return ioutil.WriteFile(*optOutput, data, 0644)
+}
+
+func cmdImport(c *client, args []string) error {
+	fs := newFlagSet("import")
+	optConfirm := fs.Uint("confirm", 0, "revert if not confirmed in seconds")
+	_ = fs.Parse(args)
+	if fs.NArg() != 1 {
+		return errors.New("usage: dde-display-ctl import [-confirm seconds] <file>")
+	}
+
+	data, err := ioutil.ReadFile(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	var cfg displayConfig
+	err = json.Unmarshal(data, &cfg)
+	if err != nil {
+		return fmt.Errorf("parse %s failed: %v", fs.Arg(0), err)
+	}
+	return c.importConfig(&cfg, *optConfirm)
+}
+
+func printJSON(v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(data))
+	return nil
+}
@@ -0,0 +1,335 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/godbus/dbus"
+)
+
+// displayConfig dump 命令输出的配置，import 命令通过 Display 的方法应用
+type displayConfig struct {
+	DisplayMode string
+	Primary     string `json:",omitempty"`
+	Monitors    []monitorConfig
+}
+
+type monitorConfig struct {
+	Name        string
+	Enabled     bool
+	X           int16
+	Y           int16
+	Width       uint16
+	Height      uint16
+	RefreshRate float64
+	// 旋转角度，0、90、180 或 270
+	Rotation   int
+	Brightness float64 `json:",omitempty"`
+}
+
+func toDisplayConfig(info *displayInfo) *displayConfig {
+	cfg := &displayConfig{
+		DisplayMode: info.DisplayMode,
+		Primary:     info.Primary,
+	}
+	for _, monitor := range info.Monitors {
+		if !monitor.Connected {
+			continue
+		}
+		cfg.Monitors = append(cfg.Monitors, monitorConfig{
+			Name:        monitor.Name,
+			Enabled:     monitor.Enabled,
+			X:           monitor.X,
+			Y:           monitor.Y,
+			Width:       monitor.CurrentMode.Width,
+			Height:      monitor.CurrentMode.Height,
+			RefreshRate: monitor.CurrentMode.Rate,
+			Rotation:    monitor.Rotation,
+			Brightness:  monitor.Brightness,
+		})
+	}
+	return cfg
+}
+
+// setMonitor 把显示器设置为 cfg，只调用有改变的方法，需要调用 apply 应用
+func (c *client) setMonitor(monitor *monitorInfo, cfg *monitorConfig) error {
+	if cfg.Enabled != monitor.Enabled {
+		err := c.callMonitor(monitor, "Enable", cfg.Enabled)
+		if err != nil {
+			return err
+		}
+	}
+	if !cfg.Enabled {
+		return nil
+	}
+
+	if cfg.Width != 0 && cfg.Height != 0 {
+		mode, err := monitor.findMode(cfg.Width, cfg.Height, cfg.RefreshRate)
+		if err != nil {
+			return err
+		}
+		if mode.Id != monitor.CurrentMode.Id {
+			err = c.callMonitor(monitor, "SetMode", mode.Id)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if cfg.X != monitor.X || cfg.Y != monitor.Y {
+		err := c.callMonitor(monitor, "SetPosition", cfg.X, cfg.Y)
+		if err != nil {
+			return err
+		}
+	}
+
+	if cfg.Rotation != monitor.Rotation {
+		rotation, err := rotationValue(cfg.Rotation)
+		if err != nil {
+			return err
+		}
+		err = c.callMonitor(monitor, "SetRotation", rotation)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *client) hasChanged() (bool, error) {
+	value, err := c.obj.GetProperty(dbusInterface + ".HasChanged")
+	if err != nil {
+		return false, err
+	}
+	hasChanged, ok := value.Value().(bool)
+	if !ok {
+		return false, errors.New("invalid type of property HasChanged")
+	}
+	return hasChanged, nil
+}
+
+// apply 应用改变并保存，confirm 不为 0 时需要用户在 confirm 秒内确认，否则恢复
+func (c *client) apply(confirm uint) error {
+	hasChanged, err := c.hasChanged()
+	if err != nil {
+		return err
+	}
+	if !hasChanged {
+		return nil
+	}
+
+	if confirm == 0 {
+		err = c.call("ApplyChanges")
+		if err != nil {
+			return err
+		}
+		return c.call("Save")
+	}
+
+	// 信号的 sender 是服务的 unique name
+	var owner string
+	err = c.conn.BusObject().Call("org.freedesktop.DBus.GetNameOwner", 0, dbusServiceName).Store(&owner)
+	if err != nil {
+		return err
+	}
+	rule := fmt.Sprintf("type='signal',sender='%s',path='%s',interface='%s',member='ChangesReverted'",
+		dbusServiceName, dbusPath, dbusInterface)
+	err = c.conn.BusObject().Call("org.freedesktop.DBus.AddMatch", 0, rule).Err
+	if err != nil {
+		return err
+	}
+	signalCh := make(chan *dbus.Signal, 10)
+	c.conn.Signal(signalCh)
+	defer c.conn.RemoveSignal(signalCh)
+
+	err = c.call("ApplyChangesWithTimeout", uint32(confirm))
+	if err != nil {
+		return err
+	}
+
+	confirmed, answered := askConfirm(time.Duration(confirm) * time.Second)
+	if confirmed {
+		return c.call("ConfirmChanges")
+	}
+	if answered {
+		// 用户拒绝时立即恢复，不用等到超时
+		err = c.call("ResetChanges")
+		if err != nil {
+			return err
+		}
+		return errors.New("changes reverted")
+	}
+
+	fmt.Fprintln(os.Stderr, "waiting for the changes to be reverted")
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case sig := <-signalCh:
+			if sig.Sender == owner && sig.Path == dbusPath && sig.Name == dbusInterface+".ChangesReverted" {
+				return errors.New("changes reverted")
+			}
+		case <-timeout:
+			return errors.New("changes not confirmed")
+		}
+	}
+}
+
+// confirmOrRevert 询问用户是否保留 SwitchMode 的改变，SwitchMode 会直接保存，
+// 用户没有确认时重新导入改变之前的配置 oldCfg
+func (c *client) confirmOrRevert(oldCfg *displayConfig, confirm uint) error {
+	if confirm == 0 {
+		return nil
+	}
+	confirmed, _ := askConfirm(time.Duration(confirm) * time.Second)
+	if confirmed {
+		return nil
+	}
+	fmt.Fprintln(os.Stderr, "reverting the changes")
+	err := c.importConfig(oldCfg, 0)
+	if err != nil {
+		return fmt.Errorf("revert changes failed: %v", err)
+	}
+	return errors.New("changes reverted")
+}
+
+// switchMode 切换显示模式，confirm 不为 0 时需要用户在 confirm 秒内确认，否则恢复
+func (c *client) switchMode(mode byte, name string, confirm uint) error {
+	info, err := c.getDisplayInfo()
+	if err != nil {
+		return err
+	}
+	err = c.call("SwitchMode", mode, name)
+	if err != nil {
+		return err
+	}
+	return c.confirmOrRevert(toDisplayConfig(info), confirm)
+}
+
+// askConfirm 在终端中询问用户是否保留改变，answered 表示用户在超时之前做了回答
+func askConfirm(timeout time.Duration) (confirmed, answered bool) {
+	fmt.Fprintf(os.Stderr, "Keep this configuration? It will be reverted in %v. [y/N] ", timeout)
+	answerCh := make(chan string, 1)
+	go func() {
+		line, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		answerCh <- line
+	}()
+
+	select {
+	case answer := <-answerCh:
+		answer = strings.ToLower(strings.TrimSpace(answer))
+		return answer == "y" || answer == "yes", true
+	case <-time.After(timeout):
+		fmt.Fprintln(os.Stderr)
+		return false, false
+	}
+}
+
+// importConfig 通过 SwitchMode、SetPrimary 和 Monitor 的方法应用配置，
+// 配置中没有连接的显示器被忽略
+func (c *client) importConfig(cfg *displayConfig, confirm uint) error {
+	mode, err := parseDisplayMode(cfg.DisplayMode)
+	if err != nil {
+		return err
+	}
+	info, err := c.getDisplayInfo()
+	if err != nil {
+		return err
+	}
+
+	switch mode {
+	case displayModeMirror:
+		err = c.switchMode(mode, "", confirm)
+	case displayModeOnlyOne:
+		var name string
+		for _, monitorCfg := range cfg.Monitors {
+			if monitorCfg.Enabled {
+				name = monitorCfg.Name
+				break
+			}
+		}
+		if name == "" {
+			return errors.New("no enabled monitor in only-one mode")
+		}
+		err = c.switchMode(mode, name, confirm)
+	default:
+		err = c.importLayout(cfg, info, confirm)
+	}
+	if err != nil {
+		return err
+	}
+
+	// 切换模式后显示器的状态已经改变
+	info, err = c.getDisplayInfo()
+	if err != nil {
+		return err
+	}
+
+	if cfg.Primary != "" && cfg.Primary != info.Primary {
+		err = c.call("SetPrimary", cfg.Primary)
+		if err != nil {
+			return err
+		}
+	}
+	for _, monitorCfg := range cfg.Monitors {
+		if monitorCfg.Brightness == 0 {
+			continue
+		}
+		monitor, err := info.getMonitor(monitorCfg.Name)
+		if err != nil || !monitor.Connected || monitor.Brightness == monitorCfg.Brightness {
+			continue
+		}
+		err = c.call("SetAndSaveBrightness", monitorCfg.Name, monitorCfg.Brightness)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// importLayout 应用扩展模式下每个显示器的模式、位置和旋转
+// 需要先切换到扩展模式时，确认要覆盖模式的切换，由 confirmOrRevert 恢复
+func (c *client) importLayout(cfg *displayConfig, info *displayInfo, confirm uint) error {
+	oldCfg := toDisplayConfig(info)
+	switched := false
+	if info.DisplayMode != displayModeName(displayModeExtend) {
+		err := c.call("SwitchMode", displayModeExtend, "")
+		if err != nil {
+			return err
+		}
+		switched = true
+		info, err = c.getDisplayInfo()
+		if err != nil {
+			return err
+		}
+	}
+
+	for i := range cfg.Monitors {
+		monitorCfg := &cfg.Monitors[i]
+		monitor, err := info.getMonitor(monitorCfg.Name)
+		if err != nil || !monitor.Connected {
+			fmt.Fprintf(os.Stderr, "monitor %s is not connected, ignore it\n", monitorCfg.Name)
+			continue
+		}
+		err = c.setMonitor(monitor, monitorCfg)
+		if err != nil {
+			_ = c.call("ResetChanges")
+			return err
+		}
+	}
+	if !switched {
+		return c.apply(confirm)
+	}
+	err := c.apply(0)
+	if err != nil {
+		return err
+	}
+	return c.confirmOrRevert(oldCfg, confirm)
+}
@@ -0,0 +1,91 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+)
+
+const usage = `Usage: dde-display-ctl [-json] <command> [arguments]
+
+Commands:
+  list                                  list monitors and their modes
+  switch [-confirm seconds] mirror|extend|only-one [name]
+                                        switch display mode
+  set <monitor> [options]               change mode, position or rotation of a monitor
+  primary <monitor>                     set the primary monitor
+  brightness <monitor> <value>          set and save brightness, value is in [0, 1]
+  apply [-confirm seconds]              apply pending changes
+  dump [-o file]                        dump current configuration as JSON
+  import [-confirm seconds] <file>      apply configuration dumped by dump
+`
+
+var optJSON bool
+
+type command struct {
+	name string
+	run  func(c *client, args []string) error
+}
+
+var commands = []command{
+	{"list", cmdList},
+	{"switch", cmdSwitch},
+	{"set", cmdSet},
+	{"primary", cmdPrimary},
+	{"brightness", cmdBrightness},
+	{"apply", cmdApply},
+	{"dump", cmdDump},
+	{"import", cmdImport},
+}
+
+func main() {
+	flag.BoolVar(&optJSON, "json", false, "output in JSON format")
+	flag.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	name := flag.Arg(0)
+	for _, cmd := range commands {
+		if cmd.name != name {
+			continue
+		}
+		c, err := newClient()
+		if err != nil {
+			fatal(err)
+		}
+		err = cmd.run(c, flag.Args()[1:])
+		if err != nil {
+			fatal(err)
+		}
+		return
+	}
+	fmt.Fprintf(os.Stderr, "unknown command %q\n", name)
+	flag.Usage()
+	os.Exit(2)
+}
+
+func fatal(err error) {
+	fmt.Fprintln(os.Stderr, "dde-display-ctl:", err)
+	os.Exit(1)
+}
+
+// newFlagSet 创建子命令的参数解析器，出错时退出
+func newFlagSet(name string) *flag.FlagSet {
+	return flag.NewFlagSet("dde-display-ctl "+name, flag.ExitOnError)
+}
+
+func checkArgs(args []string, min, max int, usage string) error {
+	if len(args) < min || len(args) > max {
+		return fmt.Errorf("usage: dde-display-ctl %s", usage)
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// printDisplayInfo 输出方便阅读的显示器信息，当前模式用 * 标记，最佳模式用 + 标记
+func printDisplayInfo(w io.Writer, info *displayInfo) {
+	fmt.Fprintf(w, "Display mode: %s\n", info.DisplayMode)
+	for _, monitor := range info.Monitors {
+		var attrs []string
+		if monitor.Primary {
+			attrs = append(attrs, "primary")
+		}
+		if !monitor.Connected {
+			attrs = append(attrs, "disconnected")
+		} else if !monitor.Enabled {
+			attrs = append(attrs, "disabled")
+		}
+		fmt.Fprintf(w, "%s", monitor.Name)
+		if len(attrs) > 0 {
+			fmt.Fprintf(w, " (%s)", strings.Join(attrs, ", "))
+		}
+		if product := strings.TrimSpace(monitor.Manufacturer + " " + monitor.Model); product != "" {
+			fmt.Fprintf(w, " %s", product)
+		}
+		fmt.Fprintln(w)
+		if !monitor.Connected {
+			continue
+		}
+
+		if monitor.Enabled {
+			fmt.Fprintf(w, "    %dx%d+%d+%d rotation %d brightness %.0f%%\n",
+				monitor.Width, monitor.Height, monitor.X, monitor.Y,
+				monitor.Rotation, monitor.Brightness*100)
+		}
+		for _, mode := range monitor.Modes {
+			mark := ""
+			if monitor.Enabled && mode.Id == monitor.CurrentMode.Id {
+				mark += "*"
+			}
+			if mode.Id == monitor.BestMode.Id {
+				mark += "+"
+			}
+			fmt.Fprintf(w, "    %-20s %s\n", mode, mark)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// 与 Display 服务中的 DisplayMode 对应
+const (
+	displayModeCustom byte = iota
+	displayModeMirror
+	displayModeExtend
+	displayModeOnlyOne
+)
+
+var displayModeNames = map[byte]string{
+	displayModeCustom:  "custom",
+	displayModeMirror:  "mirror",
+	displayModeExtend:  "extend",
+	displayModeOnlyOne: "only-one",
+}
+
+func displayModeName(mode byte) string {
+	name, ok := displayModeNames[mode]
+	if !ok {
+		return "unknown"
+	}
+	return name
+}
+
+func parseDisplayMode(name string) (byte, error) {
+	for mode, modeName := range displayModeNames {
+		if modeName == name {
+			return mode, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid display mode %q", name)
+}
+
+// randr 中的旋转值
+const (
+	rotationRotate0   uint16 = 1
+	rotationRotate90  uint16 = 2
+	rotationRotate180 uint16 = 4
+	rotationRotate270 uint16 = 8
+)
+
+var rotationDegreesMap = map[uint16]int{
+	rotationRotate0:   0,
+	rotationRotate90:  90,
+	rotationRotate180: 180,
+	rotationRotate270: 270,
+}
+
+func rotationDegrees(rotation uint16) int {
+	return rotationDegreesMap[rotation]
+}
+
+func rotationValue(degrees int) (uint16, error) {
+	for rotation, d := range rotationDegreesMap {
+		if d == degrees {
+			return rotation, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid rotation %d, must be 0, 90, 180 or 270", degrees)
+}
+
+// parseMode 解析 WIDTHxHEIGHT[@RATE] 格式的模式，没有 RATE 时 rate 为 0
+func parseMode(str string) (width, height uint16, rate float64, err error) {
+	size := str
+	if idx := strings.IndexByte(str, '@'); idx != -1 {
+		size = str[:idx]
+		rate, err = strconv.ParseFloat(str[idx+1:], 64)
+		if err != nil || rate <= 0 {
+			return 0, 0, 0, fmt.Errorf("invalid refresh rate in mode %q", str)
+		}
+	}
+	parts := strings.Split(size, "x")
+	if len(parts) != 2 {
+		return 0, 0, 0, fmt.Errorf("invalid mode %q, must be WIDTHxHEIGHT[@RATE]", str)
+	}
+	w, err1 := strconv.ParseUint(parts[0], 10, 16)
+	h, err2 := strconv.ParseUint(parts[1], 10, 16)
+	if err1 != nil || err2 != nil || w == 0 || h == 0 {
+		return 0, 0, 0, fmt.Errorf("invalid mode %q, must be WIDTHxHEIGHT[@RATE]", str)
+	}
+	return uint16(w), uint16(h), rate, nil
+}
+
+// parsePosition 解析 X,Y 格式的位置
+func parsePosition(str string) (x, y int16, err error) {
+	parts := strings.Split(str, ",")
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("invalid position %q, must be X,Y", str)
+	}
+	x0, err1 := strconv.ParseInt(strings.TrimSpace(parts[0]), 10, 16)
+	y0, err2 := strconv.ParseInt(strings.TrimSpace(parts[1]), 10, 16)
+	if err1 != nil || err2 != nil {
+		return 0, 0, fmt.Errorf("invalid position %q, must be X,Y", str)
+	}
+	return int16(x0), int16(y0), nil
+}
+
+// parseBrightness 解析亮度，支持 0.8 和 80% 两种格式
+func parseBrightness(str string) (float64, error) {
+	percent := strings.HasSuffix(str, "%")
+	value, err := strconv.ParseFloat(strings.TrimSuffix(str, "%"), 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid brightness %q", str)
+	}
+	if percent {
+		value /= 100
+	}
+	if value < 0 || value > 1 {
+		return 0, fmt.Errorf("brightness %q out of range", str)
+	}
+	return value, nil
+}
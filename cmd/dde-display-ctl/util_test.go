@@ -0,0 +1,97 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseMode(t *testing.T) {
+	w, h, rate, err := parseMode("1920x1080")
+	assert.NoError(t, err)
+	assert.Equal(t, uint16(1920), w)
+	assert.Equal(t, uint16(1080), h)
+	assert.Equal(t, 0.0, rate)
+
+	w, h, rate, err = parseMode("2560x1440@143.97")
+	assert.NoError(t, err)
+	assert.Equal(t, uint16(2560), w)
+	assert.Equal(t, uint16(1440), h)
+	assert.InDelta(t, 143.97, rate, 0.001)
+
+	for _, str := range []string{"", "1920", "1920x", "0x1080", "1920x1080@", "1920x1080@-60", "99999x1080"} {
+		_, _, _, err = parseMode(str)
+		assert.Error(t, err, str)
+	}
+}
+
+func TestParsePosition(t *testing.T) {
+	x, y, err := parsePosition("-1920, 0")
+	assert.NoError(t, err)
+	assert.Equal(t, int16(-1920), x)
+	assert.Equal(t, int16(0), y)
+
+	_, _, err = parsePosition("10")
+	assert.Error(t, err)
+	_, _, err = parsePosition("a,b")
+	assert.Error(t, err)
+}
+
+func TestParseBrightness(t *testing.T) {
+	value, err := parseBrightness("0.5")
+	assert.NoError(t, err)
+	assert.InDelta(t, 0.5, value, 0.001)
+	value, err = parseBrightness("80%")
+	assert.NoError(t, err)
+	assert.InDelta(t, 0.8, value, 0.001)
+
+	_, err = parseBrightness("120%")
+	assert.Error(t, err)
+	_, err = parseBrightness("high")
+	assert.Error(t, err)
+}
+
+func TestRotation(t *testing.T) {
+	for _, degrees := range []int{0, 90, 180, 270} {
+		rotation, err := rotationValue(degrees)
+		assert.NoError(t, err)
+		assert.Equal(t, degrees, rotationDegrees(rotation))
+	}
+	_, err := rotationValue(45)
+	assert.Error(t, err)
+}
+
+func TestDisplayMode(t *testing.T) {
+	mode, err := parseDisplayMode("only-one")
+	assert.NoError(t, err)
+	assert.Equal(t, displayModeOnlyOne, mode)
+	assert.Equal(t, "extend", displayModeName(displayModeExtend))
+	assert.Equal(t, "unknown", displayModeName(10))
+	_, err = parseDisplayMode("single")
+	assert.Error(t, err)
+}
+
+func TestFindMode(t *testing.T) {
+	monitor := &monitorInfo{
+		Name: "HDMI-1",
+		Modes: []ModeInfo{
+			{Id: 1, Width: 1920, Height: 1080, Rate: 60},
+			{Id: 2, Width: 1920, Height: 1080, Rate: 74.97},
+			{Id: 3, Width: 1280, Height: 720, Rate: 60},
+		},
+	}
+	mode, err := monitor.findMode(1920, 1080, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(2), mode.Id)
+	mode, err = monitor.findMode(1920, 1080, 60)
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(1), mode.Id)
+	_, err = monitor.findMode(1280, 720, 50)
+	assert.Error(t, err)
+	_, err = monitor.findMode(800, 600, 0)
+	assert.Error(t, err)
+}
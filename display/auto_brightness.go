@@ -0,0 +1,599 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package display
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/godbus/dbus"
+	"github.com/linuxdeepin/go-lib/dbusutil"
+)
+
+// iio-sensor-proxy 提供的服务
+const (
+	hadessSensorProxyService   = "net.hadess.SensorProxy"
+	hadessSensorProxyPath      = "/net/hadess/SensorProxy"
+	hadessSensorProxyInterface = "net.hadess.SensorProxy"
+
+	// LightLevelUnit 为 vendor 时读数不是光照度，无法对应到曲线上
+	hadessLightLevelUnitLux = "lux"
+
+	dbusPropsInterface           = "org.freedesktop.DBus.Properties"
+	dbusPropsChangedSignalMember = "PropertiesChanged"
+)
+
+const (
+	// 每次平滑的步长，光照值每隔 autoBrightnessSmoothInterval 向读数靠近这个比例
+	autoBrightnessSmoothFactor   = 0.3
+	autoBrightnessSmoothInterval = 500 * time.Millisecond
+	// 目标亮度和当前亮度相差超过这个值才调节，避免光照轻微变化时亮度来回跳动
+	autoBrightnessHysteresis = 0.05
+	// 训练时去掉新的点附近的点，log10(lux+1) 的距离
+	autoBrightnessTrainDistance = 0.25
+)
+
+// AutoBrightnessConfig 自动亮度的配置，保存在用户配置中
+type AutoBrightnessConfig struct {
+	Enabled bool
+	// 用户训练过的光照和亮度的曲线，为空时使用默认曲线
+	Curve []LuxCurvePoint `json:",omitempty"`
+}
+
+// LuxCurvePoint 光照度曲线上的点，Brightness 范围是 0 到 1
+type LuxCurvePoint struct {
+	Lux        float64
+	Brightness float64
+}
+
+func (c *AutoBrightnessConfig) clone() *AutoBrightnessConfig {
+	if c == nil {
+		return nil
+	}
+	cfg := *c
+	cfg.Curve = luxCurve(c.Curve).clone()
+	return &cfg
+}
+
+func (c *AutoBrightnessConfig) getCurve() luxCurve {
+	if c == nil || len(c.Curve) == 0 {
+		return defaultLuxCurve()
+	}
+	return luxCurve(c.Curve).clone()
+}
+
+// luxCurve 光照到亮度的映射，点按照 Lux 从小到大排列，Brightness 单调不减
+type luxCurve []LuxCurvePoint
+
+func defaultLuxCurve() luxCurve {
+	return luxCurve{
+		{Lux: 0, Brightness: 0.15},
+		{Lux: 10, Brightness: 0.3},
+		{Lux: 100, Brightness: 0.5},
+		{Lux: 1000, Brightness: 0.8},
+		{Lux: 10000, Brightness: 1},
+	}
+}
+
+func (c luxCurve) clone() luxCurve {
+	if c == nil {
+		return nil
+	}
+	result := make(luxCurve, len(c))
+	copy(result, c)
+	return result
+}
+
+// luxToLog 人眼对光照的感受接近对数关系，曲线在 log10(lux+1) 上插值
+func luxToLog(lux float64) float64 {
+	if lux < 0 {
+		lux = 0
+	}
+	return math.Log10(lux + 1)
+}
+
+// brightness 计算光照度 lux 对应的亮度，超出曲线范围时使用两端的值
+func (c luxCurve) brightness(lux float64) float64 {
+	if len(c) == 0 {
+		return 1
+	}
+	x := luxToLog(lux)
+	if x <= luxToLog(c[0].Lux) {
+		return c[0].Brightness
+	}
+	for i := 1; i < len(c); i++ {
+		x1 := luxToLog(c[i].Lux)
+		if x > x1 {
+			continue
+		}
+		x0 := luxToLog(c[i-1].Lux)
+		if x1 == x0 {
+			return c[i].Brightness
+		}
+		ratio := (x - x0) / (x1 - x0)
+		return c[i-1].Brightness + ratio*(c[i].Brightness-c[i-1].Brightness)
+	}
+	return c[len(c)-1].Brightness
+}
+
+// train 根据用户在光照度 lux 下手动调节的亮度生成新的曲线。
+// 去掉新的点附近的点和与它矛盾的点（光照更暗但是更亮，或者光照更亮但是更暗），保持曲线单调。
+func (c luxCurve) train(lux, brightness float64) luxCurve {
+	brightness = math.Max(0, math.Min(1, brightness))
+	if lux < 0 {
+		lux = 0
+	}
+	x := luxToLog(lux)
+	result := make(luxCurve, 0, len(c)+1)
+	for _, p := range c {
+		px := luxToLog(p.Lux)
+		if math.Abs(px-x) < autoBrightnessTrainDistance {
+			continue
+		}
+		if (px < x && p.Brightness > brightness) || (px > x && p.Brightness < brightness) {
+			continue
+		}
+		result = append(result, p)
+	}
+	result = append(result, LuxCurvePoint{Lux: lux, Brightness: brightness})
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Lux < result[j].Lux
+	})
+	return result
+}
+
+// smoothLuxLog 把平滑后的光照值向读数靠近一步，都是 log10(lux+1) 的值，足够接近时直接等于读数
+func smoothLuxLog(current, reading float64) float64 {
+	next := current + (reading-current)*autoBrightnessSmoothFactor
+	if math.Abs(reading-next) < 0.01 {
+		return reading
+	}
+	return next
+}
+
+// needAdjustBrightness 判断是否需要把亮度从 current 调节到 target，current 小于 0 表示还没有自动设置过
+func needAdjustBrightness(current, target float64) bool {
+	if current < 0 {
+		return true
+	}
+	return math.Abs(target-current) >= autoBrightnessHysteresis
+}
+
+// autoBrightnessEngine 读取环境光传感器，根据光照度曲线自动调节亮度，
+// 计算出的亮度通过 cb 设置。
+type autoBrightnessEngine struct {
+	mu       sync.Mutex
+	conn     *dbus.Conn
+	sigLoop  *dbusutil.SignalLoop
+	running  bool
+	stopCh   chan struct{}
+	updateCh chan struct{}
+	// 暂停时只更新光照值，不调节亮度，比如电源模块调暗屏幕时
+	paused bool
+	curve  luxCurve
+	// 传感器的读数和平滑后的值，都是 log10(lux+1)
+	reading  float64
+	smoothed float64
+	hasLevel bool
+	// 传感器的读数单位是 lux
+	unitLux bool
+	// 上次自动设置的亮度，小于 0 表示还没有设置
+	applied float64
+	// 正在跟随光照变化调节亮度
+	adjusting bool
+	cb        func(value float64)
+
+	signalAdded bool
+}
+
+func newAutoBrightnessEngine() *autoBrightnessEngine {
+	return &autoBrightnessEngine{
+		updateCh: make(chan struct{}, 1),
+		applied:  -1,
+		curve:    defaultLuxCurve(),
+	}
+}
+
+func (e *autoBrightnessEngine) sensor() dbus.BusObject {
+	return e.conn.Object(hadessSensorProxyService, hadessSensorProxyPath)
+}
+
+func (e *autoBrightnessEngine) getSensorProp(name string) (dbus.Variant, error) {
+	return e.sensor().GetProperty(hadessSensorProxyInterface + "." + name)
+}
+
+// start 向 SensorProxy 申请读取环境光传感器，开始自动调节亮度
+func (e *autoBrightnessEngine) start() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.running {
+		return nil
+	}
+	if e.conn == nil {
+		return errors.New("bus connection is nil")
+	}
+	logger.Debug("autoBrightnessEngine.start")
+
+	// 信号处理只添加一次，停止后忽略收到的信号
+	if !e.signalAdded {
+		err := e.conn.BusObject().AddMatchSignal(dbusPropsInterface, dbusPropsChangedSignalMember,
+			dbus.WithMatchObjectPath(hadessSensorProxyPath), dbus.WithMatchSender(hadessSensorProxyService)).Err
+		if err != nil {
+			return err
+		}
+		e.sigLoop.AddHandler(&dbusutil.SignalRule{
+			Path: hadessSensorProxyPath,
+			Name: dbusPropsInterface + "." + dbusPropsChangedSignalMember,
+		}, e.handlePropsChanged)
+		err = watchNameOwner(e.conn, e.sigLoop, hadessSensorProxyService, e.handleOwnerChanged)
+		if err != nil {
+			logger.Warning("failed to watch iio-sensor-proxy:", err)
+		}
+		e.signalAdded = true
+	}
+	err := e.claimNoLock()
+	if err != nil {
+		return err
+	}
+
+	e.running = true
+	e.hasLevel = false
+	e.applied = -1
+	e.adjusting = false
+	e.paused = false
+	e.stopCh = make(chan struct{})
+	go e.run(e.stopCh)
+
+	e.readLevelNoLock()
+	return nil
+}
+
+// claimNoLock 检查传感器并调用 ClaimLight，读数单位不是 lux 时不能自动调节
+func (e *autoBrightnessEngine) claimNoLock() error {
+	hasLight, err := e.getSensorProp("HasAmbientLight")
+	if err != nil {
+		return err
+	}
+	if v, _ := hasLight.Value().(bool); !v {
+		return errors.New("no ambient light sensor")
+	}
+	unit, err := e.getSensorProp("LightLevelUnit")
+	if err != nil {
+		return err
+	}
+	if v, _ := unit.Value().(string); v != hadessLightLevelUnitLux {
+		return fmt.Errorf("ambient light sensor unit is %q, not lux", v)
+	}
+	err = e.sensor().Call(hadessSensorProxyInterface+".ClaimLight", 0).Err
+	if err != nil {
+		return err
+	}
+	e.unitLux = true
+	return nil
+}
+
+func (e *autoBrightnessEngine) readLevelNoLock() {
+	level, err := e.getSensorProp("LightLevel")
+	if err != nil {
+		logger.Warning("get light level failed:", err)
+	} else if v, ok := level.Value().(float64); ok {
+		e.setReadingNoLock(v)
+	}
+}
+
+// handleOwnerChanged iio-sensor-proxy 重启后之前的申请失效，运行中时重新申请
+func (e *autoBrightnessEngine) handleOwnerChanged(newOwner string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if !e.running || newOwner == "" {
+		return
+	}
+	logger.Debug("iio-sensor-proxy restarted, claim light again")
+	err := e.claimNoLock()
+	if err != nil {
+		logger.Warning("claim light failed:", err)
+		return
+	}
+	e.readLevelNoLock()
+}
+
+func (e *autoBrightnessEngine) stop() {
+	e.mu.Lock()
+	if !e.running {
+		e.mu.Unlock()
+		return
+	}
+	logger.Debug("autoBrightnessEngine.stop")
+	e.running = false
+	close(e.stopCh)
+	e.stopCh = nil
+	e.mu.Unlock()
+
+	err := e.sensor().Call(hadessSensorProxyInterface+".ReleaseLight", 0).Err
+	if err != nil {
+		logger.Warning("release light failed:", err)
+	}
+}
+
+func (e *autoBrightnessEngine) handlePropsChanged(sig *dbus.Signal) {
+	var iface string
+	var changed map[string]dbus.Variant
+	var invalidated []string
+	err := dbus.Store(sig.Body, &iface, &changed, &invalidated)
+	if err != nil {
+		logger.Warning(err)
+		return
+	}
+	if iface != hadessSensorProxyInterface {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if unit, ok := changed["LightLevelUnit"]; ok {
+		v, _ := unit.Value().(string)
+		e.unitLux = v == hadessLightLevelUnitLux
+		if !e.unitLux {
+			logger.Warningf("ambient light sensor unit changed to %q, ignore light level", v)
+		}
+	}
+	level, ok := changed["LightLevel"]
+	if !ok {
+		return
+	}
+	v, ok := level.Value().(float64)
+	if !ok {
+		return
+	}
+	if e.running && e.unitLux {
+		e.setReadingNoLock(v)
+	}
+}
+
+// setReadingNoLock 更新传感器读数，第一次读数直接使用，不用平滑
+func (e *autoBrightnessEngine) setReadingNoLock(lux float64) {
+	e.reading = luxToLog(lux)
+	if !e.hasLevel {
+		e.smoothed = e.reading
+		e.hasLevel = true
+	}
+	select {
+	case e.updateCh <- struct{}{}:
+	default:
+	}
+}
+
+// run 在读数变化后定时平滑光照值，直到平滑后的值等于读数
+func (e *autoBrightnessEngine) run(stopCh chan struct{}) {
+	var ticker *time.Ticker
+	var tickC <-chan time.Time
+	defer func() {
+		if ticker != nil {
+			ticker.Stop()
+		}
+	}()
+
+	for {
+		select {
+		case <-stopCh:
+			return
+		case <-e.updateCh:
+		case <-tickC:
+		}
+
+		if e.step(stopCh) {
+			if ticker != nil {
+				ticker.Stop()
+				ticker = nil
+				tickC = nil
+			}
+		} else if ticker == nil {
+			ticker = time.NewTicker(autoBrightnessSmoothInterval)
+			tickC = ticker.C
+		}
+	}
+}
+
+// step 平滑一次光照值，需要时调节亮度，平滑后的值已经等于读数时返回 true
+func (e *autoBrightnessEngine) step(stopCh chan struct{}) bool {
+	e.mu.Lock()
+	if !e.hasLevel || e.stopCh != stopCh {
+		e.mu.Unlock()
+		return true
+	}
+	e.smoothed = smoothLuxLog(e.smoothed, e.reading)
+	done := e.smoothed == e.reading
+	if e.paused {
+		e.mu.Unlock()
+		return done
+	}
+	target := e.curve.brightness(math.Pow(10, e.smoothed) - 1)
+	if !e.adjusting {
+		if !needAdjustBrightness(e.applied, target) {
+			e.mu.Unlock()
+			return done
+		}
+		// 超过阈值后一直调节到光照稳定，避免最终的亮度和目标相差一个阈值
+		e.adjusting = true
+	}
+	if done {
+		e.adjusting = false
+	}
+	if target == e.applied {
+		e.mu.Unlock()
+		return done
+	}
+	e.applied = target
+	e.mu.Unlock()
+
+	logger.Debugf("auto brightness: lux %.1f, brightness %.2f", math.Pow(10, e.smoothed)-1, target)
+	if e.cb != nil {
+		e.cb(target)
+	}
+	return done
+}
+
+// train 用户在自动亮度打开时手动调节了亮度，用当前的光照值训练曲线，返回新的曲线
+func (e *autoBrightnessEngine) train(brightness float64) (luxCurve, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if !e.running || !e.hasLevel {
+		return nil, false
+	}
+	lux := math.Pow(10, e.smoothed) - 1
+	e.curve = e.curve.train(lux, brightness)
+	e.applied = brightness
+	e.paused = false
+	logger.Debugf("auto brightness trained: lux %.1f, brightness %.2f, curve %v", lux, brightness, e.curve)
+	return e.curve.clone(), true
+}
+
+func (e *autoBrightnessEngine) setCurve(curve luxCurve) {
+	e.mu.Lock()
+	e.curve = curve
+	e.mu.Unlock()
+}
+
+func (e *autoBrightnessEngine) isRunning() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.running
+}
+
+// setPaused 暂停或者恢复自动调节，恢复时重新设置一次亮度
+func (e *autoBrightnessEngine) setPaused(paused bool) {
+	e.mu.Lock()
+	e.paused = paused
+	if !paused {
+		e.applied = -1
+	}
+	e.mu.Unlock()
+	if !paused {
+		select {
+		case e.updateCh <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// dbus 上导出的方法
+func (m *Manager) setAutoBrightness(enabled bool) error {
+	if enabled {
+		if m.getBuiltinMonitor() == nil {
+			return errors.New("no builtin monitor")
+		}
+		err := m.autoBrightness.start()
+		if err != nil {
+			return err
+		}
+	} else {
+		m.autoBrightness.stop()
+	}
+
+	m.userCfgMu.Lock()
+	if m.userConfig.AutoBrightness == nil {
+		m.userConfig.AutoBrightness = &AutoBrightnessConfig{}
+	}
+	m.userConfig.AutoBrightness.Enabled = enabled
+	err := m.saveUserConfigNoLock()
+	m.userCfgMu.Unlock()
+
+	m.PropsMu.Lock()
+	m.setPropAutoBrightness(enabled)
+	m.PropsMu.Unlock()
+
+	if !enabled {
+		// 恢复到保存的亮度
+		m.restoreBuiltinBrightness()
+	}
+	return err
+}
+
+func (m *Manager) restoreBuiltinBrightness() {
+	builtin := m.getBuiltinMonitor()
+	if builtin == nil {
+		return
+	}
+	monitors := m.getConnectedMonitors()
+	configs := m.getSuitableSysMonitorConfigs(m.DisplayMode, monitors.getMonitorsId(), monitors)
+	for _, config := range configs {
+		if config.Name == builtin.Name && config.Enabled {
+			err := m.setBrightnessAndSync(config.Name, config.Brightness)
+			if err != nil {
+				logger.Warning(err)
+			}
+			return
+		}
+	}
+}
+
+// trainAutoBrightness 用户手动调节了内置显示器的亮度，打开了自动亮度时训练曲线并保存
+func (m *Manager) trainAutoBrightness(name string, value float64) {
+	builtin := m.getBuiltinMonitor()
+	if builtin == nil || builtin.Name != name {
+		return
+	}
+	curve, ok := m.autoBrightness.train(value)
+	if !ok {
+		return
+	}
+	m.userCfgMu.Lock()
+	if m.userConfig.AutoBrightness == nil {
+		m.userConfig.AutoBrightness = &AutoBrightnessConfig{Enabled: true}
+	}
+	m.userConfig.AutoBrightness.Curve = curve
+	err := m.saveUserConfigNoLock()
+	m.userCfgMu.Unlock()
+	if err != nil {
+		logger.Warning(err)
+	}
+}
+
+// pauseAutoBrightness 其他模块临时设置内置显示器的亮度时暂停自动调节
+func (m *Manager) pauseAutoBrightness(name string, paused bool) {
+	builtin := m.getBuiltinMonitor()
+	if builtin == nil || (name != "" && builtin.Name != name) {
+		return
+	}
+	if m.autoBrightness.isRunning() {
+		m.autoBrightness.setPaused(paused)
+	}
+}
+
+// initAutoBrightness 从用户配置中初始化自动亮度，在有内置显示器时才启动
+func (m *Manager) initAutoBrightness() {
+	m.userCfgMu.Lock()
+	cfg := m.userConfig.AutoBrightness.clone()
+	m.userCfgMu.Unlock()
+
+	m.autoBrightness.conn, m.autoBrightness.sigLoop = m.sysBus, m.sysSigLoop
+	if m.debugOpts.sensorProxyOnSessionBus {
+		m.autoBrightness.conn, m.autoBrightness.sigLoop = m.service.Conn(), m.sessionSigLoop
+	}
+	m.autoBrightness.setCurve(cfg.getCurve())
+	m.autoBrightness.cb = func(value float64) {
+		builtin := m.getBuiltinMonitor()
+		if builtin == nil {
+			return
+		}
+		err := m.setBrightnessAndSync(builtin.Name, value)
+		if err != nil {
+			logger.Warning(err)
+		}
+	}
+
+	if cfg == nil || !cfg.Enabled || m.getBuiltinMonitor() == nil {
+		return
+	}
+	err := m.autoBrightness.start()
+	if err != nil {
+		logger.Warning("start auto brightness failed:", err)
+		return
+	}
+	m.setPropAutoBrightness(true)
+}
@@ -0,0 +1,175 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package display
+
+import (
+	"testing"
+
+	"github.com/godbus/dbus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLuxCurve_brightness(t *testing.T) {
+	curve := luxCurve{
+		{Lux: 0, Brightness: 0.2},
+		{Lux: 99, Brightness: 0.6},
+		{Lux: 9999, Brightness: 1},
+	}
+	assert.Equal(t, 0.2, curve.brightness(0))
+	assert.Equal(t, 0.2, curve.brightness(-5))
+	assert.InDelta(t, 0.6, curve.brightness(99), 1e-9)
+	assert.Equal(t, 1.0, curve.brightness(50000))
+	// 在 log10(lux+1) 上插值，9 lux 在 0 和 99 lux 的中间
+	assert.InDelta(t, 0.4, curve.brightness(9), 1e-9)
+	assert.InDelta(t, 0.8, curve.brightness(999), 1e-9)
+
+	assert.Equal(t, 1.0, luxCurve(nil).brightness(100))
+}
+
+func TestLuxCurve_train(t *testing.T) {
+	curve := defaultLuxCurve()
+
+	// 替换附近的点
+	trained := curve.train(110, 0.6)
+	assert.Equal(t, luxCurve{
+		{Lux: 0, Brightness: 0.15},
+		{Lux: 10, Brightness: 0.3},
+		{Lux: 110, Brightness: 0.6},
+		{Lux: 1000, Brightness: 0.8},
+		{Lux: 10000, Brightness: 1},
+	}, trained)
+	assert.InDelta(t, 0.6, trained.brightness(110), 1e-9)
+	// 原来的曲线不变
+	assert.Equal(t, defaultLuxCurve(), curve)
+
+	// 去掉矛盾的点，保持单调
+	trained = curve.train(300, 0.9)
+	assert.Equal(t, luxCurve{
+		{Lux: 0, Brightness: 0.15},
+		{Lux: 10, Brightness: 0.3},
+		{Lux: 100, Brightness: 0.5},
+		{Lux: 300, Brightness: 0.9},
+		{Lux: 10000, Brightness: 1},
+	}, trained)
+
+	trained = curve.train(0, 0.05)
+	assert.Equal(t, LuxCurvePoint{Lux: 0, Brightness: 0.05}, trained[0])
+	assert.Len(t, trained, 5)
+
+	trained = curve.train(20000, 2)
+	assert.Equal(t, LuxCurvePoint{Lux: 20000, Brightness: 1}, trained[len(trained)-1])
+}
+
+func TestAutoBrightnessConfig_getCurve(t *testing.T) {
+	var cfg *AutoBrightnessConfig
+	assert.Equal(t, defaultLuxCurve(), cfg.getCurve())
+
+	cfg = &AutoBrightnessConfig{Curve: []LuxCurvePoint{{Lux: 5, Brightness: 0.5}}}
+	curve := cfg.getCurve()
+	curve[0].Brightness = 1
+	assert.Equal(t, 0.5, cfg.Curve[0].Brightness)
+	assert.Equal(t, 0.5, cfg.clone().Curve[0].Brightness)
+}
+
+func TestSmoothLuxLog(t *testing.T) {
+	v := smoothLuxLog(0, 3)
+	assert.InDelta(t, 0.9, v, 1e-9)
+
+	for i := 0; i < 100 && v != 3; i++ {
+		v = smoothLuxLog(v, 3)
+	}
+	assert.Equal(t, 3.0, v)
+}
+
+func TestNeedAdjustBrightness(t *testing.T) {
+	assert.True(t, needAdjustBrightness(-1, 0.5))
+	assert.False(t, needAdjustBrightness(0.5, 0.53))
+	assert.False(t, needAdjustBrightness(0.5, 0.47))
+	assert.True(t, needAdjustBrightness(0.5, 0.56))
+	assert.True(t, needAdjustBrightness(0.5, 0.44))
+}
+
+func TestAutoBrightnessEngine_step(t *testing.T) {
+	e := newAutoBrightnessEngine()
+	e.curve = luxCurve{{Lux: 0, Brightness: 0.2}, {Lux: 9999, Brightness: 1}}
+	var values []float64
+	e.cb = func(value float64) {
+		values = append(values, value)
+	}
+	stopCh := make(chan struct{})
+	e.stopCh = stopCh
+
+	// 还没有读数
+	assert.True(t, e.step(stopCh))
+	assert.Empty(t, values)
+
+	// 第一次读数直接使用
+	e.setReadingNoLock(99)
+	assert.True(t, e.step(stopCh))
+	assert.Len(t, values, 1)
+	assert.InDelta(t, 0.6, values[0], 1e-9)
+
+	// 光照轻微变化，不调节
+	e.setReadingNoLock(110)
+	for !e.step(stopCh) {
+	}
+	assert.Len(t, values, 1)
+
+	// 光照变化大时逐渐调节到目标亮度
+	e.setReadingNoLock(9999)
+	for !e.step(stopCh) {
+	}
+	assert.True(t, len(values) > 2)
+	assert.Equal(t, 1.0, values[len(values)-1])
+	for i := 1; i < len(values); i++ {
+		assert.True(t, values[i] > values[i-1])
+	}
+
+	// 暂停时不调节
+	values = nil
+	e.paused = true
+	e.setReadingNoLock(0)
+	for !e.step(stopCh) {
+	}
+	assert.Empty(t, values)
+
+	// 被停止的 run 不调节
+	e.paused = false
+	e.applied = -1
+	assert.True(t, e.step(make(chan struct{})))
+	assert.Empty(t, values)
+}
+
+func TestAutoBrightnessEngine_handlePropsChanged(t *testing.T) {
+	e := newAutoBrightnessEngine()
+	e.running = true
+	e.unitLux = true
+	propsChanged := func(changed map[string]dbus.Variant) *dbus.Signal {
+		return &dbus.Signal{
+			Path: hadessSensorProxyPath,
+			Name: dbusPropsInterface + "." + dbusPropsChangedSignalMember,
+			Body: []interface{}{hadessSensorProxyInterface, changed, []string{}},
+		}
+	}
+
+	e.handlePropsChanged(propsChanged(map[string]dbus.Variant{"LightLevel": dbus.MakeVariant(99.0)}))
+	assert.True(t, e.hasLevel)
+	assert.Equal(t, 2.0, e.reading)
+
+	// 单位变成 vendor 后忽略读数
+	e.handlePropsChanged(propsChanged(map[string]dbus.Variant{
+		"LightLevelUnit": dbus.MakeVariant("vendor"),
+		"LightLevel":     dbus.MakeVariant(9999.0),
+	}))
+	assert.False(t, e.unitLux)
+	assert.Equal(t, 2.0, e.reading)
+
+	e.handlePropsChanged(propsChanged(map[string]dbus.Variant{
+		"LightLevelUnit": dbus.MakeVariant(hadessLightLevelUnitLux),
+		"LightLevel":     dbus.MakeVariant(9.0),
+	}))
+	assert.True(t, e.unitLux)
+	assert.Equal(t, 1.0, e.reading)
+}
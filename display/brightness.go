@@ -69,14 +69,6 @@ func (m *Manager) saveBrightnessInCfg(valueMap map[string]float64) error {
 }
 
 func (m *Manager) changeBrightness(raised bool) error {
-	var step = 0.05
-	if m.MaxBacklightBrightness < 100 && m.MaxBacklightBrightness != 0 {
-		step = 1 / float64(m.MaxBacklightBrightness)
-	}
-	if !raised {
-		step = -step
-	}
-
 	monitors := m.getConnectedMonitors()
 
 	successMap := make(map[string]float64)
@@ -92,6 +84,10 @@ func (m *Manager) changeBrightness(raised bool) error {
 			v = 1.0
 		}
 
+		step := m.getBrightnessStep(monitor)
+		if !raised {
+			step = -step
+		}
 		var br float64
 		br = v + step
 		if br > 1.0 {
@@ -107,6 +103,7 @@ func (m *Manager) changeBrightness(raised bool) error {
 			continue
 		}
 		successMap[monitor.Name] = br
+		m.trainAutoBrightness(monitor.Name, br)
 	}
 	err := m.saveBrightnessInCfg(successMap)
 	if err != nil {
@@ -186,6 +183,8 @@ func (m *Manager) setMonitorBrightness(monitor *Monitor, brightnessValue float64
 
 	isBuiltin := m.isBuiltinMonitor(monitor.Name)
 	edid := encodeEdidBase64(monitor.edid)
+	// 所有的设置方式都按照亮度曲线转换
+	brightnessValue = m.getBrightnessCurve(monitor.uuid).Apply(brightnessValue)
 	err := brightness.Set(brightnessValue, temperature, m.getBrightnessSetter(), isBuiltin,
 		monitor.ID, m.xConn, monitor.uuid, edid)
 	return err
@@ -206,8 +205,8 @@ func (m *Manager) setBrightnessAux(fake bool, name string, value float64) error
 	if !fake && enabled {
 		temperature := m.getColorTemperatureValue()
 		// 保持最小亮度，不能全黑
-		if value <= 0.1 {
-			value = 0.1
+		if minValue := m.getBrightnessCurve(monitor.uuid).MinValue(); value <= minValue {
+			value = minValue
 		}
 		err := m.setMonitorBrightness(monitor, value, temperature)
 		if err != nil {
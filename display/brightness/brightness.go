@@ -5,8 +5,10 @@
 package brightness
 
 import (
+	"errors"
 	"fmt"
 	"math"
+	"sync"
 
 	"github.com/godbus/dbus"
 	backlight "github.com/linuxdeepin/go-dbus-factory/com.deepin.daemon.helper.backlight"
@@ -15,6 +17,8 @@ import (
 	"github.com/linuxdeepin/go-lib/multierr"
 	x "github.com/linuxdeepin/go-x11-client"
 	"github.com/linuxdeepin/go-x11-client/ext/randr"
+	"github.com/linuxdeepin/startdde/display/ddcci"
+	"github.com/linuxdeepin/startdde/display/icc"
 )
 
 var _useWayland bool
@@ -54,54 +58,43 @@ func Set(brightness float64, temperature int, setter string, isBuiltin bool, out
 	}
 
 	output := randr.Output(outputId)
+	setter = ResolveSetter(setter, isBuiltin, edidBase64)
 
-	// 亮度和色温分开设置，亮度用背光，色温用 gamma
-	setBlGamma := func() error {
-		var errs error
-		err := setBacklight(brightness, output, conn)
-		if err != nil {
-			errs = multierr.Append(errs, err)
-		}
-
-		err = setOutputCrtcGamma(gammaSetting{
-			brightness:  1,
-			temperature: temperature,
-		}, output, conn)
-		if err != nil {
-			errs = multierr.Append(errs, err)
-		}
-		return errs
-	}
-
-	// 亮度和色温都用 gamma 值设置
-	setGamma := func() error {
-		return setOutputCrtcGamma(gammaSetting{
-			brightness:  brightness,
-			temperature: temperature,
-		}, output, conn)
-	}
-
-	setFn := setGamma
+	// 自动检测仅自适应backlight和ddcci亮度调节
+	// 若两种都不支持，使用gamma调节
+	var errs error
+	var err error
 	switch setter {
 	case SetterBacklight:
-		setFn = setBlGamma
-	case SetterAuto:
-		// 自动检测仅自适应backlight和ddcci亮度调节
-		// 若两种都不支持，使用gamma调节
-		if isBuiltin && supportBacklight() {
-			setFn = setBlGamma
-		} else if supportDDCCIBrightness(edidBase64) {
-			return setDDCCIBrightness(brightness, edidBase64)
-		}
+		err = setBacklight(brightness, output, conn)
 	case SetterDDCCI:
-		return setDDCCIBrightness(brightness, edidBase64)
+		err = setDDCCIBrightness(brightness, edidBase64)
 	/* DRM 目前暂无检查是否支持接口，根据硬件驱动进行gsetting配置调节 */
 	case SetterDRM:
-		err := setBrigntnessByKwin(uuid, brightness)
-		return err
-		//case SetterGamma
+		err = setBrigntnessByKwin(uuid, brightness)
+	}
+	if err != nil {
+		errs = multierr.Append(errs, err)
+	}
+
+	err = setOutputCrtcGamma(getGammaSetting(setter, brightness, temperature, outputId), output, conn)
+	if err != nil {
+		errs = multierr.Append(errs, err)
+	}
+	return errs
+}
+
+// getGammaSetting 用背光、DDC/CI 或者 DRM 调节亮度时，gamma 只用于色温和校准曲线
+func getGammaSetting(setter string, brightness float64, temperature int, outputId uint32) gammaSetting {
+	switch setter {
+	case SetterBacklight, SetterDDCCI, SetterDRM:
+		brightness = 1
+	}
+	return gammaSetting{
+		brightness:  brightness,
+		temperature: temperature,
+		calibration: getCalibration(outputId),
 	}
-	return setFn()
 }
 
 // unused function
@@ -176,12 +169,41 @@ func setOutputCrtcGamma(setting gammaSetting, output randr.Output, conn *x.Conn)
 		return fmt.Errorf("output(%v) has invalid gamma size", output)
 	}
 
-	red, green, blue := initGammaRamp(int(gamma.Size))
+	var red, green, blue []uint16
+	if setting.calibration != nil {
+		// 在校准曲线的基础上调节亮度和色温
+		red, green, blue = setting.calibration.Resample(int(gamma.Size))
+	} else {
+		red, green, blue = initGammaRamp(int(gamma.Size))
+	}
 	fillColorRamp(red, green, blue, setting)
 	return randr.SetCrtcGammaChecked(conn, outputInfo.Crtc,
 		red, green, blue).Check(conn)
 }
 
+var (
+	// 各个 output 的校准曲线，来自 ICC 配置文件的 vcgt 标签
+	calibrations   = make(map[uint32]*icc.Vcgt)
+	calibrationsMu sync.Mutex
+)
+
+// SetCalibration 设置 output 的校准曲线，vcgt 为 nil 时清除，在下次设置亮度或色温时生效
+func SetCalibration(outputId uint32, vcgt *icc.Vcgt) {
+	calibrationsMu.Lock()
+	defer calibrationsMu.Unlock()
+	if vcgt == nil {
+		delete(calibrations, outputId)
+		return
+	}
+	calibrations[outputId] = vcgt
+}
+
+func getCalibration(outputId uint32) *icc.Vcgt {
+	calibrationsMu.Lock()
+	defer calibrationsMu.Unlock()
+	return calibrations[outputId]
+}
+
 func initGammaRamp(size int) (red, green, blue []uint16) {
 	red = make([]uint16, size)
 	green = make([]uint16, size)
@@ -255,6 +277,12 @@ func setBrigntnessByKwin(output string, value float64) error {
 }
 
 func supportDDCCIBrightness(edidBase64 string) bool {
+	if getDDCCIDisplay(edidBase64) != nil {
+		return true
+	}
+	if helper == nil {
+		return false
+	}
 	res, err := helper.CheckCfgSupport(0, "ddcci")
 	if err != nil {
 		logger.Warningf("brightness: failed to check ddc/ci support: %v", err)
@@ -294,6 +322,9 @@ func ReflashDDCCIDisplay() {
 }
 
 func setDDCCIBrightness(value float64, edidBase64 string) error {
+	if display := getDDCCIDisplay(edidBase64); display != nil {
+		return display.setBrightness(value)
+	}
 	res, err := helper.CheckCfgSupport(0, "ddcci")
 	if err != nil {
 		logger.Warningf("brightness: failed to check ddc/ci support: %v", err)
@@ -309,6 +340,9 @@ func setDDCCIBrightness(value float64, edidBase64 string) error {
 }
 
 func getDDCCIBrightness(edidBase64 string) (float64, error) {
+	if display := getDDCCIDisplay(edidBase64); display != nil {
+		return display.getBrightness()
+	}
 	br, err := ddcciHelper.GetBrightness(0, edidBase64)
 	if err != nil {
 		return 1, err
@@ -316,3 +350,70 @@ func getDDCCIBrightness(edidBase64 string) (float64, error) {
 		return (float64(br) / 100.0), err
 	}
 }
+
+// ddcciDisplay 直接通过 /dev/i2c 访问的显示器，记录亮度的最大值
+type ddcciDisplay struct {
+	display *ddcci.Display
+	max     uint16
+}
+
+var (
+	ddcciDisplays   = make(map[string]*ddcciDisplay)
+	ddcciDisplaysMu sync.Mutex
+)
+
+// SetDDCCIDisplay 设置 EDID 为 edidBase64 的显示器直接访问的 DDC/CI 设备，display 为 nil 时清除。
+// 设置后这个显示器的亮度不再通过系统的 DDC/CI 服务调节。
+func SetDDCCIDisplay(edidBase64 string, display *ddcci.Display) {
+	ddcciDisplaysMu.Lock()
+	defer ddcciDisplaysMu.Unlock()
+	if display == nil {
+		delete(ddcciDisplays, edidBase64)
+		return
+	}
+	ddcciDisplays[edidBase64] = &ddcciDisplay{display: display}
+}
+
+func getDDCCIDisplay(edidBase64 string) *ddcciDisplay {
+	ddcciDisplaysMu.Lock()
+	defer ddcciDisplaysMu.Unlock()
+	return ddcciDisplays[edidBase64]
+}
+
+func (d *ddcciDisplay) getMax() (uint16, error) {
+	ddcciDisplaysMu.Lock()
+	max := d.max
+	ddcciDisplaysMu.Unlock()
+	if max != 0 {
+		return max, nil
+	}
+	_, max, err := d.display.GetVCP(ddcci.VCPBrightness)
+	if err != nil {
+		return 0, err
+	}
+	if max == 0 {
+		return 0, errors.New("brightness: invalid ddc/ci max brightness 0")
+	}
+	ddcciDisplaysMu.Lock()
+	d.max = max
+	ddcciDisplaysMu.Unlock()
+	return max, nil
+}
+
+func (d *ddcciDisplay) setBrightness(value float64) error {
+	max, err := d.getMax()
+	if err != nil {
+		return err
+	}
+	v := uint16(math.Round(value * float64(max)))
+	logger.Debugf("brightness: ddcci set brightness %d/%d", v, max)
+	return d.display.SetVCP(ddcci.VCPBrightness, v)
+}
+
+func (d *ddcciDisplay) getBrightness() (float64, error) {
+	current, max, err := d.display.GetVCP(ddcci.VCPBrightness)
+	if err != nil || max == 0 {
+		return 1, err
+	}
+	return float64(current) / float64(max), nil
+}
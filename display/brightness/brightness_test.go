@@ -0,0 +1,41 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package brightness
+
+import (
+	"testing"
+
+	"github.com/linuxdeepin/startdde/display/icc"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_getGammaSetting(t *testing.T) {
+	const outputId = 100
+	vcgt := &icc.Vcgt{
+		Red:   []uint16{0, 30000, 65535},
+		Green: []uint16{0, 32000, 65535},
+		Blue:  []uint16{0, 34000, 65535},
+	}
+	SetCalibration(outputId, vcgt)
+	defer SetCalibration(outputId, nil)
+
+	// DDC/CI 等方式调节亮度时，gamma 仍然应用色温和校准曲线
+	for _, setter := range []string{SetterDDCCI, SetterDRM, SetterBacklight} {
+		assert.Equal(t, gammaSetting{
+			brightness:  1,
+			temperature: 5000,
+			calibration: vcgt,
+		}, getGammaSetting(setter, 0.5, 5000, outputId), setter)
+	}
+
+	assert.Equal(t, gammaSetting{
+		brightness:  0.5,
+		temperature: 5000,
+		calibration: vcgt,
+	}, getGammaSetting(SetterGamma, 0.5, 5000, outputId))
+
+	SetCalibration(outputId, nil)
+	assert.Nil(t, getGammaSetting(SetterDDCCI, 0.5, 5000, outputId).calibration)
+}
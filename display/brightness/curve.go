@@ -0,0 +1,54 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package brightness
+
+import (
+	"math"
+)
+
+// Curve 把用户设置的亮度转换成实际设置的亮度，背光、gamma、DDC/CI 等各种设置方式都使用转换后的值
+type Curve struct {
+	// 为 1 时是线性的，大于 1 时低亮度部分调节更细，接近人眼的感受
+	Gamma float64
+	// 实际设置的最小亮度，避免黑屏
+	Min float64
+}
+
+var LinearCurve = Curve{Gamma: 1}
+
+func (c Curve) gamma() float64 {
+	if c.Gamma <= 0 {
+		return 1
+	}
+	return c.Gamma
+}
+
+// Apply 计算用户设置的亮度 value 对应的实际亮度
+func (c Curve) Apply(value float64) float64 {
+	value = math.Max(0, math.Min(1, value))
+	return math.Max(c.Min, math.Pow(value, c.gamma()))
+}
+
+// MinValue 返回实际亮度为最小亮度时用户设置的亮度
+func (c Curve) MinValue() float64 {
+	if c.Min <= 0 {
+		return 0
+	}
+	return math.Pow(math.Min(c.Min, 1), 1/c.gamma())
+}
+
+// ResolveSetter 把 auto 解析为实际使用的设置方式，与 Set 中的选择一致
+func ResolveSetter(setter string, isBuiltin bool, edidBase64 string) string {
+	if setter != SetterAuto {
+		return setter
+	}
+	if isBuiltin && supportBacklight() {
+		return SetterBacklight
+	}
+	if supportDDCCIBrightness(edidBase64) {
+		return SetterDDCCI
+	}
+	return SetterGamma
+}
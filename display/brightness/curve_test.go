@@ -0,0 +1,29 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package brightness
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCurve_Apply(t *testing.T) {
+	assert.Equal(t, 0.5, LinearCurve.Apply(0.5))
+	assert.Equal(t, 1.0, LinearCurve.Apply(2))
+	assert.Equal(t, 0.0, LinearCurve.Apply(-1))
+	assert.Equal(t, 0.0, LinearCurve.MinValue())
+
+	// Gamma 为 0 时按线性处理
+	assert.Equal(t, 0.3, Curve{}.Apply(0.3))
+
+	curve := Curve{Gamma: 2, Min: 0.04}
+	assert.InDelta(t, 0.25, curve.Apply(0.5), 1e-9)
+	assert.Equal(t, 1.0, curve.Apply(1))
+	assert.Equal(t, 0.04, curve.Apply(0.1))
+	assert.Equal(t, 0.04, curve.Apply(0))
+	assert.InDelta(t, 0.2, curve.MinValue(), 1e-9)
+	assert.InDelta(t, 0.04, curve.Apply(curve.MinValue()), 1e-9)
+}
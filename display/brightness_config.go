@@ -0,0 +1,112 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package display
+
+import (
+	"github.com/linuxdeepin/startdde/display/brightness"
+)
+
+// 亮度曲线
+const (
+	// 用户设置的亮度就是实际的亮度
+	brightnessCurveLinear = "linear"
+	// 经过 gamma 校正，低亮度部分调节更细
+	brightnessCurvePerceptual = "perceptual"
+)
+
+const (
+	defaultBrightnessGamma = 2.2
+	// 保持最小亮度，不能全黑
+	defaultMinBrightness  = 0.1
+	defaultBrightnessStep = 0.05
+)
+
+// SysBrightnessConfig 亮度调节的配置，保存在系统配置中
+type SysBrightnessConfig struct {
+	// linear 或 perceptual，为空时是 linear
+	Curve string `json:",omitempty"`
+	// perceptual 曲线的 gamma 值，为 0 时是 2.2
+	Gamma float64 `json:",omitempty"`
+	// 实际设置的最小亮度，为 0 时是 0.1
+	MinBrightness float64 `json:",omitempty"`
+	// 每种设置方式的调节步长，key 是 backlight、gamma、ddcci 或 drm
+	Steps map[string]float64 `json:",omitempty"`
+	// 每个显示器的配置，key 是显示器的 uuid
+	Monitors map[string]SysMonitorBrightnessConfig `json:",omitempty"`
+}
+
+// SysMonitorBrightnessConfig 单个显示器的亮度调节配置，为 0 的值使用全局的配置
+type SysMonitorBrightnessConfig struct {
+	MinBrightness float64 `json:",omitempty"`
+	Step          float64 `json:",omitempty"`
+}
+
+// getCurve 获取显示器 uuid 的亮度曲线
+func (c *SysBrightnessConfig) getCurve(uuid string) brightness.Curve {
+	curve := brightness.Curve{Gamma: 1, Min: defaultMinBrightness}
+	if c == nil {
+		return curve
+	}
+	if c.Curve == brightnessCurvePerceptual {
+		curve.Gamma = defaultBrightnessGamma
+		if c.Gamma > 0 {
+			curve.Gamma = c.Gamma
+		}
+	}
+	if isValidMinBrightness(c.MinBrightness) {
+		curve.Min = c.MinBrightness
+	}
+	if v := c.Monitors[uuid].MinBrightness; isValidMinBrightness(v) {
+		curve.Min = v
+	}
+	return curve
+}
+
+// getStep 获取显示器 uuid 用 setter 方式调节亮度时的步长，没有配置时返回 0
+func (c *SysBrightnessConfig) getStep(uuid, setter string) float64 {
+	if c == nil {
+		return 0
+	}
+	if v := c.Monitors[uuid].Step; isValidBrightnessStep(v) {
+		return v
+	}
+	if v := c.Steps[setter]; isValidBrightnessStep(v) {
+		return v
+	}
+	return 0
+}
+
+func isValidMinBrightness(value float64) bool {
+	return value > 0 && value < 1
+}
+
+func isValidBrightnessStep(value float64) bool {
+	return value > 0 && value <= 0.5
+}
+
+func (m *Manager) getBrightnessCurve(uuid string) brightness.Curve {
+	m.sysConfig.mu.Lock()
+	defer m.sysConfig.mu.Unlock()
+	return m.sysConfig.Config.Brightness.getCurve(uuid)
+}
+
+// getBrightnessStep 获取通过键盘调节显示器亮度时的步长
+func (m *Manager) getBrightnessStep(monitor *Monitor) float64 {
+	setter := brightness.ResolveSetter(m.getBrightnessSetter(), m.isBuiltinMonitor(monitor.Name),
+		encodeEdidBase64(monitor.edid))
+
+	m.sysConfig.mu.Lock()
+	step := m.sysConfig.Config.Brightness.getStep(monitor.uuid, setter)
+	m.sysConfig.mu.Unlock()
+	if step > 0 {
+		return step
+	}
+
+	// 背光的级数比较少时，每次调节一级
+	if setter == brightness.SetterBacklight && m.MaxBacklightBrightness < 100 && m.MaxBacklightBrightness != 0 {
+		return 1 / float64(m.MaxBacklightBrightness)
+	}
+	return defaultBrightnessStep
+}
@@ -0,0 +1,53 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package display
+
+import (
+	"testing"
+
+	"github.com/linuxdeepin/startdde/display/brightness"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSysBrightnessConfig_getCurve(t *testing.T) {
+	var cfg *SysBrightnessConfig
+	// 默认与之前的行为一致，线性并且最小亮度是 0.1
+	assert.Equal(t, brightness.Curve{Gamma: 1, Min: 0.1}, cfg.getCurve("uuid1"))
+
+	cfg = &SysBrightnessConfig{
+		Curve:         brightnessCurvePerceptual,
+		MinBrightness: 0.02,
+		Monitors: map[string]SysMonitorBrightnessConfig{
+			"uuid2": {MinBrightness: 0.05},
+			"uuid3": {MinBrightness: 1.5},
+		},
+	}
+	assert.Equal(t, brightness.Curve{Gamma: defaultBrightnessGamma, Min: 0.02}, cfg.getCurve("uuid1"))
+	assert.Equal(t, brightness.Curve{Gamma: defaultBrightnessGamma, Min: 0.05}, cfg.getCurve("uuid2"))
+	assert.Equal(t, brightness.Curve{Gamma: defaultBrightnessGamma, Min: 0.02}, cfg.getCurve("uuid3"))
+
+	cfg.Gamma = 1.8
+	assert.Equal(t, 1.8, cfg.getCurve("uuid1").Gamma)
+	cfg.Curve = brightnessCurveLinear
+	assert.Equal(t, 1.0, cfg.getCurve("uuid1").Gamma)
+}
+
+func TestSysBrightnessConfig_getStep(t *testing.T) {
+	var cfg *SysBrightnessConfig
+	assert.Equal(t, 0.0, cfg.getStep("uuid1", brightness.SetterBacklight))
+
+	cfg = &SysBrightnessConfig{
+		Steps: map[string]float64{
+			brightness.SetterDDCCI: 0.1,
+			brightness.SetterGamma: 0,
+		},
+		Monitors: map[string]SysMonitorBrightnessConfig{
+			"uuid2": {Step: 0.02},
+		},
+	}
+	assert.Equal(t, 0.1, cfg.getStep("uuid1", brightness.SetterDDCCI))
+	assert.Equal(t, 0.0, cfg.getStep("uuid1", brightness.SetterGamma))
+	assert.Equal(t, 0.02, cfg.getStep("uuid2", brightness.SetterDDCCI))
+}
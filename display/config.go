@@ -29,6 +29,11 @@ var (
 )
 
 func init() {
+	initConfigFiles()
+}
+
+// initConfigFiles 根据用户的配置目录设置配置文件的路径
+func initConfigFiles() {
 	cfgDir := getCfgDir()
 	configFile = filepath.Join(cfgDir, "display.json")
 	configFileV5 = filepath.Join(cfgDir, "display_v5.json")
@@ -99,9 +104,12 @@ func loadConfigV5V6(filename string) (*ConfigV6, error) {
 	if err != nil {
 		return nil, err
 	}
+	return decodeConfigV5V6(data)
+}
 
+func decodeConfigV5V6(data []byte) (*ConfigV6, error) {
 	var c ConfigV6
-	err = json.Unmarshal(data, &c)
+	err := json.Unmarshal(data, &c)
 	if err != nil {
 		logger.Warning(err)
 	}
@@ -116,25 +124,17 @@ func loadConfigV5V6(filename string) (*ConfigV6, error) {
 	return &c, nil
 }
 
-// 加载旧配置文件,可能影响 m.DisplayMode 和系统配置中的 DisplayMode。
-func loadOldConfig(m *Manager) (*ConfigV6, error) {
-	var configV5 ConfigV5
+// 加载旧配置文件并升级为 v1 配置，可能影响 m.DisplayMode 和系统配置中的 DisplayMode。
+func loadOldConfig(m *Manager) (*configV1, error) {
 	cfgVer, err := getConfigVersion(configVersionFile)
 	if err == nil {
-		if cfgVer == "3.3" {
-			// 3.3配置文件转换
-			cfg0, err := loadConfigV3D3(configFile)
-			if err == nil {
-				configV5 = cfg0.toConfig(m)
-			} else if !os.IsNotExist(err) {
-				logger.Warning(err)
-			}
-		} else if cfgVer == "4.0" {
-			// 4.0配置文件转换
-			cfg0, err := loadConfigV4(configFile)
-			if err == nil {
-				configV5 = cfg0.toConfig(m)
-			} else if !os.IsNotExist(err) {
+		if cfgVer == "3.3" || cfgVer == "4.0" {
+			// 3.3 和 4.0 配置文件转换
+			cfg, err := loadLegacyConfig(m, cfgVer, configFile)
+			if err == nil && len(cfg.Sys.Screens) > 0 {
+				// 加载 v5 之前配置文件成功
+				return cfg, nil
+			} else if err != nil && !os.IsNotExist(err) {
 				logger.Warning(err)
 			}
 		}
@@ -142,19 +142,21 @@ func loadOldConfig(m *Manager) (*ConfigV6, error) {
 		logger.Warning(err)
 	}
 
-	if len(configV5) > 0 {
-		// 加载 v5 之前配置文件成功
-		configV6 := &ConfigV6{ConfigV5: configV5}
-		return configV6, nil
-	}
 	// NOTE: v5 到 v6 并没有更新 configVersionFile 的内容, 也使用同一个配置文件。
-	configV6, err := loadConfigV5V6(configFileV5)
+	return loadLegacyConfig(m, "5.0", configFileV5)
+}
+
+func loadLegacyConfig(m *Manager, version, filename string) (*configV1, error) {
+	// #nosec G304
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := legacyConfigMigrations.load(m, version, data)
 	if err != nil {
-		// 加载 v5 和 v6 配置文件都失败
 		return nil, err
 	}
-	// 加载 v5 或 v6 配置文件成功
-	return configV6, nil
+	return cfg.(*configV1), nil
 }
 
 func (m *Manager) saveBuiltinMonitorConfig(name string) (err error) {
@@ -0,0 +1,577 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package display
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/linuxdeepin/startdde/display/jsonschema"
+)
+
+const exportConfigVersion = "1.0"
+
+// ImportConfig 可以选择导入的模式
+const (
+	importModeMirror  = "mirror"
+	importModeExtend  = "extend"
+	importModeOnlyOne = "only-one"
+	importModeSingle  = "single"
+)
+
+// ExportedConfig ExportConfig 导出的显示配置，可以用 ImportConfig 导入
+type ExportedConfig struct {
+	Version string
+	// 只导出复制、扩展和单屏模式，其他的值不导出，导入时不改变显示模式
+	DisplayMode byte `json:",omitempty"`
+	// 系统级屏幕配置，key 是 monitorsId
+	Screens map[string]*SysScreenConfig
+	// 用户级屏幕配置，包括各模式的色温，key 是 monitorsId
+	UserScreens map[string]UserScreenConfig `json:",omitempty"`
+}
+
+// exportConfigMigrations 导出配置的版本，导入旧版本导出的配置时升级到最新版本
+var exportConfigMigrations = newConfigMigrations("exported", exportConfigVersion)
+
+func init() {
+	exportConfigMigrations.registerDecoder("1.0", func(data []byte) (interface{}, error) {
+		var cfg ExportedConfig
+		err := json.Unmarshal(data, &cfg)
+		if err != nil {
+			return nil, err
+		}
+		return &cfg, nil
+	})
+}
+
+// exportedConfigSchema 最新版本导出配置的 JSON Schema
+var exportedConfigSchema = jsonschema.MustParse(`{
+	"$schema": "http://json-schema.org/draft-07/schema#",
+	"type": "object",
+	"required": ["Version", "Screens"],
+	"additionalProperties": false,
+	"properties": {
+		"Version": {"type": "string", "minLength": 1},
+		"DisplayMode": {"type": "integer", "enum": [1, 2, 3]},
+		"Screens": {
+			"type": ["object", "null"],
+			"additionalProperties": {"$ref": "#/definitions/screen"}
+		},
+		"UserScreens": {
+			"type": ["object", "null"],
+			"additionalProperties": {
+				"type": ["object", "null"],
+				"additionalProperties": {"$ref": "#/definitions/userModeConfig"}
+			}
+		}
+	},
+	"definitions": {
+		"screen": {
+			"type": ["object", "null"],
+			"additionalProperties": false,
+			"properties": {
+				"Mirror": {"$ref": "#/definitions/modeConfig"},
+				"Extend": {"$ref": "#/definitions/modeConfig"},
+				"Single": {"$ref": "#/definitions/modeConfig"},
+				"OnlyOneMap": {
+					"type": ["object", "null"],
+					"additionalProperties": {"$ref": "#/definitions/modeConfig"}
+				},
+				"OnlyOneUuid": {"type": "string"}
+			}
+		},
+		"modeConfig": {
+			"type": ["object", "null"],
+			"additionalProperties": false,
+			"properties": {
+				"Monitors": {
+					"type": ["array", "null"],
+					"items": {"$ref": "#/definitions/monitor"}
+				}
+			}
+		},
+		"monitor": {
+			"type": "object",
+			"required": ["UUID", "Name"],
+			"additionalProperties": false,
+			"properties": {
+				"UUID": {"type": "string", "minLength": 1},
+				"Name": {"type": "string", "minLength": 1},
+				"Enabled": {"type": "boolean"},
+				"X": {"type": "integer", "minimum": -32768, "maximum": 32767},
+				"Y": {"type": "integer", "minimum": -32768, "maximum": 32767},
+				"Width": {"type": "integer", "minimum": 0, "maximum": 65535},
+				"Height": {"type": "integer", "minimum": 0, "maximum": 65535},
+				"Rotation": {"type": "integer", "enum": [0, 1, 2, 4, 8]},
+				"Reflect": {"type": "integer", "enum": [0, 16, 32, 48]},
+				"RefreshRate": {"type": "number", "minimum": 0},
+				"Brightness": {"type": "number", "minimum": 0, "maximum": 1},
+				"Primary": {"type": "boolean"},
+				"VrrPolicy": {"type": "string", "enum": ["", "never", "always", "fullscreen-only"]}
+			}
+		},
+		"userModeConfig": {
+			"type": ["object", "null"],
+			"additionalProperties": false,
+			"properties": {
+				"ColorTemperatureMode": {"type": "integer"},
+				"ColorTemperatureManual": {"type": "integer"}
+			}
+		}
+	}
+}`)
+
+func (m *Manager) exportConfig() (string, error) {
+	cfg := &ExportedConfig{
+		Version: exportConfigVersion,
+	}
+
+	m.sysConfig.mu.Lock()
+	cfg.DisplayMode = getExportedDisplayMode(m.sysConfig.Config.DisplayMode)
+	cfg.Screens = make(map[string]*SysScreenConfig, len(m.sysConfig.Config.Screens))
+	for monitorsId, screenCfg := range m.sysConfig.Config.Screens {
+		if screenCfg != nil {
+			cfg.Screens[monitorsId] = screenCfg.clone()
+		}
+	}
+	m.sysConfig.mu.Unlock()
+
+	m.userCfgMu.Lock()
+	if len(m.userConfig.Screens) > 0 {
+		cfg.UserScreens = make(map[string]UserScreenConfig, len(m.userConfig.Screens))
+		for monitorsId, screenCfg := range m.userConfig.Screens {
+			cfg.UserScreens[monitorsId] = screenCfg.clone()
+		}
+	}
+	m.userCfgMu.Unlock()
+
+	data, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// getExportedDisplayMode 与 exportedConfigSchema 中的 DisplayMode 一致
+func getExportedDisplayMode(displayMode byte) byte {
+	switch displayMode {
+	case DisplayModeMirror, DisplayModeExtend, DisplayModeOnlyOne:
+		return displayMode
+	}
+	return 0
+}
+
+// parseExportedConfig 解析导出的配置，旧版本的配置会升级到最新版本，然后按 JSON Schema 校验
+func parseExportedConfig(m *Manager, data []byte) (*ExportedConfig, error) {
+	version, err := getConfigDataVersion(data, "")
+	if err != nil {
+		return nil, err
+	}
+	if version == "" {
+		return nil, errors.New("missing Version")
+	}
+	if version == exportConfigVersion {
+		err = exportedConfigSchema.Validate(data)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	result, err := exportConfigMigrations.load(m, version, data)
+	if err != nil {
+		return nil, err
+	}
+	cfg := result.(*ExportedConfig)
+	if version != exportConfigVersion {
+		// 校验升级后的配置
+		data, err = json.Marshal(cfg)
+		if err != nil {
+			return nil, err
+		}
+		err = exportedConfigSchema.Validate(data)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return cfg, nil
+}
+
+// importFilter 导入时选择的显示器和模式，为空时表示全部
+type importFilter struct {
+	monitors map[string]bool
+	modes    map[string]bool
+}
+
+func newImportFilter(monitors, modes []string) (*importFilter, error) {
+	f := &importFilter{
+		monitors: make(map[string]bool, len(monitors)),
+		modes:    make(map[string]bool, len(modes)),
+	}
+	for _, monitor := range monitors {
+		f.monitors[monitor] = true
+	}
+	for _, mode := range modes {
+		switch mode {
+		case importModeMirror, importModeExtend, importModeOnlyOne, importModeSingle:
+			f.modes[mode] = true
+		default:
+			return nil, fmt.Errorf("invalid mode %q", mode)
+		}
+	}
+	return f, nil
+}
+
+func (f *importFilter) isAll() bool {
+	return len(f.monitors) == 0 && len(f.modes) == 0
+}
+
+func (f *importFilter) hasMode(mode string) bool {
+	return len(f.modes) == 0 || f.modes[mode]
+}
+
+// hasMonitor uuid 或名称是否被选择
+func (f *importFilter) hasMonitor(uuid, name string) bool {
+	return len(f.monitors) == 0 || f.monitors[uuid] || (name != "" && f.monitors[name])
+}
+
+// importMonitor 本机的显示器，用于把导入配置中的 uuid 对应到本机
+type importMonitor struct {
+	uuid   string
+	name   string
+	edidId string
+}
+
+// uuidMapper 把导入配置中的 uuid 对应到本机的显示器。
+// uuid 的格式是 名称|EDID 标识|v1，同一个显示器接到不同的接口时名称不同，按 EDID 标识对应。
+type uuidMapper struct {
+	monitors []importMonitor
+}
+
+func (m *Manager) newUuidMapper() *uuidMapper {
+	m.monitorMapMu.Lock()
+	defer m.monitorMapMu.Unlock()
+
+	mapper := &uuidMapper{}
+	for _, monitor := range m.monitorMap {
+		monitor.PropsMu.RLock()
+		mapper.monitors = append(mapper.monitors, importMonitor{
+			uuid:   monitor.uuid,
+			name:   monitor.Name,
+			edidId: getEdidId(monitor.edid),
+		})
+		monitor.PropsMu.RUnlock()
+	}
+	return mapper
+}
+
+func getUuidEdidId(uuid string) string {
+	parts := strings.Split(uuid, "|")
+	if len(parts) != 3 || parts[2] != "v1" {
+		return ""
+	}
+	return parts[1]
+}
+
+// lookup 查找 uuid 对应的本机显示器，没有或有多个 EDID 相同的显示器时返回 nil
+func (um *uuidMapper) lookup(uuid string) *importMonitor {
+	for i := range um.monitors {
+		if um.monitors[i].uuid == uuid {
+			return &um.monitors[i]
+		}
+	}
+	edidId := getUuidEdidId(uuid)
+	if edidId == "" {
+		return nil
+	}
+	var result *importMonitor
+	for i := range um.monitors {
+		if um.monitors[i].edidId != edidId {
+			continue
+		}
+		if result != nil {
+			return nil
+		}
+		result = &um.monitors[i]
+	}
+	return result
+}
+
+func (um *uuidMapper) mapUuid(uuid string) string {
+	if monitor := um.lookup(uuid); monitor != nil {
+		return monitor.uuid
+	}
+	return uuid
+}
+
+func (um *uuidMapper) getName(uuid string) string {
+	if monitor := um.lookup(uuid); monitor != nil {
+		return monitor.name
+	}
+	return ""
+}
+
+// mapMonitorsId 对应 monitorsId 中的每个 uuid，对应后有重复时不改变
+func (um *uuidMapper) mapMonitorsId(monitorsId string) string {
+	uuids := strings.Split(monitorsId, monitorsIdDelimiter)
+	seen := make(map[string]bool, len(uuids))
+	for i, uuid := range uuids {
+		uuids[i] = um.mapUuid(uuid)
+		if seen[uuids[i]] {
+			return monitorsId
+		}
+		seen[uuids[i]] = true
+	}
+	sort.Strings(uuids)
+	return strings.Join(uuids, monitorsIdDelimiter)
+}
+
+// mapMonitorConfigs 对应 uuid，并把名称改为本机显示器的名称
+func (um *uuidMapper) mapMonitorConfigs(configs SysMonitorConfigs) SysMonitorConfigs {
+	result := configs.clone()
+	for _, config := range result {
+		if monitor := um.lookup(config.UUID); monitor != nil {
+			config.UUID = monitor.uuid
+			config.Name = monitor.name
+		}
+	}
+	return result
+}
+
+func (um *uuidMapper) mapModeConfig(c *SysMonitorModeConfig) *SysMonitorModeConfig {
+	if c == nil {
+		return nil
+	}
+	return &SysMonitorModeConfig{Monitors: um.mapMonitorConfigs(c.Monitors)}
+}
+
+func (um *uuidMapper) mapScreenConfig(c *SysScreenConfig) *SysScreenConfig {
+	result := &SysScreenConfig{
+		Mirror:      um.mapModeConfig(c.Mirror),
+		Extend:      um.mapModeConfig(c.Extend),
+		Single:      um.mapModeConfig(c.Single),
+		OnlyOneUuid: c.OnlyOneUuid,
+	}
+	if c.OnlyOneUuid != "" {
+		result.OnlyOneUuid = um.mapUuid(c.OnlyOneUuid)
+	}
+	if len(c.OnlyOneMap) > 0 {
+		result.OnlyOneMap = make(map[string]*SysMonitorModeConfig, len(c.OnlyOneMap))
+		for uuid, config := range c.OnlyOneMap {
+			result.OnlyOneMap[um.mapUuid(uuid)] = um.mapModeConfig(config)
+		}
+	}
+	return result
+}
+
+func (um *uuidMapper) mapUserScreenConfig(c UserScreenConfig) UserScreenConfig {
+	result := make(UserScreenConfig, len(c))
+	for key, config := range c {
+		if strings.HasPrefix(key, KeyModeOnlyOnePrefix) {
+			key = KeyModeOnlyOnePrefix + um.mapUuid(key[len(KeyModeOnlyOnePrefix):])
+		}
+		result[key] = config.clone()
+	}
+	return result
+}
+
+// mergeSysMonitorModeConfig 把 imported 中选择的显示器配置合并到 local 中。
+// local 为 nil 时，只有 imported 中的显示器都被选择才使用 imported。
+func mergeSysMonitorModeConfig(local, imported *SysMonitorModeConfig, selected func(cfg *SysMonitorConfig) bool) *SysMonitorModeConfig {
+	if imported == nil {
+		return local
+	}
+	if local == nil {
+		for _, config := range imported.Monitors {
+			if !selected(config) {
+				return nil
+			}
+		}
+		return imported.clone()
+	}
+
+	result := local.clone()
+	for _, config := range imported.Monitors {
+		if !selected(config) {
+			continue
+		}
+		configCp := *config
+		old := result.Monitors.getByUuid(config.UUID)
+		if old == nil {
+			result.Monitors = append(result.Monitors, &configCp)
+		} else {
+			// 导入的不是主屏时保留原来的主屏
+			primary := old.Primary
+			*old = configCp
+			old.Primary = primary
+		}
+		if configCp.Primary {
+			result.Monitors.setPrimary(configCp.UUID)
+		}
+	}
+	return result
+}
+
+// mergeSysScreenConfig 按 filter 把 imported 合并到 local 中，返回新的配置
+func mergeSysScreenConfig(local, imported *SysScreenConfig, filter *importFilter) *SysScreenConfig {
+	result := local.clone()
+	if result == nil {
+		result = &SysScreenConfig{}
+	}
+	selected := func(cfg *SysMonitorConfig) bool {
+		return filter.hasMonitor(cfg.UUID, cfg.Name)
+	}
+
+	if filter.hasMode(importModeMirror) {
+		result.Mirror = mergeSysMonitorModeConfig(result.Mirror, imported.Mirror, selected)
+	}
+	if filter.hasMode(importModeExtend) {
+		result.Extend = mergeSysMonitorModeConfig(result.Extend, imported.Extend, selected)
+	}
+	if filter.hasMode(importModeSingle) {
+		result.Single = mergeSysMonitorModeConfig(result.Single, imported.Single, selected)
+	}
+	if filter.hasMode(importModeOnlyOne) {
+		for uuid, config := range imported.OnlyOneMap {
+			merged := mergeSysMonitorModeConfig(result.OnlyOneMap[uuid], config, selected)
+			if merged == nil {
+				continue
+			}
+			if result.OnlyOneMap == nil {
+				result.OnlyOneMap = make(map[string]*SysMonitorModeConfig)
+			}
+			result.OnlyOneMap[uuid] = merged
+		}
+		if imported.OnlyOneUuid != "" && result.OnlyOneMap[imported.OnlyOneUuid] != nil &&
+			(result.OnlyOneUuid == "" || len(filter.monitors) == 0) {
+			result.OnlyOneUuid = imported.OnlyOneUuid
+		}
+	}
+	return result
+}
+
+// mergeUserScreenConfig 按 filter 把 imported 合并到 local 中，返回新的配置。
+// 除了 OnlyOne 模式，色温是整个屏幕配置的，选择了屏幕中所有显示器时才导入。
+func mergeUserScreenConfig(local, imported UserScreenConfig, allSelected bool, filter *importFilter,
+	getName func(uuid string) string) UserScreenConfig {
+	result := local.clone()
+	if result == nil {
+		result = make(UserScreenConfig)
+	}
+	for key, config := range imported {
+		if config == nil {
+			continue
+		}
+		var ok bool
+		switch {
+		case key == KeyModeMirror:
+			ok = allSelected && filter.hasMode(importModeMirror)
+		case key == KeyModeExtend:
+			ok = allSelected && filter.hasMode(importModeExtend)
+		case key == KeySingle:
+			ok = allSelected && filter.hasMode(importModeSingle)
+		case strings.HasPrefix(key, KeyModeOnlyOnePrefix):
+			uuid := key[len(KeyModeOnlyOnePrefix):]
+			ok = filter.hasMode(importModeOnlyOne) && filter.hasMonitor(uuid, getName(uuid))
+		}
+		if ok {
+			result[key] = config.clone()
+		}
+	}
+	return result
+}
+
+// importConfig 导入 ExportConfig 导出的配置，monitors 和 modes 为空时导入全部。
+// 配置中的 uuid 会对应到本机的显示器，导入后重新应用当前连接的显示器的配置。
+func (m *Manager) importConfig(data string, monitors, modes []string) error {
+	filter, err := newImportFilter(monitors, modes)
+	if err != nil {
+		return err
+	}
+	cfg, err := parseExportedConfig(m, []byte(data))
+	if err != nil {
+		return err
+	}
+	// 导入的配置代替等待确认的改变，不再恢复
+	m.cancelConfirm()
+	mapper := m.newUuidMapper()
+
+	monitorMap := m.cloneMonitorMap()
+	currentMonitorsId := getConnectedMonitors(monitorMap).getMonitorsId()
+	needApply := false
+
+	m.sysConfig.mu.Lock()
+	sysCfg := &m.sysConfig.Config
+	for monitorsId, screenCfg := range cfg.Screens {
+		if screenCfg == nil {
+			continue
+		}
+		monitorsId = mapper.mapMonitorsId(monitorsId)
+		local := sysCfg.Screens[monitorsId]
+		merged := mergeSysScreenConfig(local, mapper.mapScreenConfig(screenCfg), filter)
+		if reflect.DeepEqual(local, merged) || (local == nil && reflect.DeepEqual(merged, &SysScreenConfig{})) {
+			continue
+		}
+		if sysCfg.Screens == nil {
+			sysCfg.Screens = make(map[string]*SysScreenConfig)
+		}
+		sysCfg.Screens[monitorsId] = merged
+		if monitorsId == currentMonitorsId.v1 {
+			needApply = true
+		}
+	}
+	if filter.isAll() && cfg.DisplayMode != 0 && sysCfg.DisplayMode != cfg.DisplayMode {
+		sysCfg.DisplayMode = cfg.DisplayMode
+		needApply = true
+	}
+	displayMode := sysCfg.DisplayMode
+	err = m.saveSysConfigNoLock("import config")
+	m.sysConfig.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	m.userCfgMu.Lock()
+	for monitorsId, screenCfg := range cfg.UserScreens {
+		uuids := strings.Split(monitorsId, monitorsIdDelimiter)
+		allSelected := true
+		for _, uuid := range uuids {
+			if !filter.hasMonitor(mapper.mapUuid(uuid), mapper.getName(uuid)) {
+				allSelected = false
+				break
+			}
+		}
+		monitorsId = mapper.mapMonitorsId(monitorsId)
+		local := m.userConfig.Screens[monitorsId]
+		merged := mergeUserScreenConfig(local, mapper.mapUserScreenConfig(screenCfg), allSelected, filter, mapper.getName)
+		if len(merged) == 0 || reflect.DeepEqual(local, merged) {
+			continue
+		}
+		if m.userConfig.Screens == nil {
+			m.userConfig.Screens = make(map[string]UserScreenConfig)
+		}
+		merged.fix()
+		m.userConfig.Screens[monitorsId] = merged
+		if monitorsId == currentMonitorsId.v1 {
+			needApply = true
+		}
+	}
+	err = m.saveUserConfigNoLock()
+	m.userCfgMu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	if !needApply || currentMonitorsId.v1 == "" {
+		return nil
+	}
+	m.applySaveMu.Lock()
+	err = m.applyDisplayConfig(displayMode, currentMonitorsId, monitorMap, true, nil)
+	m.applySaveMu.Unlock()
+	return err
+}
@@ -0,0 +1,288 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package display
+
+import (
+	"testing"
+
+	"github.com/linuxdeepin/go-x11-client/ext/randr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testUuidEdp  = "eDP-1|2fd580d2dc41168dce2efb1bf19adb54|v1"
+	testUuidHdmi = "HDMI-1|bc06f293ee6bfb16fd813648741f8ac3|v1"
+	// 同一个显示器接到 DP-2 接口
+	testUuidHdmiOnDp = "DP-2|bc06f293ee6bfb16fd813648741f8ac3|v1"
+	testUuidVga      = "VGA-1||v1"
+)
+
+const testExportedConfig = `{
+	"Version": "1.0",
+	"DisplayMode": 2,
+	"Screens": {
+		"eDP-1|2fd580d2dc41168dce2efb1bf19adb54|v1,HDMI-1|bc06f293ee6bfb16fd813648741f8ac3|v1": {
+			"Extend": {"Monitors": [
+				{"UUID": "eDP-1|2fd580d2dc41168dce2efb1bf19adb54|v1", "Name": "eDP-1", "Enabled": true,
+					"X": 0, "Y": 0, "Width": 1920, "Height": 1080, "Rotation": 1, "RefreshRate": 60, "Brightness": 1},
+				{"UUID": "HDMI-1|bc06f293ee6bfb16fd813648741f8ac3|v1", "Name": "HDMI-1", "Enabled": true,
+					"X": 1920, "Y": 0, "Width": 2560, "Height": 1440, "Rotation": 1, "RefreshRate": 60, "Brightness": 0.8,
+					"Primary": true}
+			]},
+			"OnlyOneMap": {
+				"HDMI-1|bc06f293ee6bfb16fd813648741f8ac3|v1": {"Monitors": [
+					{"UUID": "HDMI-1|bc06f293ee6bfb16fd813648741f8ac3|v1", "Name": "HDMI-1", "Enabled": true,
+						"Width": 2560, "Height": 1440, "Rotation": 1, "RefreshRate": 60, "Brightness": 1, "Primary": true}
+				]}
+			},
+			"OnlyOneUuid": "HDMI-1|bc06f293ee6bfb16fd813648741f8ac3|v1"
+		}
+	},
+	"UserScreens": {
+		"eDP-1|2fd580d2dc41168dce2efb1bf19adb54|v1,HDMI-1|bc06f293ee6bfb16fd813648741f8ac3|v1": {
+			"Extend": {"ColorTemperatureMode": 2, "ColorTemperatureManual": 5000},
+			"OnlyOne-HDMI-1|bc06f293ee6bfb16fd813648741f8ac3|v1": {"ColorTemperatureMode": 0, "ColorTemperatureManual": 6500}
+		}
+	}
+}`
+
+func TestParseExportedConfig(t *testing.T) {
+	cfg, err := parseExportedConfig(nil, []byte(testExportedConfig))
+	require.NoError(t, err)
+	assert.Equal(t, byte(DisplayModeExtend), cfg.DisplayMode)
+	require.Len(t, cfg.Screens, 1)
+	require.Len(t, cfg.UserScreens, 1)
+
+	invalid := []string{
+		`{}`,
+		`{"Version": "9.0", "Screens": {}}`,
+		`{"Version": "1.0"}`,
+		`{"Version": "1.0", "Screens": {}, "Other": 1}`,
+		`{"Version": "1.0", "Screens": {}, "DisplayMode": 5}`,
+		`{"Version": "1.0", "Screens": {"a": {"Extend": {"Monitors": [{"UUID": "a"}]}}}}`,
+		`{"Version": "1.0", "Screens": {"a": {"Extend": {"Monitors": [{"UUID": "a", "Name": "a", "Rotation": 3}]}}}}`,
+		`{"Version": "1.0", "Screens": {"a": {"Extend": {"Monitors": [{"UUID": "a", "Name": "a", "X": 40000}]}}}}`,
+		`{"Version": "1.0", "Screens": {"a": {"Extend": {"Monitors": [{"UUID": "a", "Name": "a", "Brightness": 2}]}}}}`,
+		`{"Version": "1.0", "Screens": {"a": {"Fill": {}}}}`,
+		`{"Version": "1.0", "Screens": {}, "UserScreens": {"a": {"Extend": {"ColorTemperatureMode": "auto"}}}}`,
+		`not json`,
+	}
+	for _, data := range invalid {
+		_, err = parseExportedConfig(nil, []byte(data))
+		assert.Error(t, err, data)
+	}
+}
+
+func getTestUuidMapper() *uuidMapper {
+	return &uuidMapper{
+		monitors: []importMonitor{
+			{uuid: testUuidEdp, name: "eDP-1", edidId: "2fd580d2dc41168dce2efb1bf19adb54"},
+			{uuid: testUuidHdmiOnDp, name: "DP-2", edidId: "bc06f293ee6bfb16fd813648741f8ac3"},
+			{uuid: testUuidVga, name: "VGA-1"},
+		},
+	}
+}
+
+func TestUuidMapper(t *testing.T) {
+	um := getTestUuidMapper()
+	assert.Equal(t, testUuidEdp, um.mapUuid(testUuidEdp))
+	// 名称不同时按 EDID 对应
+	assert.Equal(t, testUuidHdmiOnDp, um.mapUuid(testUuidHdmi))
+	assert.Equal(t, "DP-2", um.getName(testUuidHdmi))
+	// 没有 EDID 的显示器只能按 uuid 对应
+	assert.Equal(t, "VGA-2||v1", um.mapUuid("VGA-2||v1"))
+	assert.Equal(t, "", um.getName("VGA-2||v1"))
+	assert.Equal(t, "HDMI-2|0123|v1", um.mapUuid("HDMI-2|0123|v1"))
+
+	assert.Equal(t, testUuidHdmiOnDp+","+testUuidEdp, um.mapMonitorsId(testUuidEdp+","+testUuidHdmi))
+	// 对应后重复时不改变
+	assert.Equal(t, testUuidHdmi+","+testUuidHdmiOnDp, um.mapMonitorsId(testUuidHdmi+","+testUuidHdmiOnDp))
+
+	// EDID 相同的显示器有多个时不对应
+	um.monitors = append(um.monitors, importMonitor{uuid: "DP-3|bc06f293ee6bfb16fd813648741f8ac3|v1",
+		name: "DP-3", edidId: "bc06f293ee6bfb16fd813648741f8ac3"})
+	assert.Equal(t, testUuidHdmi, um.mapUuid(testUuidHdmi))
+
+	configs := getTestUuidMapper().mapMonitorConfigs(SysMonitorConfigs{
+		{UUID: testUuidHdmi, Name: "HDMI-1", Width: 2560},
+		{UUID: "HDMI-2|0123|v1", Name: "HDMI-2"},
+	})
+	assert.Equal(t, testUuidHdmiOnDp, configs[0].UUID)
+	assert.Equal(t, "DP-2", configs[0].Name)
+	assert.Equal(t, uint16(2560), configs[0].Width)
+	assert.Equal(t, "HDMI-2", configs[1].Name)
+
+	screenCfg := getTestUuidMapper().mapScreenConfig(&SysScreenConfig{
+		OnlyOneMap: map[string]*SysMonitorModeConfig{
+			testUuidHdmi: {Monitors: SysMonitorConfigs{{UUID: testUuidHdmi, Name: "HDMI-1"}}},
+		},
+		OnlyOneUuid: testUuidHdmi,
+	})
+	assert.Equal(t, testUuidHdmiOnDp, screenCfg.OnlyOneUuid)
+	require.NotNil(t, screenCfg.OnlyOneMap[testUuidHdmiOnDp])
+	assert.Equal(t, "DP-2", screenCfg.OnlyOneMap[testUuidHdmiOnDp].Monitors[0].Name)
+
+	userCfg := getTestUuidMapper().mapUserScreenConfig(UserScreenConfig{
+		KeyModeExtend:                       {ColorTemperatureMode: 1},
+		KeyModeOnlyOnePrefix + testUuidHdmi: {ColorTemperatureMode: 2},
+	})
+	assert.NotNil(t, userCfg[KeyModeExtend])
+	assert.NotNil(t, userCfg[KeyModeOnlyOnePrefix+testUuidHdmiOnDp])
+}
+
+func TestNewImportFilter(t *testing.T) {
+	f, err := newImportFilter(nil, nil)
+	require.NoError(t, err)
+	assert.True(t, f.isAll())
+	assert.True(t, f.hasMode(importModeMirror))
+	assert.True(t, f.hasMonitor("a", "b"))
+
+	f, err = newImportFilter([]string{"HDMI-1"}, []string{importModeExtend})
+	require.NoError(t, err)
+	assert.False(t, f.isAll())
+	assert.True(t, f.hasMode(importModeExtend))
+	assert.False(t, f.hasMode(importModeMirror))
+	assert.True(t, f.hasMonitor(testUuidHdmi, "HDMI-1"))
+	assert.False(t, f.hasMonitor(testUuidEdp, "eDP-1"))
+	assert.False(t, f.hasMonitor(testUuidEdp, ""))
+
+	_, err = newImportFilter(nil, []string{"custom"})
+	assert.Error(t, err)
+}
+
+func TestMergeSysScreenConfig(t *testing.T) {
+	cfg, err := parseExportedConfig(nil, []byte(testExportedConfig))
+	require.NoError(t, err)
+	var imported *SysScreenConfig
+	for _, screenCfg := range cfg.Screens {
+		imported = screenCfg
+	}
+
+	// 全部导入
+	f, _ := newImportFilter(nil, nil)
+	result := mergeSysScreenConfig(nil, imported, f)
+	assert.Equal(t, imported, result)
+
+	local := &SysScreenConfig{
+		Extend: &SysMonitorModeConfig{Monitors: SysMonitorConfigs{
+			{UUID: testUuidEdp, Name: "eDP-1", Enabled: true, Width: 1366, Height: 768, Primary: true},
+			{UUID: testUuidHdmi, Name: "HDMI-1", Enabled: true, X: 1366, Width: 1920, Height: 1080},
+		}},
+		Mirror: &SysMonitorModeConfig{Monitors: SysMonitorConfigs{
+			{UUID: testUuidEdp, Name: "eDP-1", Enabled: true, Width: 1024, Height: 768, Primary: true},
+		}},
+	}
+
+	// 只导入 eDP-1 的扩展模式配置，保留原来的主屏
+	f, _ = newImportFilter([]string{"eDP-1"}, []string{importModeExtend})
+	result = mergeSysScreenConfig(local, imported, f)
+	assert.Equal(t, local.Mirror, result.Mirror)
+	assert.Nil(t, result.OnlyOneMap)
+	edp := result.Extend.Monitors.getByUuid(testUuidEdp)
+	assert.Equal(t, uint16(1920), edp.Width)
+	assert.True(t, edp.Primary)
+	hdmi := result.Extend.Monitors.getByUuid(testUuidHdmi)
+	assert.Equal(t, uint16(1920), hdmi.Width)
+	assert.False(t, hdmi.Primary)
+	// 不修改 local
+	assert.Equal(t, uint16(1366), local.Extend.Monitors[0].Width)
+
+	// 导入的主屏替换原来的主屏
+	f, _ = newImportFilter([]string{testUuidHdmi}, nil)
+	result = mergeSysScreenConfig(local, imported, f)
+	assert.False(t, result.Extend.Monitors.getByUuid(testUuidEdp).Primary)
+	hdmi = result.Extend.Monitors.getByUuid(testUuidHdmi)
+	assert.True(t, hdmi.Primary)
+	assert.Equal(t, uint16(2560), hdmi.Width)
+	// 本地没有的模式配置，选择了其中所有的显示器才导入
+	require.NotNil(t, result.OnlyOneMap[testUuidHdmi])
+	assert.Equal(t, testUuidHdmi, result.OnlyOneUuid)
+
+	f, _ = newImportFilter([]string{"eDP-1"}, []string{importModeOnlyOne})
+	result = mergeSysScreenConfig(local, imported, f)
+	assert.Nil(t, result.OnlyOneMap)
+	assert.Equal(t, "", result.OnlyOneUuid)
+	assert.Equal(t, local, result)
+}
+
+func TestMergeUserScreenConfig(t *testing.T) {
+	imported := UserScreenConfig{
+		KeyModeExtend:                       {ColorTemperatureMode: 2, ColorTemperatureManual: 5000},
+		KeyModeMirror:                       nil,
+		KeyModeOnlyOnePrefix + testUuidHdmi: {ColorTemperatureMode: 1},
+	}
+	local := UserScreenConfig{
+		KeyModeExtend: {ColorTemperatureMode: 0, ColorTemperatureManual: 6500},
+	}
+	getName := func(uuid string) string {
+		if uuid == testUuidHdmi {
+			return "HDMI-1"
+		}
+		return ""
+	}
+
+	f, _ := newImportFilter(nil, nil)
+	result := mergeUserScreenConfig(local, imported, true, f, getName)
+	assert.Equal(t, int32(2), result[KeyModeExtend].ColorTemperatureMode)
+	assert.NotNil(t, result[KeyModeOnlyOnePrefix+testUuidHdmi])
+	assert.Nil(t, result[KeyModeMirror])
+	assert.Equal(t, int32(0), local[KeyModeExtend].ColorTemperatureMode)
+
+	// 没有选择屏幕中所有显示器时只导入 OnlyOne 模式的配置
+	f, _ = newImportFilter([]string{"HDMI-1"}, nil)
+	result = mergeUserScreenConfig(local, imported, false, f, getName)
+	assert.Equal(t, int32(0), result[KeyModeExtend].ColorTemperatureMode)
+	assert.NotNil(t, result[KeyModeOnlyOnePrefix+testUuidHdmi])
+
+	f, _ = newImportFilter(nil, []string{importModeExtend})
+	result = mergeUserScreenConfig(nil, imported, true, f, getName)
+	assert.Len(t, result, 1)
+	assert.Equal(t, int32(2), result[KeyModeExtend].ColorTemperatureMode)
+}
+
+func TestManager_exportImportConfig(t *testing.T) {
+	m := newTestHeadlessManager(t)
+	mm := m.mm.(*headlessMonitorManager)
+	hdmi := m.getConnectedMonitors().GetByName("HDMI-1")
+	require.NotNil(t, hdmi)
+
+	require.Nil(t, hdmi.SetModeBySize(1024, 768))
+	require.Nil(t, m.ApplyChanges())
+	require.Nil(t, m.Save())
+	exported, err := m.exportConfig()
+	require.NoError(t, err)
+	_, err = parseExportedConfig(m, []byte(exported))
+	require.NoError(t, err)
+
+	require.Nil(t, hdmi.SetModeBySize(1920, 1080))
+	require.Nil(t, m.ApplyChanges())
+	require.Nil(t, m.Save())
+	require.Equal(t, uint16(1920), mm.getMonitorByName("HDMI-1").Width)
+
+	// 导入代替等待确认的改变
+	require.Nil(t, hdmi.SetRotation(randr.RotationRotate90))
+	require.NoError(t, m.applyChangesWithTimeout(maxConfirmTimeout))
+	require.NotNil(t, m.getConfirm())
+
+	require.NoError(t, m.importConfig(exported, nil, nil))
+	assert.Nil(t, m.getConfirm())
+	info := mm.getMonitorByName("HDMI-1")
+	assert.Equal(t, uint16(1024), info.Width)
+	assert.Equal(t, uint16(randr.RotationRotate0), info.Rotation)
+	assert.Equal(t, DisplayModeExtend, m.DisplayMode)
+
+	again, err := m.exportConfig()
+	require.NoError(t, err)
+	assert.Equal(t, exported, again)
+
+	// 不能导出的显示模式不导出，导出的配置仍然有效
+	m.sysConfig.Config.DisplayMode = DisplayModeCustom
+	exported, err = m.exportConfig()
+	require.NoError(t, err)
+	cfg, err := parseExportedConfig(m, []byte(exported))
+	require.NoError(t, err)
+	assert.Equal(t, byte(0), cfg.DisplayMode)
+}
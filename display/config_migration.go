@@ -0,0 +1,152 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package display
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// configVersion 一个版本的配置格式，decode 解析 JSON，upgrade 转换为 next 版本的配置
+type configVersion struct {
+	decode  func(data []byte) (interface{}, error)
+	next    string
+	upgrade func(m *Manager, cfg interface{}) (interface{}, error)
+}
+
+// configMigrations 配置的版本注册表，从任意已注册版本逐步升级到 latest。
+// 新增版本时只需注册新版本的解析函数和上一版本的升级函数。
+type configMigrations struct {
+	name     string
+	latest   string
+	versions map[string]*configVersion
+}
+
+func newConfigMigrations(name, latest string) *configMigrations {
+	return &configMigrations{
+		name:     name,
+		latest:   latest,
+		versions: make(map[string]*configVersion),
+	}
+}
+
+// registerDecoder 注册版本 version 的解析函数
+func (r *configMigrations) registerDecoder(version string, decode func(data []byte) (interface{}, error)) {
+	r.getVersion(version).decode = decode
+}
+
+// registerUpgrade 注册从版本 from 到版本 to 的升级函数，每个版本只能升级到一个版本
+func (r *configMigrations) registerUpgrade(from, to string, upgrade func(m *Manager, cfg interface{}) (interface{}, error)) {
+	v := r.getVersion(from)
+	if v.upgrade != nil {
+		panic(fmt.Sprintf("%s config: upgrade from version %q already registered", r.name, from))
+	}
+	v.next = to
+	v.upgrade = upgrade
+}
+
+func (r *configMigrations) getVersion(version string) *configVersion {
+	v := r.versions[version]
+	if v == nil {
+		v = &configVersion{}
+		r.versions[version] = v
+	}
+	return v
+}
+
+// load 以版本 version 解析 data，并升级到最新版本
+func (r *configMigrations) load(m *Manager, version string, data []byte) (interface{}, error) {
+	v := r.versions[version]
+	if v == nil || v.decode == nil {
+		return nil, fmt.Errorf("%s config: unsupported version %q", r.name, version)
+	}
+	cfg, err := v.decode(data)
+	if err != nil {
+		return nil, err
+	}
+	return r.migrate(m, version, cfg)
+}
+
+// loadOrLatest 与 load 相同，但不认识的版本按照最新版本解析，比如降级后读取新版本写入的配置，
+// 这时丢弃不认识的字段比丢弃整个配置好。
+func (r *configMigrations) loadOrLatest(m *Manager, version string, data []byte) (interface{}, error) {
+	v := r.versions[version]
+	if v == nil || v.decode == nil {
+		logger.Warningf("%s config: unknown version %q, decode as version %q", r.name, version, r.latest)
+		version = r.latest
+	}
+	return r.load(m, version, data)
+}
+
+// migrate 把版本 version 的配置 cfg 升级到最新版本
+func (r *configMigrations) migrate(m *Manager, version string, cfg interface{}) (interface{}, error) {
+	// 最多升级的次数，防止注册了循环的升级
+	for i := 0; version != r.latest; i++ {
+		v := r.versions[version]
+		if v == nil || v.upgrade == nil {
+			return nil, fmt.Errorf("%s config: no upgrade from version %q", r.name, version)
+		}
+		if i >= len(r.versions) {
+			return nil, fmt.Errorf("%s config: upgrade loop at version %q", r.name, version)
+		}
+		var err error
+		cfg, err = v.upgrade(m, cfg)
+		if err != nil {
+			return nil, fmt.Errorf("%s config: upgrade from version %q to %q failed: %v", r.name, version, v.next, err)
+		}
+		logger.Debugf("%s config: upgraded from version %q to %q", r.name, version, v.next)
+		version = v.next
+	}
+	return cfg, nil
+}
+
+// getConfigDataVersion 获取 JSON 配置中 Version 字段的值，没有时返回 defaultVersion
+func getConfigDataVersion(data []byte, defaultVersion string) (string, error) {
+	var header struct {
+		Version string
+	}
+	err := json.Unmarshal(data, &header)
+	if err != nil {
+		return "", err
+	}
+	if header.Version == "" {
+		return defaultVersion, nil
+	}
+	return header.Version, nil
+}
+
+// sysConfigMigrations 系统级配置 SysRootConfig 的版本
+var sysConfigMigrations = newConfigMigrations("system", sysConfigVersion)
+
+// userConfigMigrations 用户级配置 UserConfig 的版本
+var userConfigMigrations = newConfigMigrations("user", userConfigVersion)
+
+// legacyConfigMigrations 系统级和用户级配置分开之前的旧配置，最终升级为 *configV1
+var legacyConfigMigrations = newConfigMigrations("legacy", sysConfigVersion)
+
+// configV1 旧配置升级后得到的系统级和用户级配置
+type configV1 struct {
+	Sys  SysConfig
+	User UserConfig
+}
+
+func init() {
+	sysConfigMigrations.registerDecoder("1.0", func(data []byte) (interface{}, error) {
+		var cfg SysRootConfig
+		err := json.Unmarshal(data, &cfg)
+		if err != nil {
+			return nil, err
+		}
+		return &cfg, nil
+	})
+	userConfigMigrations.registerDecoder("1.0", func(data []byte) (interface{}, error) {
+		var cfg UserConfig
+		err := json.Unmarshal(data, &cfg)
+		if err != nil {
+			return nil, err
+		}
+		return &cfg, nil
+	})
+}
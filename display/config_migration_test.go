@@ -0,0 +1,130 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package display
+
+import (
+	"encoding/json"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigMigrations(t *testing.T) {
+	type testConfigV1 struct{ Size int }
+	type testConfigV2 struct{ Width, Height int }
+	type testConfigV3 struct{ Size string }
+
+	r := newConfigMigrations("test", "3")
+	r.registerDecoder("1", func(data []byte) (interface{}, error) {
+		var cfg testConfigV1
+		err := json.Unmarshal(data, &cfg)
+		return &cfg, err
+	})
+	r.registerUpgrade("1", "2", func(m *Manager, cfg interface{}) (interface{}, error) {
+		size := cfg.(*testConfigV1).Size
+		return &testConfigV2{Width: size, Height: size}, nil
+	})
+	r.registerUpgrade("2", "3", func(m *Manager, cfg interface{}) (interface{}, error) {
+		v2 := cfg.(*testConfigV2)
+		return &testConfigV3{Size: strconv.Itoa(v2.Width) + "x" + strconv.Itoa(v2.Height)}, nil
+	})
+
+	cfg, err := r.load(nil, "1", []byte(`{"Size": 10}`))
+	require.NoError(t, err)
+	assert.Equal(t, &testConfigV3{Size: "10x10"}, cfg)
+
+	cfg, err = r.migrate(nil, "2", &testConfigV2{Width: 1, Height: 2})
+	require.NoError(t, err)
+	assert.Equal(t, &testConfigV3{Size: "1x2"}, cfg)
+
+	// 没有解析函数的版本不能加载
+	_, err = r.load(nil, "2", []byte(`{}`))
+	assert.Error(t, err)
+	_, err = r.load(nil, "0", []byte(`{}`))
+	assert.Error(t, err)
+	_, err = r.load(nil, "1", []byte(`[`))
+	assert.Error(t, err)
+
+	assert.Panics(t, func() {
+		r.registerUpgrade("1", "3", nil)
+	})
+
+	// 升级失败和循环
+	r.registerUpgrade("4", "5", func(m *Manager, cfg interface{}) (interface{}, error) {
+		return nil, assert.AnError
+	})
+	_, err = r.migrate(nil, "4", nil)
+	assert.Error(t, err)
+	r.registerUpgrade("6", "7", func(m *Manager, cfg interface{}) (interface{}, error) { return cfg, nil })
+	r.registerUpgrade("7", "6", func(m *Manager, cfg interface{}) (interface{}, error) { return cfg, nil })
+	_, err = r.migrate(nil, "6", nil)
+	assert.Error(t, err)
+}
+
+func TestGetConfigDataVersion(t *testing.T) {
+	version, err := getConfigDataVersion([]byte(`{"Version": "1.0"}`), "0")
+	require.NoError(t, err)
+	assert.Equal(t, "1.0", version)
+
+	version, err = getConfigDataVersion([]byte(`{}`), "0")
+	require.NoError(t, err)
+	assert.Equal(t, "0", version)
+
+	_, err = getConfigDataVersion([]byte(`{"Version": 1}`), "0")
+	assert.Error(t, err)
+}
+
+func TestLoadLegacyConfig(t *testing.T) {
+	// 不是自定义模式时，升级不会修改显示模式
+	m := &Manager{DisplayMode: DisplayModeMirror}
+	for version, filename := range map[string]string{
+		"3.3": configPath_v3,
+		"4.0": configPath_v4,
+		"5.0": configPath_v5,
+	} {
+		cfg, err := loadLegacyConfig(m, version, filename)
+		require.NoError(t, err, version)
+		assert.NotEmpty(t, cfg.Sys.Screens, version)
+	}
+
+	cfg, err := loadLegacyConfig(m, "5.0", configPath_v5)
+	require.NoError(t, err)
+	screenCfg := cfg.Sys.Screens["HDMI-1bc06f293ee6bfb16fd813648741f8ac3,eDP-12fd580d2dc41168dce2efb1bf19adb54"]
+	require.NotNil(t, screenCfg)
+	require.NotNil(t, screenCfg.Extend)
+	assert.Len(t, screenCfg.Extend.Monitors, 2)
+
+	_, err = loadLegacyConfig(m, "2.0", configPath_v4)
+	assert.Error(t, err)
+}
+
+func TestLoadSysAndUserConfig(t *testing.T) {
+	result, err := sysConfigMigrations.load(nil, sysConfigVersion,
+		[]byte(`{"Version": "1.0", "Config": {"DisplayMode": 2}}`))
+	require.NoError(t, err)
+	assert.Equal(t, byte(DisplayModeExtend), result.(*SysRootConfig).Config.DisplayMode)
+
+	result, err = userConfigMigrations.load(nil, userConfigVersion,
+		[]byte(`{"Version": "1.0", "RotationLocked": true}`))
+	require.NoError(t, err)
+	assert.True(t, result.(*UserConfig).RotationLocked)
+
+	_, err = userConfigMigrations.load(nil, "9.0", []byte(`{}`))
+	assert.Error(t, err)
+
+	// 不认识的版本按照最新版本解析
+	result, err = userConfigMigrations.loadOrLatest(nil, "9.0",
+		[]byte(`{"Version": "9.0", "RotationLocked": true, "NewField": 1}`))
+	require.NoError(t, err)
+	assert.True(t, result.(*UserConfig).RotationLocked)
+	result, err = sysConfigMigrations.loadOrLatest(nil, "9.0",
+		[]byte(`{"Version": "9.0", "Config": {"DisplayMode": 2}}`))
+	require.NoError(t, err)
+	assert.Equal(t, byte(DisplayModeExtend), result.(*SysRootConfig).Config.DisplayMode)
+	_, err = legacyConfigMigrations.loadOrLatest(nil, "9.0", []byte(`{}`))
+	assert.Error(t, err)
+}
@@ -7,6 +7,7 @@ package display
 import (
 	"testing"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
@@ -49,3 +50,31 @@ func TestConfig(t *testing.T) {
 	_, err = loadConfigV5V6(configPath_v5)
 	require.Nil(t, err)
 }
+
+func TestSysConfig_updateUuid(t *testing.T) {
+	monitors := Monitors{
+		{Name: "HDMI-1", uuid: "HDMI-1|abc|v1", uuidV0: "HDMI-1abc"},
+	}
+	cfg := &SysConfig{
+		OutputColors: map[string]SysOutputColorConfig{
+			"HDMI-1abc": {MaxBpc: 10},
+			"DP-1def":   {MaxBpc: 8},
+		},
+		Brightness: &SysBrightnessConfig{
+			Monitors: map[string]SysMonitorBrightnessConfig{
+				"HDMI-1abc": {Step: 0.1},
+			},
+		},
+	}
+	assert.True(t, cfg.updateUuid(monitors))
+	assert.Equal(t, map[string]SysOutputColorConfig{
+		"HDMI-1|abc|v1": {MaxBpc: 10},
+		// 找不到显示器时不更新
+		"DP-1def": {MaxBpc: 8},
+	}, cfg.OutputColors)
+	assert.Equal(t, map[string]SysMonitorBrightnessConfig{
+		"HDMI-1|abc|v1": {Step: 0.1},
+	}, cfg.Brightness.Monitors)
+
+	assert.False(t, cfg.updateUuid(monitors))
+}
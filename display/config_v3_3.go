@@ -88,15 +88,27 @@ type MonitorConfigV3D3 struct {
 
 type ConfigV3D3 map[string]*ScreenConfigV3D3
 
+func init() {
+	legacyConfigMigrations.registerDecoder("3.3", func(data []byte) (interface{}, error) {
+		return decodeConfigV3D3(data)
+	})
+	legacyConfigMigrations.registerUpgrade("3.3", "5.0", func(m *Manager, cfg interface{}) (interface{}, error) {
+		return &ConfigV6{ConfigV5: cfg.(ConfigV3D3).toConfig(m)}, nil
+	})
+}
+
 func loadConfigV3D3(filename string) (ConfigV3D3, error) {
 	// #nosec G304
 	data, err := ioutil.ReadFile(filename)
 	if err != nil {
 		return nil, err
 	}
+	return decodeConfigV3D3(data)
+}
 
+func decodeConfigV3D3(data []byte) (ConfigV3D3, error) {
 	var c ConfigV3D3
-	err = json.Unmarshal(data, &c)
+	err := json.Unmarshal(data, &c)
 	if err != nil {
 		return nil, err
 	}
@@ -36,15 +36,27 @@ type OnlyOneModeConfig struct {
 	Monitors []*MonitorConfigV5
 }
 
+func init() {
+	legacyConfigMigrations.registerDecoder("4.0", func(data []byte) (interface{}, error) {
+		return decodeConfigV4(data)
+	})
+	legacyConfigMigrations.registerUpgrade("4.0", "5.0", func(m *Manager, cfg interface{}) (interface{}, error) {
+		return &ConfigV6{ConfigV5: cfg.(ConfigV4).toConfig(m)}, nil
+	})
+}
+
 func loadConfigV4(filename string) (ConfigV4, error) {
 	// #nosec G304
 	data, err := ioutil.ReadFile(filename)
 	if err != nil {
 		return nil, err
 	}
+	return decodeConfigV4(data)
+}
 
+func decodeConfigV4(data []byte) (ConfigV4, error) {
 	var c ConfigV4
-	err = json.Unmarshal(data, &c)
+	err := json.Unmarshal(data, &c)
 	if err != nil {
 		return nil, err
 	}
@@ -53,6 +53,19 @@ type ConfigV6 struct {
 	FillMode *FillModeConfigsV5
 }
 
+func init() {
+	legacyConfigMigrations.registerDecoder("5.0", func(data []byte) (interface{}, error) {
+		return decodeConfigV5V6(data)
+	})
+	legacyConfigMigrations.registerUpgrade("5.0", sysConfigVersion, func(m *Manager, cfg interface{}) (interface{}, error) {
+		cfgV6 := cfg.(*ConfigV6)
+		return &configV1{
+			Sys:  cfgV6.toSysConfigV1(),
+			User: cfgV6.toUserConfigV1(),
+		}, nil
+	})
+}
+
 func (cfgV6 *ConfigV6) toSysConfigV1() (sysCfg SysConfig) {
 	if cfgV6.FillMode != nil {
 		sysCfg.FillModes = cfgV6.FillMode.FillModeMap
@@ -0,0 +1,206 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package display
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	defaultConfirmTimeout = 15
+	maxConfirmTimeout     = 120
+)
+
+// changesConfirm 等待确认的改变，超时未确认则恢复到应用之前的配置。
+type changesConfirm struct {
+	monitorsId monitorsId
+	// 应用改变之前的配置和显示模式，配置中标记了之前的主屏
+	configs     SysMonitorConfigs
+	displayMode byte
+	done        chan struct{}
+}
+
+// toPrevSysMonitorConfigs 获取改变之前的配置，有备份的显示器使用备份中的值。
+func toPrevSysMonitorConfigs(monitors Monitors, primary string) SysMonitorConfigs {
+	configs := toSysMonitorConfigs(monitors, primary)
+	for i, monitor := range monitors {
+		monitor.PropsMu.RLock()
+		b := monitor.backup
+		monitor.PropsMu.RUnlock()
+		if b == nil {
+			continue
+		}
+		cfg := configs[i]
+		cfg.Enabled = b.Enabled
+		cfg.X = b.X
+		cfg.Y = b.Y
+		cfg.Width = b.Mode.Width
+		cfg.Height = b.Mode.Height
+		cfg.RefreshRate = b.Mode.Rate
+		cfg.Rotation = b.Rotation
+		cfg.Reflect = b.Reflect
+		cfg.Brightness = b.Brightness
+		cfg.VrrPolicy = b.VrrPolicy
+	}
+	return configs
+}
+
+func (m *Manager) applyChangesWithTimeout(seconds uint32) error {
+	if seconds == 0 {
+		seconds = defaultConfirmTimeout
+	}
+	if seconds > maxConfirmTimeout {
+		return fmt.Errorf("timeout out of range, max %d", maxConfirmTimeout)
+	}
+
+	m.PropsMu.RLock()
+	hasChanged := m.HasChanged
+	primary := m.Primary
+	displayMode := m.DisplayMode
+	m.PropsMu.RUnlock()
+	if !hasChanged {
+		return errors.New("no changes")
+	}
+
+	m.confirmMu.Lock()
+	if m.confirm != nil {
+		m.confirmMu.Unlock()
+		return errors.New("the previous changes are waiting for confirmation")
+	}
+
+	monitors := m.getConnectedMonitors()
+	confirm := &changesConfirm{
+		monitorsId:  monitors.getMonitorsId(),
+		configs:     toPrevSysMonitorConfigs(monitors, primary),
+		displayMode: displayMode,
+		done:        make(chan struct{}),
+	}
+	m.confirm = confirm
+	m.confirmMu.Unlock()
+
+	err := m.applyChangesNoConfirm()
+	if err != nil {
+		m.takeConfirm(confirm)
+		return err
+	}
+
+	go m.runConfirmCountdown(confirm, seconds)
+	return nil
+}
+
+// takeConfirm 取出等待确认的改变，如果已经不是 confirm，比如已经被确认或恢复，返回 false。
+func (m *Manager) takeConfirm(confirm *changesConfirm) bool {
+	m.confirmMu.Lock()
+	defer m.confirmMu.Unlock()
+	if m.confirm != confirm || confirm == nil {
+		return false
+	}
+	m.confirm = nil
+	close(confirm.done)
+	return true
+}
+
+func (m *Manager) getConfirm() *changesConfirm {
+	m.confirmMu.Lock()
+	defer m.confirmMu.Unlock()
+	return m.confirm
+}
+
+func (m *Manager) runConfirmCountdown(confirm *changesConfirm, seconds uint32) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	remaining := seconds
+	m.emitSignalConfirmCountdown(remaining)
+	for {
+		select {
+		case <-confirm.done:
+			return
+		case <-ticker.C:
+			remaining--
+			m.emitSignalConfirmCountdown(remaining)
+			if remaining == 0 {
+				err := m.revertChanges(confirm)
+				if err != nil {
+					logger.Warning("revert changes failed:", err)
+				}
+				return
+			}
+		}
+	}
+}
+
+// confirmChanges 确认改变，并保存配置。
+func (m *Manager) confirmChanges() error {
+	if !m.takeConfirm(m.getConfirm()) {
+		return errors.New("no changes waiting for confirmation")
+	}
+	return m.save()
+}
+
+// cancelConfirm 放弃等待确认，不恢复配置，用于显示器连接改变、改变被重置、
+// 保存或者应用新的改变时。
+func (m *Manager) cancelConfirm() {
+	confirm := m.getConfirm()
+	if m.takeConfirm(confirm) {
+		logger.Debug("cancel waiting for confirmation")
+	}
+}
+
+func (m *Manager) revertChanges(confirm *changesConfirm) error {
+	if !m.takeConfirm(confirm) {
+		return nil
+	}
+	logger.Info("changes not confirmed in time, revert")
+
+	m.resetChangesWithoutApply()
+	m.markClean()
+	m.futureConfig.clear()
+
+	monitorMap := m.cloneMonitorMap()
+	monitorsId := getConnectedMonitors(monitorMap).getMonitorsId()
+	if monitorsId != confirm.monitorsId {
+		// 显示器连接已经改变，由 delayApplyConfig 应用新的配置。
+		return nil
+	}
+
+	// 等待确认时可能切换了显示模式或者主屏，一起恢复，主屏由 confirm.configs 中的 Primary 恢复
+	m.applySaveMu.Lock()
+	err := m.applySysMonitorConfigs(confirm.displayMode, monitorsId, monitorMap, confirm.configs, nil)
+	m.applySaveMu.Unlock()
+	if err != nil {
+		return err
+	}
+	err = m.revertDisplayModeConfig(confirm.displayMode)
+	if err != nil {
+		logger.Warning(err)
+	}
+
+	err = m.service.Emit(m, "ChangesReverted")
+	if err != nil {
+		logger.Warning(err)
+	}
+	return nil
+}
+
+// revertDisplayModeConfig 恢复系统级配置中的显示模式
+func (m *Manager) revertDisplayModeConfig(displayMode byte) error {
+	m.sysConfig.mu.Lock()
+	defer m.sysConfig.mu.Unlock()
+	if m.sysConfig.Config.DisplayMode == displayMode {
+		return nil
+	}
+	m.sysConfig.Config.DisplayMode = displayMode
+	return m.saveSysConfigNoLock("revert changes")
+}
+
+func (m *Manager) emitSignalConfirmCountdown(remaining uint32) {
+	err := m.service.Emit(m, "ConfirmCountdown", remaining)
+	if err != nil {
+		logger.Warning(err)
+	}
+}
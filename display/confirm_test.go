@@ -0,0 +1,111 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package display
+
+import (
+	"testing"
+
+	"github.com/linuxdeepin/go-x11-client/ext/randr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_toPrevSysMonitorConfigs(t *testing.T) {
+	mode := ModeInfo{Width: 1920, Height: 1080, Rate: 60}
+	changed := &Monitor{
+		uuid:       "HDMI-1|a|v1",
+		Name:       "HDMI-1",
+		Enabled:    true,
+		X:          1920,
+		Width:      1280,
+		Height:     720,
+		Brightness: 0.5,
+		backup: &MonitorBackup{
+			Enabled:    true,
+			Mode:       mode,
+			Brightness: 1,
+		},
+	}
+	unchanged := &Monitor{
+		uuid:    "eDP-1|b|v1",
+		Name:    "eDP-1",
+		Enabled: true,
+		Width:   1366,
+		Height:  768,
+	}
+
+	configs := toPrevSysMonitorConfigs(Monitors{changed, unchanged}, "eDP-1")
+	assert.Len(t, configs, 2)
+	assert.Equal(t, int16(0), configs[0].X)
+	assert.Equal(t, uint16(1920), configs[0].Width)
+	assert.Equal(t, uint16(1080), configs[0].Height)
+	assert.Equal(t, float64(60), configs[0].RefreshRate)
+	assert.Equal(t, float64(1), configs[0].Brightness)
+	assert.False(t, configs[0].Primary)
+	assert.Equal(t, uint16(1366), configs[1].Width)
+	assert.True(t, configs[1].Primary)
+
+	// 不修改显示器的属性
+	assert.Equal(t, int16(1920), changed.X)
+}
+
+func TestManager_saveDuringConfirm(t *testing.T) {
+	m := newTestHeadlessManager(t)
+	mm := m.mm.(*headlessMonitorManager)
+	hdmi := m.getConnectedMonitors().GetByName("HDMI-1")
+	require.NotNil(t, hdmi)
+
+	require.Nil(t, hdmi.SetModeBySize(1024, 768))
+	require.NoError(t, m.applyChangesWithTimeout(maxConfirmTimeout))
+	confirm := m.getConfirm()
+	require.NotNil(t, confirm)
+
+	// 倒计时中保存，不再恢复
+	require.Nil(t, m.Save())
+	assert.Nil(t, m.getConfirm())
+	require.NoError(t, m.revertChanges(confirm))
+	assert.Equal(t, uint16(1024), mm.getMonitorByName("HDMI-1").Width)
+
+	// 倒计时中应用新的改变，之前的改变也不再恢复
+	require.Nil(t, hdmi.SetRotation(randr.RotationRotate90))
+	require.NoError(t, m.applyChangesWithTimeout(maxConfirmTimeout))
+	confirm = m.getConfirm()
+	require.NotNil(t, confirm)
+	require.Nil(t, hdmi.SetRotation(randr.RotationRotate0))
+	require.NoError(t, m.applyChanges())
+	assert.Nil(t, m.getConfirm())
+	require.NoError(t, m.revertChanges(confirm))
+	assert.Equal(t, uint16(randr.RotationRotate0), mm.getMonitorByName("HDMI-1").Rotation)
+}
+
+func TestManager_revertChanges(t *testing.T) {
+	m := newTestHeadlessManager(t)
+	mm := m.mm.(*headlessMonitorManager)
+	hdmi := m.getConnectedMonitors().GetByName("HDMI-1")
+	require.NotNil(t, hdmi)
+	require.Equal(t, "eDP-1", m.Primary)
+
+	require.Nil(t, hdmi.SetModeBySize(1024, 768))
+	require.NoError(t, m.applyChangesWithTimeout(maxConfirmTimeout))
+	confirm := m.getConfirm()
+	require.NotNil(t, confirm)
+
+	// 等待确认时修改主屏和显示模式
+	require.NoError(t, m.setPrimary("HDMI-1"))
+	require.Equal(t, "HDMI-1", m.Primary)
+	require.Nil(t, m.SwitchMode(DisplayModeOnlyOne, "HDMI-1"))
+	require.Equal(t, DisplayModeOnlyOne, m.DisplayMode)
+	require.False(t, mm.getMonitorByName("eDP-1").Enabled)
+
+	require.NoError(t, m.revertChanges(confirm))
+	assert.Nil(t, m.getConfirm())
+	assert.Equal(t, DisplayModeExtend, m.DisplayMode)
+	assert.Equal(t, DisplayModeExtend, m.sysConfig.Config.DisplayMode)
+	assert.Equal(t, "eDP-1", m.Primary)
+	assert.True(t, mm.getMonitorByName("eDP-1").Enabled)
+	info := mm.getMonitorByName("HDMI-1")
+	assert.True(t, info.Enabled)
+	assert.Equal(t, uint16(1920), info.Width)
+}
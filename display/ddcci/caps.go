@@ -0,0 +1,162 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package ddcci
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Capabilities 解析后的能力字符串，比如：
+// (prot(monitor)type(lcd)model(P2418D)cmds(01 02 03 07 0C E3 F3)vcp(10 12 60(0F 11) D6(01 04))mccs_ver(2.1))
+type Capabilities struct {
+	// 顶层的字段，key 是字段名，比如 type、model、mccs_ver，value 是括号中的原始内容
+	Fields map[string]string
+	// 支持的 VCP 功能码，value 是可以设置的值，没有列出时为空
+	VCP map[byte][]uint16
+}
+
+// Supports 判断是否支持功能码 code
+func (c *Capabilities) Supports(code byte) bool {
+	if c == nil {
+		return false
+	}
+	_, ok := c.VCP[code]
+	return ok
+}
+
+// ParseCapabilities 解析能力字符串，最外层的括号可以省略
+func ParseCapabilities(str string) (*Capabilities, error) {
+	str = strings.TrimSpace(str)
+	if strings.HasPrefix(str, "(") && strings.HasSuffix(str, ")") {
+		end, err := findCloseParen(str, 0)
+		if err != nil {
+			return nil, err
+		}
+		if end == len(str)-1 {
+			str = str[1:end]
+		}
+	}
+
+	caps := &Capabilities{
+		Fields: make(map[string]string),
+		VCP:    make(map[byte][]uint16),
+	}
+	for i := 0; i < len(str); {
+		if str[i] == ' ' {
+			i++
+			continue
+		}
+		open := strings.IndexByte(str[i:], '(')
+		if open < 0 {
+			return nil, fmt.Errorf("capabilities: missing value of %q", str[i:])
+		}
+		open += i
+		end, err := findCloseParen(str, open)
+		if err != nil {
+			return nil, err
+		}
+		name := strings.TrimSpace(str[i:open])
+		caps.Fields[name] = str[open+1 : end]
+		i = end + 1
+	}
+
+	if vcp, ok := caps.Fields["vcp"]; ok {
+		err := parseVCPCodes(vcp, caps.VCP)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return caps, nil
+}
+
+// findCloseParen 返回与 str[open] 处的左括号配对的右括号的位置
+func findCloseParen(str string, open int) (int, error) {
+	depth := 0
+	for i := open; i < len(str); i++ {
+		switch str[i] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return i, nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("capabilities: unbalanced parentheses at %d", open)
+}
+
+// parseVCPCodes 解析 vcp 字段，功能码后面可以跟着括号中的可选值。
+// 有的显示器的功能码之间没有空格，所以按两个十六进制字符一组解析。
+func parseVCPCodes(str string, result map[byte][]uint16) error {
+	for i := 0; i < len(str); {
+		if str[i] == ' ' {
+			i++
+			continue
+		}
+		code, err := parseHexByte(str, i)
+		if err != nil {
+			return err
+		}
+		i += 2
+		var values []uint16
+		if i < len(str) && str[i] == '(' {
+			end, err := findCloseParen(str, i)
+			if err != nil {
+				return err
+			}
+			values, err = parseVCPValues(str[i+1 : end])
+			if err != nil {
+				return err
+			}
+			i = end + 1
+		}
+		result[code] = values
+	}
+	return nil
+}
+
+// parseVCPValues 解析功能码的可选值，忽略嵌套的括号
+func parseVCPValues(str string) ([]uint16, error) {
+	var values []uint16
+	depth := 0
+	for i := 0; i < len(str); {
+		switch str[i] {
+		case ' ':
+			i++
+			continue
+		case '(':
+			depth++
+			i++
+			continue
+		case ')':
+			depth--
+			i++
+			continue
+		}
+		value, err := parseHexByte(str, i)
+		if err != nil {
+			return nil, err
+		}
+		i += 2
+		if depth == 0 {
+			values = append(values, uint16(value))
+		}
+	}
+	return values, nil
+}
+
+func parseHexByte(str string, i int) (byte, error) {
+	if i+2 > len(str) {
+		return 0, fmt.Errorf("capabilities: invalid hex %q", str[i:])
+	}
+	v, err := strconv.ParseUint(str[i:i+2], 16, 8)
+	if err != nil {
+		return 0, fmt.Errorf("capabilities: invalid hex %q", str[i:i+2])
+	}
+	return byte(v), nil
+}
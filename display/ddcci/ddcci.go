@@ -0,0 +1,247 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+// Package ddcci 通过 /dev/i2c 设备直接使用 DDC/CI 协议控制外接显示器，读写 MCCS 定义的 VCP 功能。
+package ddcci
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// i2c 从设备地址
+const (
+	addrDDCCI = 0x37
+	addrEdid  = 0x50
+)
+
+// 计算校验和用的地址
+const (
+	// 显示器的写地址，主机写消息时参与计算
+	displayAddr = 0x6e
+	// 主机的源地址，是主机写消息的第一个字节
+	hostAddr = 0x51
+	// 显示器回复消息时参与计算的虚拟主机地址
+	virtualHostAddr = 0x50
+)
+
+// DDC/CI 命令
+const (
+	cmdGetVCP            = 0x01
+	cmdGetVCPReply       = 0x02
+	cmdSetVCP            = 0x03
+	cmdCapabilities      = 0xf3
+	cmdCapabilitiesReply = 0xe3
+)
+
+// MCCS 定义的 VCP 功能码
+const (
+	VCPBrightness  byte = 0x10
+	VCPContrast    byte = 0x12
+	VCPInputSource byte = 0x60
+	VCPVolume      byte = 0x62
+	VCPPowerMode   byte = 0xd6
+)
+
+const (
+	edidSize = 128
+	// 每次回复的能力字符串片段最多 32 字节
+	capabilitiesFragmentSize = 32
+	// 能力字符串的最大长度，防止显示器一直返回数据
+	maxCapabilitiesSize = 4096
+)
+
+// 显示器处理命令需要时间，DDC/CI 标准规定了命令之间的最小间隔，测试时设置为 0
+var (
+	replyDelay   = 40 * time.Millisecond
+	commandDelay = 50 * time.Millisecond
+	retryTimes   = 3
+)
+
+var (
+	ErrChecksum    = errors.New("ddc/ci: invalid checksum")
+	ErrNullMessage = errors.New("ddc/ci: null message")
+	ErrUnsupported = errors.New("ddc/ci: unsupported vcp code")
+)
+
+// Bus i2c 总线，读写指定地址的从设备，测试时可以替换成假的设备
+type Bus interface {
+	Read(addr uint16, buf []byte) error
+	Write(addr uint16, data []byte) error
+	Close() error
+}
+
+// Display 一个支持 DDC/CI 的显示器，方法可以并发调用
+type Display struct {
+	mu      sync.Mutex
+	bus     Bus
+	lastCmd time.Time
+}
+
+func NewDisplay(bus Bus) *Display {
+	return &Display{bus: bus}
+}
+
+// Open 打开 i2c 设备文件 path 对应的显示器，比如 /dev/i2c-5
+func Open(path string) (*Display, error) {
+	bus, err := openBus(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewDisplay(bus), nil
+}
+
+func (d *Display) Close() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.bus.Close()
+}
+
+// checksum 计算 addr 和 data 所有字节的异或
+func checksum(addr byte, data []byte) byte {
+	result := addr
+	for _, b := range data {
+		result ^= b
+	}
+	return result
+}
+
+func (d *Display) waitCommandDelay() {
+	if d.lastCmd.IsZero() {
+		return
+	}
+	wait := commandDelay - time.Since(d.lastCmd)
+	if wait > 0 {
+		time.Sleep(wait)
+	}
+}
+
+// writeNoLock 发送消息，消息格式是：源地址、0x80|长度、payload、校验和
+func (d *Display) writeNoLock(payload []byte) error {
+	d.waitCommandDelay()
+	msg := make([]byte, 0, len(payload)+3)
+	msg = append(msg, hostAddr, 0x80|byte(len(payload)))
+	msg = append(msg, payload...)
+	msg = append(msg, checksum(displayAddr, msg))
+	err := d.bus.Write(addrDDCCI, msg)
+	d.lastCmd = time.Now()
+	return err
+}
+
+// readNoLock 读取显示器的回复，payload 最长为 size 字节
+func (d *Display) readNoLock(size int) ([]byte, error) {
+	buf := make([]byte, size+3)
+	err := d.bus.Read(addrDDCCI, buf)
+	d.lastCmd = time.Now()
+	if err != nil {
+		return nil, err
+	}
+	if buf[0] != displayAddr || buf[1]&0x80 == 0 {
+		return nil, fmt.Errorf("ddc/ci: invalid reply header %#x %#x", buf[0], buf[1])
+	}
+	length := int(buf[1] &^ 0x80)
+	if length > size {
+		return nil, fmt.Errorf("ddc/ci: reply length %d too long", length)
+	}
+	if checksum(virtualHostAddr, buf[:length+2]) != buf[length+2] {
+		return nil, ErrChecksum
+	}
+	if length == 0 {
+		// 显示器忙或者不支持命令
+		return nil, ErrNullMessage
+	}
+	return buf[2 : length+2], nil
+}
+
+// request 发送命令并读取回复，校验出错时重试
+func (d *Display) request(payload []byte, replySize int) (reply []byte, err error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for i := 0; i < retryTimes; i++ {
+		err = d.writeNoLock(payload)
+		if err != nil {
+			return nil, err
+		}
+		time.Sleep(replyDelay)
+		reply, err = d.readNoLock(replySize)
+		if err == nil {
+			return reply, nil
+		}
+	}
+	return nil, err
+}
+
+// GetVCP 获取功能码 code 的当前值和最大值
+func (d *Display) GetVCP(code byte) (current, max uint16, err error) {
+	reply, err := d.request([]byte{cmdGetVCP, code}, 8)
+	if err != nil {
+		return 0, 0, err
+	}
+	// 回复：0x02、结果、功能码、类型、最大值高位、最大值低位、当前值高位、当前值低位
+	if len(reply) != 8 || reply[0] != cmdGetVCPReply || reply[2] != code {
+		return 0, 0, fmt.Errorf("ddc/ci: invalid get vcp reply % x", reply)
+	}
+	if reply[1] != 0 {
+		return 0, 0, ErrUnsupported
+	}
+	max = uint16(reply[4])<<8 | uint16(reply[5])
+	current = uint16(reply[6])<<8 | uint16(reply[7])
+	return current, max, nil
+}
+
+// SetVCP 设置功能码 code 的值，显示器不回复
+func (d *Display) SetVCP(code byte, value uint16) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.writeNoLock([]byte{cmdSetVCP, code, byte(value >> 8), byte(value)})
+}
+
+// Capabilities 分段读取显示器的能力字符串
+func (d *Display) Capabilities() (string, error) {
+	var sb strings.Builder
+	for sb.Len() < maxCapabilitiesSize {
+		offset := sb.Len()
+		reply, err := d.request([]byte{cmdCapabilities, byte(offset >> 8), byte(offset)},
+			capabilitiesFragmentSize+3)
+		if err != nil {
+			return "", err
+		}
+		// 回复：0xe3、偏移高位、偏移低位、数据
+		if len(reply) < 3 || reply[0] != cmdCapabilitiesReply {
+			return "", fmt.Errorf("ddc/ci: invalid capabilities reply % x", reply)
+		}
+		if int(reply[1])<<8|int(reply[2]) != offset {
+			return "", fmt.Errorf("ddc/ci: capabilities offset mismatch, want %d", offset)
+		}
+		data := reply[3:]
+		if len(data) == 0 {
+			break
+		}
+		sb.Write(data)
+	}
+	return strings.TrimRight(sb.String(), "\x00"), nil
+}
+
+// ReadEdid 通过 i2c 读取显示器的 EDID 基本块
+func (d *Display) ReadEdid() ([]byte, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return readEdid(d.bus)
+}
+
+func readEdid(bus Bus) ([]byte, error) {
+	err := bus.Write(addrEdid, []byte{0})
+	if err != nil {
+		return nil, err
+	}
+	buf := make([]byte, edidSize)
+	err = bus.Read(addrEdid, buf)
+	if err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
@@ -0,0 +1,267 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package ddcci
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeVCP struct {
+	current uint16
+	max     uint16
+}
+
+// fakeBus 模拟一个支持 DDC/CI 的显示器
+type fakeBus struct {
+	edid []byte
+	caps string
+	vcp  map[byte]*fakeVCP
+	// 下一次读取返回的数据
+	reply []byte
+	// 前几次回复的校验和是错的
+	badChecksum int
+	closed      bool
+}
+
+func newFakeBus() *fakeBus {
+	edid := make([]byte, edidSize)
+	copy(edid, []byte{0, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0})
+	edid[8] = 0x10
+	return &fakeBus{
+		edid: edid,
+		caps: "(prot(monitor)type(lcd)model(FAKE)cmds(01 02 03 F3)vcp(10 12 60(0F 11 12) D6(01 04) 62)mccs_ver(2.1))",
+		vcp: map[byte]*fakeVCP{
+			VCPBrightness:  {current: 50, max: 100},
+			VCPContrast:    {current: 75, max: 100},
+			VCPInputSource: {current: 0x0f, max: 0x12},
+			VCPPowerMode:   {current: 1, max: 5},
+			VCPVolume:      {current: 30, max: 100},
+		},
+	}
+}
+
+func (b *fakeBus) setReply(payload []byte) {
+	msg := append([]byte{displayAddr, 0x80 | byte(len(payload))}, payload...)
+	sum := checksum(virtualHostAddr, msg)
+	if b.badChecksum > 0 {
+		b.badChecksum--
+		sum++
+	}
+	b.reply = append(msg, sum)
+}
+
+func (b *fakeBus) Write(addr uint16, data []byte) error {
+	if addr == addrEdid {
+		return nil
+	}
+	if addr != addrDDCCI {
+		return errors.New("no device")
+	}
+	if len(data) < 3 || data[0] != hostAddr || checksum(displayAddr, data[:len(data)-1]) != data[len(data)-1] {
+		return errors.New("invalid message")
+	}
+	payload := data[2 : len(data)-1]
+	switch payload[0] {
+	case cmdGetVCP:
+		code := payload[1]
+		v, ok := b.vcp[code]
+		if !ok {
+			b.setReply([]byte{cmdGetVCPReply, 1, code, 0, 0, 0, 0, 0})
+			break
+		}
+		b.setReply([]byte{cmdGetVCPReply, 0, code, 0, byte(v.max >> 8), byte(v.max),
+			byte(v.current >> 8), byte(v.current)})
+	case cmdSetVCP:
+		if v, ok := b.vcp[payload[1]]; ok {
+			v.current = uint16(payload[2])<<8 | uint16(payload[3])
+		}
+	case cmdCapabilities:
+		offset := int(payload[1])<<8 | int(payload[2])
+		end := offset + capabilitiesFragmentSize
+		if end > len(b.caps) {
+			end = len(b.caps)
+		}
+		reply := []byte{cmdCapabilitiesReply, payload[1], payload[2]}
+		if offset < len(b.caps) {
+			reply = append(reply, b.caps[offset:end]...)
+		}
+		b.setReply(reply)
+	}
+	return nil
+}
+
+func (b *fakeBus) Read(addr uint16, buf []byte) error {
+	if addr == addrEdid {
+		copy(buf, b.edid)
+		return nil
+	}
+	copy(buf, b.reply)
+	return nil
+}
+
+func (b *fakeBus) Close() error {
+	b.closed = true
+	return nil
+}
+
+func init() {
+	replyDelay = 0
+	commandDelay = 0
+}
+
+func TestDisplay_VCP(t *testing.T) {
+	bus := newFakeBus()
+	d := NewDisplay(bus)
+
+	current, max, err := d.GetVCP(VCPBrightness)
+	require.NoError(t, err)
+	assert.Equal(t, uint16(50), current)
+	assert.Equal(t, uint16(100), max)
+
+	err = d.SetVCP(VCPBrightness, 80)
+	require.NoError(t, err)
+	current, _, err = d.GetVCP(VCPBrightness)
+	require.NoError(t, err)
+	assert.Equal(t, uint16(80), current)
+
+	_, _, err = d.GetVCP(0x14)
+	assert.Equal(t, ErrUnsupported, err)
+
+	// 校验和错误时重试
+	bus.badChecksum = 1
+	current, _, err = d.GetVCP(VCPVolume)
+	require.NoError(t, err)
+	assert.Equal(t, uint16(30), current)
+
+	bus.badChecksum = retryTimes
+	_, _, err = d.GetVCP(VCPVolume)
+	assert.Equal(t, ErrChecksum, err)
+
+	require.NoError(t, d.Close())
+	assert.True(t, bus.closed)
+}
+
+func TestDisplay_Capabilities(t *testing.T) {
+	bus := newFakeBus()
+	d := NewDisplay(bus)
+
+	str, err := d.Capabilities()
+	require.NoError(t, err)
+	assert.Equal(t, bus.caps, str)
+
+	edid, err := d.ReadEdid()
+	require.NoError(t, err)
+	assert.Equal(t, bus.edid, edid)
+}
+
+func TestParseCapabilities(t *testing.T) {
+	caps, err := ParseCapabilities(newFakeBus().caps)
+	require.NoError(t, err)
+	assert.Equal(t, "lcd", caps.Fields["type"])
+	assert.Equal(t, "FAKE", caps.Fields["model"])
+	assert.Equal(t, "2.1", caps.Fields["mccs_ver"])
+	assert.Equal(t, map[byte][]uint16{
+		VCPBrightness:  nil,
+		VCPContrast:    nil,
+		VCPInputSource: {0x0f, 0x11, 0x12},
+		VCPPowerMode:   {0x01, 0x04},
+		VCPVolume:      nil,
+	}, caps.VCP)
+	assert.True(t, caps.Supports(VCPInputSource))
+	assert.False(t, caps.Supports(0x14))
+
+	// 没有最外层的括号，功能码之间没有空格，有嵌套的括号
+	caps, err = ParseCapabilities("type(lcd)vcp(1012DF(01(02 03)04))")
+	require.NoError(t, err)
+	assert.Equal(t, map[byte][]uint16{
+		VCPBrightness: nil,
+		VCPContrast:   nil,
+		0xdf:          {0x01, 0x04},
+	}, caps.VCP)
+
+	_, err = ParseCapabilities("(vcp(10 12)")
+	assert.Error(t, err)
+	_, err = ParseCapabilities("vcp(1G)")
+	assert.Error(t, err)
+	_, err = ParseCapabilities("type")
+	assert.Error(t, err)
+}
+
+func TestFindBusPath(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ddcci")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	oldDrmSysDir, oldDevDir, oldOpenBus := drmSysDir, devDir, openBus
+	defer func() {
+		drmSysDir, devDir, openBus = oldDrmSysDir, oldDevDir, oldOpenBus
+	}()
+	drmSysDir = filepath.Join(dir, "drm")
+	devDir = filepath.Join(dir, "dev")
+	// 查找时不能打开任何 i2c 设备
+	openBus = func(path string) (Bus, error) {
+		t.Errorf("unexpected open %s", path)
+		return nil, os.ErrPermission
+	}
+
+	bus := newFakeBus()
+	otherEdid := make([]byte, edidSize)
+
+	// HDMI 接口通过 ddc 链接找到适配器，DP 接口的适配器是子目录
+	hdmi := filepath.Join(drmSysDir, "card0-HDMI-A-1")
+	dp := filepath.Join(drmSysDir, "card0-DP-1")
+	require.NoError(t, os.MkdirAll(hdmi, 0755))
+	require.NoError(t, os.MkdirAll(filepath.Join(dp, "i2c-7"), 0755))
+	require.NoError(t, os.Symlink("../../i2c-3", filepath.Join(hdmi, "ddc")))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(hdmi, "edid"), otherEdid, 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dp, "edid"), bus.edid, 0644))
+
+	path, err := FindBusPath(bus.edid)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(devDir, "i2c-7"), path)
+
+	otherEdid[0] = 1
+	_, err = FindBusPath(otherEdid)
+	assert.Equal(t, ErrNotFound, err)
+	otherEdid[0] = 0
+	path, err = FindBusPath(otherEdid)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(devDir, "i2c-3"), path)
+
+	// 内置面板即使 EDID 相同也不使用
+	edp := filepath.Join(drmSysDir, "card0-eDP-1")
+	require.NoError(t, os.RemoveAll(dp))
+	require.NoError(t, os.MkdirAll(edp, 0755))
+	require.NoError(t, os.Symlink("../../i2c-1", filepath.Join(edp, "ddc")))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(edp, "edid"), bus.edid, 0644))
+	_, err = FindBusPath(bus.edid)
+	assert.Equal(t, ErrNotFound, err)
+
+	// sysfs 中没有连接器时不探测 i2c 适配器
+	require.NoError(t, os.RemoveAll(drmSysDir))
+	require.NoError(t, os.MkdirAll(devDir, 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(devDir, "i2c-4"), nil, 0644))
+	_, err = FindBusPath(bus.edid)
+	assert.Equal(t, ErrNotFound, err)
+
+	_, err = FindBusPath(bus.edid[:10])
+	assert.Error(t, err)
+}
+
+func Test_isBuiltinConnector(t *testing.T) {
+	assert.True(t, isBuiltinConnector("card0-eDP-1"))
+	assert.True(t, isBuiltinConnector("card1-LVDS-1"))
+	assert.True(t, isBuiltinConnector("card0-DSI-1"))
+	assert.False(t, isBuiltinConnector("card0-DP-1"))
+	assert.False(t, isBuiltinConnector("card0-HDMI-A-1"))
+	assert.False(t, isBuiltinConnector("card0"))
+}
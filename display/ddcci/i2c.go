@@ -0,0 +1,159 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package ddcci
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+// 设置 i2c 从设备地址的 ioctl，见 linux/i2c-dev.h
+const ioctlI2CSlave = 0x0703
+
+var (
+	drmSysDir = "/sys/class/drm"
+	devDir    = "/dev"
+)
+
+// 内置面板的 DRM 连接器类型，笔记本的屏幕通过背光调节亮度，不使用 DDC/CI
+var builtinConnectorTypes = []string{"eDP", "LVDS", "DSI"}
+
+// openBus 打开 i2c 设备，测试时替换
+var openBus = openI2CBus
+
+var ErrNotFound = errors.New("ddc/ci: not found i2c bus of monitor")
+
+type i2cBus struct {
+	file *os.File
+	addr uint16
+}
+
+func openI2CBus(path string) (Bus, error) {
+	file, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return nil, err
+	}
+	return &i2cBus{file: file}, nil
+}
+
+func (b *i2cBus) setAddr(addr uint16) error {
+	if b.addr == addr {
+		return nil
+	}
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, b.file.Fd(), ioctlI2CSlave, uintptr(addr))
+	if errno != 0 {
+		return fmt.Errorf("set i2c slave address %#x failed: %v", addr, errno)
+	}
+	b.addr = addr
+	return nil
+}
+
+func (b *i2cBus) Read(addr uint16, buf []byte) error {
+	err := b.setAddr(addr)
+	if err != nil {
+		return err
+	}
+	n, err := b.file.Read(buf)
+	if err != nil {
+		return err
+	}
+	if n != len(buf) {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+
+func (b *i2cBus) Write(addr uint16, data []byte) error {
+	err := b.setAddr(addr)
+	if err != nil {
+		return err
+	}
+	_, err = b.file.Write(data)
+	return err
+}
+
+func (b *i2cBus) Close() error {
+	return b.file.Close()
+}
+
+func edidEqual(a, b []byte) bool {
+	if len(a) < edidSize || len(b) < edidSize {
+		return false
+	}
+	return bytes.Equal(a[:edidSize], b[:edidSize])
+}
+
+// FindBusPath 找到 EDID 为 edid 的显示器连接的 i2c 设备文件。
+// 只在 /sys/class/drm 中按 EDID 找到 DRM 连接器，再找连接器的 i2c 适配器，
+// 不会在 i2c 适配器上读写来探测，以免影响其他设备。内置面板的连接器不使用。
+func FindBusPath(edid []byte) (string, error) {
+	if len(edid) < edidSize {
+		return "", errors.New("ddc/ci: invalid edid")
+	}
+	connectors, err := filepath.Glob(filepath.Join(drmSysDir, "card*-*"))
+	if err != nil {
+		return "", err
+	}
+	for _, connector := range connectors {
+		if isBuiltinConnector(filepath.Base(connector)) {
+			continue
+		}
+		data, err := ioutil.ReadFile(filepath.Join(connector, "edid"))
+		if err != nil || !edidEqual(data, edid) {
+			continue
+		}
+		adapter := getConnectorAdapter(connector)
+		if adapter == "" {
+			continue
+		}
+		return filepath.Join(devDir, adapter), nil
+	}
+	return "", ErrNotFound
+}
+
+// isBuiltinConnector 判断 DRM 连接器是否是内置面板，name 比如 card0-eDP-1
+func isBuiltinConnector(name string) bool {
+	idx := strings.Index(name, "-")
+	if idx < 0 {
+		return false
+	}
+	connectorType := name[idx+1:]
+	for _, t := range builtinConnectorTypes {
+		if strings.HasPrefix(connectorType, t+"-") {
+			return true
+		}
+	}
+	return false
+}
+
+// getConnectorAdapter 获取 DRM 连接器的 i2c 适配器名，比如 i2c-5
+func getConnectorAdapter(connector string) string {
+	// 大部分驱动有 ddc 链接，DP 接口的适配器是连接器的子目录
+	target, err := os.Readlink(filepath.Join(connector, "ddc"))
+	if err == nil {
+		return filepath.Base(target)
+	}
+	adapters, _ := filepath.Glob(filepath.Join(connector, "i2c-*"))
+	if len(adapters) > 0 {
+		return filepath.Base(adapters[0])
+	}
+	return ""
+}
+
+// Find 打开 EDID 为 edid 的显示器
+func Find(edid []byte) (*Display, error) {
+	path, err := FindBusPath(edid)
+	if err != nil {
+		return nil, err
+	}
+	return Open(path)
+}
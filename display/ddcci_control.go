@@ -0,0 +1,190 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package display
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/linuxdeepin/go-lib/strv"
+	"github.com/linuxdeepin/startdde/display/brightness"
+	"github.com/linuxdeepin/startdde/display/ddcci"
+)
+
+// 可以通过 DDC/CI 控制的功能
+const (
+	ddcciControlBrightness  = "brightness"
+	ddcciControlContrast    = "contrast"
+	ddcciControlInputSource = "input-source"
+	ddcciControlPowerMode   = "power-mode"
+	ddcciControlVolume      = "volume"
+)
+
+// ddcciControls 功能名称和 VCP 功能码，按 DdcciControls 属性中的顺序排列
+var ddcciControls = []struct {
+	name string
+	code byte
+}{
+	{ddcciControlBrightness, ddcci.VCPBrightness},
+	{ddcciControlContrast, ddcci.VCPContrast},
+	{ddcciControlInputSource, ddcci.VCPInputSource},
+	{ddcciControlPowerMode, ddcci.VCPPowerMode},
+	{ddcciControlVolume, ddcci.VCPVolume},
+}
+
+func getDdcciControlCode(name string) (byte, bool) {
+	for _, control := range ddcciControls {
+		if control.name == name {
+			return control.code, true
+		}
+	}
+	return 0, false
+}
+
+// getDdcciControls 获取显示器支持的功能，没有能力字符串时 caps 为 nil，用 supports 逐个判断
+func getDdcciControls(caps *ddcci.Capabilities, supports func(code byte) bool) strv.Strv {
+	var result strv.Strv
+	for _, control := range ddcciControls {
+		if caps != nil {
+			if !caps.Supports(control.code) {
+				continue
+			}
+		} else if !supports(control.code) {
+			continue
+		}
+		result = append(result, control.name)
+	}
+	return result
+}
+
+// probeMonitorDdcci 查找显示器的 i2c 设备并读取支持的功能，比较慢，在 goroutine 中调用
+func (m *Manager) probeMonitorDdcci(monitor *Monitor) {
+	monitor.PropsMu.RLock()
+	edidData := monitor.edid
+	found := monitor.ddcciDisplay != nil
+	monitor.PropsMu.RUnlock()
+	if len(edidData) == 0 || found {
+		return
+	}
+
+	display, err := ddcci.Find(edidData)
+	if err != nil {
+		logger.Debugf("monitor %v ddc/ci not available: %v", monitor.Name, err)
+		return
+	}
+
+	capsStr, err := display.Capabilities()
+	var caps *ddcci.Capabilities
+	if err == nil {
+		caps, err = ddcci.ParseCapabilities(capsStr)
+	}
+	if err != nil {
+		logger.Debugf("monitor %v get ddc/ci capabilities failed: %v", monitor.Name, err)
+	}
+	controls := getDdcciControls(caps, func(code byte) bool {
+		_, _, err := display.GetVCP(code)
+		return err == nil
+	})
+	if len(controls) == 0 {
+		_ = display.Close()
+		return
+	}
+
+	monitor.PropsMu.Lock()
+	if !monitor.realConnected || monitor.ddcciDisplay != nil {
+		monitor.PropsMu.Unlock()
+		_ = display.Close()
+		return
+	}
+	monitor.ddcciDisplay = display
+	monitor.ddcciCaps = caps
+	monitor.setPropDdcciControls(controls)
+	monitor.PropsMu.Unlock()
+	logger.Debugf("monitor %v ddc/ci controls: %v", monitor.Name, controls)
+
+	if controls.Contains(ddcciControlBrightness) {
+		brightness.SetDDCCIDisplay(encodeEdidBase64(edidData), display)
+	}
+}
+
+// releaseMonitorDdcci 显示器断开时关闭 i2c 设备
+func (m *Manager) releaseMonitorDdcci(monitor *Monitor) {
+	monitor.PropsMu.Lock()
+	display := monitor.ddcciDisplay
+	edidData := monitor.edid
+	monitor.ddcciDisplay = nil
+	monitor.ddcciCaps = nil
+	monitor.setPropDdcciControls(nil)
+	monitor.PropsMu.Unlock()
+	if display == nil {
+		return
+	}
+
+	brightness.SetDDCCIDisplay(encodeEdidBase64(edidData), nil)
+	err := display.Close()
+	if err != nil {
+		logger.Warning(err)
+	}
+}
+
+func (m *Manager) updateMonitorDdcci(monitor *Monitor, connected bool) {
+	if connected {
+		go m.probeMonitorDdcci(monitor)
+	} else {
+		m.releaseMonitorDdcci(monitor)
+	}
+}
+
+// getDdcciControl 获取功能 name 使用的设备、功能码和可以设置的值
+func (m *Monitor) getDdcciControl(name string) (*ddcci.Display, byte, []uint16, error) {
+	code, ok := getDdcciControlCode(name)
+	if !ok {
+		return nil, 0, nil, fmt.Errorf("invalid ddc/ci control %q", name)
+	}
+	m.PropsMu.RLock()
+	defer m.PropsMu.RUnlock()
+	if !m.DdcciControls.Contains(name) {
+		return nil, 0, nil, fmt.Errorf("monitor %v not support ddc/ci control %q", m.Name, name)
+	}
+	var values []uint16
+	if m.ddcciCaps != nil {
+		values = m.ddcciCaps.VCP[code]
+	}
+	return m.ddcciDisplay, code, values, nil
+}
+
+func (m *Monitor) getDdcciControlValue(name string) (current, max uint16, err error) {
+	display, code, _, err := m.getDdcciControl(name)
+	if err != nil {
+		return 0, 0, err
+	}
+	return display.GetVCP(code)
+}
+
+func (m *Monitor) setDdcciControlValue(name string, value uint32) error {
+	display, code, values, err := m.getDdcciControl(name)
+	if err != nil {
+		return err
+	}
+	if value > math.MaxUint16 {
+		return fmt.Errorf("invalid ddc/ci control value %v", value)
+	}
+	if len(values) > 0 && !uint16SliceContains(values, uint16(value)) {
+		return fmt.Errorf("ddc/ci control %q not support value %#x", name, value)
+	}
+	return display.SetVCP(code, uint16(value))
+}
+
+func (m *Monitor) getDdcciControlValues(name string) ([]uint32, error) {
+	_, _, values, err := m.getDdcciControl(name)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]uint32, len(values))
+	for i, v := range values {
+		result[i] = uint32(v)
+	}
+	return result, nil
+}
@@ -0,0 +1,57 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package display
+
+import (
+	"testing"
+
+	"github.com/linuxdeepin/go-lib/strv"
+	"github.com/linuxdeepin/startdde/display/ddcci"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetDdcciControls(t *testing.T) {
+	caps, err := ddcci.ParseCapabilities("(type(lcd)vcp(10 12 60(0F 11) 14(05 06)))")
+	require.NoError(t, err)
+	controls := getDdcciControls(caps, nil)
+	assert.Equal(t, strv.Strv{ddcciControlBrightness, ddcciControlContrast, ddcciControlInputSource}, controls)
+
+	// 没有能力字符串时逐个判断
+	controls = getDdcciControls(nil, func(code byte) bool {
+		return code == ddcci.VCPVolume || code == ddcci.VCPPowerMode
+	})
+	assert.Equal(t, strv.Strv{ddcciControlPowerMode, ddcciControlVolume}, controls)
+
+	code, ok := getDdcciControlCode(ddcciControlInputSource)
+	assert.True(t, ok)
+	assert.Equal(t, ddcci.VCPInputSource, code)
+	_, ok = getDdcciControlCode("sharpness")
+	assert.False(t, ok)
+}
+
+func TestMonitor_getDdcciControl(t *testing.T) {
+	caps, err := ddcci.ParseCapabilities("vcp(10 60(0F 11))")
+	require.NoError(t, err)
+	monitor := &Monitor{
+		Name:          "HDMI-1",
+		DdcciControls: strv.Strv{ddcciControlBrightness, ddcciControlInputSource},
+		ddcciCaps:     caps,
+	}
+
+	_, _, values, err := monitor.getDdcciControl(ddcciControlInputSource)
+	require.NoError(t, err)
+	assert.Equal(t, []uint16{0x0f, 0x11}, values)
+
+	_, err = monitor.getDdcciControlValues(ddcciControlVolume)
+	assert.Error(t, err)
+	_, err = monitor.getDdcciControlValues("sharpness")
+	assert.Error(t, err)
+	// 不能设置没有列出的输入源
+	err = monitor.setDdcciControlValue(ddcciControlInputSource, 0x12)
+	assert.Error(t, err)
+	err = monitor.setDdcciControlValue(ddcciControlBrightness, 0x10000)
+	assert.Error(t, err)
+}
@@ -45,7 +45,16 @@ func (h *scaleFactorsHelper) SetScaleFactors(factors map[string]float64) error {
 		_scaleFactors = factors
 		return nil
 	}
-	return _dpy.setScaleFactors(factors)
+	err := _dpy.setScaleFactors(factors)
+	if err != nil {
+		return err
+	}
+	// 显示器缩放比例不同时，需要重新设置 crtc 变换
+	err = _dpy.applyMonitorsScale()
+	if err != nil {
+		logger.Warning("apply monitors scale failed:", err)
+	}
+	return nil
 }
 
 func (h *scaleFactorsHelper) GetScaleFactors() (map[string]float64, error) {
@@ -126,6 +135,14 @@ func StartPart2() error {
 	return nil
 }
 
+// Stop 会话退出前释放申请的传感器
+func Stop() {
+	if _dpy == nil {
+		return
+	}
+	_dpy.destroy()
+}
+
 func SetLogLevel(level log.Priority) {
 	logger.SetLogLevel(level)
 }
@@ -69,6 +69,19 @@ func (v *Manager) emitPropChangedBrightness(value map[string]float64) error {
 	return v.service.EmitPropertyChanged(v, "Brightness", value)
 }
 
+func (v *Manager) setPropAutoBrightness(value bool) (changed bool) {
+	if v.AutoBrightness != value {
+		v.AutoBrightness = value
+		v.emitPropChangedAutoBrightness(value)
+		return true
+	}
+	return false
+}
+
+func (v *Manager) emitPropChangedAutoBrightness(value bool) error {
+	return v.service.EmitPropertyChanged(v, "AutoBrightness", value)
+}
+
 func (v *Manager) setPropTouchscreens(value dxTouchscreens) {
 	v.Touchscreens = value
 	v.emitPropChangedTouchscreens(value)
@@ -174,6 +187,97 @@ func (v *Manager) emitPropChangedMaxBacklightBrightness(value uint32) error {
 	return v.service.EmitPropertyChanged(v, "MaxBacklightBrightness", value)
 }
 
+func (v *Manager) setPropMirrorSource(value string) (changed bool) {
+	if v.MirrorSource != value {
+		v.MirrorSource = value
+		v.emitPropChangedMirrorSource(value)
+		return true
+	}
+	return false
+}
+
+func (v *Manager) emitPropChangedMirrorSource(value string) error {
+	return v.service.EmitPropertyChanged(v, "MirrorSource", value)
+}
+
+func (v *Manager) setPropMirrorScaling(value string) (changed bool) {
+	if v.MirrorScaling != value {
+		v.MirrorScaling = value
+		v.emitPropChangedMirrorScaling(value)
+		return true
+	}
+	return false
+}
+
+func (v *Manager) emitPropChangedMirrorScaling(value string) error {
+	return v.service.EmitPropertyChanged(v, "MirrorScaling", value)
+}
+
+func (v *Manager) setPropNightLightSchedule(value string) (changed bool) {
+	if v.NightLightSchedule != value {
+		v.NightLightSchedule = value
+		v.emitPropChangedNightLightSchedule(value)
+		return true
+	}
+	return false
+}
+
+func (v *Manager) emitPropChangedNightLightSchedule(value string) error {
+	return v.service.EmitPropertyChanged(v, "NightLightSchedule", value)
+}
+
+func (v *Manager) setPropNightLightFrom(value string) (changed bool) {
+	if v.NightLightFrom != value {
+		v.NightLightFrom = value
+		v.emitPropChangedNightLightFrom(value)
+		return true
+	}
+	return false
+}
+
+func (v *Manager) emitPropChangedNightLightFrom(value string) error {
+	return v.service.EmitPropertyChanged(v, "NightLightFrom", value)
+}
+
+func (v *Manager) setPropNightLightTo(value string) (changed bool) {
+	if v.NightLightTo != value {
+		v.NightLightTo = value
+		v.emitPropChangedNightLightTo(value)
+		return true
+	}
+	return false
+}
+
+func (v *Manager) emitPropChangedNightLightTo(value string) error {
+	return v.service.EmitPropertyChanged(v, "NightLightTo", value)
+}
+
+func (v *Manager) setPropRotationLocked(value bool) (changed bool) {
+	if v.RotationLocked != value {
+		v.RotationLocked = value
+		v.emitPropChangedRotationLocked(value)
+		return true
+	}
+	return false
+}
+
+func (v *Manager) emitPropChangedRotationLocked(value bool) error {
+	return v.service.EmitPropertyChanged(v, "RotationLocked", value)
+}
+
+func (v *Manager) setPropLidClosePolicy(value string) (changed bool) {
+	if v.LidClosePolicy != value {
+		v.LidClosePolicy = value
+		v.emitPropChangedLidClosePolicy(value)
+		return true
+	}
+	return false
+}
+
+func (v *Manager) emitPropChangedLidClosePolicy(value string) error {
+	return v.service.EmitPropertyChanged(v, "LidClosePolicy", value)
+}
+
 func (v *Manager) setPropColorTemperatureMode(value int32) (changed bool) {
 	if v.ColorTemperatureMode != value {
 		v.ColorTemperatureMode = value
@@ -265,6 +369,84 @@ func (v *Monitor) emitPropChangedModel(value string) error {
 	return v.service.EmitPropertyChanged(v, "Model", value)
 }
 
+func (v *Monitor) setPropSerialNumber(value string) (changed bool) {
+	if v.SerialNumber != value {
+		v.SerialNumber = value
+		v.emitPropChangedSerialNumber(value)
+		return true
+	}
+	return false
+}
+
+func (v *Monitor) emitPropChangedSerialNumber(value string) error {
+	return v.service.EmitPropertyChanged(v, "SerialNumber", value)
+}
+
+func (v *Monitor) setPropManufactureDate(value string) (changed bool) {
+	if v.ManufactureDate != value {
+		v.ManufactureDate = value
+		v.emitPropChangedManufactureDate(value)
+		return true
+	}
+	return false
+}
+
+func (v *Monitor) emitPropChangedManufactureDate(value string) error {
+	return v.service.EmitPropertyChanged(v, "ManufactureDate", value)
+}
+
+func (v *Monitor) setPropHDRCapable(value bool) (changed bool) {
+	if v.HDRCapable != value {
+		v.HDRCapable = value
+		v.emitPropChangedHDRCapable(value)
+		return true
+	}
+	return false
+}
+
+func (v *Monitor) emitPropChangedHDRCapable(value bool) error {
+	return v.service.EmitPropertyChanged(v, "HDRCapable", value)
+}
+
+func (v *Monitor) setPropVRRRange(value []uint32) (changed bool) {
+	if !uint32SliceEqual(v.VRRRange, value) {
+		v.VRRRange = value
+		v.emitPropChangedVRRRange(value)
+		return true
+	}
+	return false
+}
+
+func (v *Monitor) emitPropChangedVRRRange(value []uint32) error {
+	return v.service.EmitPropertyChanged(v, "VRRRange", value)
+}
+
+func (v *Monitor) setPropVrrCapable(value bool) (changed bool) {
+	if v.VrrCapable != value {
+		v.VrrCapable = value
+		v.emitPropChangedVrrCapable(value)
+		return true
+	}
+	return false
+}
+
+func (v *Monitor) emitPropChangedVrrCapable(value bool) error {
+	return v.service.EmitPropertyChanged(v, "VrrCapable", value)
+}
+
+func (v *Monitor) setPropVrrPolicy(value string) (changed bool) {
+	if v.VrrPolicy != value {
+		v.VrrPolicy = value
+		v.emitPropChangedVrrPolicy(value)
+		return true
+	}
+	return false
+}
+
+func (v *Monitor) emitPropChangedVrrPolicy(value string) error {
+	return v.service.EmitPropertyChanged(v, "VrrPolicy", value)
+}
+
 func (v *Monitor) setPropRotations(value []uint16) (changed bool) {
 	if !uint16SliceEqual(v.Rotations, value) {
 		v.Rotations = value
@@ -486,6 +668,19 @@ func (v *Monitor) emitPropChangedCurrentRotateMode(value uint8) error {
 	return v.service.EmitPropertyChanged(v, "CurrentRotateMode", value)
 }
 
+func (v *Monitor) setPropScale(value float64) (changed bool) {
+	if v.Scale != value {
+		v.Scale = value
+		v.emitPropChangedScale(value)
+		return true
+	}
+	return false
+}
+
+func (v *Monitor) emitPropChangedScale(value float64) error {
+	return v.service.EmitPropertyChanged(v, "Scale", value)
+}
+
 func (v *Monitor) setPropCurrentMode(value ModeInfo) (changed bool) {
 	if v.CurrentMode != value {
 		v.CurrentMode = value
@@ -524,3 +719,107 @@ func (v *Monitor) setPropAvailableFillModes(value strv.Strv) (changed bool) {
 func (v *Monitor) emitPropChangedAvailableFillModes(value strv.Strv) error {
 	return v.service.EmitPropertyChanged(v, "AvailableFillModes", value)
 }
+
+func (v *Monitor) setPropBroadcastRGB(value string) (changed bool) {
+	if v.BroadcastRGB != value {
+		v.BroadcastRGB = value
+		v.emitPropChangedBroadcastRGB(value)
+		return true
+	}
+	return false
+}
+
+func (v *Monitor) emitPropChangedBroadcastRGB(value string) error {
+	return v.service.EmitPropertyChanged(v, "BroadcastRGB", value)
+}
+
+func (v *Monitor) setPropAvailableBroadcastRGB(value strv.Strv) (changed bool) {
+	if !v.AvailableBroadcastRGB.Equal(value) {
+		v.AvailableBroadcastRGB = value
+		v.emitPropChangedAvailableBroadcastRGB(value)
+		return true
+	}
+	return false
+}
+
+func (v *Monitor) emitPropChangedAvailableBroadcastRGB(value strv.Strv) error {
+	return v.service.EmitPropertyChanged(v, "AvailableBroadcastRGB", value)
+}
+
+func (v *Monitor) setPropMaxBpc(value uint32) (changed bool) {
+	if v.MaxBpc != value {
+		v.MaxBpc = value
+		v.emitPropChangedMaxBpc(value)
+		return true
+	}
+	return false
+}
+
+func (v *Monitor) emitPropChangedMaxBpc(value uint32) error {
+	return v.service.EmitPropertyChanged(v, "MaxBpc", value)
+}
+
+func (v *Monitor) setPropMaxBpcRange(value []uint32) (changed bool) {
+	if !uint32SliceEqual(v.MaxBpcRange, value) {
+		v.MaxBpcRange = value
+		v.emitPropChangedMaxBpcRange(value)
+		return true
+	}
+	return false
+}
+
+func (v *Monitor) emitPropChangedMaxBpcRange(value []uint32) error {
+	return v.service.EmitPropertyChanged(v, "MaxBpcRange", value)
+}
+
+func (v *Monitor) setPropColorspace(value string) (changed bool) {
+	if v.Colorspace != value {
+		v.Colorspace = value
+		v.emitPropChangedColorspace(value)
+		return true
+	}
+	return false
+}
+
+func (v *Monitor) emitPropChangedColorspace(value string) error {
+	return v.service.EmitPropertyChanged(v, "Colorspace", value)
+}
+
+func (v *Monitor) setPropAvailableColorspaces(value strv.Strv) (changed bool) {
+	if !v.AvailableColorspaces.Equal(value) {
+		v.AvailableColorspaces = value
+		v.emitPropChangedAvailableColorspaces(value)
+		return true
+	}
+	return false
+}
+
+func (v *Monitor) emitPropChangedAvailableColorspaces(value strv.Strv) error {
+	return v.service.EmitPropertyChanged(v, "AvailableColorspaces", value)
+}
+
+func (v *Monitor) setPropIccProfile(value string) (changed bool) {
+	if v.IccProfile != value {
+		v.IccProfile = value
+		v.emitPropChangedIccProfile(value)
+		return true
+	}
+	return false
+}
+
+func (v *Monitor) emitPropChangedIccProfile(value string) error {
+	return v.service.EmitPropertyChanged(v, "IccProfile", value)
+}
+
+func (v *Monitor) setPropDdcciControls(value strv.Strv) (changed bool) {
+	if !v.DdcciControls.Equal(value) {
+		v.DdcciControls = value
+		v.emitPropChangedDdcciControls(value)
+		return true
+	}
+	return false
+}
+
+func (v *Monitor) emitPropChangedDdcciControls(value strv.Strv) error {
+	return v.service.EmitPropertyChanged(v, "DdcciControls", value)
+}
@@ -41,6 +41,14 @@ type SysConfig struct {
 	ScaleFactors map[string]float64 // 缩放比例
 	FillModes    map[string]string  // key 是特殊的 fillMode Key
 	Cache        SysCache
+	// 复制模式的源显示器的 uuid，为空时使用主屏
+	MirrorSource string `json:",omitempty"`
+	// 复制模式下分辨率不同时的缩放方式，letterbox 或 stretch
+	MirrorScaling string `json:",omitempty"`
+	// 显示器的颜色输出设置，key 是显示器的 uuid
+	OutputColors map[string]SysOutputColorConfig `json:",omitempty"`
+	// 亮度曲线、最小亮度和调节步长
+	Brightness *SysBrightnessConfig `json:",omitempty"`
 }
 
 type SysCache struct {
@@ -52,6 +60,20 @@ type SysCache struct {
 type UserConfig struct {
 	Version string
 	Screens map[string]UserScreenConfig
+	// 命名配置，key 是配置名
+	Profiles map[string]*Profile `json:",omitempty"`
+	// 自动调节色温的配置
+	NightLight *NightLightConfig `json:",omitempty"`
+	// 显示器的 ICC 配置文件路径，key 是显示器的 uuid
+	IccProfiles map[string]string `json:",omitempty"`
+	// 自动亮度的配置
+	AutoBrightness *AutoBrightnessConfig `json:",omitempty"`
+	// 锁定自动旋转
+	RotationLocked bool `json:",omitempty"`
+	// 合上笔记本盖子时的处理方式，为空时是 external-only
+	LidClosePolicy string `json:",omitempty"`
+	// 合上盖子前的显示模式，key 是 monitorsId
+	LidSwitchStates map[string]*LidSwitchState `json:",omitempty"`
 }
 
 func (cfg *UserConfig) fix() {
@@ -304,6 +326,46 @@ func (cfg *SysConfig) updateUuid(monitors Monitors) (changed bool) {
 	for key, value := range fillModesAdditional {
 		fillModes[key] = value
 	}
+
+	// 更新 outputColors 中的 uuid
+	outputColors := cfg.OutputColors
+	outputColorKeys := make([]string, 0, len(outputColors))
+	for key := range outputColors {
+		outputColorKeys = append(outputColorKeys, key)
+	}
+	if updateUuidKeys(outputColorKeys, monitors, func(oldKey, newKey string) {
+		outputColors[newKey] = outputColors[oldKey]
+		delete(outputColors, oldKey)
+	}) {
+		changed = true
+	}
+
+	// 更新亮度配置中的 uuid
+	if cfg.Brightness != nil {
+		monitorBrightness := cfg.Brightness.Monitors
+		monitorBrightnessKeys := make([]string, 0, len(monitorBrightness))
+		for key := range monitorBrightness {
+			monitorBrightnessKeys = append(monitorBrightnessKeys, key)
+		}
+		if updateUuidKeys(monitorBrightnessKeys, monitors, func(oldKey, newKey string) {
+			monitorBrightness[newKey] = monitorBrightness[oldKey]
+			delete(monitorBrightness, oldKey)
+		}) {
+			changed = true
+		}
+	}
+	return
+}
+
+// updateUuidKeys 更新以 uuid 为键的 map 中旧版本的 uuid，rename 把 oldKey 的值移动到 newKey
+func updateUuidKeys(keys []string, monitors Monitors, rename func(oldKey, newKey string)) (changed bool) {
+	for _, key := range keys {
+		uuid, uuidChanged := updateUuid(key, monitors)
+		if uuidChanged {
+			rename(key, uuid)
+			changed = true
+		}
+	}
 	return
 }
 
@@ -537,6 +599,8 @@ type SysMonitorConfig struct {
 	RefreshRate float64
 	Brightness  float64
 	Primary     bool
+	// 可变刷新率策略，为空时是 never
+	VrrPolicy string `json:",omitempty"`
 }
 
 func (c *SysMonitorConfig) fix() {
@@ -547,6 +611,9 @@ func (c *SysMonitorConfig) fix() {
 	if !isValidBrightness(c.Brightness) {
 		c.Brightness = 1
 	}
+	if c.VrrPolicy != "" && !isValidVrrPolicy(c.VrrPolicy) {
+		c.VrrPolicy = ""
+	}
 }
 
 func (c *SysMonitorConfig) modify(changes monitorChanges) {
@@ -567,6 +634,8 @@ func (c *SysMonitorConfig) modify(changes monitorChanges) {
 			c.RefreshRate, ok = value.(float64)
 		case monitorPropEnabled:
 			c.Enabled, ok = value.(bool)
+		case monitorPropVrrPolicy:
+			c.VrrPolicy, ok = value.(string)
 		default:
 			ok = true
 			logger.Warningf("invalid monitor property name, uuid: %v, name: %v", c.UUID, name)
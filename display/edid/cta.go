@@ -0,0 +1,227 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package edid
+
+import (
+	"math"
+)
+
+// CTA-861 数据块的标签
+const (
+	ctaTagVendor   = 3
+	ctaTagExtended = 7
+)
+
+// CTA-861 扩展数据块的标签
+const (
+	ctaExtTagColorimetry = 5
+	ctaExtTagHDRStatic   = 6
+	ctaExtTagHFSCDB      = 0x79
+)
+
+// 厂商数据块的 IEEE OUI
+const (
+	ouiHDMI      = 0x000c03
+	ouiHDMIForum = 0xc45dd8
+	ouiAMD       = 0x00001a
+)
+
+// HDR 静态元数据中的传输函数
+const (
+	EOTFTraditionalSDR = 1 << iota
+	EOTFTraditionalHDR
+	EOTFSmpteST2084
+	EOTFHLG
+)
+
+// 色度数据块中支持的色彩空间
+const (
+	ColorimetryXvYCC601   = "xvYCC601"
+	ColorimetryXvYCC709   = "xvYCC709"
+	ColorimetrySYCC601    = "sYCC601"
+	ColorimetryOpYCC601   = "opYCC601"
+	ColorimetryOpRGB      = "opRGB"
+	ColorimetryBT2020cYCC = "BT2020cYCC"
+	ColorimetryBT2020YCC  = "BT2020YCC"
+	ColorimetryBT2020RGB  = "BT2020RGB"
+	ColorimetryST2113RGB  = "ST2113RGB"
+	ColorimetryICtCp      = "ICtCp"
+	ColorimetryDCIP3      = "DCI-P3"
+)
+
+// 色度数据块第 1 个字节中各位对应的色彩空间
+var colorimetryNames = []string{
+	ColorimetryXvYCC601,
+	ColorimetryXvYCC709,
+	ColorimetrySYCC601,
+	ColorimetryOpYCC601,
+	ColorimetryOpRGB,
+	ColorimetryBT2020cYCC,
+	ColorimetryBT2020YCC,
+	ColorimetryBT2020RGB,
+}
+
+// 色度数据块第 2 个字节的高 3 位对应的色彩空间
+var colorimetryNames2 = []struct {
+	bit  byte
+	name string
+}{
+	{0x20, ColorimetryST2113RGB},
+	{0x40, ColorimetryICtCp},
+	{0x80, ColorimetryDCIP3},
+}
+
+// CTA CTA-861 扩展块中的信息
+type CTA struct {
+	Revision uint8
+	// 是否是 HDMI 显示器，存在 HDMI 厂商数据块
+	HDMI bool
+	// 支持的色彩空间
+	Colorimetry []string
+	HDR         *HDRStaticMetadata
+	// HDMI Forum 厂商数据块中的 VRR 范围
+	HdmiVRR VRRRange
+	// AMD 厂商数据块中的 FreeSync 范围
+	FreeSync VRRRange
+}
+
+// HDRStaticMetadata HDR 静态元数据，亮度的单位为 cd/m^2，为 0 表示未知。
+type HDRStaticMetadata struct {
+	EOTFs                uint8
+	MaxLuminance         float64
+	MaxFrameAvgLuminance float64
+	MinLuminance         float64
+}
+
+func (e *Edid) parseCTA(block []byte) {
+	cta := &CTA{
+		Revision: block[1],
+	}
+	e.CTA = cta
+
+	dtdOffset := int(block[2])
+	if dtdOffset == 0 || dtdOffset > blockSize-1 {
+		return
+	}
+
+	// 数据块集合位于 4 到 dtdOffset 之间
+	for i := 4; i < dtdOffset; {
+		tag := block[i] >> 5
+		length := int(block[i] & 0x1f)
+		if i+1+length > dtdOffset {
+			break
+		}
+		cta.parseDataBlock(tag, block[i+1:i+1+length])
+		i += 1 + length
+	}
+
+	// 之后是详细时序，直到像素时钟为 0
+	for i := dtdOffset; i+18 <= blockSize-1; i += 18 {
+		desc := block[i : i+18]
+		if desc[0] == 0 && desc[1] == 0 {
+			break
+		}
+		e.DetailedTimings = append(e.DetailedTimings, parseDetailedTiming(desc))
+	}
+}
+
+func (cta *CTA) parseDataBlock(tag byte, payload []byte) {
+	switch tag {
+	case ctaTagVendor:
+		if len(payload) < 3 {
+			return
+		}
+		oui := uint32(payload[0]) | uint32(payload[1])<<8 | uint32(payload[2])<<16
+		cta.parseVendorBlock(oui, payload[3:])
+
+	case ctaTagExtended:
+		if len(payload) < 1 {
+			return
+		}
+		data := payload[1:]
+		switch payload[0] {
+		case ctaExtTagColorimetry:
+			cta.Colorimetry = parseColorimetry(data)
+		case ctaExtTagHDRStatic:
+			cta.HDR = parseHDRStaticMetadata(data)
+		case ctaExtTagHFSCDB:
+			// 与 HDMI Forum 厂商数据块的结构相同，但开头是 2 个保留字节而不是 OUI
+			if len(data) >= 2 {
+				cta.parseHdmiForum(data[2:])
+			}
+		}
+	}
+}
+
+func (cta *CTA) parseVendorBlock(oui uint32, data []byte) {
+	switch oui {
+	case ouiHDMI:
+		cta.HDMI = true
+	case ouiHDMIForum:
+		cta.parseHdmiForum(data)
+	case ouiAMD:
+		// data[0] data[1] 是版本
+		if len(data) >= 4 {
+			cta.FreeSync = VRRRange{
+				Min: uint32(data[2]),
+				Max: uint32(data[3]),
+			}
+		}
+	}
+}
+
+// parseHdmiForum 解析 HDMI Forum 厂商数据块，data 从版本字节开始
+func (cta *CTA) parseHdmiForum(data []byte) {
+	if len(data) < 7 {
+		return
+	}
+	cta.HdmiVRR = VRRRange{
+		Min: uint32(data[5] & 0x3f),
+		Max: uint32(data[5]&0xc0)<<2 | uint32(data[6]),
+	}
+}
+
+func parseColorimetry(data []byte) []string {
+	if len(data) < 2 {
+		return nil
+	}
+	var result []string
+	for i, name := range colorimetryNames {
+		if data[0]&(1<<uint(i)) != 0 {
+			result = append(result, name)
+		}
+	}
+	for _, v := range colorimetryNames2 {
+		if data[1]&v.bit != 0 {
+			result = append(result, v.name)
+		}
+	}
+	return result
+}
+
+func parseHDRStaticMetadata(data []byte) *HDRStaticMetadata {
+	if len(data) < 2 {
+		return nil
+	}
+	hdr := &HDRStaticMetadata{
+		EOTFs: data[0] & 0x3f,
+	}
+	// data[1] 是支持的静态元数据类型
+	if len(data) >= 3 && data[2] != 0 {
+		hdr.MaxLuminance = decodeLuminance(data[2])
+	}
+	if len(data) >= 4 && data[3] != 0 {
+		hdr.MaxFrameAvgLuminance = decodeLuminance(data[3])
+	}
+	if len(data) >= 5 && hdr.MaxLuminance != 0 {
+		hdr.MinLuminance = hdr.MaxLuminance * math.Pow(float64(data[4])/255, 2) / 100
+	}
+	return hdr
+}
+
+// decodeLuminance 亮度编码为 50*2^(cv/32)
+func decodeLuminance(cv byte) float64 {
+	return 50 * math.Pow(2, float64(cv)/32)
+}
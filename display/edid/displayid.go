@@ -0,0 +1,101 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package edid
+
+// DisplayID 数据块的标签
+const (
+	didTagTypeITiming   = 0x03 // DisplayID 1.x
+	didTagTypeVIITiming = 0x22 // DisplayID 2.0
+	didTagAdaptiveSync  = 0x2b // DisplayID 2.0
+)
+
+const (
+	didTimingDescSize       = 20
+	didAdaptiveSyncDescSize = 6
+)
+
+// DisplayID DisplayID 扩展块中的信息
+type DisplayID struct {
+	// 0x12 表示 1.2，0x20 表示 2.0
+	Version uint8
+	// 1.x 中是显示器类型，2.0 中是主要用途
+	ProductType  uint8
+	AdaptiveSync []VRRRange
+}
+
+func (e *Edid) parseDisplayID(block []byte) {
+	// block[0] 是扩展块标签，之后是 DisplayID 段，最后 1 字节是 EDID 的校验和
+	section := block[1 : blockSize-1]
+	did := &DisplayID{
+		Version:     section[0],
+		ProductType: section[2],
+	}
+	e.DisplayID = did
+
+	end := 4 + int(section[1])
+	if end > len(section) {
+		end = len(section)
+	}
+	for i := 4; i+3 <= end; {
+		tag := section[i]
+		revision := section[i+1]
+		length := int(section[i+2])
+		if tag == 0 && length == 0 {
+			// 填充
+			break
+		}
+		if i+3+length > end {
+			break
+		}
+		payload := section[i+3 : i+3+length]
+		switch tag {
+		case didTagTypeITiming:
+			e.DetailedTimings = append(e.DetailedTimings, parseDidTimings(payload, 10)...)
+		case didTagTypeVIITiming:
+			e.DetailedTimings = append(e.DetailedTimings, parseDidTimings(payload, 1)...)
+		case didTagAdaptiveSync:
+			did.AdaptiveSync = append(did.AdaptiveSync, parseDidAdaptiveSync(revision, payload)...)
+		}
+		i += 3 + length
+	}
+}
+
+// parseDidTimings 解析 Type I 和 Type VII 详细时序，clockUnit 是像素时钟的单位，以 kHz 为单位。
+// 各值都是实际值减 1 后存储的。
+func parseDidTimings(payload []byte, clockUnit uint32) (timings []DetailedTiming) {
+	le16 := func(b []byte) uint16 {
+		return uint16(b[0]) | uint16(b[1])<<8
+	}
+	for i := 0; i+didTimingDescSize <= len(payload); i += didTimingDescSize {
+		d := payload[i : i+didTimingDescSize]
+		clock := uint32(d[0]) | uint32(d[1])<<8 | uint32(d[2])<<16
+		timings = append(timings, DetailedTiming{
+			PixelClock:  (clock + 1) * clockUnit,
+			Interlaced:  d[3]&0x10 != 0,
+			HActive:     le16(d[4:]) + 1,
+			HBlank:      le16(d[6:]) + 1,
+			HSyncOffset: le16(d[8:])&0x7fff + 1,
+			HSyncWidth:  le16(d[10:]) + 1,
+			VActive:     le16(d[12:]) + 1,
+			VBlank:      le16(d[14:]) + 1,
+			VSyncOffset: le16(d[16:])&0x7fff + 1,
+			VSyncWidth:  le16(d[18:]) + 1,
+		})
+	}
+	return
+}
+
+// parseDidAdaptiveSync 解析自适应同步数据块，修订号的 4~6 位表示描述符比 6 字节多出的字节数
+func parseDidAdaptiveSync(revision byte, payload []byte) (ranges []VRRRange) {
+	size := didAdaptiveSyncDescSize + int((revision>>4)&0x7)
+	for i := 0; i+size <= len(payload); i += size {
+		d := payload[i : i+size]
+		ranges = append(ranges, VRRRange{
+			Min: uint32(d[2]),
+			Max: uint32(d[3]) + uint32(d[4]&0x3)<<8 + 1,
+		})
+	}
+	return
+}
@@ -0,0 +1,327 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+// Package edid 解析显示器的 EDID，支持基本块、CTA-861 扩展块和 DisplayID 扩展块。
+package edid
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const blockSize = 128
+
+// 扩展块的标签
+const (
+	extTagCTA       = 0x02
+	extTagDisplayID = 0x70
+)
+
+// 显示器描述符的标签
+const (
+	descTagSerial      = 0xff
+	descTagText        = 0xfe
+	descTagRangeLimits = 0xfd
+	descTagName        = 0xfc
+)
+
+var header = []byte{0x00, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x00}
+
+var (
+	ErrTooShort      = errors.New("edid too short")
+	ErrInvalidHeader = errors.New("invalid edid header")
+	ErrChecksum      = errors.New("invalid edid checksum")
+)
+
+type Edid struct {
+	// 3 个字母的厂商代码
+	Manufacturer string
+	ProductCode  uint16
+	// 基本块中的数字序列号，为 0 表示没有
+	SerialNumber uint32
+	// 序列号描述符中的字符串
+	SerialString string
+	// 名称描述符中的显示器名称
+	MonitorName string
+	// 其他文本描述符中的字符串
+	Texts []string
+
+	// 生产的周，为 0 表示未知
+	Week uint8
+	// 生产的年份，IsModelYear 为 true 时表示型号的年份
+	Year        uint16
+	IsModelYear bool
+
+	Version  uint8
+	Revision uint8
+	Digital  bool
+	// 以厘米为单位的物理尺寸，为 0 表示未知
+	WidthCm  uint8
+	HeightCm uint8
+
+	// 详细时序，第一个是显示器的原生分辨率，之后依次是扩展块中的时序
+	DetailedTimings []DetailedTiming
+	RangeLimits     *RangeLimits
+
+	CTA       *CTA
+	DisplayID *DisplayID
+}
+
+// DetailedTiming 详细时序
+type DetailedTiming struct {
+	// 像素时钟，单位 kHz
+	PixelClock  uint32
+	HActive     uint16
+	HBlank      uint16
+	HSyncOffset uint16
+	HSyncWidth  uint16
+	VActive     uint16
+	VBlank      uint16
+	VSyncOffset uint16
+	VSyncWidth  uint16
+	// 以毫米为单位的图像尺寸
+	WidthMm    uint16
+	HeightMm   uint16
+	Interlaced bool
+}
+
+// RefreshRate 根据像素时钟计算刷新率
+func (t DetailedTiming) RefreshRate() float64 {
+	total := float64(t.HActive+t.HBlank) * float64(t.VActive+t.VBlank)
+	if total == 0 {
+		return 0
+	}
+	rate := float64(t.PixelClock) * 1000 / total
+	if t.Interlaced {
+		rate *= 2
+	}
+	return rate
+}
+
+// RangeLimits 显示器范围限制描述符
+type RangeLimits struct {
+	MinVRate uint16 // Hz
+	MaxVRate uint16 // Hz
+	MinHRate uint16 // kHz
+	MaxHRate uint16 // kHz
+	// 最大像素时钟，单位 MHz
+	MaxPixelClock uint16
+}
+
+// Parse 解析 EDID，扩展块的校验和错误时忽略这个扩展块。
+func Parse(data []byte) (*Edid, error) {
+	if len(data) < blockSize {
+		return nil, ErrTooShort
+	}
+	if !bytes.Equal(data[:len(header)], header) {
+		return nil, ErrInvalidHeader
+	}
+	if !checksumOk(data[:blockSize]) {
+		return nil, ErrChecksum
+	}
+
+	e := &Edid{}
+	e.parseBaseBlock(data[:blockSize])
+
+	extCount := int(data[126])
+	for i := 1; i <= extCount; i++ {
+		if len(data) < (i+1)*blockSize {
+			break
+		}
+		block := data[i*blockSize : (i+1)*blockSize]
+		if !checksumOk(block) {
+			continue
+		}
+		switch block[0] {
+		case extTagCTA:
+			e.parseCTA(block)
+		case extTagDisplayID:
+			e.parseDisplayID(block)
+		}
+	}
+	return e, nil
+}
+
+func checksumOk(block []byte) bool {
+	var sum byte
+	for _, b := range block {
+		sum += b
+	}
+	return sum == 0
+}
+
+func (e *Edid) parseBaseBlock(block []byte) {
+	e.Manufacturer = parseManufacturer(block[8], block[9])
+	e.ProductCode = uint16(block[10]) | uint16(block[11])<<8
+	e.SerialNumber = uint32(block[12]) | uint32(block[13])<<8 | uint32(block[14])<<16 | uint32(block[15])<<24
+
+	week := block[16]
+	if block[17] != 0 {
+		e.Year = uint16(block[17]) + 1990
+	}
+	if week == 0xff {
+		e.IsModelYear = true
+	} else {
+		e.Week = week
+	}
+
+	e.Version = block[18]
+	e.Revision = block[19]
+	e.Digital = block[20]&0x80 != 0
+	e.WidthCm = block[21]
+	e.HeightCm = block[22]
+
+	for i := 0; i < 4; i++ {
+		desc := block[54+i*18 : 54+(i+1)*18]
+		if desc[0] != 0 || desc[1] != 0 {
+			e.DetailedTimings = append(e.DetailedTimings, parseDetailedTiming(desc))
+			continue
+		}
+		e.parseDisplayDescriptor(desc)
+	}
+}
+
+func parseManufacturer(b1, b2 byte) string {
+	v := uint16(b1)<<8 | uint16(b2)
+	var name []byte
+	for i := 2; i >= 0; i-- {
+		c := byte((v>>(5*uint(i)))&0x1f) + 'A' - 1
+		if c < 'A' || c > 'Z' {
+			return ""
+		}
+		name = append(name, c)
+	}
+	return string(name)
+}
+
+func (e *Edid) parseDisplayDescriptor(desc []byte) {
+	switch desc[3] {
+	case descTagSerial:
+		e.SerialString = parseDescriptorText(desc[5:])
+	case descTagName:
+		e.MonitorName = parseDescriptorText(desc[5:])
+	case descTagText:
+		if text := parseDescriptorText(desc[5:]); text != "" {
+			e.Texts = append(e.Texts, text)
+		}
+	case descTagRangeLimits:
+		e.RangeLimits = parseRangeLimits(desc)
+	}
+}
+
+// parseDescriptorText 描述符中的文本以 0x0a 结尾，用空格填充
+func parseDescriptorText(data []byte) string {
+	if idx := bytes.IndexByte(data, 0x0a); idx >= 0 {
+		data = data[:idx]
+	}
+	var sb strings.Builder
+	for _, b := range data {
+		if b >= ' ' && b <= '~' {
+			sb.WriteByte(b)
+		}
+	}
+	return strings.TrimSpace(sb.String())
+}
+
+func parseRangeLimits(desc []byte) *RangeLimits {
+	// EDID 1.4 中 desc[4] 的低 4 位表示对应的值需要加上 255
+	offsets := desc[4]
+	withOffset := func(v byte, bit uint) uint16 {
+		if offsets&(1<<bit) != 0 {
+			return uint16(v) + 255
+		}
+		return uint16(v)
+	}
+	return &RangeLimits{
+		MinVRate:      withOffset(desc[5], 0),
+		MaxVRate:      withOffset(desc[6], 1),
+		MinHRate:      withOffset(desc[7], 2),
+		MaxHRate:      withOffset(desc[8], 3),
+		MaxPixelClock: uint16(desc[9]) * 10,
+	}
+}
+
+func parseDetailedTiming(d []byte) DetailedTiming {
+	return DetailedTiming{
+		PixelClock:  (uint32(d[0]) | uint32(d[1])<<8) * 10,
+		HActive:     uint16(d[2]) | uint16(d[4]&0xf0)<<4,
+		HBlank:      uint16(d[3]) | uint16(d[4]&0x0f)<<8,
+		VActive:     uint16(d[5]) | uint16(d[7]&0xf0)<<4,
+		VBlank:      uint16(d[6]) | uint16(d[7]&0x0f)<<8,
+		HSyncOffset: uint16(d[8]) | uint16(d[11]&0xc0)<<2,
+		HSyncWidth:  uint16(d[9]) | uint16(d[11]&0x30)<<4,
+		VSyncOffset: uint16(d[10]>>4) | uint16(d[11]&0x0c)<<2,
+		VSyncWidth:  uint16(d[10]&0x0f) | uint16(d[11]&0x03)<<4,
+		WidthMm:     uint16(d[12]) | uint16(d[14]&0xf0)<<4,
+		HeightMm:    uint16(d[13]) | uint16(d[14]&0x0f)<<8,
+		Interlaced:  d[17]&0x80 != 0,
+	}
+}
+
+// NativeTiming 获取显示器的原生时序，即基本块中的第一个详细时序
+func (e *Edid) NativeTiming() (DetailedTiming, bool) {
+	if len(e.DetailedTimings) == 0 {
+		return DetailedTiming{}, false
+	}
+	return e.DetailedTimings[0], true
+}
+
+// Serial 获取序列号，优先使用序列号描述符中的字符串
+func (e *Edid) Serial() string {
+	if e.SerialString != "" {
+		return e.SerialString
+	}
+	if e.SerialNumber != 0 {
+		return fmt.Sprintf("%d", e.SerialNumber)
+	}
+	return ""
+}
+
+// ManufactureDate 获取生产日期，格式为 2020-W15，不知道周时只有年份，都不知道时为空。
+func (e *Edid) ManufactureDate() string {
+	if e.Year == 0 {
+		return ""
+	}
+	if e.IsModelYear || e.Week == 0 || e.Week > 54 {
+		return fmt.Sprintf("%d", e.Year)
+	}
+	return fmt.Sprintf("%d-W%02d", e.Year, e.Week)
+}
+
+// HDRCapable 是否支持 HDR，即 HDR 静态元数据中支持 PQ 或 HLG 传输函数。
+func (e *Edid) HDRCapable() bool {
+	if e.CTA == nil || e.CTA.HDR == nil {
+		return false
+	}
+	return e.CTA.HDR.EOTFs&(EOTFSmpteST2084|EOTFHLG) != 0
+}
+
+// VRRRange 获取可变刷新率的范围，依次使用 DisplayID 的自适应同步、HDMI Forum 和 AMD FreeSync 中的信息。
+func (e *Edid) VRRRange() (min, max uint32, ok bool) {
+	var ranges []VRRRange
+	if e.DisplayID != nil {
+		ranges = append(ranges, e.DisplayID.AdaptiveSync...)
+	}
+	if e.CTA != nil {
+		ranges = append(ranges, e.CTA.HdmiVRR, e.CTA.FreeSync)
+	}
+	for _, r := range ranges {
+		if r.valid() {
+			return r.Min, r.Max, true
+		}
+	}
+	return 0, 0, false
+}
+
+// VRRRange 可变刷新率的范围，单位 Hz
+type VRRRange struct {
+	Min uint32
+	Max uint32
+}
+
+func (r VRRRange) valid() bool {
+	return r.Min > 0 && r.Max > r.Min
+}
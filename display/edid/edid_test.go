@@ -0,0 +1,219 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package edid
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func readTestEdid(t *testing.T, name string) []byte {
+	data, err := ioutil.ReadFile(filepath.Join("testdata", name))
+	require.NoError(t, err)
+	return data
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		file            string
+		manufacturer    string
+		productCode     uint16
+		serial          string
+		manufactureDate string
+		monitorName     string
+		widthCm         uint8
+		heightCm        uint8
+		nativeWidth     uint16
+		nativeHeight    uint16
+		nativeRate      float64
+		numTimings      int
+		rangeLimits     *RangeLimits
+		hdrCapable      bool
+		vrrMin          uint32
+		vrrMax          uint32
+		vrrOk           bool
+		colorimetry     []string
+	}{
+		{
+			file:            "vsc-va2478.bin",
+			manufacturer:    "VSC",
+			productCode:     0x8335,
+			serial:          "VDW201543333",
+			manufactureDate: "2020-W15",
+			monitorName:     "VA2478-H-2",
+			widthCm:         53,
+			heightCm:        30,
+			nativeWidth:     1920,
+			nativeHeight:    1080,
+			nativeRate:      60,
+			numTimings:      5,
+			rangeLimits: &RangeLimits{
+				MinVRate:      50,
+				MaxVRate:      75,
+				MinHRate:      24,
+				MaxHRate:      82,
+				MaxPixelClock: 180,
+			},
+		},
+		{
+			file:            "hdr-vrr.bin",
+			manufacturer:    "GSM",
+			productCode:     0x5b09,
+			serial:          "123456",
+			manufactureDate: "2021",
+			monitorName:     "LG ULTRAGEAR",
+			widthCm:         60,
+			heightCm:        34,
+			nativeWidth:     3840,
+			nativeHeight:    2160,
+			nativeRate:      60,
+			numTimings:      2,
+			rangeLimits: &RangeLimits{
+				MinVRate:      40,
+				MaxVRate:      144,
+				MinHRate:      30,
+				MaxHRate:      255,
+				MaxPixelClock: 600,
+			},
+			hdrCapable:  true,
+			vrrMin:      48,
+			vrrMax:      144,
+			vrrOk:       true,
+			colorimetry: []string{ColorimetryBT2020YCC, ColorimetryBT2020RGB, ColorimetryDCIP3},
+		},
+		{
+			file:            "displayid.bin",
+			manufacturer:    "SAM",
+			productCode:     0x7075,
+			serial:          "H4ZT500123",
+			manufactureDate: "2022-W23",
+			monitorName:     "Odyssey G7",
+			widthCm:         70,
+			heightCm:        40,
+			nativeWidth:     2560,
+			nativeHeight:    1440,
+			nativeRate:      59.95,
+			numTimings:      2,
+			// DisplayID 中的范围优先于 FreeSync 的 48~144
+			vrrMin: 48,
+			vrrMax: 165,
+			vrrOk:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			e, err := Parse(readTestEdid(t, tt.file))
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.manufacturer, e.Manufacturer)
+			assert.Equal(t, tt.productCode, e.ProductCode)
+			assert.Equal(t, tt.serial, e.Serial())
+			assert.Equal(t, tt.manufactureDate, e.ManufactureDate())
+			assert.Equal(t, tt.monitorName, e.MonitorName)
+			assert.Equal(t, tt.widthCm, e.WidthCm)
+			assert.Equal(t, tt.heightCm, e.HeightCm)
+			assert.True(t, e.Digital)
+
+			native, ok := e.NativeTiming()
+			require.True(t, ok)
+			assert.Equal(t, tt.nativeWidth, native.HActive)
+			assert.Equal(t, tt.nativeHeight, native.VActive)
+			assert.InDelta(t, tt.nativeRate, native.RefreshRate(), 0.01)
+			assert.Len(t, e.DetailedTimings, tt.numTimings)
+			assert.Equal(t, tt.rangeLimits, e.RangeLimits)
+
+			assert.Equal(t, tt.hdrCapable, e.HDRCapable())
+			vrrMin, vrrMax, vrrOk := e.VRRRange()
+			assert.Equal(t, tt.vrrMin, vrrMin)
+			assert.Equal(t, tt.vrrMax, vrrMax)
+			assert.Equal(t, tt.vrrOk, vrrOk)
+
+			require.NotNil(t, e.CTA)
+			assert.Equal(t, tt.colorimetry, e.CTA.Colorimetry)
+		})
+	}
+}
+
+func TestParseHDRStaticMetadata(t *testing.T) {
+	e, err := Parse(readTestEdid(t, "hdr-vrr.bin"))
+	require.NoError(t, err)
+	require.NotNil(t, e.CTA)
+	assert.True(t, e.CTA.HDMI)
+	assert.Equal(t, VRRRange{Min: 48, Max: 144}, e.CTA.HdmiVRR)
+
+	hdr := e.CTA.HDR
+	require.NotNil(t, hdr)
+	assert.Equal(t, uint8(EOTFTraditionalSDR|EOTFSmpteST2084|EOTFHLG), hdr.EOTFs)
+	assert.InDelta(t, 603.7, hdr.MaxLuminance, 0.1)
+	assert.InDelta(t, 351.3, hdr.MaxFrameAvgLuminance, 0.1)
+	assert.InDelta(t, 0.095, hdr.MinLuminance, 0.001)
+}
+
+func TestParseDisplayID(t *testing.T) {
+	e, err := Parse(readTestEdid(t, "displayid.bin"))
+	require.NoError(t, err)
+	require.NotNil(t, e.DisplayID)
+	assert.Equal(t, uint8(0x20), e.DisplayID.Version)
+	assert.Equal(t, uint8(3), e.DisplayID.ProductType)
+	assert.Equal(t, []VRRRange{{Min: 48, Max: 165}}, e.DisplayID.AdaptiveSync)
+	assert.Equal(t, VRRRange{Min: 48, Max: 144}, e.CTA.FreeSync)
+
+	timing := e.DetailedTimings[1]
+	assert.Equal(t, uint16(2560), timing.HActive)
+	assert.Equal(t, uint16(1440), timing.VActive)
+	assert.Equal(t, uint32(645115), timing.PixelClock)
+	assert.InDelta(t, 165, timing.RefreshRate(), 0.01)
+}
+
+func TestParseInvalid(t *testing.T) {
+	data := readTestEdid(t, "vsc-va2478.bin")
+
+	_, err := Parse(data[:100])
+	assert.Equal(t, ErrTooShort, err)
+
+	invalid := append([]byte{}, data...)
+	invalid[0] = 0xff
+	_, err = Parse(invalid)
+	assert.Equal(t, ErrInvalidHeader, err)
+
+	invalid = append([]byte{}, data...)
+	invalid[20]++
+	_, err = Parse(invalid)
+	assert.Equal(t, ErrChecksum, err)
+
+	// 扩展块校验和错误时忽略扩展块
+	invalid = append([]byte{}, data...)
+	invalid[blockSize+10]++
+	e, err := Parse(invalid)
+	require.NoError(t, err)
+	assert.Nil(t, e.CTA)
+	assert.Len(t, e.DetailedTimings, 1)
+
+	// 只有基本块
+	e, err = Parse(data[:blockSize])
+	require.NoError(t, err)
+	assert.Equal(t, "VSC", e.Manufacturer)
+	assert.Nil(t, e.CTA)
+}
+
+func TestManufactureDate(t *testing.T) {
+	tests := []struct {
+		e    Edid
+		want string
+	}{
+		{Edid{}, ""},
+		{Edid{Year: 2019}, "2019"},
+		{Edid{Year: 2019, Week: 3}, "2019-W03"},
+		{Edid{Year: 2019, IsModelYear: true}, "2019"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, tt.e.ManufactureDate())
+	}
+}
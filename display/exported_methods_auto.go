@@ -12,6 +12,21 @@ func (v *Manager) GetExportedMethods() dbusutil.ExportedMethods {
 			Name: "ApplyChanges",
 			Fn:   v.ApplyChanges,
 		},
+		{
+			Name:   "ApplyChangesWithTimeout",
+			Fn:     v.ApplyChangesWithTimeout,
+			InArgs: []string{"seconds"},
+		},
+		{
+			Name:   "ApplyProfile",
+			Fn:     v.ApplyProfile,
+			InArgs: []string{"name"},
+		},
+		{
+			Name:   "ArrangeMonitors",
+			Fn:     v.ArrangeMonitors,
+			InArgs: []string{"layoutSpec"},
+		},
 		{
 			Name:   "AssociateTouch",
 			Fn:     v.AssociateTouch,
@@ -38,11 +53,25 @@ func (v *Manager) GetExportedMethods() dbusutil.ExportedMethods {
 			Fn:     v.ChangeBrightness,
 			InArgs: []string{"raised"},
 		},
+		{
+			Name: "ConfirmChanges",
+			Fn:   v.ConfirmChanges,
+		},
 		{
 			Name:   "DeleteCustomMode",
 			Fn:     v.DeleteCustomMode,
 			InArgs: []string{"name"},
 		},
+		{
+			Name:   "DeleteProfile",
+			Fn:     v.DeleteProfile,
+			InArgs: []string{"name"},
+		},
+		{
+			Name:    "ExportConfig",
+			Fn:      v.ExportConfig,
+			OutArgs: []string{"config"},
+		},
 		{
 			Name:    "GetBrightness",
 			Fn:      v.GetBrightness,
@@ -58,6 +87,11 @@ func (v *Manager) GetExportedMethods() dbusutil.ExportedMethods {
 			Fn:      v.GetRealDisplayMode,
 			OutArgs: []string{"outArg0"},
 		},
+		{
+			Name:   "ImportConfig",
+			Fn:     v.ImportConfig,
+			InArgs: []string{"config", "monitors", "modes"},
+		},
 		{
 			Name:    "ListOutputNames",
 			Fn:      v.ListOutputNames,
@@ -68,6 +102,11 @@ func (v *Manager) GetExportedMethods() dbusutil.ExportedMethods {
 			Fn:      v.ListOutputsCommonModes,
 			OutArgs: []string{"outArg0"},
 		},
+		{
+			Name:    "ListProfiles",
+			Fn:      v.ListProfiles,
+			OutArgs: []string{"outArg0"},
+		},
 		{
 			Name:   "ModifyConfigName",
 			Fn:     v.ModifyConfigName,
@@ -85,15 +124,29 @@ func (v *Manager) GetExportedMethods() dbusutil.ExportedMethods {
 			Name: "ResetChanges",
 			Fn:   v.ResetChanges,
 		},
+		{
+			Name: "ResetNightLightLocation",
+			Fn:   v.ResetNightLightLocation,
+		},
 		{
 			Name: "Save",
 			Fn:   v.Save,
 		},
+		{
+			Name:   "SaveProfile",
+			Fn:     v.SaveProfile,
+			InArgs: []string{"name", "rules"},
+		},
 		{
 			Name:   "SetAndSaveBrightness",
 			Fn:     v.SetAndSaveBrightness,
 			InArgs: []string{"outputName", "value"},
 		},
+		{
+			Name:   "SetAutoBrightness",
+			Fn:     v.SetAutoBrightness,
+			InArgs: []string{"enabled"},
+		},
 		{
 			Name:   "SetBrightness",
 			Fn:     v.SetBrightness,
@@ -104,16 +157,41 @@ func (v *Manager) GetExportedMethods() dbusutil.ExportedMethods {
 			Fn:     v.SetColorTemperature,
 			InArgs: []string{"value"},
 		},
+		{
+			Name:   "SetLidClosePolicy",
+			Fn:     v.SetLidClosePolicy,
+			InArgs: []string{"policy"},
+		},
 		{
 			Name:   "SetMethodAdjustCCT",
 			Fn:     v.SetMethodAdjustCCT,
 			InArgs: []string{"adjustMethod"},
 		},
+		{
+			Name:   "SetMirrorSource",
+			Fn:     v.SetMirrorSource,
+			InArgs: []string{"name", "scaling"},
+		},
+		{
+			Name:   "SetNightLightLocation",
+			Fn:     v.SetNightLightLocation,
+			InArgs: []string{"latitude", "longitude"},
+		},
+		{
+			Name:   "SetNightLightSchedule",
+			Fn:     v.SetNightLightSchedule,
+			InArgs: []string{"schedule", "from", "to"},
+		},
 		{
 			Name:   "SetPrimary",
 			Fn:     v.SetPrimary,
 			InArgs: []string{"outputName"},
 		},
+		{
+			Name:   "SetRotationLocked",
+			Fn:     v.SetRotationLocked,
+			InArgs: []string{"locked"},
+		},
 		{
 			Name:    "SupportSetColorTemperature",
 			Fn:      v.SupportSetColorTemperature,
@@ -133,6 +211,53 @@ func (v *Monitor) GetExportedMethods() dbusutil.ExportedMethods {
 			Fn:     v.Enable,
 			InArgs: []string{"enabled"},
 		},
+		{
+			Name:    "GetDdcciControl",
+			Fn:      v.GetDdcciControl,
+			InArgs:  []string{"name"},
+			OutArgs: []string{"value", "max"},
+		},
+		{
+			Name:    "GetDdcciControlValues",
+			Fn:      v.GetDdcciControlValues,
+			InArgs:  []string{"name"},
+			OutArgs: []string{"values"},
+		},
+		{
+			Name:    "GetIccProfile",
+			Fn:      v.GetIccProfile,
+			OutArgs: []string{"filename"},
+		},
+		{
+			Name:    "ListIccProfiles",
+			Fn:      v.ListIccProfiles,
+			OutArgs: []string{"profiles"},
+		},
+		{
+			Name:   "SetBroadcastRGB",
+			Fn:     v.SetBroadcastRGB,
+			InArgs: []string{"value"},
+		},
+		{
+			Name:   "SetColorspace",
+			Fn:     v.SetColorspace,
+			InArgs: []string{"value"},
+		},
+		{
+			Name:   "SetDdcciControl",
+			Fn:     v.SetDdcciControl,
+			InArgs: []string{"name", "value"},
+		},
+		{
+			Name:   "SetIccProfile",
+			Fn:     v.SetIccProfile,
+			InArgs: []string{"filename"},
+		},
+		{
+			Name:   "SetMaxBpc",
+			Fn:     v.SetMaxBpc,
+			InArgs: []string{"value"},
+		},
 		{
 			Name:   "SetMode",
 			Fn:     v.SetMode,
@@ -163,5 +288,10 @@ func (v *Monitor) GetExportedMethods() dbusutil.ExportedMethods {
 			Fn:     v.SetRotation,
 			InArgs: []string{"value"},
 		},
+		{
+			Name:   "SetVrrPolicy",
+			Fn:     v.SetVrrPolicy,
+			InArgs: []string{"policy"},
+		},
 	}
 }
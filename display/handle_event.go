@@ -42,6 +42,9 @@ func (m *Manager) handleMonitorChanged(monitorInfo *MonitorInfo) {
 		}
 		options[optionDisableCrtc] = true
 	}
+	if currentNumMonitors != prevCurrentNumMonitors {
+		m.evalProfileRules()
+	}
 	m.updateMonitorsId(options)
 }
 
@@ -53,6 +56,7 @@ func (m *Manager) handleMonitorAdded(monitorInfo *MonitorInfo) {
 		return
 	}
 	m.updatePropMonitors()
+	m.evalProfileRules()
 	m.updateMonitorsId(nil)
 }
 
@@ -66,12 +70,19 @@ func (m *Manager) handleMonitorRemoved(monitorId uint32) {
 	}
 
 	m.handleMonitorConnectedChanged(monitor, false)
+	m.releaseMonitorDdcci(monitor)
 	m.updatePropMonitors()
+	m.evalProfileRules()
 	m.updateMonitorsId(nil)
 }
 
 func (m *Manager) handleOutputPropertyChanged(ev *randr.OutputPropertyNotifyEvent) {
 	logger.Debug("output property changed", ev.Output, ev.Atom)
+	atomVrrCapable, err := m.xConn.GetAtom(outputPropVrrCapable)
+	if err == nil && ev.Atom == atomVrrCapable {
+		capable := getOutputVrrCapable(m.xConn, ev.Output)
+		m.updateMonitorVrrCapable(uint32(ev.Output), capable)
+	}
 }
 
 func (m *Manager) handleScreenChanged(ev *randr.ScreenChangeNotifyEvent, cfgTsChanged bool) {
@@ -0,0 +1,472 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package display
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/linuxdeepin/go-x11-client/ext/randr"
+)
+
+// 热插拔事件的类型
+const (
+	headlessEventConnect    = "connect"
+	headlessEventDisconnect = "disconnect"
+)
+
+// HeadlessScenario 虚拟显示器的场景，描述输出设备、模式和热插拔事件。
+// 用于单元测试，以及 VNC、xrdp 等没有真实输出设备的会话。
+type HeadlessScenario struct {
+	Outputs []HeadlessOutput
+	// 主屏的名称，为空时是第一个启用的输出设备
+	Primary string `json:",omitempty"`
+	// 按顺序触发的热插拔事件
+	Events []HeadlessEvent `json:",omitempty"`
+	// 机箱类型，例如 laptop，为空时从系统获取
+	Chassis string `json:",omitempty"`
+}
+
+type HeadlessOutput struct {
+	Name string
+	// EDID 的 base64 编码
+	Edid string `json:",omitempty"`
+	// EDID 文件的路径，相对路径相对于场景文件所在的目录，Edid 为空时使用
+	EdidFile     string `json:",omitempty"`
+	Disconnected bool   `json:",omitempty"`
+	MmWidth      uint32 `json:",omitempty"`
+	MmHeight     uint32 `json:",omitempty"`
+	Modes        []HeadlessMode
+	// 首选模式在 Modes 中的序号
+	PreferredMode int `json:",omitempty"`
+	// 当前的配置，为 nil 时没有启用
+	Current *HeadlessCrtc `json:",omitempty"`
+	// 是否支持可变刷新率
+	VrrCapable bool `json:",omitempty"`
+}
+
+type HeadlessMode struct {
+	Width  uint16
+	Height uint16
+	Rate   float64
+}
+
+type HeadlessCrtc struct {
+	// 当前模式在 Modes 中的序号
+	Mode     int
+	X        int16
+	Y        int16
+	Rotation uint16 `json:",omitempty"`
+}
+
+type HeadlessEvent struct {
+	// 距离上一个事件的毫秒数
+	Delay int `json:",omitempty"`
+	// connect 或 disconnect
+	Type   string
+	Output string
+	// 连接时更换的 EDID 的 base64 编码，为空时不变
+	Edid string `json:",omitempty"`
+}
+
+// headlessMonitorManager 由场景驱动的 monitorManager，不操作真实的输出设备，
+// 行为与 X 下一致：输出设备一直存在，热插拔只改变连接状态。
+type headlessMonitorManager struct {
+	hooks      monitorManagerHooks
+	mu         sync.Mutex
+	monitorMap map[uint32]*MonitorInfo
+	primary    uint32
+	events     []HeadlessEvent
+	eventsOnce sync.Once
+	chassis    string
+	// 设置过的 VRR 策略，键是显示器 id
+	vrrPolicies map[uint32]string
+}
+
+func loadHeadlessScenario(filename string) (*HeadlessScenario, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var scenario HeadlessScenario
+	err = json.Unmarshal(data, &scenario)
+	if err != nil {
+		return nil, fmt.Errorf("parse headless scenario %q failed: %v", filename, err)
+	}
+
+	// EDID 文件的相对路径相对于场景文件
+	dir := filepath.Dir(filename)
+	for i := range scenario.Outputs {
+		output := &scenario.Outputs[i]
+		if output.Edid != "" || output.EdidFile == "" {
+			continue
+		}
+		edidFile := output.EdidFile
+		if !filepath.IsAbs(edidFile) {
+			edidFile = filepath.Join(dir, edidFile)
+		}
+		edidData, err := ioutil.ReadFile(edidFile)
+		if err != nil {
+			return nil, err
+		}
+		output.Edid = encodeEdidBase64(edidData)
+	}
+	return &scenario, nil
+}
+
+func newHeadlessMonitorManagerFromFile(filename string) (*headlessMonitorManager, error) {
+	scenario, err := loadHeadlessScenario(filename)
+	if err != nil {
+		return nil, err
+	}
+	logger.Infof("use headless monitors from %v", filename)
+	return newHeadlessMonitorManager(scenario)
+}
+
+func newHeadlessMonitorManager(scenario *HeadlessScenario) (*headlessMonitorManager, error) {
+	mm := &headlessMonitorManager{
+		monitorMap: make(map[uint32]*MonitorInfo),
+		events:     scenario.Events,
+		chassis:    scenario.Chassis,
+	}
+	var modeId uint32
+	for i, output := range scenario.Outputs {
+		// 输出设备的 id 从 1 开始，与在场景中的顺序一致
+		id := uint32(i + 1)
+		monitor, err := output.toMonitorInfo(id, &modeId)
+		if err != nil {
+			return nil, err
+		}
+		if monitor.Name == scenario.Primary || (scenario.Primary == "" && mm.primary == 0 && monitor.Enabled) {
+			mm.primary = id
+		}
+		mm.monitorMap[id] = monitor
+	}
+	if scenario.Primary != "" && mm.primary == 0 {
+		return nil, fmt.Errorf("invalid primary output %q", scenario.Primary)
+	}
+	for _, event := range scenario.Events {
+		if event.Type != headlessEventConnect && event.Type != headlessEventDisconnect {
+			return nil, fmt.Errorf("invalid headless event type %q", event.Type)
+		}
+		if mm.getMonitorByName(event.Output) == nil {
+			return nil, fmt.Errorf("invalid headless event output %q", event.Output)
+		}
+	}
+	return mm, nil
+}
+
+func (output *HeadlessOutput) toMonitorInfo(id uint32, modeId *uint32) (*MonitorInfo, error) {
+	if output.Name == "" {
+		return nil, errors.New("empty output name")
+	}
+	if len(output.Modes) == 0 {
+		return nil, fmt.Errorf("output %v has no modes", output.Name)
+	}
+	monitor := &MonitorInfo{
+		ID:        id,
+		Name:      output.Name,
+		Connected: !output.Disconnected,
+		MmWidth:   output.MmWidth,
+		MmHeight:  output.MmHeight,
+		Rotations: randr.RotationRotate0 | randr.RotationRotate90 |
+			randr.RotationRotate180 | randr.RotationRotate270,
+		Rotation:   randr.RotationRotate0,
+		VrrCapable: output.VrrCapable,
+	}
+	for _, mode := range output.Modes {
+		*modeId++
+		monitor.Modes = append(monitor.Modes, ModeInfo{
+			Id:     *modeId,
+			name:   fmt.Sprintf("%dx%d", mode.Width, mode.Height),
+			Width:  mode.Width,
+			Height: mode.Height,
+			Rate:   mode.Rate,
+		})
+	}
+	if output.PreferredMode < 0 || output.PreferredMode >= len(monitor.Modes) {
+		return nil, fmt.Errorf("output %v invalid preferred mode %d", output.Name, output.PreferredMode)
+	}
+	monitor.PreferredMode = monitor.Modes[output.PreferredMode]
+
+	err := monitor.setHeadlessEdid(output.Edid)
+	if err != nil {
+		return nil, fmt.Errorf("output %v: %v", output.Name, err)
+	}
+
+	if output.Current != nil && monitor.Connected {
+		current := output.Current
+		if current.Mode < 0 || current.Mode >= len(monitor.Modes) {
+			return nil, fmt.Errorf("output %v invalid current mode %d", output.Name, current.Mode)
+		}
+		if current.Rotation == 0 {
+			current.Rotation = randr.RotationRotate0
+		}
+		monitor.setHeadlessCrtc(monitor.Modes[current.Mode], current.X, current.Y, current.Rotation)
+	}
+	monitor.VirtualConnected = monitor.Connected
+	return monitor, nil
+}
+
+// setHeadlessEdid 设置 EDID 和从 EDID 得到的信息
+func (m *MonitorInfo) setHeadlessEdid(edidBase64 string) error {
+	edidData, err := decodeEdidBase64(edidBase64)
+	if err != nil {
+		return fmt.Errorf("decode edid failed: %v", err)
+	}
+	m.EDID = edidData
+	m.UUID = getOutputUuid(m.Name, "", m.EDID)
+	m.UuidV0 = getOutputUuidV0(m.Name, m.EDID)
+	m.Manufacturer, m.Model = parseEdid(m.EDID)
+	m.parseEdidInfo()
+	return nil
+}
+
+func (m *MonitorInfo) setHeadlessCrtc(mode ModeInfo, x, y int16, rotation uint16) {
+	m.Enabled = true
+	m.CurrentMode = mode
+	m.X = x
+	m.Y = y
+	m.Rotation = rotation
+	m.Width = mode.Width
+	m.Height = mode.Height
+	swapWidthHeightWithRotation(rotation, &m.Width, &m.Height)
+}
+
+func (m *MonitorInfo) disableHeadlessCrtc() {
+	m.Enabled = false
+	m.CurrentMode = ModeInfo{}
+	m.X = 0
+	m.Y = 0
+	m.Width = 0
+	m.Height = 0
+	m.Rotation = randr.RotationRotate0
+}
+
+func (mm *headlessMonitorManager) setHooks(hooks monitorManagerHooks) {
+	mm.hooks = hooks
+	// 设置了 hooks 后才能处理事件
+	mm.eventsOnce.Do(func() {
+		if len(mm.events) > 0 {
+			go mm.playEvents()
+		}
+	})
+}
+
+func (mm *headlessMonitorManager) playEvents() {
+	for _, event := range mm.events {
+		time.Sleep(time.Duration(event.Delay) * time.Millisecond)
+		logger.Debugf("headless event %+v", event)
+		err := mm.hotplug(event.Output, event.Type == headlessEventConnect, event.Edid)
+		if err != nil {
+			logger.Warning(err)
+		}
+	}
+}
+
+// hotplug 连接或断开输出设备，edidBase64 不为空时更换连接的显示器
+func (mm *headlessMonitorManager) hotplug(name string, connected bool, edidBase64 string) error {
+	mm.mu.Lock()
+	monitor := mm.getMonitorByName(name)
+	if monitor == nil {
+		mm.mu.Unlock()
+		return fmt.Errorf("invalid output %q", name)
+	}
+	monitor.Connected = connected
+	monitor.VirtualConnected = connected
+	if connected && edidBase64 != "" {
+		err := monitor.setHeadlessEdid(edidBase64)
+		if err != nil {
+			mm.mu.Unlock()
+			return err
+		}
+	}
+	if !connected {
+		monitor.disableHeadlessCrtc()
+	}
+	monitorCp := *monitor
+	mm.mu.Unlock()
+
+	if mm.hooks != nil {
+		mm.hooks.handleMonitorChanged(&monitorCp)
+	}
+	return nil
+}
+
+func (mm *headlessMonitorManager) getMonitorByName(name string) *MonitorInfo {
+	for _, monitor := range mm.monitorMap {
+		if monitor.Name == name {
+			return monitor
+		}
+	}
+	return nil
+}
+
+func (mm *headlessMonitorManager) getMonitors() []*MonitorInfo {
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+	monitors := make([]*MonitorInfo, 0, len(mm.monitorMap))
+	for _, monitor := range mm.monitorMap {
+		monitorCp := *monitor
+		monitors = append(monitors, &monitorCp)
+	}
+	sort.Slice(monitors, func(i, j int) bool {
+		return monitors[i].ID < monitors[j].ID
+	})
+	return monitors
+}
+
+func (mm *headlessMonitorManager) getMonitor(id uint32) *MonitorInfo {
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+	return mm.getMonitorNoLock(id)
+}
+
+func (mm *headlessMonitorManager) getMonitorNoLock(id uint32) *MonitorInfo {
+	monitorInfo, ok := mm.monitorMap[id]
+	if !ok {
+		return nil
+	}
+	monitor := *monitorInfo
+	return &monitor
+}
+
+func (mm *headlessMonitorManager) getPrimaryMonitor() *MonitorInfo {
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+	return mm.getMonitorNoLock(mm.primary)
+}
+
+func (mm *headlessMonitorManager) apply(monitorsId monitorsId, monitorMap map[uint32]*Monitor, prevScreenSize screenSize,
+	options applyOptions, fillModes map[string]string, primaryMonitorID uint32, displayMode byte) error {
+	logger.Debug("headless apply", monitorsId)
+
+	mm.mu.Lock()
+	// 先检查所有的配置，出错时不改变任何输出设备
+	modes := make(map[uint32]ModeInfo)
+	for id, monitor := range monitorMap {
+		monitorInfo, ok := mm.monitorMap[id]
+		if !ok {
+			mm.mu.Unlock()
+			return fmt.Errorf("invalid monitor id %v", id)
+		}
+		if !monitor.Enabled {
+			continue
+		}
+		if !monitorInfo.Connected {
+			mm.mu.Unlock()
+			return fmt.Errorf("can not enable disconnected monitor %v", monitor.Name)
+		}
+		mode, ok := findHeadlessMode(monitorInfo.Modes, monitor.CurrentMode)
+		if !ok {
+			mm.mu.Unlock()
+			return fmt.Errorf("monitor %v has no mode %+v", monitor.Name, monitor.CurrentMode)
+		}
+		modes[id] = mode
+	}
+
+	var changedMonitors []*MonitorInfo
+	for id, monitor := range monitorMap {
+		monitorInfo := mm.monitorMap[id]
+		if monitor.Enabled {
+			monitorInfo.setHeadlessCrtc(modes[id], monitor.X, monitor.Y, monitor.Rotation|monitor.Reflect)
+		} else {
+			monitorInfo.disableHeadlessCrtc()
+		}
+		monitorCp := *monitorInfo
+		changedMonitors = append(changedMonitors, &monitorCp)
+	}
+	mm.mu.Unlock()
+
+	if mm.hooks == nil {
+		return nil
+	}
+	for _, monitorInfo := range changedMonitors {
+		mm.hooks.handleMonitorChanged(monitorInfo)
+	}
+	if primary := mm.getPrimaryMonitor(); primary != nil {
+		mm.hooks.handlePrimaryRectChanged(primary)
+	}
+	return nil
+}
+
+// findHeadlessMode 优先按 id 查找模式，找不到时按大小和刷新率查找
+func findHeadlessMode(modes []ModeInfo, mode ModeInfo) (ModeInfo, bool) {
+	for _, m := range modes {
+		if m.Id == mode.Id {
+			return m, true
+		}
+	}
+	for _, m := range modes {
+		if m.Width == mode.Width && m.Height == mode.Height && m.Rate == mode.Rate {
+			return m, true
+		}
+	}
+	return ModeInfo{}, false
+}
+
+func (mm *headlessMonitorManager) setMonitorPrimary(monitorId uint32) error {
+	mm.mu.Lock()
+	monitor := mm.getMonitorNoLock(monitorId)
+	if monitor == nil {
+		mm.mu.Unlock()
+		return fmt.Errorf("invalid monitor id %v", monitorId)
+	}
+	mm.primary = monitorId
+	mm.mu.Unlock()
+
+	if mm.hooks != nil {
+		mm.hooks.handlePrimaryRectChanged(monitor)
+	}
+	return nil
+}
+
+func (mm *headlessMonitorManager) setMonitorFillMode(monitor *Monitor, fillMode string) error {
+	return nil
+}
+
+func (mm *headlessMonitorManager) supportVrrPolicy() bool {
+	return true
+}
+
+func (mm *headlessMonitorManager) setMonitorVrrPolicy(monitor *Monitor, policy string) error {
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+	if mm.getMonitorNoLock(monitor.ID) == nil {
+		return fmt.Errorf("invalid monitor id %v", monitor.ID)
+	}
+	if mm.vrrPolicies == nil {
+		mm.vrrPolicies = make(map[uint32]string)
+	}
+	mm.vrrPolicies[monitor.ID] = policy
+	return nil
+}
+
+func (mm *headlessMonitorManager) getVrrPolicy(monitorId uint32) string {
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+	return mm.vrrPolicies[monitorId]
+}
+
+func (mm *headlessMonitorManager) setMonitorOutputColor(monitor *Monitor, cfg SysOutputColorConfig) error {
+	return errors.New("headless monitor not support output color")
+}
+
+func (mm *headlessMonitorManager) showCursor(show bool) error {
+	return nil
+}
+
+func (mm *headlessMonitorManager) HandleEvent(ev interface{}) {
+}
+
+func (mm *headlessMonitorManager) HandleScreenChanged(e *randr.ScreenChangeNotifyEvent) (cfgTsChanged bool) {
+	return false
+}
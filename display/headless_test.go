@@ -0,0 +1,320 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package display
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/linuxdeepin/go-lib/dbusutil"
+	"github.com/linuxdeepin/go-x11-client/ext/randr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testMonitorManagerHooks 记录 monitorManager 发出的事件
+type testMonitorManagerHooks struct {
+	changed     []*MonitorInfo
+	primaryRect []*MonitorInfo
+}
+
+func (h *testMonitorManagerHooks) handleMonitorAdded(monitorInfo *MonitorInfo) {}
+
+func (h *testMonitorManagerHooks) handleMonitorRemoved(monitorId uint32) {}
+
+func (h *testMonitorManagerHooks) handleMonitorChanged(monitorInfo *MonitorInfo) {
+	h.changed = append(h.changed, monitorInfo)
+}
+
+func (h *testMonitorManagerHooks) handlePrimaryRectChanged(monitorInfo *MonitorInfo) {
+	h.primaryRect = append(h.primaryRect, monitorInfo)
+}
+
+func (h *testMonitorManagerHooks) getMonitorsId() monitorsId {
+	return monitorsId{}
+}
+
+func loadTestHeadlessMonitorManager(t *testing.T) *headlessMonitorManager {
+	scenario, err := loadHeadlessScenario("testdata/headless/laptop-hdmi.json")
+	require.NoError(t, err)
+	// 测试中手动触发事件
+	scenario.Events = nil
+	mm, err := newHeadlessMonitorManager(scenario)
+	require.NoError(t, err)
+	return mm
+}
+
+// newTestHeadlessManager 通过 newManagerWithOptions 和 init 创建使用虚拟显示器的 Manager，
+// 使用测试的私有 D-Bus，用户配置文件和系统级配置在每个测试中都是新的，初始为扩展模式。
+func newTestHeadlessManager(t *testing.T) *Manager {
+	require.NoError(t, _testEnvErr)
+
+	oldUserConfigFile := userConfigFile
+	oldHasRandr1d2 := _hasRandr1d2
+	oldDpy := _dpy
+	t.Cleanup(func() {
+		userConfigFile = oldUserConfigFile
+		_hasRandr1d2 = oldHasRandr1d2
+		_dpy = oldDpy
+		_testSysDisplay.setConfig("")
+	})
+	userConfigFile = filepath.Join(t.TempDir(), "display-user.json")
+	_testSysDisplay.setConfig(jsonMarshal(&SysRootConfig{
+		Version: sysConfigVersion,
+		Config:  SysConfig{DisplayMode: DisplayModeExtend},
+	}))
+	// 虚拟显示器按照 randr 1.2 处理
+	_hasRandr1d2 = true
+
+	service, err := dbusutil.NewSessionService()
+	require.NoError(t, err)
+	mm := loadTestHeadlessMonitorManager(t)
+	m := newManagerWithOptions(service, managerOptions{
+		mm:      mm,
+		chassis: mm.chassis,
+	})
+	// 私有的 D-Bus 上没有 login1，当作活动的会话以便保存系统级配置
+	m.sessionActive = true
+	m.init()
+	require.Equal(t, DisplayModeExtend, m.DisplayMode)
+
+	// Monitor 的 dbus 方法通过 _dpy 访问 Manager
+	_dpy = m
+	return m
+}
+
+func TestHeadlessMonitorManager(t *testing.T) {
+	mm := loadTestHeadlessMonitorManager(t)
+	monitors := mm.getMonitors()
+	require.Len(t, monitors, 3)
+
+	edp, hdmi, dp := monitors[0], monitors[1], monitors[2]
+	assert.Equal(t, "eDP-1", edp.Name)
+	assert.True(t, edp.Enabled)
+	assert.Equal(t, "eDP-1||v1", edp.UUID)
+	assert.Equal(t, uint16(1920), edp.Width)
+
+	assert.Equal(t, "HDMI-1", hdmi.Name)
+	assert.Len(t, hdmi.EDID, 256)
+	assert.NotEqual(t, "HDMI-1||v1", hdmi.UUID)
+	assert.Equal(t, int16(1920), hdmi.X)
+	assert.Equal(t, hdmi.Modes[0], hdmi.PreferredMode)
+	assert.True(t, hdmi.VirtualConnected)
+	assert.Equal(t, "HDMI-1", mm.getPrimaryMonitor().Name)
+
+	assert.Equal(t, "DP-1", dp.Name)
+	assert.False(t, dp.Connected)
+	assert.False(t, dp.Enabled)
+
+	// 模式的 id 不重复
+	ids := make(map[uint32]bool)
+	for _, monitor := range monitors {
+		for _, mode := range monitor.Modes {
+			assert.False(t, ids[mode.Id])
+			ids[mode.Id] = true
+		}
+	}
+}
+
+func TestHeadlessMonitorManager_apply(t *testing.T) {
+	mm := loadTestHeadlessMonitorManager(t)
+	hooks := &testMonitorManagerHooks{}
+	mm.setHooks(hooks)
+
+	edp := mm.getMonitor(1)
+	hdmi := mm.getMonitor(2)
+	monitorMap := map[uint32]*Monitor{
+		1: {ID: 1, Name: edp.Name, Enabled: false},
+		2: {ID: 2, Name: hdmi.Name, Enabled: true, X: 0, Y: 0, CurrentMode: hdmi.Modes[2],
+			Rotation: randr.RotationRotate90},
+	}
+	err := mm.apply(monitorsId{}, monitorMap, screenSize{}, nil, nil, 2, DisplayModeOnlyOne)
+	require.NoError(t, err)
+
+	assert.Len(t, hooks.changed, 2)
+	edp = mm.getMonitor(1)
+	assert.False(t, edp.Enabled)
+	assert.Equal(t, uint16(0), edp.Width)
+	hdmi = mm.getMonitor(2)
+	assert.True(t, hdmi.Enabled)
+	assert.Equal(t, hdmi.Modes[2], hdmi.CurrentMode)
+	assert.Equal(t, uint16(768), hdmi.Width)
+	assert.Equal(t, uint16(1024), hdmi.Height)
+	assert.Equal(t, uint16(randr.RotationRotate90), hdmi.Rotation)
+	require.Len(t, hooks.primaryRect, 1)
+	assert.Equal(t, "HDMI-1", hooks.primaryRect[0].Name)
+
+	// 按大小和刷新率查找模式
+	monitorMap[2].CurrentMode = ModeInfo{Width: 1920, Height: 1080, Rate: 50}
+	err = mm.apply(monitorsId{}, monitorMap, screenSize{}, nil, nil, 2, DisplayModeOnlyOne)
+	require.NoError(t, err)
+	assert.Equal(t, hdmi.Modes[1], mm.getMonitor(2).CurrentMode)
+
+	// 不能启用没有的模式和断开的显示器，出错时不改变
+	monitorMap[2].CurrentMode = ModeInfo{Width: 800, Height: 600, Rate: 60}
+	err = mm.apply(monitorsId{}, monitorMap, screenSize{}, nil, nil, 2, DisplayModeOnlyOne)
+	assert.Error(t, err)
+	monitorMap[3] = &Monitor{ID: 3, Name: "DP-1", Enabled: true, CurrentMode: ModeInfo{Width: 3840, Height: 2160, Rate: 60}}
+	monitorMap[2].CurrentMode = hdmi.Modes[0]
+	err = mm.apply(monitorsId{}, monitorMap, screenSize{}, nil, nil, 2, DisplayModeOnlyOne)
+	assert.Error(t, err)
+	assert.Equal(t, hdmi.Modes[1], mm.getMonitor(2).CurrentMode)
+
+	err = mm.setMonitorPrimary(1)
+	require.NoError(t, err)
+	assert.Equal(t, "eDP-1", mm.getPrimaryMonitor().Name)
+	assert.Error(t, mm.setMonitorPrimary(10))
+}
+
+func TestHeadlessMonitorManager_hotplug(t *testing.T) {
+	mm := loadTestHeadlessMonitorManager(t)
+	hooks := &testMonitorManagerHooks{}
+	mm.setHooks(hooks)
+
+	hdmi := mm.getMonitor(2)
+	err := mm.hotplug("HDMI-1", false, "")
+	require.NoError(t, err)
+	require.Len(t, hooks.changed, 1)
+	assert.False(t, hooks.changed[0].Connected)
+	assert.False(t, hooks.changed[0].Enabled)
+	// 断开后 uuid 不变
+	assert.Equal(t, hdmi.UUID, hooks.changed[0].UUID)
+
+	scenario, err := loadHeadlessScenario("testdata/headless/laptop-hdmi.json")
+	require.NoError(t, err)
+	err = mm.hotplug("DP-1", true, scenario.Events[0].Edid)
+	require.NoError(t, err)
+	dp := mm.getMonitor(3)
+	assert.True(t, dp.Connected)
+	assert.False(t, dp.Enabled)
+	assert.NotEqual(t, "DP-1||v1", dp.UUID)
+	assert.True(t, dp.HDRCapable)
+
+	assert.Error(t, mm.hotplug("VGA-1", true, ""))
+}
+
+func TestNewHeadlessMonitorManager(t *testing.T) {
+	modes := []HeadlessMode{{Width: 1920, Height: 1080, Rate: 60}}
+	_, err := newHeadlessMonitorManager(&HeadlessScenario{
+		Outputs: []HeadlessOutput{{Name: "HDMI-1"}},
+	})
+	assert.Error(t, err)
+	_, err = newHeadlessMonitorManager(&HeadlessScenario{
+		Outputs: []HeadlessOutput{{Name: "HDMI-1", Modes: modes, Current: &HeadlessCrtc{Mode: 1}}},
+	})
+	assert.Error(t, err)
+	_, err = newHeadlessMonitorManager(&HeadlessScenario{
+		Outputs: []HeadlessOutput{{Name: "HDMI-1", Modes: modes}},
+		Primary: "DP-1",
+	})
+	assert.Error(t, err)
+	_, err = newHeadlessMonitorManager(&HeadlessScenario{
+		Outputs: []HeadlessOutput{{Name: "HDMI-1", Modes: modes}},
+		Events:  []HeadlessEvent{{Type: "unplug", Output: "HDMI-1"}},
+	})
+	assert.Error(t, err)
+
+	mm, err := newHeadlessMonitorManager(&HeadlessScenario{
+		Outputs: []HeadlessOutput{
+			{Name: "HDMI-1", Modes: modes},
+			{Name: "DP-1", Modes: modes, Current: &HeadlessCrtc{}},
+		},
+	})
+	require.NoError(t, err)
+	// 没有指定主屏时使用第一个启用的
+	assert.Equal(t, "DP-1", mm.getPrimaryMonitor().Name)
+}
+
+func TestManager_headlessSwitchMode(t *testing.T) {
+	m := newTestHeadlessManager(t)
+	mm := m.mm.(*headlessMonitorManager)
+	edp := mm.getMonitorByName("eDP-1")
+	hdmi := mm.getMonitorByName("HDMI-1")
+
+	require.Nil(t, m.SwitchMode(DisplayModeMirror, ""))
+	assert.Equal(t, DisplayModeMirror, m.DisplayMode)
+	edp, hdmi = mm.getMonitor(edp.ID), mm.getMonitor(hdmi.ID)
+	assert.True(t, edp.Enabled)
+	assert.True(t, hdmi.Enabled)
+	assert.Equal(t, edp.getRect(), hdmi.getRect())
+
+	require.Nil(t, m.SwitchMode(DisplayModeOnlyOne, "HDMI-1"))
+	assert.Equal(t, DisplayModeOnlyOne, m.DisplayMode)
+	assert.False(t, mm.getMonitor(edp.ID).Enabled)
+	assert.True(t, mm.getMonitor(hdmi.ID).Enabled)
+	assert.Equal(t, "HDMI-1", m.Primary)
+
+	require.Nil(t, m.SwitchMode(DisplayModeExtend, ""))
+	assert.Equal(t, DisplayModeExtend, m.DisplayMode)
+	edp, hdmi = mm.getMonitor(edp.ID), mm.getMonitor(hdmi.ID)
+	assert.True(t, edp.Enabled)
+	assert.True(t, hdmi.Enabled)
+	// 扩展模式下显示器不重叠
+	assert.NotEqual(t, edp.X, hdmi.X)
+
+	assert.NotNil(t, m.SwitchMode(DisplayModeOnlyOne, "DP-1"))
+}
+
+func TestManager_headlessHotplug(t *testing.T) {
+	m := newTestHeadlessManager(t)
+	mm := m.mm.(*headlessMonitorManager)
+	require.Len(t, m.getConnectedMonitors(), 2)
+	oldMonitorsId := m.getMonitorsId()
+
+	require.NoError(t, mm.hotplug("HDMI-1", false, ""))
+	// 不等待 updateMonitorsId 中的延时，直接应用配置
+	require.NotNil(t, m.delayApplyTimer)
+	m.delayApplyTimer.Stop()
+	m.delayApplyConfig()
+	monitors := m.getConnectedMonitors()
+	require.Len(t, monitors, 1)
+	assert.Equal(t, "eDP-1", monitors[0].Name)
+	assert.True(t, mm.getMonitorByName("eDP-1").Enabled)
+	assert.NotEqual(t, oldMonitorsId, m.getMonitorsId())
+	assert.Len(t, m.Monitors, 1)
+
+	scenario, err := loadHeadlessScenario("testdata/headless/laptop-hdmi.json")
+	require.NoError(t, err)
+	require.NoError(t, mm.hotplug("DP-1", true, scenario.Events[0].Edid))
+	m.delayApplyTimer.Stop()
+	m.delayApplyConfig()
+	monitors = m.getConnectedMonitors()
+	require.Len(t, monitors, 2)
+	dp := monitors.GetByName("DP-1")
+	require.NotNil(t, dp)
+	assert.True(t, dp.Enabled)
+	assert.True(t, mm.getMonitorByName("DP-1").Enabled)
+	assert.Len(t, m.Monitors, 2)
+}
+
+func TestManager_headlessApplyChanges(t *testing.T) {
+	m := newTestHeadlessManager(t)
+	mm := m.mm.(*headlessMonitorManager)
+	hdmi := m.getConnectedMonitors().GetByName("HDMI-1")
+	require.NotNil(t, hdmi)
+
+	require.Nil(t, hdmi.SetModeBySize(1024, 768))
+	require.Nil(t, hdmi.SetRotation(randr.RotationRotate90))
+	assert.True(t, m.HasChanged)
+	// 应用之前不改变输出设备
+	assert.Equal(t, uint16(1920), mm.getMonitorByName("HDMI-1").Width)
+
+	require.Nil(t, m.ApplyChanges())
+	info := mm.getMonitorByName("HDMI-1")
+	assert.Equal(t, uint16(768), info.Width)
+	assert.Equal(t, uint16(1024), info.Height)
+	assert.Equal(t, uint16(randr.RotationRotate90), info.Rotation)
+	assert.Equal(t, uint16(768), hdmi.Width)
+
+	// 恢复成保存的配置
+	require.Nil(t, m.ResetChanges())
+	assert.False(t, m.HasChanged)
+	info = mm.getMonitorByName("HDMI-1")
+	assert.Equal(t, uint16(1920), info.Width)
+	assert.Equal(t, uint16(randr.RotationRotate0), info.Rotation)
+	hdmi.PropsMu.RLock()
+	assert.Empty(t, hdmi.changes)
+	hdmi.PropsMu.RUnlock()
+}
@@ -0,0 +1,280 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+// Package icc 解析 ICC 色彩配置文件，只读取显示器校准需要的头部、描述和 VCGT 标签。
+package icc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"unicode/utf16"
+)
+
+const headerSize = 128
+
+// 设备类别
+const (
+	ClassMonitor = "mntr"
+	ClassInput   = "scnr"
+	ClassOutput  = "prtr"
+)
+
+// 标签和标签类型的签名
+const (
+	tagDesc = "desc"
+	tagVcgt = "vcgt"
+
+	typeDesc = "desc"
+	typeMluc = "mluc"
+	typeVcgt = "vcgt"
+)
+
+// vcgt 标签中曲线的类型
+const (
+	vcgtTypeTable   = 0
+	vcgtTypeFormula = 1
+)
+
+// 由公式生成的曲线的采样数
+const vcgtFormulaSize = 256
+
+var (
+	ErrTooShort  = errors.New("icc profile too short")
+	ErrSignature = errors.New("invalid icc profile signature")
+)
+
+type Profile struct {
+	// 设备类别，比如 mntr
+	DeviceClass string
+	// 颜色空间，比如 RGB
+	ColorSpace string
+	// 版本，比如 2.1 或 4.3
+	Version string
+	// 描述，通常是配置文件的名称
+	Description string
+	// 显卡的校准曲线，没有 vcgt 标签时为 nil
+	Vcgt *Vcgt
+}
+
+// Vcgt 显卡 gamma 表，每个通道的值从 0 到 65535
+type Vcgt struct {
+	Red   []uint16
+	Green []uint16
+	Blue  []uint16
+}
+
+func Parse(data []byte) (*Profile, error) {
+	if len(data) < headerSize+4 {
+		return nil, ErrTooShort
+	}
+	if string(data[36:40]) != "acsp" {
+		return nil, ErrSignature
+	}
+	// 头部中的大小至少要包含头部和标签数量
+	size := binary.BigEndian.Uint32(data[0:4])
+	if uint64(size) < headerSize+4 || uint64(size) > uint64(len(data)) {
+		return nil, ErrTooShort
+	}
+	data = data[:size]
+
+	p := &Profile{
+		DeviceClass: strings.TrimSpace(string(data[12:16])),
+		ColorSpace:  strings.TrimSpace(string(data[16:20])),
+		Version:     fmt.Sprintf("%d.%d", data[8], data[9]>>4),
+	}
+
+	tagCount := binary.BigEndian.Uint32(data[headerSize:])
+	tableEnd := headerSize + 4 + uint64(tagCount)*12
+	if tableEnd > uint64(len(data)) {
+		return nil, ErrTooShort
+	}
+	for i := 0; i < int(tagCount); i++ {
+		entry := data[headerSize+4+i*12:]
+		sig := string(entry[0:4])
+		offset := binary.BigEndian.Uint32(entry[4:8])
+		length := binary.BigEndian.Uint32(entry[8:12])
+		if uint64(offset)+uint64(length) > uint64(len(data)) || length < 8 {
+			return nil, fmt.Errorf("tag %q out of range", sig)
+		}
+		tagData := data[offset : offset+length]
+
+		var err error
+		switch sig {
+		case tagDesc:
+			p.Description, err = parseDescription(tagData)
+		case tagVcgt:
+			p.Vcgt, err = parseVcgt(tagData)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("parse tag %q: %v", sig, err)
+		}
+	}
+	return p, nil
+}
+
+// parseDescription 解析 ICC v2 的 desc 类型或者 ICC v4 的 mluc 类型
+func parseDescription(data []byte) (string, error) {
+	switch string(data[0:4]) {
+	case typeDesc:
+		if len(data) < 12 {
+			return "", ErrTooShort
+		}
+		count := binary.BigEndian.Uint32(data[8:12])
+		if uint64(count) > uint64(len(data)-12) {
+			return "", ErrTooShort
+		}
+		str := data[12 : 12+count]
+		if idx := bytes.IndexByte(str, 0); idx != -1 {
+			str = str[:idx]
+		}
+		return string(str), nil
+
+	case typeMluc:
+		if len(data) < 16 {
+			return "", ErrTooShort
+		}
+		count := binary.BigEndian.Uint32(data[8:12])
+		recordSize := binary.BigEndian.Uint32(data[12:16])
+		if count == 0 || recordSize < 12 || 16+uint64(recordSize) > uint64(len(data)) {
+			return "", ErrTooShort
+		}
+		// 只用第一条记录
+		record := data[16 : 16+recordSize]
+		length := binary.BigEndian.Uint32(record[4:8])
+		offset := binary.BigEndian.Uint32(record[8:12])
+		if uint64(offset)+uint64(length) > uint64(len(data)) || length%2 != 0 {
+			return "", ErrTooShort
+		}
+		str := data[offset : offset+length]
+		u16 := make([]uint16, len(str)/2)
+		for i := range u16 {
+			u16[i] = binary.BigEndian.Uint16(str[i*2:])
+		}
+		return strings.TrimRight(string(utf16.Decode(u16)), "\x00"), nil
+	}
+	return "", fmt.Errorf("unknown type %q", data[0:4])
+}
+
+func parseVcgt(data []byte) (*Vcgt, error) {
+	if string(data[0:4]) != typeVcgt {
+		return nil, fmt.Errorf("unknown type %q", data[0:4])
+	}
+	if len(data) < 12 {
+		return nil, ErrTooShort
+	}
+	gammaType := binary.BigEndian.Uint32(data[8:12])
+	switch gammaType {
+	case vcgtTypeTable:
+		return parseVcgtTable(data[12:])
+	case vcgtTypeFormula:
+		return parseVcgtFormula(data[12:])
+	}
+	return nil, fmt.Errorf("unknown vcgt type %d", gammaType)
+}
+
+func parseVcgtTable(data []byte) (*Vcgt, error) {
+	if len(data) < 6 {
+		return nil, ErrTooShort
+	}
+	channels := int(binary.BigEndian.Uint16(data[0:2]))
+	count := int(binary.BigEndian.Uint16(data[2:4]))
+	entrySize := int(binary.BigEndian.Uint16(data[4:6]))
+	if (channels != 1 && channels != 3) || count < 2 || (entrySize != 1 && entrySize != 2) {
+		return nil, fmt.Errorf("invalid vcgt table: channels %d, count %d, entry size %d",
+			channels, count, entrySize)
+	}
+	data = data[6:]
+	if len(data) < channels*count*entrySize {
+		return nil, ErrTooShort
+	}
+
+	curves := make([][]uint16, channels)
+	for c := range curves {
+		curve := make([]uint16, count)
+		for i := range curve {
+			pos := (c*count + i) * entrySize
+			if entrySize == 1 {
+				curve[i] = uint16(data[pos]) * 257
+			} else {
+				curve[i] = binary.BigEndian.Uint16(data[pos:])
+			}
+		}
+		curves[c] = curve
+	}
+	if channels == 1 {
+		return &Vcgt{Red: curves[0], Green: curves[0], Blue: curves[0]}, nil
+	}
+	return &Vcgt{Red: curves[0], Green: curves[1], Blue: curves[2]}, nil
+}
+
+// parseVcgtFormula 解析公式类型，每个通道有 gamma、最小值和最大值，都是 s15Fixed16 类型
+func parseVcgtFormula(data []byte) (*Vcgt, error) {
+	if len(data) < 36 {
+		return nil, ErrTooShort
+	}
+	readFixed := func(pos int) float64 {
+		return float64(int32(binary.BigEndian.Uint32(data[pos:]))) / 65536
+	}
+	var curves [3][]uint16
+	for c := range curves {
+		gamma := readFixed(c * 12)
+		min := readFixed(c*12 + 4)
+		max := readFixed(c*12 + 8)
+		if gamma <= 0 {
+			return nil, fmt.Errorf("invalid vcgt gamma %v", gamma)
+		}
+		curve := make([]uint16, vcgtFormulaSize)
+		for i := range curve {
+			x := float64(i) / (vcgtFormulaSize - 1)
+			v := min + (max-min)*math.Pow(x, gamma)
+			curve[i] = uint16(math.Round(clamp01(v) * math.MaxUint16))
+		}
+		curves[c] = curve
+	}
+	return &Vcgt{Red: curves[0], Green: curves[1], Blue: curves[2]}, nil
+}
+
+func clamp01(v float64) float64 {
+	if v < 0 {
+		return 0
+	}
+	if v > 1 {
+		return 1
+	}
+	return v
+}
+
+// Resample 把校准曲线线性插值成 size 个值，用于填充 crtc 的 gamma 表
+func (v *Vcgt) Resample(size int) (red, green, blue []uint16) {
+	return resample(v.Red, size), resample(v.Green, size), resample(v.Blue, size)
+}
+
+func resample(curve []uint16, size int) []uint16 {
+	result := make([]uint16, size)
+	if size == 0 || len(curve) == 0 {
+		return result
+	}
+	if size == 1 || len(curve) == 1 {
+		for i := range result {
+			result[i] = curve[0]
+		}
+		return result
+	}
+	for i := range result {
+		pos := float64(i) * float64(len(curve)-1) / float64(size-1)
+		idx := int(pos)
+		if idx >= len(curve)-1 {
+			result[i] = curve[len(curve)-1]
+			continue
+		}
+		frac := pos - float64(idx)
+		v := (1-frac)*float64(curve[idx]) + frac*float64(curve[idx+1])
+		result[i] = uint16(math.Round(v))
+	}
+	return result
+}
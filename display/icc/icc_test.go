@@ -0,0 +1,139 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package icc
+
+import (
+	"encoding/binary"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func readTestProfile(t *testing.T, name string) []byte {
+	data, err := ioutil.ReadFile(filepath.Join("testdata", name))
+	require.NoError(t, err)
+	return data
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		file        string
+		version     string
+		description string
+		hasVcgt     bool
+	}{
+		{"v2-vcgt-table.icc", "2.1", "Calibrated VA2478", true},
+		{"v4-vcgt-formula.icc", "4.3", "Laptop Panel", true},
+		{"srgb-no-vcgt.icc", "2.1", "sRGB", false},
+	}
+	for _, test := range tests {
+		t.Run(test.file, func(t *testing.T) {
+			p, err := Parse(readTestProfile(t, test.file))
+			require.NoError(t, err)
+			assert.Equal(t, ClassMonitor, p.DeviceClass)
+			assert.Equal(t, "RGB", p.ColorSpace)
+			assert.Equal(t, test.version, p.Version)
+			assert.Equal(t, test.description, p.Description)
+			assert.Equal(t, test.hasVcgt, p.Vcgt != nil)
+		})
+	}
+}
+
+func TestParse_invalid(t *testing.T) {
+	data := readTestProfile(t, "srgb-no-vcgt.icc")
+
+	_, err := Parse(data[:100])
+	assert.Equal(t, ErrTooShort, err)
+
+	bad := append([]byte(nil), data...)
+	copy(bad[36:40], "xxxx")
+	_, err = Parse(bad)
+	assert.Equal(t, ErrSignature, err)
+
+	// 文件长度比头部中的短
+	_, err = Parse(data[:len(data)-4])
+	assert.Equal(t, ErrTooShort, err)
+
+	// 头部中的大小比头部还小
+	for _, size := range []byte{0, 8, 100, headerSize, headerSize + 3} {
+		bad = append([]byte(nil), data...)
+		binary.BigEndian.PutUint32(bad[0:4], uint32(size))
+		_, err = Parse(bad)
+		assert.Equal(t, ErrTooShort, err, size)
+	}
+
+	// 截断在标签表中
+	bad = append([]byte(nil), data[:headerSize+8]...)
+	binary.BigEndian.PutUint32(bad[0:4], uint32(len(bad)))
+	_, err = Parse(bad)
+	assert.Equal(t, ErrTooShort, err)
+
+	// 标签数量过大
+	bad = append([]byte(nil), data...)
+	binary.BigEndian.PutUint32(bad[headerSize:], 0xffffffff)
+	_, err = Parse(bad)
+	assert.Equal(t, ErrTooShort, err)
+
+	// 标签超出文件范围
+	bad = append([]byte(nil), data...)
+	binary.BigEndian.PutUint32(bad[headerSize+4+4:], uint32(len(bad)-4))
+	_, err = Parse(bad)
+	assert.Error(t, err)
+	bad = append([]byte(nil), data...)
+	binary.BigEndian.PutUint32(bad[headerSize+4+8:], 0xffffffff)
+	_, err = Parse(bad)
+	assert.Error(t, err)
+}
+
+func TestParse_truncated(t *testing.T) {
+	for _, file := range []string{"v2-vcgt-table.icc", "v4-vcgt-formula.icc", "srgb-no-vcgt.icc"} {
+		data := readTestProfile(t, file)
+		// 在每个长度截断，并修改头部中的大小，不能 panic
+		for n := 0; n < len(data); n++ {
+			bad := append([]byte(nil), data[:n]...)
+			if n >= 4 {
+				binary.BigEndian.PutUint32(bad[0:4], uint32(n))
+			}
+			assert.NotPanics(t, func() {
+				_, _ = Parse(bad)
+			}, "%s %d", file, n)
+		}
+	}
+}
+
+func TestVcgt(t *testing.T) {
+	p, err := Parse(readTestProfile(t, "v2-vcgt-table.icc"))
+	require.NoError(t, err)
+	v := p.Vcgt
+	assert.Len(t, v.Red, 256)
+	assert.Equal(t, uint16(65535), v.Red[255])
+	assert.Equal(t, uint16(58982), v.Green[255])
+	assert.Equal(t, uint16(52428), v.Blue[255])
+
+	p, err = Parse(readTestProfile(t, "v4-vcgt-formula.icc"))
+	require.NoError(t, err)
+	v = p.Vcgt
+	assert.Len(t, v.Red, vcgtFormulaSize)
+	// gamma 2.0 的中间值
+	assert.InDelta(t, 65535*0.25, float64(v.Green[vcgtFormulaSize/2]), 300)
+	// 范围 [0.1, 0.9]
+	assert.InDelta(t, 65535*0.1, float64(v.Blue[0]), 1)
+	assert.InDelta(t, 65535*0.9, float64(v.Blue[vcgtFormulaSize-1]), 1)
+}
+
+func TestVcgt_Resample(t *testing.T) {
+	v := &Vcgt{
+		Red:   []uint16{0, 65535},
+		Green: []uint16{0, 32768, 65535},
+		Blue:  []uint16{1000},
+	}
+	red, green, blue := v.Resample(5)
+	assert.Equal(t, []uint16{0, 16384, 32768, 49151, 65535}, red)
+	assert.Equal(t, []uint16{0, 16384, 32768, 49152, 65535}, green)
+	assert.Equal(t, []uint16{1000, 1000, 1000, 1000, 1000}, blue)
+}
@@ -0,0 +1,277 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package display
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/linuxdeepin/go-lib/xdg/basedir"
+	x "github.com/linuxdeepin/go-x11-client"
+	"github.com/linuxdeepin/startdde/display/brightness"
+	"github.com/linuxdeepin/startdde/display/icc"
+)
+
+// X 下给色彩管理的应用使用的根窗口属性，第 n 个屏幕是 _ICC_PROFILE_n
+const atomNameIccProfile = "_ICC_PROFILE"
+
+func getIccProfileDirs() []string {
+	return []string{
+		filepath.Join(basedir.GetUserDataDir(), "icc"),
+		"/usr/local/share/color/icc",
+		"/usr/share/color/icc",
+	}
+}
+
+func isIccProfileFile(name string) bool {
+	ext := strings.ToLower(filepath.Ext(name))
+	return ext == ".icc" || ext == ".icm"
+}
+
+// loadIccProfile 读取并解析 ICC 配置文件，只接受显示器类别的配置文件
+func loadIccProfile(filename string) (*icc.Profile, []byte, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, nil, err
+	}
+	profile, err := icc.Parse(data)
+	if err != nil {
+		return nil, nil, fmt.Errorf("parse icc profile %q failed: %v", filename, err)
+	}
+	if profile.DeviceClass != icc.ClassMonitor {
+		return nil, nil, fmt.Errorf("icc profile %q is not for monitor", filename)
+	}
+	return profile, data, nil
+}
+
+// iccProfileCacheEntry 记录文件是否为显示器类别的 ICC 配置文件，文件的修改时间或大小变化后重新解析
+type iccProfileCacheEntry struct {
+	modTime time.Time
+	size    int64
+	valid   bool
+}
+
+var (
+	_iccProfileCache   = make(map[string]iccProfileCacheEntry)
+	_iccProfileCacheMu sync.Mutex
+)
+
+// isValidIccProfile 判断 path 是否为显示器类别的 ICC 配置文件，按路径和修改时间缓存结果
+func isValidIccProfile(path string, info os.FileInfo) bool {
+	entry, ok := _iccProfileCache[path]
+	if ok && entry.modTime.Equal(info.ModTime()) && entry.size == info.Size() {
+		return entry.valid
+	}
+	_, _, err := loadIccProfile(path)
+	if err != nil {
+		logger.Debug(err)
+	}
+	_iccProfileCache[path] = iccProfileCacheEntry{
+		modTime: info.ModTime(),
+		size:    info.Size(),
+		valid:   err == nil,
+	}
+	return err == nil
+}
+
+// listIccProfiles 列出 dirs 中所有显示器类别的 ICC 配置文件
+func listIccProfiles(dirs []string) []string {
+	_iccProfileCacheMu.Lock()
+	defer _iccProfileCacheMu.Unlock()
+
+	var result []string
+	seen := make(map[string]struct{})
+	for _, dir := range dirs {
+		_ = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+			if err != nil || info.IsDir() || !isIccProfileFile(path) {
+				return nil
+			}
+			seen[path] = struct{}{}
+			if isValidIccProfile(path, info) {
+				result = append(result, path)
+			}
+			return nil
+		})
+	}
+	// 删除已经不存在的文件
+	for path := range _iccProfileCache {
+		if _, ok := seen[path]; !ok {
+			delete(_iccProfileCache, path)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+func (m *Manager) getIccProfileConfig(uuid string) string {
+	m.userCfgMu.Lock()
+	defer m.userCfgMu.Unlock()
+	return m.userConfig.IccProfiles[uuid]
+}
+
+// setMonitorIccProfile 给显示器设置 ICC 配置文件，filename 为空时清除，按显示器 uuid 保存到用户配置中
+func (m *Manager) setMonitorIccProfile(monitor *Monitor, filename string) error {
+	var vcgt *icc.Vcgt
+	if filename != "" {
+		filename = filepath.Clean(filename)
+		profile, _, err := loadIccProfile(filename)
+		if err != nil {
+			return err
+		}
+		vcgt = profile.Vcgt
+	}
+
+	monitor.PropsMu.Lock()
+	uuid := monitor.uuid
+	name := monitor.Name
+	br := monitor.Brightness
+	monitor.setPropIccProfile(filename)
+	monitor.PropsMu.Unlock()
+
+	m.userCfgMu.Lock()
+	if filename == "" {
+		delete(m.userConfig.IccProfiles, uuid)
+	} else {
+		if m.userConfig.IccProfiles == nil {
+			m.userConfig.IccProfiles = make(map[string]string)
+		}
+		m.userConfig.IccProfiles[uuid] = filename
+	}
+	err := m.saveUserConfigNoLock()
+	m.userCfgMu.Unlock()
+	if err != nil {
+		logger.Warning(err)
+	}
+
+	brightness.SetCalibration(monitor.ID, vcgt)
+	m.updateIccProfileAtoms()
+
+	// 重新设置亮度，使校准曲线生效
+	if m.canSetBrightness(name) && br > 0 {
+		err = m.setBrightness(name, br)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applyIccProfiles 在应用显示器配置之后，加载连接的显示器的 ICC 配置文件的校准曲线，
+// 在设置亮度时生效。
+func (m *Manager) applyIccProfiles() {
+	for _, monitor := range m.getConnectedMonitors() {
+		filename := m.getIccProfileConfig(monitor.uuid)
+		var vcgt *icc.Vcgt
+		if filename != "" {
+			profile, _, err := loadIccProfile(filename)
+			if err != nil {
+				logger.Warning(err)
+				filename = ""
+			} else {
+				vcgt = profile.Vcgt
+			}
+		}
+		brightness.SetCalibration(monitor.ID, vcgt)
+
+		monitor.PropsMu.Lock()
+		monitor.setPropIccProfile(filename)
+		monitor.PropsMu.Unlock()
+	}
+	m.updateIccProfileAtoms()
+}
+
+// getIccProfileMonitors 获取启用的显示器，主屏排在第一个，其他的按位置排序，
+// 与 _ICC_PROFILE 和 _ICC_PROFILE_n 对应。
+func (m *Manager) getIccProfileMonitors() Monitors {
+	m.PropsMu.RLock()
+	primary := m.Primary
+	m.PropsMu.RUnlock()
+
+	var monitors Monitors
+	for _, monitor := range m.getConnectedMonitors() {
+		monitor.PropsMu.RLock()
+		enabled := monitor.Enabled
+		monitor.PropsMu.RUnlock()
+		if enabled {
+			monitors = append(monitors, monitor)
+		}
+	}
+	sort.SliceStable(monitors, func(i, j int) bool {
+		a, b := monitors[i], monitors[j]
+		if (a.Name == primary) != (b.Name == primary) {
+			return a.Name == primary
+		}
+		if a.X != b.X {
+			return a.X < b.X
+		}
+		return a.Y < b.Y
+	})
+	return monitors
+}
+
+func getIccProfileAtomName(index int) string {
+	if index == 0 {
+		return atomNameIccProfile
+	}
+	return fmt.Sprintf("%s_%d", atomNameIccProfile, index)
+}
+
+// updateIccProfileAtoms 在根窗口上设置 _ICC_PROFILE 属性，内容是 ICC 配置文件的数据
+func (m *Manager) updateIccProfileAtoms() {
+	if _useWayland || m.xConn == nil {
+		return
+	}
+	m.iccAtomsMu.Lock()
+	defer m.iccAtomsMu.Unlock()
+
+	root := m.xConn.GetDefaultScreen().Root
+	monitors := m.getIccProfileMonitors()
+	for i, monitor := range monitors {
+		atom, err := m.xConn.GetAtom(getIccProfileAtomName(i))
+		if err != nil {
+			logger.Warning(err)
+			continue
+		}
+		monitor.PropsMu.RLock()
+		filename := monitor.IccProfile
+		monitor.PropsMu.RUnlock()
+
+		var data []byte
+		if filename != "" {
+			_, data, err = loadIccProfile(filename)
+			if err != nil {
+				logger.Warning(err)
+			}
+		}
+		if len(data) == 0 {
+			err = x.DeletePropertyChecked(m.xConn, root, atom).Check(m.xConn)
+		} else {
+			err = x.ChangePropertyChecked(m.xConn, x.PropModeReplace, root, atom,
+				x.AtomCardinal, 8, data).Check(m.xConn)
+		}
+		if err != nil {
+			logger.Warningf("set %s failed: %v", getIccProfileAtomName(i), err)
+		}
+	}
+
+	// 删除已经不存在的显示器的属性
+	for i := len(monitors); i < m.iccAtomsNum; i++ {
+		atom, err := m.xConn.GetAtom(getIccProfileAtomName(i))
+		if err != nil {
+			continue
+		}
+		err = x.DeletePropertyChecked(m.xConn, root, atom).Check(m.xConn)
+		if err != nil {
+			logger.Warning(err)
+		}
+	}
+	m.iccAtomsNum = len(monitors)
+}
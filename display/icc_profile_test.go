@@ -0,0 +1,63 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package display
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_listIccProfiles(t *testing.T) {
+	dir := filepath.Join("icc", "testdata")
+	profiles := listIccProfiles([]string{dir, "/non-exist-dir"})
+	assert.Equal(t, []string{
+		filepath.Join(dir, "srgb-no-vcgt.icc"),
+		filepath.Join(dir, "v2-vcgt-table.icc"),
+		filepath.Join(dir, "v4-vcgt-formula.icc"),
+	}, profiles)
+
+	assert.True(t, isIccProfileFile("a.ICM"))
+	assert.False(t, isIccProfileFile("a.txt"))
+}
+
+func Test_getIccProfileAtomName(t *testing.T) {
+	assert.Equal(t, "_ICC_PROFILE", getIccProfileAtomName(0))
+	assert.Equal(t, "_ICC_PROFILE_2", getIccProfileAtomName(2))
+}
+
+func Test_listIccProfilesCache(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "test.icc")
+	data, err := ioutil.ReadFile(filepath.Join("icc", "testdata", "srgb-no-vcgt.icc"))
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(filename, data, 0644))
+
+	assert.Equal(t, []string{filename}, listIccProfiles([]string{dir}))
+	_iccProfileCacheMu.Lock()
+	entry, ok := _iccProfileCache[filename]
+	_iccProfileCacheMu.Unlock()
+	assert.True(t, ok)
+	assert.True(t, entry.valid)
+
+	// 文件修改后重新解析
+	require.NoError(t, ioutil.WriteFile(filename, []byte("invalid"), 0644))
+	modTime := entry.modTime.Add(time.Second)
+	require.NoError(t, os.Chtimes(filename, modTime, modTime))
+	assert.Empty(t, listIccProfiles([]string{dir}))
+
+	// 文件删除后从缓存中移除
+	require.NoError(t, os.Remove(filename))
+	assert.Empty(t, listIccProfiles([]string{dir}))
+	_iccProfileCacheMu.Lock()
+	_, ok = _iccProfileCache[filename]
+	_iccProfileCacheMu.Unlock()
+	assert.False(t, ok)
+}
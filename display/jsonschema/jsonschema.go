@@ -0,0 +1,342 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+// Package jsonschema 校验 JSON 数据，支持 JSON Schema draft-07 的一个子集：
+// type、enum、properties、required、additionalProperties、items、minimum、maximum、
+// minLength、maxLength、pattern，以及引用 definitions 的 $ref。
+package jsonschema
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+const refPrefix = "#/definitions/"
+
+// Schema 解析后的 JSON Schema
+type Schema struct {
+	Ref                  string                `json:"$ref"`
+	Type                 typeList              `json:"type"`
+	Enum                 []interface{}         `json:"enum"`
+	Properties           map[string]*Schema    `json:"properties"`
+	Required             []string              `json:"required"`
+	AdditionalProperties *additionalProperties `json:"additionalProperties"`
+	Items                *Schema               `json:"items"`
+	Minimum              *float64              `json:"minimum"`
+	Maximum              *float64              `json:"maximum"`
+	MinLength            *int                  `json:"minLength"`
+	MaxLength            *int                  `json:"maxLength"`
+	Pattern              string                `json:"pattern"`
+	Definitions          map[string]*Schema    `json:"definitions"`
+
+	root    *Schema
+	pattern *regexp.Regexp
+}
+
+// typeList type 关键字，可以是字符串或字符串数组
+type typeList []string
+
+func (t *typeList) UnmarshalJSON(data []byte) error {
+	var name string
+	if json.Unmarshal(data, &name) == nil {
+		*t = typeList{name}
+		return nil
+	}
+	var names []string
+	err := json.Unmarshal(data, &names)
+	if err != nil {
+		return errors.New("type must be a string or an array of strings")
+	}
+	*t = names
+	return nil
+}
+
+// additionalProperties 可以是布尔值或 Schema
+type additionalProperties struct {
+	allowed bool
+	schema  *Schema
+}
+
+func (a *additionalProperties) UnmarshalJSON(data []byte) error {
+	if json.Unmarshal(data, &a.allowed) == nil {
+		return nil
+	}
+	a.allowed = true
+	a.schema = &Schema{}
+	return json.Unmarshal(data, a.schema)
+}
+
+var validTypes = []string{"object", "array", "string", "number", "integer", "boolean", "null"}
+
+// Parse 解析 JSON Schema，检查类型名、正则表达式和 $ref 是否有效
+func Parse(data []byte) (*Schema, error) {
+	var s Schema
+	err := json.Unmarshal(data, &s)
+	if err != nil {
+		return nil, err
+	}
+	err = s.compile(&s, "")
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// MustParse 与 Parse 相同，出错时 panic，用于初始化包级变量
+func MustParse(data string) *Schema {
+	s, err := Parse([]byte(data))
+	if err != nil {
+		panic(fmt.Sprintf("jsonschema: %v", err))
+	}
+	return s
+}
+
+func (s *Schema) compile(root *Schema, path string) error {
+	s.root = root
+	for _, name := range s.Type {
+		if !containsString(validTypes, name) {
+			return fmt.Errorf("%s: invalid type %q", pathString(path), name)
+		}
+	}
+	if s.Ref != "" {
+		if _, err := s.resolveRef(); err != nil {
+			return fmt.Errorf("%s: %v", pathString(path), err)
+		}
+	}
+	if s.Pattern != "" {
+		var err error
+		s.pattern, err = regexp.Compile(s.Pattern)
+		if err != nil {
+			return fmt.Errorf("%s: %v", pathString(path), err)
+		}
+	}
+
+	for name, sub := range s.Properties {
+		if err := sub.compile(root, path+"/properties/"+name); err != nil {
+			return err
+		}
+	}
+	for name, sub := range s.Definitions {
+		if err := sub.compile(root, path+"/definitions/"+name); err != nil {
+			return err
+		}
+	}
+	if s.AdditionalProperties != nil && s.AdditionalProperties.schema != nil {
+		if err := s.AdditionalProperties.schema.compile(root, path+"/additionalProperties"); err != nil {
+			return err
+		}
+	}
+	if s.Items != nil {
+		if err := s.Items.compile(root, path+"/items"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Schema) resolveRef() (*Schema, error) {
+	if !strings.HasPrefix(s.Ref, refPrefix) {
+		return nil, fmt.Errorf("unsupported $ref %q", s.Ref)
+	}
+	def := s.root.Definitions[s.Ref[len(refPrefix):]]
+	if def == nil {
+		return nil, fmt.Errorf("definition of $ref %q not found", s.Ref)
+	}
+	return def, nil
+}
+
+// ValidationError 校验失败的位置和原因，Path 是 JSON Pointer 格式
+type ValidationError struct {
+	Path    string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return pathString(e.Path) + ": " + e.Message
+}
+
+func pathString(path string) string {
+	if path == "" {
+		return "/"
+	}
+	return path
+}
+
+// Validate 校验 JSON 数据，不满足时返回 *ValidationError
+func (s *Schema) Validate(data []byte) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value interface{}
+	err := decoder.Decode(&value)
+	if err != nil {
+		return err
+	}
+	if decoder.More() {
+		return errors.New("invalid JSON: extra data after the top-level value")
+	}
+	return s.validate(value, "")
+}
+
+func (s *Schema) validate(value interface{}, path string) error {
+	if s.Ref != "" {
+		def, err := s.resolveRef()
+		if err != nil {
+			return &ValidationError{path, err.Error()}
+		}
+		return def.validate(value, path)
+	}
+
+	if len(s.Type) > 0 && !s.matchType(value) {
+		return &ValidationError{path, fmt.Sprintf("expected %s, got %s",
+			strings.Join(s.Type, " or "), typeName(value))}
+	}
+	if len(s.Enum) > 0 && !s.matchEnum(value) {
+		return &ValidationError{path, fmt.Sprintf("value %v is not one of %v", jsonString(value), jsonString(s.Enum))}
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return s.validateObject(v, path)
+	case []interface{}:
+		if s.Items != nil {
+			for i, item := range v {
+				if err := s.Items.validate(item, fmt.Sprintf("%s/%d", path, i)); err != nil {
+					return err
+				}
+			}
+		}
+	case string:
+		n := utf8.RuneCountInString(v)
+		if s.MinLength != nil && n < *s.MinLength {
+			return &ValidationError{path, fmt.Sprintf("string shorter than %d", *s.MinLength)}
+		}
+		if s.MaxLength != nil && n > *s.MaxLength {
+			return &ValidationError{path, fmt.Sprintf("string longer than %d", *s.MaxLength)}
+		}
+		if s.pattern != nil && !s.pattern.MatchString(v) {
+			return &ValidationError{path, fmt.Sprintf("string %q does not match pattern %q", v, s.Pattern)}
+		}
+	case json.Number:
+		f, _ := v.Float64()
+		if s.Minimum != nil && f < *s.Minimum {
+			return &ValidationError{path, fmt.Sprintf("%v is less than minimum %v", v, *s.Minimum)}
+		}
+		if s.Maximum != nil && f > *s.Maximum {
+			return &ValidationError{path, fmt.Sprintf("%v is greater than maximum %v", v, *s.Maximum)}
+		}
+	}
+	return nil
+}
+
+func (s *Schema) validateObject(obj map[string]interface{}, path string) error {
+	for _, name := range s.Required {
+		if _, ok := obj[name]; !ok {
+			return &ValidationError{path, fmt.Sprintf("missing required property %q", name)}
+		}
+	}
+
+	names := make([]string, 0, len(obj))
+	for name := range obj {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		propPath := path + "/" + escapePointer(name)
+		if sub, ok := s.Properties[name]; ok {
+			if err := sub.validate(obj[name], propPath); err != nil {
+				return err
+			}
+			continue
+		}
+		additional := s.AdditionalProperties
+		if additional == nil {
+			continue
+		}
+		if !additional.allowed {
+			return &ValidationError{path, fmt.Sprintf("unknown property %q", name)}
+		}
+		if additional.schema != nil {
+			if err := additional.schema.validate(obj[name], propPath); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (s *Schema) matchType(value interface{}) bool {
+	actual := typeName(value)
+	for _, name := range s.Type {
+		if name == actual {
+			return true
+		}
+		if name == "number" && actual == "integer" {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Schema) matchEnum(value interface{}) bool {
+	if n, ok := value.(json.Number); ok {
+		f, _ := n.Float64()
+		value = f
+	}
+	for _, item := range s.Enum {
+		if reflect.DeepEqual(item, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func typeName(value interface{}) string {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case json.Number:
+		f, err := v.Float64()
+		if err == nil && f == math.Trunc(f) && !math.IsInf(f, 0) {
+			return "integer"
+		}
+		return "number"
+	case bool:
+		return "boolean"
+	case nil:
+		return "null"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+func jsonString(v interface{}) string {
+	data, _ := json.Marshal(v)
+	return string(data)
+}
+
+// escapePointer 按 JSON Pointer 的规则转义属性名
+func escapePointer(name string) string {
+	name = strings.Replace(name, "~", "~0", -1)
+	return strings.Replace(name, "/", "~1", -1)
+}
+
+func containsString(list []string, str string) bool {
+	for _, item := range list {
+		if item == str {
+			return true
+		}
+	}
+	return false
+}
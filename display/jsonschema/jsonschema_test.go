@@ -0,0 +1,97 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package jsonschema
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testSchema = `{
+	"type": "object",
+	"required": ["Version"],
+	"additionalProperties": false,
+	"properties": {
+		"Version": {"type": "string", "pattern": "^[0-9]+\\.[0-9]+$"},
+		"Mode": {"type": "integer", "enum": [1, 2, 3]},
+		"Name": {"type": ["string", "null"], "minLength": 1, "maxLength": 4},
+		"Monitors": {
+			"type": "object",
+			"additionalProperties": {"$ref": "#/definitions/monitor"}
+		},
+		"Tags": {"type": "array", "items": {"type": "string"}}
+	},
+	"definitions": {
+		"monitor": {
+			"type": "object",
+			"required": ["Width"],
+			"properties": {
+				"Width": {"type": "integer", "minimum": 1, "maximum": 65535},
+				"Rate": {"type": "number", "minimum": 0}
+			}
+		}
+	}
+}`
+
+func TestValidate(t *testing.T) {
+	s, err := Parse([]byte(testSchema))
+	require.NoError(t, err)
+
+	valid := []string{
+		`{"Version": "1.0"}`,
+		`{"Version": "1.0", "Mode": 2, "Name": null, "Tags": []}`,
+		`{"Version": "1.0", "Name": "abcd", "Monitors": {"a/b": {"Width": 1920, "Rate": 59.95, "X": 0}}}`,
+		`{"Version": "1.0", "Monitors": {"a": {"Width": 1.0, "Rate": 60}}}`,
+	}
+	for _, data := range valid {
+		assert.NoError(t, s.Validate([]byte(data)), data)
+	}
+
+	invalid := map[string]string{
+		`[]`:                                   "/: expected object, got array",
+		`{}`:                                   `/: missing required property "Version"`,
+		`{"Version": 1}`:                       "/Version: expected string, got integer",
+		`{"Version": "v1"}`:                    `/Version: string "v1" does not match pattern "^[0-9]+\\.[0-9]+$"`,
+		`{"Version": "1.0", "Mode": 4}`:        "/Mode: value 4 is not one of [1,2,3]",
+		`{"Version": "1.0", "Mode": 1.5}`:      "/Mode: expected integer, got number",
+		`{"Version": "1.0", "Name": ""}`:       "/Name: string shorter than 1",
+		`{"Version": "1.0", "Name": "abcde"}`:  "/Name: string longer than 4",
+		`{"Version": "1.0", "Other": true}`:    `/: unknown property "Other"`,
+		`{"Version": "1.0", "Tags": ["a", 1]}`: "/Tags/1: expected string, got integer",
+		`{"Version": "1.0", "Monitors": {"a/b": {"Width": 0}}}`:  "/Monitors/a~1b/Width: 0 is less than minimum 1",
+		`{"Version": "1.0", "Monitors": {"a": {"Rate": 60}}}`:    `/Monitors/a: missing required property "Width"`,
+		`{"Version": "1.0", "Monitors": {"a": {"Width": 1e6}}}`:  "/Monitors/a/Width: 1e6 is greater than maximum 65535",
+		`{"Version": "1.0", "Monitors": {"a": {"Width": "1"}}}`:  "/Monitors/a/Width: expected integer, got string",
+		`{"Version": "1.0", "Monitors": {"a": {"Width": 1}}} {}`: "invalid JSON: extra data after the top-level value",
+	}
+	for data, msg := range invalid {
+		err := s.Validate([]byte(data))
+		if assert.Error(t, err, data) {
+			assert.Equal(t, msg, err.Error(), data)
+		}
+	}
+
+	assert.Error(t, s.Validate([]byte(`{"Version": `)))
+}
+
+func TestParse(t *testing.T) {
+	_, err := Parse([]byte(`{"type": "map"}`))
+	assert.Error(t, err)
+	_, err = Parse([]byte(`{"type": 1}`))
+	assert.Error(t, err)
+	_, err = Parse([]byte(`{"properties": {"a": {"$ref": "#/definitions/b"}}}`))
+	assert.Error(t, err)
+	_, err = Parse([]byte(`{"$ref": "other.json"}`))
+	assert.Error(t, err)
+	_, err = Parse([]byte(`{"pattern": "("}`))
+	assert.Error(t, err)
+
+	assert.Panics(t, func() {
+		MustParse(`{"type": "map"}`)
+	})
+	assert.NotNil(t, MustParse(`{"additionalProperties": {"type": "string"}}`))
+}
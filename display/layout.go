@@ -0,0 +1,597 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package display
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+)
+
+const (
+	// 显示器边缘距离小于此值时对齐
+	layoutSnapDistance = 50
+	// 屏幕尺寸的最大值，显示器坐标是 int16 类型
+	layoutMaxSize = math.MaxInt16
+)
+
+const (
+	layoutRelationLeftOf  = "left-of"
+	layoutRelationRightOf = "right-of"
+	layoutRelationAbove   = "above"
+	layoutRelationBelow   = "below"
+)
+
+// layoutRect 显示器在屏幕上占据的矩形，宽和高是经过旋转和变换的。
+type layoutRect struct {
+	name   string
+	x, y   int
+	width  int
+	height int
+}
+
+func (r layoutRect) right() int {
+	return r.x + r.width
+}
+
+func (r layoutRect) bottom() int {
+	return r.y + r.height
+}
+
+func (r layoutRect) overlaps(other layoutRect) bool {
+	return r.x < other.right() && other.x < r.right() &&
+		r.y < other.bottom() && other.y < r.bottom()
+}
+
+func (r layoutRect) overlapsAny(others []layoutRect) bool {
+	for _, other := range others {
+		if r.overlaps(other) {
+			return true
+		}
+	}
+	return false
+}
+
+// gap 两个矩形之间水平和垂直方向上的空隙之和，相接或重叠时为 0。
+func (r layoutRect) gap(other layoutRect) int {
+	dx := 0
+	if other.x > r.right() {
+		dx = other.x - r.right()
+	} else if r.x > other.right() {
+		dx = r.x - other.right()
+	}
+	dy := 0
+	if other.y > r.bottom() {
+		dy = other.y - r.bottom()
+	} else if r.y > other.bottom() {
+		dy = r.y - other.bottom()
+	}
+	return dx + dy
+}
+
+func absInt(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+func clampInt(v, min, max int) int {
+	if v < min {
+		return min
+	}
+	if v > max {
+		return max
+	}
+	return v
+}
+
+// snapAxis 在与 other 相邻摆放时，对齐另一个方向上的起始或结束边缘，并保证两者有共同的边。
+func snapAxis(pos, size, otherPos, otherSize int) int {
+	if absInt(pos-otherPos) <= layoutSnapDistance {
+		pos = otherPos
+	} else if absInt(pos+size-(otherPos+otherSize)) <= layoutSnapDistance {
+		pos = otherPos + otherSize - size
+	}
+	return clampInt(pos, otherPos-size+1, otherPos+otherSize-1)
+}
+
+// getAttachCandidates 获取 r 贴着 other 的四条边摆放的位置
+func getAttachCandidates(r, other layoutRect) []layoutRect {
+	y := snapAxis(r.y, r.height, other.y, other.height)
+	x := snapAxis(r.x, r.width, other.x, other.width)
+
+	result := make([]layoutRect, 4)
+	for i := range result {
+		result[i] = r
+	}
+	result[0].x, result[0].y = other.right(), y
+	result[1].x, result[1].y = other.x-r.width, y
+	result[2].x, result[2].y = x, other.bottom()
+	result[3].x, result[3].y = x, other.y-r.height
+	return result
+}
+
+// adjacent 两个矩形是否有一段共同的边
+func (r layoutRect) adjacent(other layoutRect) bool {
+	if r.right() == other.x || other.right() == r.x {
+		return r.y < other.bottom() && other.y < r.bottom()
+	}
+	if r.bottom() == other.y || other.bottom() == r.y {
+		return r.x < other.right() && other.x < r.right()
+	}
+	return false
+}
+
+func getLayoutAnchorIndex(rects []layoutRect, anchor string) (int, error) {
+	for i, r := range rects {
+		if r.name == anchor {
+			return i, nil
+		}
+	}
+	return -1, fmt.Errorf("anchor monitor %q not found or not enabled", anchor)
+}
+
+func checkLayoutSizes(rects []layoutRect) error {
+	if len(rects) == 0 {
+		return errors.New("no enabled monitor")
+	}
+	for _, r := range rects {
+		if r.width <= 0 || r.height <= 0 {
+			return fmt.Errorf("invalid size %dx%d of monitor %s", r.width, r.height, r.name)
+		}
+	}
+	return nil
+}
+
+// placeLayout 从锚点显示器开始，依次摆放离已摆放的显示器最近的显示器，
+// place 返回显示器摆放后的位置，锚点显示器位置不变。
+func placeLayout(rects []layoutRect, anchorIdx int, place func(r layoutRect, placed []layoutRect) layoutRect) []layoutRect {
+	result := make([]layoutRect, len(rects))
+	placed := make([]layoutRect, 0, len(rects))
+	isPlaced := make([]bool, len(rects))
+	result[anchorIdx] = rects[anchorIdx]
+	placed = append(placed, rects[anchorIdx])
+	isPlaced[anchorIdx] = true
+
+	for len(placed) < len(rects) {
+		// 先摆放离已摆放的显示器最近的
+		idx := -1
+		minGap := 0
+		for i, r := range rects {
+			if isPlaced[i] {
+				continue
+			}
+			for _, other := range placed {
+				gap := r.gap(other)
+				if idx == -1 || gap < minGap {
+					idx = i
+					minGap = gap
+				}
+			}
+		}
+
+		r := place(rects[idx], placed)
+		placed = append(placed, r)
+		isPlaced[idx] = true
+		result[idx] = r
+	}
+	return result
+}
+
+// getNearestAttachment 获取 r 贴着已摆放的显示器、离原位置最近且不重叠的位置，
+// 返回的距离是水平和垂直方向上移动距离的最大值。
+func getNearestAttachment(r layoutRect, placed []layoutRect) (best layoutRect, distance int, found bool) {
+	for _, other := range placed {
+		for _, candidate := range getAttachCandidates(r, other) {
+			if candidate.overlapsAny(placed) {
+				continue
+			}
+			d := absInt(candidate.x - r.x)
+			if dy := absInt(candidate.y - r.y); dy > d {
+				d = dy
+			}
+			if !found || d < distance {
+				best = candidate
+				distance = d
+				found = true
+			}
+		}
+	}
+	return
+}
+
+// moveLayoutToOrigin 使摆放的左上角位于原点，并检查屏幕尺寸
+func moveLayoutToOrigin(rects []layoutRect) error {
+	minX, minY := rects[0].x, rects[0].y
+	for _, r := range rects {
+		if r.x < minX {
+			minX = r.x
+		}
+		if r.y < minY {
+			minY = r.y
+		}
+	}
+	var width, height int
+	for i := range rects {
+		rects[i].x -= minX
+		rects[i].y -= minY
+		if rects[i].right() > width {
+			width = rects[i].right()
+		}
+		if rects[i].bottom() > height {
+			height = rects[i].bottom()
+		}
+	}
+	if width > layoutMaxSize || height > layoutMaxSize {
+		return fmt.Errorf("layout size %dx%d exceeds the maximum %d", width, height, layoutMaxSize)
+	}
+	return nil
+}
+
+// checkLayout 检查显示器之间没有重叠，并且都通过共同的边与锚点显示器相连
+func checkLayout(rects []layoutRect, anchorIdx int) error {
+	for i, r := range rects {
+		for _, other := range rects[i+1:] {
+			if r.overlaps(other) {
+				return fmt.Errorf("monitor %s overlaps monitor %s", r.name, other.name)
+			}
+		}
+	}
+
+	connected := make([]bool, len(rects))
+	connected[anchorIdx] = true
+	queue := []int{anchorIdx}
+	for len(queue) > 0 {
+		r := rects[queue[0]]
+		queue = queue[1:]
+		for i, other := range rects {
+			if !connected[i] && r.adjacent(other) {
+				connected[i] = true
+				queue = append(queue, i)
+			}
+		}
+	}
+
+	for i, r := range rects {
+		if connected[i] {
+			continue
+		}
+		var nearest layoutRect
+		minGap := -1
+		for j, other := range rects {
+			if !connected[j] {
+				continue
+			}
+			gap := r.gap(other)
+			if minGap == -1 || gap < minGap {
+				nearest = other
+				minGap = gap
+			}
+		}
+		if minGap == 0 {
+			return fmt.Errorf("monitor %s only touches monitor %s at a corner", r.name, nearest.name)
+		}
+		return fmt.Errorf("monitor %s is %d pixels away from monitor %s, monitors must be adjacent",
+			r.name, minGap, nearest.name)
+	}
+	return nil
+}
+
+// normalizeLayout 规范化用户设置的显示器摆放：距离在 layoutSnapDistance 之内的显示器贴在一起并对齐相近的边缘，
+// 然后检查摆放，存在重叠或者空隙时返回错误，最后使左上角位于原点。锚点显示器的位置不会因为对齐而改变。
+func normalizeLayout(rects []layoutRect, anchor string) ([]layoutRect, error) {
+	err := checkLayoutSizes(rects)
+	if err != nil {
+		return nil, err
+	}
+	anchorIdx, err := getLayoutAnchorIndex(rects, anchor)
+	if err != nil {
+		return nil, err
+	}
+
+	result := placeLayout(rects, anchorIdx, func(r layoutRect, placed []layoutRect) layoutRect {
+		best, distance, found := getNearestAttachment(r, placed)
+		if found && distance <= layoutSnapDistance {
+			return best
+		}
+		return r
+	})
+	err = checkLayout(result, anchorIdx)
+	if err != nil {
+		return nil, err
+	}
+	err = moveLayoutToOrigin(result)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// packLayout 重新摆放显示器，去除显示器之间的空隙和重叠，并使左上角位于原点。
+// 用于缩放改变了显示器占据的尺寸之后，每个显示器都选择贴着已摆放的显示器、离原位置最近且不重叠的位置。
+func packLayout(rects []layoutRect, anchor string) ([]layoutRect, error) {
+	err := checkLayoutSizes(rects)
+	if err != nil {
+		return nil, err
+	}
+	anchorIdx, err := getLayoutAnchorIndex(rects, anchor)
+	if err != nil {
+		return nil, err
+	}
+
+	result := placeLayout(rects, anchorIdx, func(r layoutRect, placed []layoutRect) layoutRect {
+		best, _, found := getNearestAttachment(r, placed)
+		if found {
+			return best
+		}
+		// 放在已摆放的显示器的最右边
+		maxRight := placed[0].right()
+		for _, other := range placed {
+			if other.right() > maxRight {
+				maxRight = other.right()
+			}
+		}
+		r.x, r.y = maxRight, placed[0].y
+		return r
+	})
+	err = moveLayoutToOrigin(result)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// layoutRelation 一个显示器相对于另一个显示器的位置
+type layoutRelation struct {
+	// 要摆放的显示器名称
+	Name string
+	// left-of, right-of, above 或 below
+	Relation string
+	// 参照的显示器名称
+	Of string
+	// 为 true 时在另一个方向上居中对齐，否则对齐上边缘或左边缘。
+	Centered bool
+}
+
+// layoutSpec 是 ArrangeMonitors 方法的参数
+type layoutSpec struct {
+	// 位于原点的显示器名称，为空时使用主屏
+	Anchor    string
+	Relations []layoutRelation
+}
+
+func parseLayoutSpec(data string) (*layoutSpec, error) {
+	var spec layoutSpec
+	err := json.Unmarshal([]byte(data), &spec)
+	if err != nil {
+		return nil, fmt.Errorf("invalid layout spec: %v", err)
+	}
+	return &spec, nil
+}
+
+// placeRelative 根据关系计算 r 相对于 of 的位置
+func placeRelative(r, of layoutRect, relation layoutRelation) (layoutRect, error) {
+	switch relation.Relation {
+	case layoutRelationLeftOf, layoutRelationRightOf:
+		if relation.Relation == layoutRelationLeftOf {
+			r.x = of.x - r.width
+		} else {
+			r.x = of.right()
+		}
+		r.y = of.y
+		if relation.Centered {
+			r.y = of.y + (of.height-r.height)/2
+		}
+	case layoutRelationAbove, layoutRelationBelow:
+		if relation.Relation == layoutRelationAbove {
+			r.y = of.y - r.height
+		} else {
+			r.y = of.bottom()
+		}
+		r.x = of.x
+		if relation.Centered {
+			r.x = of.x + (of.width-r.width)/2
+		}
+	default:
+		return r, fmt.Errorf("invalid relation %q of monitor %s", relation.Relation, relation.Name)
+	}
+	return r, nil
+}
+
+// arrangeLayout 根据 spec 中的关系摆放 rects 中的显示器，结果经过 normalizeLayout 规范化，以 spec.Anchor 为锚点。
+func arrangeLayout(rects []layoutRect, spec *layoutSpec) ([]layoutRect, error) {
+	idxMap := make(map[string]int, len(rects))
+	for i, r := range rects {
+		idxMap[r.name] = i
+	}
+
+	if _, ok := idxMap[spec.Anchor]; !ok {
+		return nil, fmt.Errorf("anchor monitor %q not found or not enabled", spec.Anchor)
+	}
+	relationMap := make(map[string]layoutRelation, len(spec.Relations))
+	for _, relation := range spec.Relations {
+		if _, ok := idxMap[relation.Name]; !ok {
+			return nil, fmt.Errorf("monitor %q not found or not enabled", relation.Name)
+		}
+		if _, ok := idxMap[relation.Of]; !ok {
+			return nil, fmt.Errorf("monitor %q not found or not enabled", relation.Of)
+		}
+		if relation.Name == relation.Of {
+			return nil, fmt.Errorf("monitor %s can not be placed relative to itself", relation.Name)
+		}
+		if relation.Name == spec.Anchor {
+			return nil, fmt.Errorf("anchor monitor %s can not be placed relative to others", relation.Name)
+		}
+		if _, ok := relationMap[relation.Name]; ok {
+			return nil, fmt.Errorf("duplicate relations of monitor %s", relation.Name)
+		}
+		relationMap[relation.Name] = relation
+	}
+
+	result := make([]layoutRect, len(rects))
+	copy(result, rects)
+	placed := make(map[string]bool, len(rects))
+	anchorIdx := idxMap[spec.Anchor]
+	result[anchorIdx].x, result[anchorIdx].y = 0, 0
+	placed[spec.Anchor] = true
+
+	// 每轮至少摆放一个显示器，否则存在循环的关系
+	for len(placed) < len(rects) {
+		progress := false
+		for _, relation := range spec.Relations {
+			if placed[relation.Name] || !placed[relation.Of] {
+				continue
+			}
+			idx := idxMap[relation.Name]
+			r, err := placeRelative(result[idx], result[idxMap[relation.Of]], relation)
+			if err != nil {
+				return nil, err
+			}
+			result[idx] = r
+			placed[relation.Name] = true
+			progress = true
+		}
+		if !progress {
+			break
+		}
+	}
+
+	for _, r := range rects {
+		if placed[r.name] {
+			continue
+		}
+		if _, ok := relationMap[r.name]; ok {
+			return nil, fmt.Errorf("monitor %s can not be placed, relations are cyclic", r.name)
+		}
+		return nil, fmt.Errorf("monitor %s is not arranged", r.name)
+	}
+
+	return normalizeLayout(result, spec.Anchor)
+}
+
+// getConfigsLayoutRects 获取启用的显示器配置对应的矩形和锚点，锚点是主屏，没有主屏时是第一个显示器。
+// 配置中的宽和高是经过旋转调整的，还需要考虑变换。
+func (m *Manager) getConfigsLayoutRects(configs SysMonitorConfigs, monitors Monitors) ([]layoutRect, []*SysMonitorConfig, string) {
+	var enabledConfigs []*SysMonitorConfig
+	var enabledMonitors Monitors
+	var anchor string
+	for _, config := range configs {
+		if !config.Enabled {
+			continue
+		}
+		monitor := monitors.GetByUuidAndName(config.UUID, config.Name)
+		if monitor == nil {
+			monitor = monitors.GetByUuid(config.UUID)
+		}
+		if monitor == nil {
+			continue
+		}
+		if config.Primary || len(enabledConfigs) == 0 {
+			anchor = config.Name
+		}
+		enabledConfigs = append(enabledConfigs, config)
+		enabledMonitors = append(enabledMonitors, monitor)
+	}
+
+	m.sysConfig.mu.Lock()
+	factors := m.sysConfig.Config.ScaleFactors
+	m.sysConfig.mu.Unlock()
+	_, transforms := getTransformScales(factors, enabledMonitors, DisplayModeExtend)
+
+	rects := make([]layoutRect, len(enabledConfigs))
+	for i, config := range enabledConfigs {
+		width, height := getScaledSize(config.Width, config.Height, transforms[enabledMonitors[i].ID])
+		rects[i] = layoutRect{
+			name:   config.Name,
+			x:      int(config.X),
+			y:      int(config.Y),
+			width:  int(width),
+			height: int(height),
+		}
+	}
+	return rects, enabledConfigs, anchor
+}
+
+// updateConfigsLayout 用 layoutFn 计算显示器配置的摆放，修改 configs 中的坐标。
+func (m *Manager) updateConfigsLayout(configs SysMonitorConfigs, monitors Monitors,
+	layoutFn func(rects []layoutRect, anchor string) ([]layoutRect, error)) error {
+	rects, enabledConfigs, anchor := m.getConfigsLayoutRects(configs, monitors)
+	if len(rects) == 0 {
+		return nil
+	}
+	result, err := layoutFn(rects, anchor)
+	if err != nil {
+		return err
+	}
+	for i, config := range enabledConfigs {
+		config.X = int16(result[i].x)
+		config.Y = int16(result[i].y)
+	}
+	return nil
+}
+
+// normalizeConfigsLayout 规范化用户设置的显示器配置的摆放，存在重叠或者空隙时返回错误。
+func (m *Manager) normalizeConfigsLayout(configs SysMonitorConfigs, monitors Monitors) error {
+	return m.updateConfigsLayout(configs, monitors, normalizeLayout)
+}
+
+// packConfigsLayout 显示器占据的尺寸改变之后，重新摆放显示器配置。
+func (m *Manager) packConfigsLayout(configs SysMonitorConfigs, monitors Monitors) error {
+	return m.updateConfigsLayout(configs, monitors, packLayout)
+}
+
+// arrangeMonitors 根据 specJson 摆放显示器，与 SetPosition 一样只是改变显示器的位置，需要调用 ApplyChanges 应用。
+func (m *Manager) arrangeMonitors(specJson string) error {
+	m.PropsMu.RLock()
+	displayMode := m.DisplayMode
+	primary := m.Primary
+	m.PropsMu.RUnlock()
+	if displayMode == DisplayModeMirror {
+		return errors.New("not allow arrange monitors in mirror mode")
+	}
+	if m.getInApply() {
+		return errors.New("in apply")
+	}
+
+	spec, err := parseLayoutSpec(specJson)
+	if err != nil {
+		return err
+	}
+	if spec.Anchor == "" {
+		spec.Anchor = primary
+	}
+
+	var monitors Monitors
+	var rects []layoutRect
+	for _, monitor := range m.getConnectedMonitors() {
+		monitor.PropsMu.RLock()
+		enabled := monitor.Enabled
+		width, height := monitor.getScaledSize()
+		monitor.PropsMu.RUnlock()
+		if !enabled {
+			continue
+		}
+		monitors = append(monitors, monitor)
+		rects = append(rects, layoutRect{
+			name:   monitor.Name,
+			width:  int(width),
+			height: int(height),
+		})
+	}
+
+	result, err := arrangeLayout(rects, spec)
+	if err != nil {
+		return err
+	}
+
+	for i, monitor := range monitors {
+		monitor.PropsMu.Lock()
+		monitor.changePosition(int16(result[i].x), int16(result[i].y))
+		monitor.PropsMu.Unlock()
+	}
+	return nil
+}
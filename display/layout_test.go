@@ -0,0 +1,234 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package display
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_normalizeLayout(t *testing.T) {
+	tests := []struct {
+		name   string
+		rects  []layoutRect
+		anchor string
+		want   []layoutRect
+	}{
+		{
+			name: "single monitor moved to origin",
+			rects: []layoutRect{
+				{name: "eDP-1", x: 100, y: 200, width: 1920, height: 1080},
+			},
+			anchor: "eDP-1",
+			want: []layoutRect{
+				{name: "eDP-1", x: 0, y: 0, width: 1920, height: 1080},
+			},
+		},
+		{
+			name: "snap small gap",
+			rects: []layoutRect{
+				{name: "eDP-1", x: 0, y: 0, width: 1920, height: 1080},
+				{name: "HDMI-1", x: 1950, y: 0, width: 1920, height: 1080},
+			},
+			anchor: "eDP-1",
+			want: []layoutRect{
+				{name: "eDP-1", x: 0, y: 0, width: 1920, height: 1080},
+				{name: "HDMI-1", x: 1920, y: 0, width: 1920, height: 1080},
+			},
+		},
+		{
+			name: "snap small overlap and top edge",
+			rects: []layoutRect{
+				{name: "eDP-1", x: 0, y: 0, width: 1920, height: 1080},
+				{name: "HDMI-1", x: 1880, y: 30, width: 1280, height: 1024},
+			},
+			anchor: "eDP-1",
+			want: []layoutRect{
+				{name: "eDP-1", x: 0, y: 0, width: 1920, height: 1080},
+				{name: "HDMI-1", x: 1920, y: 0, width: 1280, height: 1024},
+			},
+		},
+		{
+			name: "snap bottom edge",
+			rects: []layoutRect{
+				{name: "eDP-1", x: 0, y: 0, width: 1920, height: 1080},
+				{name: "HDMI-1", x: 1920, y: 100, width: 1280, height: 1024},
+			},
+			anchor: "eDP-1",
+			want: []layoutRect{
+				{name: "eDP-1", x: 0, y: 0, width: 1920, height: 1080},
+				{name: "HDMI-1", x: 1920, y: 56, width: 1280, height: 1024},
+			},
+		},
+		{
+			name: "negative position and rotated monitor",
+			rects: []layoutRect{
+				{name: "eDP-1", x: 0, y: 0, width: 1920, height: 1080},
+				{name: "DP-1", x: -1080, y: -400, width: 1080, height: 1920},
+			},
+			anchor: "eDP-1",
+			want: []layoutRect{
+				{name: "eDP-1", x: 1080, y: 400, width: 1920, height: 1080},
+				{name: "DP-1", x: 0, y: 0, width: 1080, height: 1920},
+			},
+		},
+		{
+			name: "anchor keeps its position",
+			rects: []layoutRect{
+				{name: "eDP-1", x: 0, y: 0, width: 1920, height: 1080},
+				{name: "HDMI-1", x: 1940, y: 20, width: 1920, height: 1080},
+			},
+			anchor: "HDMI-1",
+			want: []layoutRect{
+				{name: "eDP-1", x: 0, y: 0, width: 1920, height: 1080},
+				{name: "HDMI-1", x: 1920, y: 0, width: 1920, height: 1080},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := normalizeLayout(tt.rects, tt.anchor)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_normalizeLayoutError(t *testing.T) {
+	_, err := normalizeLayout(nil, "")
+	assert.Error(t, err)
+
+	_, err = normalizeLayout([]layoutRect{{name: "eDP-1", width: 0, height: 1080}}, "eDP-1")
+	assert.EqualError(t, err, "invalid size 0x1080 of monitor eDP-1")
+
+	_, err = normalizeLayout([]layoutRect{{name: "eDP-1", width: 1920, height: 1080}}, "HDMI-1")
+	assert.EqualError(t, err, `anchor monitor "HDMI-1" not found or not enabled`)
+
+	_, err = normalizeLayout([]layoutRect{
+		{name: "DP-1", width: 20000, height: 1080},
+		{name: "DP-2", x: 20000, width: 20000, height: 1080},
+	}, "DP-1")
+	assert.EqualError(t, err, "layout size 40000x1080 exceeds the maximum 32767")
+
+	_, err = normalizeLayout([]layoutRect{
+		{name: "eDP-1", width: 1920, height: 1080},
+		{name: "HDMI-1", x: 1800, width: 1920, height: 1080},
+	}, "eDP-1")
+	assert.EqualError(t, err, "monitor eDP-1 overlaps monitor HDMI-1")
+
+	_, err = normalizeLayout([]layoutRect{
+		{name: "eDP-1", width: 1920, height: 1080},
+		{name: "HDMI-1", x: 300, y: 1500, width: 1280, height: 720},
+	}, "eDP-1")
+	assert.EqualError(t, err, "monitor HDMI-1 is 420 pixels away from monitor eDP-1, monitors must be adjacent")
+}
+
+func Test_packLayout(t *testing.T) {
+	rects := []layoutRect{
+		{name: "eDP-1", x: 0, y: 0, width: 1920, height: 1080},
+		{name: "HDMI-1", x: 1800, y: 30, width: 1280, height: 1024},
+		{name: "DP-1", x: 300, y: 1500, width: 1280, height: 720},
+	}
+	got, err := packLayout(rects, "eDP-1")
+	require.NoError(t, err)
+	assert.Equal(t, []layoutRect{
+		{name: "eDP-1", x: 0, y: 0, width: 1920, height: 1080},
+		{name: "HDMI-1", x: 1920, y: 0, width: 1280, height: 1024},
+		{name: "DP-1", x: 300, y: 1080, width: 1280, height: 720},
+	}, got)
+}
+
+func Test_arrangeLayout(t *testing.T) {
+	rects := []layoutRect{
+		{name: "eDP-1", width: 1920, height: 1080},
+		{name: "HDMI-1", width: 1280, height: 720},
+		{name: "DP-1", width: 2560, height: 1440},
+	}
+
+	spec, err := parseLayoutSpec(`{"Anchor":"eDP-1","Relations":[
+		{"Name":"HDMI-1","Relation":"left-of","Of":"eDP-1","Centered":true},
+		{"Name":"DP-1","Relation":"above","Of":"eDP-1"}]}`)
+	require.NoError(t, err)
+	got, err := arrangeLayout(rects, spec)
+	require.NoError(t, err)
+	assert.Equal(t, []layoutRect{
+		{name: "eDP-1", x: 1280, y: 1440, width: 1920, height: 1080},
+		{name: "HDMI-1", x: 0, y: 1620, width: 1280, height: 720},
+		{name: "DP-1", x: 1280, y: 0, width: 2560, height: 1440},
+	}, got)
+
+	tests := []struct {
+		name   string
+		spec   layoutSpec
+		errStr string
+	}{
+		{
+			name:   "anchor not found",
+			spec:   layoutSpec{Anchor: "VGA-1"},
+			errStr: `anchor monitor "VGA-1" not found or not enabled`,
+		},
+		{
+			name: "invalid relation",
+			spec: layoutSpec{Anchor: "eDP-1", Relations: []layoutRelation{
+				{Name: "HDMI-1", Relation: "behind", Of: "eDP-1"},
+				{Name: "DP-1", Relation: "above", Of: "eDP-1"},
+			}},
+			errStr: `invalid relation "behind" of monitor HDMI-1`,
+		},
+		{
+			name: "cyclic",
+			spec: layoutSpec{Anchor: "eDP-1", Relations: []layoutRelation{
+				{Name: "HDMI-1", Relation: "above", Of: "DP-1"},
+				{Name: "DP-1", Relation: "above", Of: "HDMI-1"},
+			}},
+			errStr: "monitor HDMI-1 can not be placed, relations are cyclic",
+		},
+		{
+			name: "not arranged",
+			spec: layoutSpec{Anchor: "eDP-1", Relations: []layoutRelation{
+				{Name: "HDMI-1", Relation: "above", Of: "eDP-1"},
+			}},
+			errStr: "monitor DP-1 is not arranged",
+		},
+		{
+			name: "duplicate",
+			spec: layoutSpec{Anchor: "eDP-1", Relations: []layoutRelation{
+				{Name: "HDMI-1", Relation: "above", Of: "eDP-1"},
+				{Name: "HDMI-1", Relation: "below", Of: "eDP-1"},
+			}},
+			errStr: "duplicate relations of monitor HDMI-1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := arrangeLayout(rects, &tt.spec)
+			assert.EqualError(t, err, tt.errStr)
+		})
+	}
+}
+
+func TestManager_applyChangesInvalidLayout(t *testing.T) {
+	m := newTestHeadlessManager(t)
+	hdmi := m.getConnectedMonitors().GetByName("HDMI-1")
+	require.NotNil(t, hdmi)
+
+	// 用户设置的位置有空隙时不应用
+	require.Nil(t, hdmi.SetPosition(2120, 0))
+	err := m.applyChanges()
+	assert.EqualError(t, err,
+		"invalid layout: monitor HDMI-1 is 200 pixels away from monitor eDP-1, monitors must be adjacent")
+
+	// 只改变模式时重新摆放
+	m = newTestHeadlessManager(t)
+	hdmi = m.getConnectedMonitors().GetByName("HDMI-1")
+	require.NotNil(t, hdmi)
+	edp := m.getConnectedMonitors().GetByName("eDP-1")
+	require.NotNil(t, edp)
+	require.Nil(t, edp.SetModeBySize(1280, 720))
+	require.NoError(t, m.applyChanges())
+	assert.Equal(t, int16(1280), hdmi.X)
+}
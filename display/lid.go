@@ -0,0 +1,205 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package display
+
+import (
+	"fmt"
+)
+
+// 合上笔记本盖子时的处理方式
+const (
+	// 不改变显示配置
+	lidClosePolicyNone = "none"
+	// 连接了外接显示器时只使用外接显示器，打开盖子时恢复
+	lidClosePolicyExternalOnly = "external-only"
+)
+
+func isValidLidClosePolicy(policy string) bool {
+	return policy == lidClosePolicyNone || policy == lidClosePolicyExternalOnly
+}
+
+func getLidClosePolicy(policy string) string {
+	if policy == "" {
+		return lidClosePolicyExternalOnly
+	}
+	return policy
+}
+
+// LidSwitchState 合上盖子前的显示模式，打开盖子时恢复。按 monitorsId 保存在用户配置中，
+// 合上盖子时注销了也能在下次打开时恢复。
+type LidSwitchState struct {
+	DisplayMode byte
+	// 合上盖子前是单屏模式时显示的显示器
+	OnlyOneUuid string `json:",omitempty"`
+}
+
+// getExternalOnlyMonitor 选择合上盖子时使用的外接显示器，优先使用主屏
+func getExternalOnlyMonitor(monitors Monitors, builtin *Monitor, primary string,
+	getDefault func([]*Monitor) *Monitor) *Monitor {
+	var externals []*Monitor
+	for _, monitor := range monitors {
+		if monitor.ID == builtin.ID {
+			continue
+		}
+		if monitor.Name == primary {
+			return monitor
+		}
+		externals = append(externals, monitor)
+	}
+	if len(externals) == 0 {
+		return nil
+	}
+	return getDefault(externals)
+}
+
+func (m *Manager) getLidSwitchState(monitorsId monitorsId) *LidSwitchState {
+	m.userCfgMu.Lock()
+	defer m.userCfgMu.Unlock()
+	state := m.userConfig.LidSwitchStates[monitorsId.v1]
+	if state == nil {
+		return nil
+	}
+	result := *state
+	return &result
+}
+
+// setLidSwitchState 保存合上盖子前的显示模式，state 为 nil 时删除
+func (m *Manager) setLidSwitchState(monitorsId monitorsId, state *LidSwitchState) {
+	m.userCfgMu.Lock()
+	defer m.userCfgMu.Unlock()
+	if state == nil {
+		if _, ok := m.userConfig.LidSwitchStates[monitorsId.v1]; !ok {
+			return
+		}
+		delete(m.userConfig.LidSwitchStates, monitorsId.v1)
+	} else {
+		if m.userConfig.LidSwitchStates == nil {
+			m.userConfig.LidSwitchStates = make(map[string]*LidSwitchState)
+		}
+		m.userConfig.LidSwitchStates[monitorsId.v1] = state
+	}
+	err := m.saveUserConfigNoLock()
+	if err != nil {
+		logger.Warning(err)
+	}
+}
+
+// handleLidClosedChanged 处理笔记本盖子的开合
+func (m *Manager) handleLidClosedChanged(closed bool) {
+	m.PropsMu.RLock()
+	policy := m.LidClosePolicy
+	m.PropsMu.RUnlock()
+	if policy != lidClosePolicyExternalOnly {
+		return
+	}
+
+	builtin := m.getBuiltinMonitor()
+	if builtin == nil {
+		return
+	}
+	monitors := m.getConnectedMonitors()
+	if len(monitors) < 2 {
+		return
+	}
+
+	var err error
+	if closed {
+		err = m.switchToExternalOnly(monitors, builtin)
+	} else {
+		err = m.restoreLidSwitchState(monitors, builtin)
+	}
+	if err != nil {
+		logger.Warningf("handle lid closed %v failed: %v", closed, err)
+	}
+}
+
+// checkLidState 在启动和显示器插拔后按照盖子当前的状态处理，
+// 合上盖子后登录或者连接外接显示器时不会收到 LidClosed 改变的信号。
+func (m *Manager) checkLidState() {
+	if m.sysBus == nil {
+		return
+	}
+	obj := m.sysBus.Object(login1Service, login1Path)
+	m.handleLidClosedChanged(getBoolProperty(obj, login1PropLidClosed))
+}
+
+// switchToExternalOnly 合上盖子时切换到只使用外接显示器的单屏模式，记录切换前的模式
+func (m *Manager) switchToExternalOnly(monitors Monitors, builtin *Monitor) error {
+	monitorsId := monitors.getMonitorsId()
+	screenCfg := m.getSysScreenConfig(monitorsId)
+
+	m.PropsMu.RLock()
+	displayMode := m.DisplayMode
+	primary := m.Primary
+	m.PropsMu.RUnlock()
+
+	if displayMode == DisplayModeOnlyOne && screenCfg.OnlyOneUuid != builtin.uuid {
+		// 已经只使用外接显示器了
+		return nil
+	}
+
+	external := getExternalOnlyMonitor(monitors, builtin, primary, m.getDefaultPrimaryMonitor)
+	if external == nil {
+		return nil
+	}
+
+	state := &LidSwitchState{DisplayMode: displayMode}
+	if displayMode == DisplayModeOnlyOne {
+		state.OnlyOneUuid = screenCfg.OnlyOneUuid
+	}
+	logger.Debugf("lid closed, switch to only %v, previous state: %+v", external.Name, state)
+	err := m.switchMode(DisplayModeOnlyOne, external.Name)
+	if err != nil {
+		return err
+	}
+	m.setLidSwitchState(monitorsId, state)
+	return nil
+}
+
+// restoreLidSwitchState 打开盖子时恢复合上盖子前的模式，如果合上期间用户已经修改了模式则不恢复
+func (m *Manager) restoreLidSwitchState(monitors Monitors, builtin *Monitor) error {
+	monitorsId := monitors.getMonitorsId()
+	state := m.getLidSwitchState(monitorsId)
+	if state == nil {
+		return nil
+	}
+	m.setLidSwitchState(monitorsId, nil)
+
+	screenCfg := m.getSysScreenConfig(monitorsId)
+	m.PropsMu.RLock()
+	displayMode := m.DisplayMode
+	m.PropsMu.RUnlock()
+	if displayMode != DisplayModeOnlyOne || screenCfg.OnlyOneUuid == builtin.uuid {
+		logger.Debug("display mode changed while lid closed, not restore")
+		return nil
+	}
+
+	name := ""
+	if state.DisplayMode == DisplayModeOnlyOne {
+		monitor := monitors.GetByUuid(state.OnlyOneUuid)
+		if monitor == nil {
+			return fmt.Errorf("monitor %q not connected", state.OnlyOneUuid)
+		}
+		name = monitor.Name
+	}
+	logger.Debugf("lid opened, restore state: %+v", state)
+	return m.switchMode(state.DisplayMode, name)
+}
+
+// dbus 上导出的方法
+func (m *Manager) setLidClosePolicy(policy string) error {
+	if !isValidLidClosePolicy(policy) {
+		return fmt.Errorf("invalid lid close policy %q", policy)
+	}
+	m.userCfgMu.Lock()
+	m.userConfig.LidClosePolicy = policy
+	err := m.saveUserConfigNoLock()
+	m.userCfgMu.Unlock()
+
+	m.PropsMu.Lock()
+	m.setPropLidClosePolicy(policy)
+	m.PropsMu.Unlock()
+	return err
+}
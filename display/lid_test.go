@@ -0,0 +1,57 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package display
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetLidClosePolicy(t *testing.T) {
+	assert.Equal(t, lidClosePolicyExternalOnly, getLidClosePolicy(""))
+	assert.Equal(t, lidClosePolicyNone, getLidClosePolicy(lidClosePolicyNone))
+	assert.True(t, isValidLidClosePolicy(lidClosePolicyNone))
+	assert.True(t, isValidLidClosePolicy(lidClosePolicyExternalOnly))
+	assert.False(t, isValidLidClosePolicy(""))
+	assert.False(t, isValidLidClosePolicy("suspend"))
+}
+
+func TestGetExternalOnlyMonitor(t *testing.T) {
+	builtin := &Monitor{ID: 1, Name: "eDP-1"}
+	hdmi := &Monitor{ID: 2, Name: "HDMI-1"}
+	dp := &Monitor{ID: 3, Name: "DP-1"}
+	getFirst := func(monitors []*Monitor) *Monitor {
+		return monitors[0]
+	}
+
+	monitors := Monitors{builtin, hdmi, dp}
+	// 优先使用主屏
+	assert.Equal(t, dp, getExternalOnlyMonitor(monitors, builtin, "DP-1", getFirst))
+	// 主屏是内置显示器时使用默认的
+	assert.Equal(t, hdmi, getExternalOnlyMonitor(monitors, builtin, "eDP-1", getFirst))
+	// 没有外接显示器
+	assert.Nil(t, getExternalOnlyMonitor(Monitors{builtin}, builtin, "eDP-1", getFirst))
+}
+
+func TestManager_handleLidClosedChanged(t *testing.T) {
+	m := newTestHeadlessManager(t)
+	m.LidClosePolicy = lidClosePolicyExternalOnly
+	require.Equal(t, DisplayModeExtend, m.DisplayMode)
+	monitorsId := m.getConnectedMonitors().getMonitorsId()
+
+	m.handleLidClosedChanged(true)
+	assert.Equal(t, DisplayModeOnlyOne, m.DisplayMode)
+	builtin := m.getBuiltinMonitor()
+	assert.False(t, builtin.Enabled)
+	assert.True(t, m.getConnectedMonitors().GetByName("HDMI-1").Enabled)
+	assert.Equal(t, &LidSwitchState{DisplayMode: DisplayModeExtend}, m.getLidSwitchState(monitorsId))
+
+	m.handleLidClosedChanged(false)
+	assert.Equal(t, DisplayModeExtend, m.DisplayMode)
+	assert.True(t, builtin.Enabled)
+	assert.Nil(t, m.getLidSwitchState(monitorsId))
+}
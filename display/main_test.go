@@ -0,0 +1,236 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package display
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/godbus/dbus"
+)
+
+const (
+	testSysDisplayService   = "com.deepin.system.Display"
+	testSysDisplayPath      = "/com/deepin/system/Display"
+	testSysDisplayInterface = "com.deepin.system.Display"
+)
+
+// 测试环境的错误，需要 D-Bus 和 gsettings 的测试在出错时失败
+var _testEnvErr error
+
+// 代替系统的 com.deepin.system.Display 服务
+var _testSysDisplay = &testSysDisplay{}
+
+// testSysDisplay 在内存中保存系统级配置
+type testSysDisplay struct {
+	mu  sync.Mutex
+	cfg string
+}
+
+func (d *testSysDisplay) GetConfig() (string, *dbus.Error) {
+	return d.getConfig(), nil
+}
+
+func (d *testSysDisplay) SetConfig(cfg string) *dbus.Error {
+	d.setConfig(cfg)
+	return nil
+}
+
+func (d *testSysDisplay) getConfig() string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.cfg
+}
+
+func (d *testSysDisplay) setConfig(cfg string) {
+	d.mu.Lock()
+	d.cfg = cfg
+	d.mu.Unlock()
+}
+
+// TestMain 测试使用临时的配置目录、gsettings 的内存后端和私有的 D-Bus，不会修改用户的配置，
+// 也不依赖会话和系统的 D-Bus。
+func TestMain(m *testing.M) {
+	os.Exit(runTests(m))
+}
+
+func runTests(m *testing.M) int {
+	dir, err := ioutil.TempDir("", "startdde-display-test")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer os.RemoveAll(dir)
+
+	cleanup, err := setupTestEnv(dir)
+	if err != nil {
+		_testEnvErr = fmt.Errorf("failed to setup test environment: %v", err)
+		fmt.Fprintln(os.Stderr, _testEnvErr)
+	}
+	if cleanup != nil {
+		defer cleanup()
+	}
+	return m.Run()
+}
+
+func setupTestEnv(dir string) (cleanup func(), err error) {
+	for env, name := range map[string]string{
+		"XDG_CONFIG_HOME": "config",
+		"XDG_CACHE_HOME":  "cache",
+		"XDG_DATA_HOME":   "data",
+	} {
+		err = os.Setenv(env, filepath.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+	}
+	initConfigFiles()
+
+	err = setupTestSchemas(filepath.Join(dir, "schemas"))
+	if err != nil {
+		return nil, err
+	}
+	return startTestBus(dir)
+}
+
+// setupTestSchemas 编译 display 和测试用的 xsettings 的 schema，使用内存后端
+func setupTestSchemas(dir string) error {
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return err
+	}
+	for _, filename := range []string{
+		"../misc/schemas/com.deepin.dde.display.gschema.xml",
+		"testdata/schemas/com.deepin.xsettings.gschema.xml",
+	} {
+		data, err := ioutil.ReadFile(filename)
+		if err != nil {
+			return err
+		}
+		err = ioutil.WriteFile(filepath.Join(dir, filepath.Base(filename)), data, 0644)
+		if err != nil {
+			return err
+		}
+	}
+	out, err := exec.Command("glib-compile-schemas", dir).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("glib-compile-schemas: %v, %s", err, out)
+	}
+	err = os.Setenv("GSETTINGS_SCHEMA_DIR", dir)
+	if err != nil {
+		return err
+	}
+	return os.Setenv("GSETTINGS_BACKEND", "memory")
+}
+
+const testBusConfig = `<!DOCTYPE busconfig PUBLIC "-//freedesktop//DTD D-Bus Bus Configuration 1.0//EN"
+ "http://www.freedesktop.org/standards/dbus/1.0/busconfig.dtd">
+<busconfig>
+  <type>session</type>
+  <listen>unix:path=%s</listen>
+  <policy context="default">
+    <allow send_destination="*" eavesdrop="true"/>
+    <allow eavesdrop="true"/>
+    <allow own="*"/>
+  </policy>
+</busconfig>
+`
+
+// startTestBus 启动私有的 dbus-daemon，同时作为会话和系统总线，并在上面提供系统级配置的服务
+func startTestBus(dir string) (cleanup func(), err error) {
+	socketFile := filepath.Join(dir, "bus")
+	configFile := filepath.Join(dir, "bus.conf")
+	err = ioutil.WriteFile(configFile, []byte(fmt.Sprintf(testBusConfig, socketFile)), 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := exec.Command("dbus-daemon", "--config-file="+configFile, "--nofork", "--print-address")
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	err = cmd.Start()
+	if err != nil {
+		return nil, err
+	}
+	cleanup = func() {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+	}
+
+	address, err := bufio.NewReader(stdout).ReadString('\n')
+	if err != nil {
+		return cleanup, fmt.Errorf("failed to read the bus address: %v", err)
+	}
+	address = strings.TrimSpace(address)
+	err = os.Setenv("DBUS_SESSION_BUS_ADDRESS", address)
+	if err != nil {
+		return cleanup, err
+	}
+	err = setTestSystemBusAddress(address, socketFile)
+	if err != nil {
+		return cleanup, err
+	}
+
+	err = exportTestSysDisplay(address)
+	return cleanup, err
+}
+
+// setTestSystemBusAddress 一些版本的 godbus 把 DBUS_SYSTEM_BUS_ADDRESS 当作 socket 文件的路径，
+// 使用能连接上的那一种
+func setTestSystemBusAddress(address, socketFile string) error {
+	var err error
+	for _, value := range []string{address, socketFile} {
+		err = os.Setenv("DBUS_SYSTEM_BUS_ADDRESS", value)
+		if err != nil {
+			return err
+		}
+		var conn *dbus.Conn
+		conn, err = dbus.SystemBusPrivate()
+		if err == nil {
+			conn.Close()
+			return nil
+		}
+	}
+	return err
+}
+
+func exportTestSysDisplay(address string) error {
+	conn, err := dbus.Dial(address)
+	if err != nil {
+		return err
+	}
+	err = conn.Auth(nil)
+	if err == nil {
+		err = conn.Hello()
+	}
+	if err == nil {
+		err = conn.Export(_testSysDisplay, testSysDisplayPath, testSysDisplayInterface)
+	}
+	if err != nil {
+		conn.Close()
+		return err
+	}
+
+	reply, err := conn.RequestName(testSysDisplayService, dbus.NameFlagDoNotQueue)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	if reply != dbus.RequestNameReplyPrimaryOwner {
+		conn.Close()
+		return errors.New("name " + testSysDisplayService + " already taken")
+	}
+	return nil
+}
@@ -131,9 +131,10 @@ type Manager struct {
 	sysSigLoop     *dbusutil.SignalLoop
 	sessionSigLoop *dbusutil.SignalLoop
 	// 系统级 dbus-daemon 服务
-	dbusDaemon   ofdbus.DBus
-	sensorProxy  dbus.BusObject
-	inputDevices inputdevices.InputDevices
+	dbusDaemon ofdbus.DBus
+	// 重力传感器，没有内置显示器时为 nil
+	rotationSensor rotationSensor
+	inputDevices   inputdevices.InputDevices
 	// 系统级 display 服务
 	sysDisplay sysdisplay.Display
 	xConn      *x.Conn
@@ -147,11 +148,13 @@ type Manager struct {
 	builtinMonitorMu         sync.Mutex
 	candidateBuiltinMonitors []*Monitor // 候补的
 
-	monitorMap     map[uint32]*Monitor
-	monitorMapMu   sync.Mutex
-	mm             monitorManager
-	debugOpts      debugOptions
-	redshiftRunner *redshiftRunner
+	monitorMap   map[uint32]*Monitor
+	monitorMapMu sync.Mutex
+	mm           monitorManager
+	debugOpts    debugOptions
+	nightLight   *nightLightEngine
+	// 根据环境光自动调节内置显示器的亮度
+	autoBrightness *autoBrightnessEngine
 
 	sessionActive bool
 	newSysCfg     *SysRootConfig
@@ -165,6 +168,7 @@ type Manager struct {
 	hasBuiltinMonitor        bool
 	rotateScreenTimeDelay    int32
 	setFillModeMu            sync.Mutex
+	setOutputColorMu         sync.Mutex
 	delayApplyTimer          *time.Timer
 	delayApplyOptions        applyOptions
 	prevCurrentNumMonitors   int
@@ -175,6 +179,27 @@ type Manager struct {
 	applySaveMu              sync.Mutex
 	inApply                  bool
 	futureConfig             monitorsFutureConfig
+	// 显示器连接或断开时满足启用条件的命名配置，在 delayApplyConfig 中应用
+	pendingProfile string
+	// 应用或保存 CurrentCustomId 时连接的显示器，显示器改变后清除 CurrentCustomId
+	currentProfileMonitorsId monitorsId
+	// 由 ApplyChangesWithTimeout 应用、等待确认的改变
+	confirm   *changesConfirm
+	confirmMu sync.Mutex
+	// 根窗口上设置的 _ICC_PROFILE 属性的数量
+	iccAtomsNum int
+	iccAtomsMu  sync.Mutex
+	// 自动旋转到的方向，为 0 表示没有自动旋转
+	autoRotation   uint16
+	autoRotationMu sync.Mutex
+
+	//nolint
+	signals *struct {
+		ConfirmCountdown struct {
+			secondsLeft uint32
+		}
+		ChangesReverted struct{}
+	}
 
 	// dbusutil-gen: equal=objPathsEqual
 	Monitors []dbus.ObjectPath
@@ -184,6 +209,8 @@ type Manager struct {
 	DisplayMode  byte
 	// dbusutil-gen: equal=nil
 	Brightness map[string]float64
+	// 是否根据环境光自动调节亮度
+	AutoBrightness bool
 
 	// dbusutil-gen: equal=nil
 	Touchscreens dxTouchscreens
@@ -204,6 +231,19 @@ type Manager struct {
 	ScreenWidth            uint16
 	ScreenHeight           uint16
 	MaxBacklightBrightness uint32
+	// 复制模式的源显示器，为空时使用主屏
+	MirrorSource string
+	// 复制模式下分辨率不同时的缩放方式
+	MirrorScaling string
+	// 自动调节色温的时间表，sun 或 custom
+	NightLightSchedule string
+	// 自定义时间表的开始和结束时间
+	NightLightFrom string
+	NightLightTo   string
+	// 锁定时不根据重力传感器自动旋转
+	RotationLocked bool
+	// 合上笔记本盖子时的处理方式，none 或 external-only
+	LidClosePolicy string
 
 	// method of adjust color temperature according to time and location
 	ColorTemperatureMode int32
@@ -227,24 +267,54 @@ type monitorSizeInfo struct {
 
 var _ monitorManagerHooks = (*Manager)(nil)
 
+// managerOptions 创建 Manager 时替换掉从系统获取的部分，用于虚拟显示器和单元测试
+type managerOptions struct {
+	// 为空时根据显示服务器创建
+	mm monitorManager
+	// 机箱类型，为空时从系统获取
+	chassis string
+}
+
 func newManager(service *dbusutil.Service) *Manager {
+	var opts managerOptions
+	// 使用虚拟显示器，用于测试和没有真实输出设备的远程会话
+	if filename := os.Getenv("DISPLAY_HEADLESS_SCENARIO"); filename != "" {
+		mm, err := newHeadlessMonitorManagerFromFile(filename)
+		if err != nil {
+			logger.Warning(err)
+		} else {
+			opts.mm = mm
+			opts.chassis = mm.chassis
+			// 虚拟显示器支持每个输出设备单独设置，按照 randr 1.2 处理
+			_hasRandr1d2 = true
+		}
+	}
+	return newManagerWithOptions(service, opts)
+}
+
+func newManagerWithOptions(service *dbusutil.Service, opts managerOptions) *Manager {
 	m := &Manager{
 		service:        service,
 		monitorMap:     make(map[uint32]*Monitor),
 		Brightness:     make(map[string]float64),
-		redshiftRunner: newRedshiftRunner(),
+		nightLight:     newNightLightEngine(),
+		autoBrightness: newAutoBrightnessEngine(),
 		unsupportGammaDrmList: []string{
 			"Loongson",
 		},
 	}
 	m.initPrimary = false
-	m.redshiftRunner.cb = func(value int) {
+	m.nightLight.cb = func(value int) {
 		m.setColorTempOneShot()
 	}
 
-	chassis, err := getComputeChassis()
-	if err != nil {
-		logger.Warning(err)
+	var err error
+	chassis := opts.chassis
+	if chassis == "" {
+		chassis, err = getComputeChassis()
+		if err != nil {
+			logger.Warning(err)
+		}
 	}
 	if chassis == "laptop" || chassis == "all-in-one" {
 		m.hasBuiltinMonitor = true
@@ -260,10 +330,13 @@ func newManager(service *dbusutil.Service) *Manager {
 
 	m.xConn = _xConn
 
-	screen := m.xConn.GetDefaultScreen()
-	if screen != nil {
-		m.ScreenWidth = screen.WidthInPixels
-		m.ScreenHeight = screen.HeightInPixels
+	// 单元测试中没有 X 连接
+	if m.xConn != nil {
+		screen := m.xConn.GetDefaultScreen()
+		if screen != nil {
+			m.ScreenWidth = screen.WidthInPixels
+			m.ScreenHeight = screen.HeightInPixels
+		}
 	}
 	sessionSigLoop := dbusutil.NewSignalLoop(m.service.Conn(), 10)
 	m.sessionSigLoop = sessionSigLoop
@@ -274,11 +347,16 @@ func newManager(service *dbusutil.Service) *Manager {
 		logger.Warning(err)
 	}
 
+	m.mm = opts.mm
 	if _useWayland {
-		m.mm = newKMonitorManager(sessionSigLoop)
+		if m.mm == nil {
+			m.mm = newKMonitorManager(sessionSigLoop)
+		}
 		m.tm = newWaylandTouchscreenManager(service.Conn(), m.sysBus)
 	} else {
-		m.mm = newXMonitorManager(m.xConn, _hasRandr1d2)
+		if m.mm == nil {
+			m.mm = newXMonitorManager(m.xConn, _hasRandr1d2)
+		}
 		m.tm = newXTouchscreenManager(m.sysBus)
 	}
 
@@ -319,6 +397,17 @@ func newManager(service *dbusutil.Service) *Manager {
 		logger.Warning("failed to connect signal PrepareForSleep:", err)
 	}
 
+	err = loginManager.LidClosed().ConnectChanged(func(hasValue bool, closed bool) {
+		if !hasValue {
+			return
+		}
+		logger.Debug("lid closed changed", closed)
+		go m.handleLidClosedChanged(closed)
+	})
+	if err != nil {
+		logger.Warning("failed to connect LidClosed changed:", err)
+	}
+
 	selfSessionPath, err := loginManager.GetSessionByPID(0, uint32(os.Getpid()))
 	if err != nil {
 		logger.Warningf("get session path failed: %v", err)
@@ -352,7 +441,7 @@ func newManager(service *dbusutil.Service) *Manager {
 
 		// 监听用户的session Active属性改变信号，当切换到当前已经登录的用户时
 		// 需要从内核重新获取当前屏幕的状态，将锁屏界面旋转到对应方向
-		if m.builtinMonitor != nil {
+		if m.getBuiltinMonitor() != nil {
 			m.initScreenRotation()
 		}
 	})
@@ -735,6 +824,7 @@ func (m *Manager) updateMonitorsId(options applyOptions) (changed bool) {
 	newMonitorsId := getConnectedMonitors(monitorMap).getMonitorsId()
 	if newMonitorsId != oldMonitorsId && (newMonitorsId.v1 != "" || _useWayland) {
 		m.monitorsId = newMonitorsId
+		m.cancelConfirm()
 		logger.Debugf("monitors id changed, old monitors id: %v, new monitors id: %v", oldMonitorsId.v1, newMonitorsId.v1)
 		m.markClean()
 
@@ -780,18 +870,33 @@ func (m *Manager) delayApplyConfig() {
 		m.delaySwitchMode = nil
 		m.PropsMu.Unlock()
 
+	} else if profile := m.takePendingProfile(); profile != "" {
+		logger.Debugf("delay call applyProfile, monitorsId: %v, profile: %v", monitorsId, profile)
+		err := m.applyProfile(profile)
+		if err != nil {
+			logger.Warning("apply profile failed:", err)
+			m.applySaveMu.Lock()
+			m.applyConfig(true, options)
+			m.applySaveMu.Unlock()
+			m.resetCurrentProfile(monitorsId)
+		}
+		paths = monitors.getPaths()
+
 	} else {
 		logger.Debugf("delay call applyConfig, monitorsId: %v, options: %v", monitorsId, options)
 
 		m.applySaveMu.Lock()
 		paths = m.applyConfig(true, options)
 		m.applySaveMu.Unlock()
+		m.resetCurrentProfile(monitorsId)
 	}
 
 	logger.Debug("update prop Monitors:", paths)
 	m.PropsMu.Lock()
 	m.setPropMonitors(paths)
 	m.PropsMu.Unlock()
+
+	m.checkLidState()
 }
 
 func (m *Manager) getDelayApplyOptions() applyOptions {
@@ -856,7 +961,7 @@ func (m *Manager) migrateOldConfig() {
 	m.gsColorTemperatureMode = m.settings.GetInt(gsKeyColorTemperatureMode)
 	m.gsColorTemperatureManual = m.settings.GetInt(gsKeyColorTemperatureManual)
 	m.initBrightness()
-	configV6, err := loadOldConfig(m)
+	oldCfg, err := loadOldConfig(m)
 	if err != nil {
 		// 旧配置加载失败
 		if !os.IsNotExist(err) {
@@ -865,12 +970,12 @@ func (m *Manager) migrateOldConfig() {
 	} else {
 		// 旧配置加载成功
 		if logger.GetLogLevel() == log.LevelDebug {
-			logger.Debug("migrateOldConfig configV6:", spew.Sdump(configV6))
+			logger.Debug("migrateOldConfig configV1:", spew.Sdump(oldCfg))
 		}
-		sysCfg := configV6.toSysConfigV1()
+		sysCfg := oldCfg.Sys
 		sysCfg.DisplayMode = m.DisplayMode
 		m.sysConfig.Config = sysCfg
-		m.userConfig = configV6.toUserConfigV1()
+		m.userConfig = oldCfg.User
 		m.userConfig.fix()
 		if err := m.saveUserConfig(); err != nil {
 			logger.Warning(err)
@@ -950,6 +1055,7 @@ func (m *Manager) init() {
 
 		m.initBuiltinMonitor()
 		m.monitorsId = m.getMonitorsId()
+		m.currentProfileMonitorsId = m.monitorsId
 		m.updatePropMonitors()
 
 		pmi := m.mm.getPrimaryMonitor()
@@ -982,16 +1088,21 @@ func (m *Manager) init() {
 	}
 
 	m.DisplayMode = m.sysConfig.Config.DisplayMode
+	m.initMirrorProps()
 
 	err := m.loadUserConfig()
 	if err != nil {
 		logger.Warning("loadUserConfig err:", err)
 	}
+	m.initNightLight()
+	m.RotationLocked = m.userConfig.RotationLocked
+	m.LidClosePolicy = getLidClosePolicy(m.userConfig.LidClosePolicy)
 
 	// NOTE: m.listenXEvents 应该在 m.applyDisplayConfig 之前，否则会造成它里面的 m.apply 函数的等待超时。
 	m.listenXEvents()
 	// 此时不需要设置色温，在 StartPart2 中做。为性能考虑。
 	m.applyConfig(false, nil)
+	m.checkLidState()
 	if m.builtinMonitor != nil {
 		m.listenSettingsChanged() // 监听旋转屏幕延时值
 		m.initScreenRotation()    // 获取初始屏幕的状态（屏幕方向）
@@ -1000,6 +1111,7 @@ func (m *Manager) init() {
 		// 没有内建屏,不监听内核信号
 		logger.Info("built-in screen does not exist")
 	}
+	m.initAutoBrightness()
 
 	go func() {
 		// 每次设置过主屏后，都将此值同步到xsettings
@@ -1231,8 +1343,20 @@ func (m *Manager) addMonitor(monitorInfo *MonitorInfo) error {
 		uuidV0:             monitorInfo.UuidV0,
 		Manufacturer:       monitorInfo.Manufacturer,
 		Model:              monitorInfo.Model,
+		SerialNumber:       monitorInfo.SerialNumber,
+		ManufactureDate:    monitorInfo.ManufactureDate,
+		HDRCapable:         monitorInfo.HDRCapable,
+		VRRRange:           monitorInfo.VRRRange,
+		VrrCapable:         monitorInfo.VrrCapable,
+		VrrPolicy:          vrrPolicyNever,
 		AvailableFillModes: monitorInfo.AvailableFillModes,
+		BroadcastRGB:       monitorInfo.BroadcastRGB,
+		MaxBpc:             monitorInfo.MaxBpc,
+		MaxBpcRange:        monitorInfo.MaxBpcRange,
+		Colorspace:         monitorInfo.Colorspace,
 	}
+	monitor.AvailableBroadcastRGB = monitorInfo.AvailableBroadcastRGB
+	monitor.AvailableColorspaces = monitorInfo.AvailableColorspaces
 
 	monitor.Modes = m.filterModeInfos(monitorInfo.Modes, monitorInfo.PreferredMode)
 	monitor.BestMode = getBestMode(monitor.Modes, monitorInfo.PreferredMode)
@@ -1244,6 +1368,8 @@ func (m *Manager) addMonitor(monitorInfo *MonitorInfo) error {
 	monitor.Width = monitorInfo.Width
 	monitor.Height = monitorInfo.Height
 	monitor.Brightness = 1
+	monitor.Scale = 1
+	monitor.transform = identityTransform
 
 	monitor.Reflects = getReflects(monitorInfo.Rotations)
 	monitor.Rotations = getRotations(monitorInfo.Rotations)
@@ -1270,6 +1396,9 @@ func (m *Manager) addMonitor(monitorInfo *MonitorInfo) error {
 		logger.Warning("call SetWriteCallback err:", err)
 		return err
 	}
+	if monitorInfo.Connected {
+		m.updateMonitorDdcci(monitor, true)
+	}
 	// 如果屏幕添加，刷新一下ddcci的display列表
 	go brightness.ReflashDDCCIDisplay()
 	return nil
@@ -1294,6 +1423,7 @@ func (m *Manager) updateMonitor(monitorInfo *MonitorInfo) {
 
 	m.handleMonitorConnectedChanged(monitor, monitorInfo.Connected)
 	monitor.PropsMu.Lock()
+	realConnectedChanged := monitor.realConnected != monitorInfo.Connected
 
 	if monitor.uuid != monitorInfo.UUID {
 		logger.Debugf("%v uuid changed, old:%q, new %q", monitor, monitor.uuid, monitorInfo.UUID)
@@ -1304,6 +1434,20 @@ func (m *Manager) updateMonitor(monitorInfo *MonitorInfo) {
 	monitor.setPropAvailableFillModes(monitorInfo.AvailableFillModes)
 	monitor.setPropManufacturer(monitorInfo.Manufacturer)
 	monitor.setPropModel(monitorInfo.Model)
+	monitor.setPropSerialNumber(monitorInfo.SerialNumber)
+	monitor.setPropManufactureDate(monitorInfo.ManufactureDate)
+	monitor.setPropHDRCapable(monitorInfo.HDRCapable)
+	monitor.setPropVRRRange(monitorInfo.VRRRange)
+	monitor.setPropVrrCapable(monitorInfo.VrrCapable)
+	if !monitorInfo.VrrCapable {
+		monitor.setPropVrrPolicy(vrrPolicyNever)
+	}
+	monitor.setPropAvailableBroadcastRGB(monitorInfo.AvailableBroadcastRGB)
+	monitor.setPropBroadcastRGB(monitorInfo.BroadcastRGB)
+	monitor.setPropMaxBpcRange(monitorInfo.MaxBpcRange)
+	monitor.setPropMaxBpc(monitorInfo.MaxBpc)
+	monitor.setPropAvailableColorspaces(monitorInfo.AvailableColorspaces)
+	monitor.setPropColorspace(monitorInfo.Colorspace)
 	monitor.setPropModes(m.filterModeInfos(monitorInfo.Modes, monitorInfo.PreferredMode))
 	bestMode := getBestMode(monitor.Modes, monitorInfo.PreferredMode)
 	monitor.setPropBestMode(bestMode)
@@ -1332,6 +1476,9 @@ func (m *Manager) updateMonitor(monitorInfo *MonitorInfo) {
 	monitor.setPropRefreshRate(monitorInfo.CurrentMode.Rate)
 	monitor.PropsMu.Unlock()
 
+	if realConnectedChanged {
+		m.updateMonitorDdcci(monitor, monitorInfo.Connected)
+	}
 	m.updateScreenSize()
 }
 
@@ -1351,8 +1498,14 @@ func (m *Manager) handleMonitorConnectedChanged(monitor *Monitor, connected bool
 func (m *Manager) buildConfigForModeMirror(monitors Monitors) (monitorCfgs SysMonitorConfigs, err error) {
 	logger.Debug("switch mode mirror")
 	commonSizes := getMonitorsCommonSizes(monitors)
-	if len(commonSizes) == 0 {
-		err = errors.New("not found common size")
+	source, isSourceSet := m.getMirrorSource(monitors)
+	if len(commonSizes) == 0 || isSourceSet {
+		// 没有共同的分辨率或者指定了源显示器，以源显示器的分辨率为准，其他显示器通过 crtc 变换缩放
+		if source == nil {
+			err = errors.New("not found mirror source")
+			return
+		}
+		monitorCfgs = buildConfigForMirrorSource(monitors, source)
 		return
 	}
 	maxSize := getMaxAreaSize(commonSizes)
@@ -1506,9 +1659,19 @@ func (m *Manager) apply(monitorsId monitorsId, monitorMap map[uint32]*Monitor, o
 	m.setInApply(true)
 
 	// NOTE: 应该限制只有 Manager.apply 才能调用 mm.apply
+	scaleDisplayMode := displayMode
+	if scaleDisplayMode == DisplayModeInvalid {
+		m.PropsMu.RLock()
+		scaleDisplayMode = m.DisplayMode
+		m.PropsMu.RUnlock()
+	}
+	m.updateMonitorsScale(monitorMap, scaleDisplayMode)
 	m.applyMu.Lock()
 	err := m.mm.apply(monitorsId, monitorMap, prevScreenSize, options, m.sysConfig.Config.FillModes, primaryMonitorID, displayMode)
 	m.applyMu.Unlock()
+	if err == nil {
+		m.syncMonitorsScale(monitorMap)
+	}
 
 	m.setInApply(false)
 
@@ -1616,6 +1779,11 @@ func (m *Manager) buildConfigForModeExtend(monitors Monitors) (monitorCfgs SysMo
 	sortMonitorsByPrimaryAndId(monitors, primaryMonitor)
 	var xOffset int
 
+	m.sysConfig.mu.Lock()
+	factors := m.sysConfig.Config.ScaleFactors
+	m.sysConfig.mu.Unlock()
+	_, transforms := getTransformScales(factors, monitors, DisplayModeExtend)
+
 	for _, monitor := range monitors {
 		cfg := monitor.toBasicSysConfig()
 		cfg.Enabled = true
@@ -1636,7 +1804,9 @@ func (m *Manager) buildConfigForModeExtend(monitors Monitors) (monitorCfgs SysMo
 		cfg.Rotation = randr.RotationRotate0
 		//cfg.Reflect = 0
 		cfg.Brightness = 1
-		xOffset += int(cfg.Width)
+		// 显示器有变换时，占据的宽度与分辨率不同
+		width, _ := getScaledSize(cfg.Width, cfg.Height, transforms[monitor.ID])
+		xOffset += int(width)
 		monitorCfgs = append(monitorCfgs, cfg)
 	}
 	return
@@ -1850,6 +2020,8 @@ func (m *Manager) setDisplayMode(mode byte) {
 }
 
 func (m *Manager) save() (err error) {
+	// 保存即确认等待确认的改变
+	m.cancelConfirm()
 	if m.getInApply() {
 		logger.Debug("no save, in apply")
 		return nil
@@ -1946,7 +2118,13 @@ func (mfc *monitorsFutureConfig) getConfigs(monitorsId monitorsId) SysMonitorCon
 	return mfc.configs.clone()
 }
 
+// applyChanges 应用改变，之前等待确认的改变不再恢复
 func (m *Manager) applyChanges() error {
+	m.cancelConfirm()
+	return m.applyChangesNoConfirm()
+}
+
+func (m *Manager) applyChangesNoConfirm() error {
 	if m.getInApply() {
 		logger.Debug("no apply changes, in apply")
 		return nil
@@ -1965,7 +2143,10 @@ func (m *Manager) applyChanges() error {
 	monitorsId := monitors.getMonitorsId()
 
 	configs := m.getSuitableSysMonitorConfigs(m.DisplayMode, monitorsId, monitors)
-	for _, config := range configs {
+	// 自动旋转的方向只应用，不保存，savedConfigs 中保持配置中的方向
+	savedConfigs := configs.clone()
+	positionChanged := false
+	for i, config := range configs {
 		monitor := monitors.GetByUuidAndName(config.UUID, config.Name)
 		if monitor == nil {
 			monitor = monitors.GetByUuid(config.UUID)
@@ -1973,7 +2154,35 @@ func (m *Manager) applyChanges() error {
 		if monitor == nil {
 			continue
 		}
+		if _, ok := monitor.changes[monitorPropX]; ok {
+			positionChanged = true
+		}
+		rotation := config.Rotation
+		autoRotated := m.keepAutoRotation(config, monitor)
 		config.modify(monitor.changes)
+		savedConfig := *config
+		if autoRotated {
+			setConfigRotation(&savedConfig, rotation)
+		}
+		savedConfigs[i] = &savedConfig
+	}
+
+	if m.DisplayMode != DisplayModeMirror {
+		// 用户改变了位置时对齐相近的边缘，存在重叠或者空隙时不应用；
+		// 只改变了模式或者方向时，重新摆放显示器以去除尺寸变化产生的空隙和重叠
+		layoutFn := m.packConfigsLayout
+		if positionChanged {
+			layoutFn = m.normalizeConfigsLayout
+		}
+		err := layoutFn(configs, monitors)
+		if err != nil {
+			return fmt.Errorf("invalid layout: %v", err)
+		}
+		// 保存的配置中可能保持着自动旋转之前的方向，按其尺寸重新摆放
+		err = m.packConfigsLayout(savedConfigs, monitors)
+		if err != nil {
+			return fmt.Errorf("invalid layout: %v", err)
+		}
 	}
 
 	err := m.applySysMonitorConfigs(DisplayModeInvalid, monitorsId, monitorMap, configs, nil)
@@ -1981,7 +2190,7 @@ func (m *Manager) applyChanges() error {
 		logger.Warning("[applyChanges] apply sys monitor configs failed:", err)
 		m.futureConfig.clear()
 	} else {
-		m.futureConfig.setConfigs(monitorsId, configs)
+		m.futureConfig.setConfigs(monitorsId, savedConfigs)
 	}
 	return err
 }
@@ -2102,6 +2311,10 @@ func (m *Manager) applySysMonitorConfigs(mode byte, monitorsId monitorsId, monit
 		m.setPropDisplayMode(mode)
 	}
 
+	m.applyVrrPolicies(configs)
+	m.applyOutputColors(configs)
+	m.applyIccProfiles()
+
 	// 异步处理亮度设置
 	go func() {
 		for _, config := range configs {
@@ -2224,6 +2437,8 @@ func (m *Manager) updatePropMonitors() {
 	m.PropsMu.Lock()
 	m.setPropMonitors(paths)
 	m.PropsMu.Unlock()
+
+	m.checkLidState()
 }
 
 func (m *Manager) initTouchscreens() {
@@ -2370,13 +2585,18 @@ func (m *Manager) loadUserConfig() error {
 		}
 		return err
 	}
-	var cfg UserConfig
-	err = json.Unmarshal(content, &cfg)
+	version, err := getConfigDataVersion(content, userConfigVersion)
+	if err != nil {
+		return err
+	}
+	result, err := userConfigMigrations.loadOrLatest(m, version, content)
 	if err != nil {
 		return err
 	}
+	cfg := result.(*UserConfig)
 	cfg.fix()
-	m.userConfig = cfg
+	m.userConfig = *cfg
+	m.CustomIdList = cfg.getProfileNames()
 	return nil
 }
 
@@ -2446,13 +2666,17 @@ func (m *Manager) getSysConfig() (*SysRootConfig, error) {
 	if err != nil {
 		return nil, err
 	}
-	var rootCfg SysRootConfig
-	err = jsonUnmarshal(cfgJSON, &rootCfg)
+	version, err := getConfigDataVersion([]byte(cfgJSON), sysConfigVersion)
 	if err != nil {
 		return nil, err
 	}
+	result, err := sysConfigMigrations.loadOrLatest(m, version, []byte(cfgJSON))
+	if err != nil {
+		return nil, err
+	}
+	rootCfg := result.(*SysRootConfig)
 	rootCfg.fix()
-	return &rootCfg, nil
+	return rootCfg, nil
 }
 
 // saveSysConfig 保存系统级配置
@@ -2466,10 +2690,13 @@ func (m *Manager) saveSysConfig(reason string) error {
 
 type debugOptions struct {
 	printSaveCfgDetail bool
+	// 使用会话总线上的 SensorProxy 服务，用于模拟传感器测试
+	sensorProxyOnSessionBus bool
 }
 
 func (m *Manager) initDebugOptions() {
 	m.debugOpts.printSaveCfgDetail = os.Getenv("DISPLAY_PRINT_SAVE_CFG_DETAIL") == "1"
+	m.debugOpts.sensorProxyOnSessionBus = os.Getenv("DISPLAY_SENSOR_PROXY_BUS") == "session"
 }
 
 func (m *Manager) saveSysConfigNoLock(reason string) error {
@@ -2671,25 +2898,26 @@ func (m *Manager) handleTouchscreenChanged() {
 
 /* 根据从内核获取的屏幕的初始状态(屏幕的方向)，旋转桌面到对应的方向 */
 func (m *Manager) initScreenRotation() {
-	if m.sensorProxy == nil {
-		m.sensorProxy = m.sysBus.Object(sensorProxyInterface, sensorProxyPath)
+	if m.rotationSensor == nil {
+		m.rotationSensor = m.newRotationSensor()
+	}
+	// 锁定时不旋转，也不要重新申请传感器
+	m.PropsMu.RLock()
+	locked := m.RotationLocked
+	m.PropsMu.RUnlock()
+	if locked {
+		return
 	}
 
-	screenRatationStatus := "normal"
-	if m.sensorProxy != nil {
-		err := m.sensorProxy.Call(sensorProxyGetScreenStatus, 0).Store(&screenRatationStatus)
-		if err != nil {
-			logger.Warning("failed to get screen rotation status", err)
-			return
-		}
-
-		startBuildInScreenRotationMutex.Lock()
-		defer startBuildInScreenRotationMutex.Unlock()
-		rotationRotate, ok := rotationScreenValue[strings.TrimSpace(screenRatationStatus)]
-		if ok {
-			m.startBuildInScreenRotation(rotationRotate)
-		}
+	rotation, err := m.rotationSensor.getRotation()
+	if err != nil {
+		logger.Warning("failed to get screen rotation status", err)
+		return
 	}
+
+	startBuildInScreenRotationMutex.Lock()
+	defer startBuildInScreenRotationMutex.Unlock()
+	m.startBuildInScreenRotation(rotation)
 }
 
 // 检查当前连接的所有触控面板, 如果没有映射配置, 那么调用 OSD 弹窗.
@@ -2730,81 +2958,150 @@ func (m *Manager) getRateFilter() RateFilterMap {
 }
 
 func (m *Manager) listenRotateSignal() {
-	systemBus, err := dbus.SystemBus()
+	if m.rotationSensor == nil {
+		m.rotationSensor = m.newRotationSensor()
+	}
+
+	var rotationScreenTimer *time.Timer
+	var mu sync.Mutex
+	err := m.rotationSensor.listen(func(rotation uint16) {
+		mu.Lock()
+		defer mu.Unlock()
+		if rotationScreenTimer != nil {
+			rotationScreenTimer.Stop()
+		}
+		rotationScreenTimer = time.AfterFunc(time.Millisecond*time.Duration(m.rotateScreenTimeDelay), func() {
+			startBuildInScreenRotationMutex.Lock()
+			defer startBuildInScreenRotationMutex.Unlock()
+			m.startBuildInScreenRotation(rotation)
+		})
+	})
 	if err != nil {
-		logger.Fatal(err)
+		logger.Warning("failed to listen rotation sensor:", err)
+	}
+	m.PropsMu.RLock()
+	locked := m.RotationLocked
+	m.PropsMu.RUnlock()
+	if locked {
+		m.rotationSensor.release()
+	}
+}
+
+// startBuildInScreenRotation 把内置显示器旋转到传感器的方向，自动旋转的结果不保存到配置中。
+// 锁定旋转或者用户正在修改配置时只记录方向。
+func (m *Manager) startBuildInScreenRotation(latestRotationValue uint16) {
+	// 判断旋转信号值是否符合要求
+	if !isValidAutoRotation(latestRotationValue) {
+		logger.Warningf("get Rotation screen value failed: %d", latestRotationValue)
+		return
+	}
+
+	m.PropsMu.RLock()
+	locked := m.RotationLocked
+	hasChanged := m.HasChanged
+	m.PropsMu.RUnlock()
+	if locked || hasChanged {
+		logger.Debugf("skip auto rotation, locked: %v, has changed: %v", locked, hasChanged)
+		return
 	}
 
-	err = systemBus.BusObject().AddMatchSignal(sensorProxyInterface, sensorProxySignalName,
-		dbus.WithMatchObjectPath(sensorProxyPath), dbus.WithMatchSender(sensorProxyInterface)).Err
+	builtinMonitor := m.getBuiltinMonitor()
+	if builtinMonitor == nil {
+		return
+	}
+	builtinMonitor.PropsMu.RLock()
+	rotation := builtinMonitor.Rotation
+	builtinMonitor.PropsMu.RUnlock()
+	if rotation == latestRotationValue {
+		return
+	}
+
+	err := builtinMonitor.SetRotation(latestRotationValue)
 	if err != nil {
-		logger.Fatal(err)
+		logger.Warning("call SetRotation failed:", err)
+		return
 	}
 
-	signalCh := make(chan *dbus.Signal, 10)
-	m.sysBus.Signal(signalCh)
-	go func() {
-		var rotationScreenTimer *time.Timer
-		rotateScreenValue := "normal"
+	// 使旋转后配置生效
+	if err := m.applyChanges(); err != nil {
+		logger.Warning("call applyChanges failed:", err)
+		m.resetChangesWithoutApply()
+		return
+	}
+	m.setAutoRotation(latestRotationValue)
+	m.markClean()
 
-		for sig := range signalCh {
-			if sig.Path != sensorProxyPath || sig.Name != sensorProxySignal {
-				continue
-			}
+	builtinMonitor.setPropCurrentRotateMode(RotationFinishModeAuto)
+}
 
-			err = dbus.Store(sig.Body, &rotateScreenValue)
-			if err != nil {
-				logger.Warning("call dbus.Store err:", err)
-				continue
-			}
+func (m *Manager) setAutoRotation(rotation uint16) {
+	m.autoRotationMu.Lock()
+	m.autoRotation = rotation
+	m.autoRotationMu.Unlock()
+}
 
-			if rotationScreenTimer == nil {
-				rotationScreenTimer = time.AfterFunc(time.Millisecond*time.Duration(m.rotateScreenTimeDelay), func() {
-					startBuildInScreenRotationMutex.Lock()
-					defer startBuildInScreenRotationMutex.Unlock()
-					rotationRotate, ok := rotationScreenValue[strings.TrimSpace(rotateScreenValue)]
-					if ok {
-						m.startBuildInScreenRotation(rotationRotate)
-					}
-				})
-			} else {
-				rotationScreenTimer.Reset(time.Millisecond * time.Duration(m.rotateScreenTimeDelay))
-			}
-		}
-	}()
+func (m *Manager) getAutoRotation() uint16 {
+	m.autoRotationMu.Lock()
+	defer m.autoRotationMu.Unlock()
+	return m.autoRotation
 }
 
-func (m *Manager) startBuildInScreenRotation(latestRotationValue uint16) {
-	// 判断旋转信号值是否符合要求
-	if latestRotationValue != randr.RotationRotate0 &&
-		latestRotationValue != randr.RotationRotate90 &&
-		latestRotationValue != randr.RotationRotate270 {
-		logger.Warningf("get Rotation screen value failed: %d", latestRotationValue)
-		return
+// keepAutoRotation 应用其他改变时保持内置显示器自动旋转的方向，不恢复成配置中的方向。
+// 用户手动旋转了内置显示器时不再保持。返回是否修改了 config 的方向。
+func (m *Manager) keepAutoRotation(config *SysMonitorConfig, monitor *Monitor) bool {
+	builtinMonitor := m.getBuiltinMonitor()
+	if builtinMonitor == nil || builtinMonitor.ID != monitor.ID {
+		return false
+	}
+	if _, ok := monitor.changes[monitorPropRotation]; ok {
+		m.setAutoRotation(0)
+		return false
 	}
+	rotation := m.getAutoRotation()
+	if rotation == 0 || rotation == config.Rotation {
+		return false
+	}
+	setConfigRotation(config, rotation)
+	return true
+}
 
-	if m.builtinMonitor != nil {
-		err := m.builtinMonitor.SetRotation(latestRotationValue)
-		if err != nil {
-			logger.Warning("call SetRotation failed:", err)
-			return
-		}
+// setConfigRotation 修改 config 的方向，需要时交换宽和高
+func setConfigRotation(config *SysMonitorConfig, rotation uint16) {
+	if needSwapWidthHeight(rotation) != needSwapWidthHeight(config.Rotation) {
+		config.Width, config.Height = config.Height, config.Width
+	}
+	config.Rotation = rotation
+}
 
-		// 使旋转后配置生效
-		err = m.ApplyChanges()
-		if err != nil {
-			logger.Warning("call ApplyChanges failed:", err)
-			return
-		}
+// setRotationLocked 锁定或者解锁自动旋转，解锁时旋转到传感器当前的方向
+func (m *Manager) setRotationLocked(locked bool) error {
+	m.userCfgMu.Lock()
+	m.userConfig.RotationLocked = locked
+	err := m.saveUserConfigNoLock()
+	m.userCfgMu.Unlock()
 
-		err = m.Save()
-		if err != nil {
-			logger.Warning("call Save failed:", err)
-			return
-		}
+	m.PropsMu.Lock()
+	changed := m.setPropRotationLocked(locked)
+	m.PropsMu.Unlock()
+
+	if !changed || m.getBuiltinMonitor() == nil || m.rotationSensor == nil {
+		return err
+	}
+	if locked {
+		// 锁定后不再需要传感器，释放后 iio-sensor-proxy 可以关闭加速度计
+		m.rotationSensor.release()
+	} else {
+		go m.initScreenRotation()
+	}
+	return err
+}
 
-		m.builtinMonitor.setPropCurrentRotateMode(RotationFinishModeAuto)
+// destroy 释放重力传感器和环境光传感器
+func (m *Manager) destroy() {
+	if m.rotationSensor != nil {
+		m.rotationSensor.release()
 	}
+	m.autoBrightness.stop()
 }
 
 func (m *Manager) listenSettingsChanged() {
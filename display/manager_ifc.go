@@ -24,8 +24,51 @@ func (m *Manager) ApplyChanges() *dbus.Error {
 	return dbusutil.ToError(err)
 }
 
+// ApplyChangesWithTimeout 应用改变，seconds 秒内没有调用 ConfirmChanges 则恢复改变之前的配置，seconds 为 0 时使用默认值
+func (m *Manager) ApplyChangesWithTimeout(seconds uint32) *dbus.Error {
+	logger.Debug("dbus call ApplyChangesWithTimeout", seconds)
+	err := m.applyChangesWithTimeout(seconds)
+	return dbusutil.ToError(err)
+}
+
+// ConfirmChanges 确认 ApplyChangesWithTimeout 应用的改变，并保存
+func (m *Manager) ConfirmChanges() *dbus.Error {
+	logger.Debug("dbus call ConfirmChanges")
+	err := m.confirmChanges()
+	return dbusutil.ToError(err)
+}
+
+// ArrangeMonitors 根据 JSON 格式的 layoutSpec 摆放显示器，需要调用 ApplyChanges 应用
+func (m *Manager) ArrangeMonitors(layoutSpec string) *dbus.Error {
+	logger.Debug("dbus call ArrangeMonitors", layoutSpec)
+	err := m.arrangeMonitors(layoutSpec)
+	return dbusutil.ToError(err)
+}
+
+// ExportConfig 导出 JSON 格式的显示配置，包括所有屏幕配置、显示模式和色温
+func (m *Manager) ExportConfig() (string, *dbus.Error) {
+	logger.Debug("dbus call ExportConfig")
+	cfg, err := m.exportConfig()
+	return cfg, dbusutil.ToError(err)
+}
+
+// ImportConfig 导入 ExportConfig 导出的配置，monitors 是要导入的显示器名称或 uuid，
+// modes 是要导入的模式 mirror、extend、only-one 或 single，为空时导入全部。
+func (m *Manager) ImportConfig(config string, monitors, modes []string) *dbus.Error {
+	logger.Debug("dbus call ImportConfig", monitors, modes)
+	err := m.importConfig(config, monitors, modes)
+	return dbusutil.ToError(err)
+}
+
+func (m *Manager) SetMirrorSource(name, scaling string) *dbus.Error {
+	logger.Debug("dbus call SetMirrorSource", name, scaling)
+	err := m.setMirrorSource(name, scaling)
+	return dbusutil.ToError(err)
+}
+
 func (m *Manager) ResetChanges() *dbus.Error {
 	logger.Debug("dbus call ResetChanges")
+	m.cancelConfirm()
 	m.PropsMu.Lock()
 	if !m.HasChanged {
 		m.PropsMu.Unlock()
@@ -146,14 +189,44 @@ func (m *Manager) ListOutputsCommonModes() ([]ModeInfo, *dbus.Error) {
 	return result, nil
 }
 
-// ModifyConfigName 废弃方法
+// ModifyConfigName 重命名命名配置
 func (m *Manager) ModifyConfigName(name, newName string) *dbus.Error {
-	return dbusutil.ToError(errors.New("obsoleted method"))
+	logger.Debug("dbus call ModifyConfigName", name, newName)
+	err := m.renameProfile(name, newName)
+	return dbusutil.ToError(err)
 }
 
-// DeleteCustomMode 废弃方法
+// DeleteCustomMode 同 DeleteProfile
 func (m *Manager) DeleteCustomMode(name string) *dbus.Error {
-	return dbusutil.ToError(errors.New("obsoleted method"))
+	return m.DeleteProfile(name)
+}
+
+// SaveProfile 以当前的显示状态保存命名配置，rules 是 JSON 格式的 ProfileRules，为空时保留原有的启用条件。
+func (m *Manager) SaveProfile(name, rules string) *dbus.Error {
+	logger.Debug("dbus call SaveProfile", name, rules)
+	err := m.saveProfile(name, rules)
+	return dbusutil.ToError(err)
+}
+
+// ApplyProfile 应用命名配置
+func (m *Manager) ApplyProfile(name string) *dbus.Error {
+	logger.Debug("dbus call ApplyProfile", name)
+	err := m.applyProfile(name)
+	return dbusutil.ToError(err)
+}
+
+// ListProfiles 列出所有命名配置的名字
+func (m *Manager) ListProfiles() ([]string, *dbus.Error) {
+	m.userCfgMu.Lock()
+	defer m.userCfgMu.Unlock()
+	return m.userConfig.getProfileNames(), nil
+}
+
+// DeleteProfile 删除命名配置
+func (m *Manager) DeleteProfile(name string) *dbus.Error {
+	logger.Debug("dbus call DeleteProfile", name)
+	err := m.deleteProfile(name)
+	return dbusutil.ToError(err)
 }
 
 // RefreshBrightness 重置亮度，主要被 session/power 模块调用。从配置恢复亮度。
@@ -171,6 +244,7 @@ func (m *Manager) RefreshBrightness() *dbus.Error {
 		}
 	}
 	m.syncPropBrightness()
+	m.pauseAutoBrightness("", false)
 	return nil
 }
 
@@ -192,6 +266,8 @@ func (m *Manager) SetAndSaveBrightness(outputName string, value float64) *dbus.E
 		return dbusutil.ToError(err)
 	}
 
+	m.trainAutoBrightness(outputName, value)
+
 	err = m.saveBrightnessInCfg(map[string]float64{
 		outputName: value,
 	})
@@ -202,6 +278,14 @@ func (m *Manager) SetAndSaveBrightness(outputName string, value float64) *dbus.E
 	return nil
 }
 
+// SetAutoBrightness 打开或关闭根据环境光自动调节内置显示器的亮度，
+// 打开时用户手动调节亮度会训练光照和亮度的曲线。
+func (m *Manager) SetAutoBrightness(enabled bool) *dbus.Error {
+	logger.Debug("dbus call SetAutoBrightness", enabled)
+	err := m.setAutoBrightness(enabled)
+	return dbusutil.ToError(err)
+}
+
 // SetBrightness 设置亮度但是不保存, 主要被 session/power 模块调用。
 func (m *Manager) SetBrightness(outputName string, value float64) *dbus.Error {
 	logger.Debug("dbus call SetBrightness", outputName, value)
@@ -214,6 +298,8 @@ func (m *Manager) SetBrightness(outputName string, value float64) *dbus.Error {
 		return dbusutil.ToError(fmt.Errorf("the port %s cannot set brightness", outputName))
 	}
 
+	// 其他模块临时设置亮度时不自动调节，直到 RefreshBrightness 恢复
+	m.pauseAutoBrightness(outputName, true)
 	err := m.setBrightnessAndSync(outputName, value)
 	if err != nil {
 		logger.Warning(err)
@@ -228,6 +314,21 @@ func (m *Manager) SetPrimary(outputName string) *dbus.Error {
 	return dbusutil.ToError(err)
 }
 
+// SetLidClosePolicy 设置合上笔记本盖子时的处理方式，none 表示不处理，
+// external-only 表示连接了外接显示器时只使用外接显示器，打开盖子时恢复。
+func (m *Manager) SetLidClosePolicy(policy string) *dbus.Error {
+	logger.Debug("dbus call SetLidClosePolicy", policy)
+	err := m.setLidClosePolicy(policy)
+	return dbusutil.ToError(err)
+}
+
+// SetRotationLocked 锁定时不根据重力传感器自动旋转内置显示器
+func (m *Manager) SetRotationLocked(locked bool) *dbus.Error {
+	logger.Debug("dbus call SetRotationLocked", locked)
+	err := m.setRotationLocked(locked)
+	return dbusutil.ToError(err)
+}
+
 func (m *Manager) CanRotate() (bool, *dbus.Error) {
 	if os.Getenv("DEEPIN_DISPLAY_DISABLE_ROTATE") == "1" {
 		return false, nil
@@ -286,6 +387,28 @@ func (m *Manager) SetColorTemperature(value int32) *dbus.Error {
 	return dbusutil.ToError(err)
 }
 
+// SetNightLightSchedule 设置自动调节色温的时间表，schedule 是 sun 或 custom，
+// custom 时 from 和 to 是 HH:MM 格式的开始和结束时间
+func (m *Manager) SetNightLightSchedule(schedule, from, to string) *dbus.Error {
+	logger.Debug("dbus call SetNightLightSchedule", schedule, from, to)
+	err := m.setNightLightSchedule(schedule, from, to)
+	return dbusutil.ToError(err)
+}
+
+// SetNightLightLocation 手动设置用于计算日出日落的位置
+func (m *Manager) SetNightLightLocation(latitude, longitude float64) *dbus.Error {
+	logger.Debug("dbus call SetNightLightLocation", latitude, longitude)
+	err := m.setNightLightLocation(&NightLightLocation{Latitude: latitude, Longitude: longitude})
+	return dbusutil.ToError(err)
+}
+
+// ResetNightLightLocation 清除手动设置的位置，改为自动获取
+func (m *Manager) ResetNightLightLocation() *dbus.Error {
+	logger.Debug("dbus call ResetNightLightLocation")
+	err := m.setNightLightLocation(nil)
+	return dbusutil.ToError(err)
+}
+
 func (m *Manager) GetRealDisplayMode() (uint8, *dbus.Error) {
 	monitors := m.getConnectedMonitors()
 
@@ -0,0 +1,229 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package display
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/linuxdeepin/go-x11-client/ext/randr"
+)
+
+// 复制模式下源显示器与其他显示器分辨率不同时的缩放方式
+const (
+	// 保持宽高比，画面居中，不足的部分留黑边
+	mirrorScalingLetterbox = "letterbox"
+	// 拉伸画面填满显示器
+	mirrorScalingStretch = "stretch"
+)
+
+func isValidMirrorScaling(scaling string) bool {
+	return scaling == mirrorScalingLetterbox || scaling == mirrorScalingStretch
+}
+
+// getMirrorTransforms 计算把 srcWidth x srcHeight 的源画面缩放到尺寸为 sizes 的各个显示器上所需的变换。
+// 与源显示器尺寸相同的显示器不变换。letterbox 时按比例缩放，在超出源画面的方向上居中并留黑边，
+// 所有显示器在屏幕上占据的区域都与源画面相同，不会扩大屏幕。
+func getMirrorTransforms(srcWidth, srcHeight uint16, sizes []Size, scaling string) []crtcTransform {
+	transforms := make([]crtcTransform, len(sizes))
+	for i, size := range sizes {
+		transforms[i] = identityTransform
+		if srcWidth == 0 || srcHeight == 0 || size.width == 0 || size.height == 0 {
+			continue
+		}
+		if size.width == srcWidth && size.height == srcHeight {
+			continue
+		}
+
+		scaleX := float64(srcWidth) / float64(size.width)
+		scaleY := float64(srcHeight) / float64(size.height)
+		if scaling == mirrorScalingStretch {
+			transforms[i] = crtcTransform{
+				scaleX: roundTransformScale(scaleX),
+				scaleY: roundTransformScale(scaleY),
+			}
+			continue
+		}
+
+		scale := scaleX
+		if scaleY > scale {
+			scale = scaleY
+		}
+		transform := newScaleTransform(roundTransformScale(scale))
+		width, height := getScaledSize(size.width, size.height, transform)
+		transform.padWidth = subUint16(width, srcWidth)
+		transform.padHeight = subUint16(height, srcHeight)
+		transforms[i] = transform
+	}
+	return transforms
+}
+
+// getMirrorSource 获取复制模式的源显示器，没有设置或者源显示器未连接时使用主屏。
+func (m *Manager) getMirrorSource(monitors Monitors) (source *Monitor, isSet bool) {
+	m.sysConfig.mu.Lock()
+	uuid := m.sysConfig.Config.MirrorSource
+	m.sysConfig.mu.Unlock()
+
+	if uuid != "" {
+		for _, monitor := range monitors {
+			if monitor.uuid == uuid {
+				return monitor, true
+			}
+		}
+	}
+
+	m.PropsMu.RLock()
+	primary := m.Primary
+	m.PropsMu.RUnlock()
+	source = monitors.GetByName(primary)
+	if source == nil {
+		source = m.getDefaultPrimaryMonitor(monitors)
+	}
+	return source, false
+}
+
+func (m *Manager) getMirrorScaling() string {
+	m.sysConfig.mu.Lock()
+	scaling := m.sysConfig.Config.MirrorScaling
+	m.sysConfig.mu.Unlock()
+	if !isValidMirrorScaling(scaling) {
+		return mirrorScalingLetterbox
+	}
+	return scaling
+}
+
+func getRotatedModeSize(monitor *Monitor) Size {
+	width := monitor.CurrentMode.Width
+	height := monitor.CurrentMode.Height
+	swapWidthHeightWithRotation(monitor.Rotation, &width, &height)
+	return Size{width: width, height: height}
+}
+
+// updateMirrorTransforms 复制模式下根据源显示器计算显示器的变换，显示器的位置保持配置中的值。
+func (m *Manager) updateMirrorTransforms(monitors Monitors) map[uint32]crtcTransform {
+	transforms := make(map[uint32]crtcTransform, len(monitors))
+	source, _ := m.getMirrorSource(monitors)
+	if source == nil {
+		return transforms
+	}
+
+	srcSize := getRotatedModeSize(source)
+	sizes := make([]Size, len(monitors))
+	for i, monitor := range monitors {
+		sizes[i] = getRotatedModeSize(monitor)
+	}
+	result := getMirrorTransforms(srcSize.width, srcSize.height, sizes, m.getMirrorScaling())
+	for i, monitor := range monitors {
+		transforms[monitor.ID] = result[i]
+	}
+	return transforms
+}
+
+// buildConfigForMirrorSource 以源显示器的最佳分辨率构建复制模式的配置，
+// 其他显示器优先使用相同的分辨率，没有时使用最佳分辨率，在应用时通过 crtc 变换缩放源画面。
+func buildConfigForMirrorSource(monitors Monitors, source *Monitor) (monitorCfgs SysMonitorConfigs) {
+	srcMode := source.BestMode
+	for _, monitor := range monitors {
+		cfg := monitor.toBasicSysConfig()
+		cfg.Enabled = true
+		if monitor.ID == source.ID {
+			cfg.Primary = true
+		}
+		mode := getFirstModeBySize(monitor.Modes, srcMode.Width, srcMode.Height)
+		if mode.isZero() {
+			mode = monitor.BestMode
+		}
+		cfg.Width = mode.Width
+		cfg.Height = mode.Height
+		cfg.RefreshRate = mode.Rate
+		cfg.X = 0
+		cfg.Y = 0
+		cfg.Rotation = randr.RotationRotate0
+		cfg.Reflect = 0
+		cfg.Brightness = 1
+		monitorCfgs = append(monitorCfgs, cfg)
+	}
+	return
+}
+
+// setMirrorSource 设置复制模式的源显示器和缩放方式，name 为空时使用主屏，如果当前是复制模式则重新应用。
+func (m *Manager) setMirrorSource(name, scaling string) error {
+	if scaling == "" {
+		scaling = mirrorScalingLetterbox
+	}
+	if !isValidMirrorScaling(scaling) {
+		return fmt.Errorf("invalid mirror scaling %q", scaling)
+	}
+
+	monitorMap := m.cloneMonitorMap()
+	monitors := getConnectedMonitors(monitorMap)
+	var uuid string
+	if name != "" {
+		monitor := monitors.GetByName(name)
+		if monitor == nil {
+			return fmt.Errorf("monitor %q not found", name)
+		}
+		uuid = monitor.uuid
+	}
+
+	m.sysConfig.mu.Lock()
+	m.sysConfig.Config.MirrorSource = uuid
+	m.sysConfig.Config.MirrorScaling = scaling
+	err := m.saveSysConfigNoLock("mirror source changed")
+	m.sysConfig.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	m.PropsMu.Lock()
+	m.setPropMirrorSource(name)
+	m.setPropMirrorScaling(scaling)
+	displayMode := m.DisplayMode
+	m.PropsMu.Unlock()
+
+	if displayMode != DisplayModeMirror || len(monitors) < 2 {
+		return nil
+	}
+
+	source, _ := m.getMirrorSource(monitors)
+	if source == nil {
+		return errors.New("not found mirror source")
+	}
+	configs := buildConfigForMirrorSource(monitors, source)
+	monitorsId := monitors.getMonitorsId()
+
+	m.applySaveMu.Lock()
+	defer m.applySaveMu.Unlock()
+	err = m.applySysMonitorConfigs(DisplayModeMirror, monitorsId, monitorMap, configs, nil)
+	if err != nil {
+		return err
+	}
+	screenCfg := m.getSysScreenConfig(monitorsId)
+	screenCfg.setMonitorConfigs(DisplayModeMirror, "", configs)
+	m.setSysScreenConfig(monitorsId, screenCfg)
+	return m.saveSysConfig("mode mirror")
+}
+
+// initMirrorProps 根据系统配置初始化复制模式相关的属性
+func (m *Manager) initMirrorProps() {
+	m.sysConfig.mu.Lock()
+	uuid := m.sysConfig.Config.MirrorSource
+	m.sysConfig.mu.Unlock()
+
+	var name string
+	if uuid != "" {
+		for _, monitor := range m.getConnectedMonitors() {
+			if monitor.uuid == uuid {
+				name = monitor.Name
+				break
+			}
+		}
+	}
+
+	m.PropsMu.Lock()
+	m.MirrorSource = name
+	m.MirrorScaling = m.getMirrorScaling()
+	m.PropsMu.Unlock()
+}
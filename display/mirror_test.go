@@ -0,0 +1,97 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package display
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_getMirrorTransforms(t *testing.T) {
+	tests := []struct {
+		name    string
+		width   uint16
+		height  uint16
+		sizes   []Size
+		scaling string
+		want    []crtcTransform
+	}{
+		{
+			name:    "same size",
+			width:   1920,
+			height:  1080,
+			sizes:   []Size{{1920, 1080}, {1920, 1080}, {1920, 1080}},
+			scaling: mirrorScalingLetterbox,
+			want:    []crtcTransform{identityTransform, identityTransform, identityTransform},
+		},
+		{
+			name:    "letterbox 4:3 projector",
+			width:   1920,
+			height:  1080,
+			sizes:   []Size{{1920, 1080}, {1024, 768}},
+			scaling: mirrorScalingLetterbox,
+			// 1024x768 缩放 1.875 倍后为 1920x1440，上下各留 180 的黑边
+			want: []crtcTransform{
+				identityTransform,
+				{scaleX: 1.875, scaleY: 1.875, padHeight: 360},
+			},
+		},
+		{
+			name:    "letterbox different aspect ratios",
+			width:   1920,
+			height:  1200,
+			sizes:   []Size{{1920, 1200}, {1280, 720}, {1280, 1024}},
+			scaling: mirrorScalingLetterbox,
+			want: []crtcTransform{
+				identityTransform,
+				// 1280x720 缩放为 2134x1200
+				{scaleX: 1.667, scaleY: 1.667, padWidth: 214},
+				// 1280x1024 缩放为 1920x1536
+				{scaleX: 1.5, scaleY: 1.5, padHeight: 336},
+			},
+		},
+		{
+			name:    "stretch",
+			width:   1920,
+			height:  1080,
+			sizes:   []Size{{1920, 1080}, {1280, 1024}},
+			scaling: mirrorScalingStretch,
+			want: []crtcTransform{
+				identityTransform,
+				{scaleX: 1.5, scaleY: 1.055},
+			},
+		},
+		{
+			name:    "invalid source size",
+			sizes:   []Size{{1920, 1080}},
+			scaling: mirrorScalingLetterbox,
+			want:    []crtcTransform{identityTransform},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := getMirrorTransforms(tt.width, tt.height, tt.sizes, tt.scaling)
+			assert.Equal(t, tt.want, got)
+			// 所有显示器在屏幕上占据的区域与源画面相同
+			for i, size := range tt.sizes {
+				if tt.width == 0 {
+					continue
+				}
+				w, h := getScaledSize(size.width, size.height, got[i])
+				assert.InDelta(t, float64(tt.width), float64(w), 1)
+				assert.InDelta(t, float64(tt.height), float64(h), 1)
+			}
+		})
+	}
+}
+
+func Test_isValidMirrorScaling(t *testing.T) {
+	assert.True(t, isValidMirrorScaling(mirrorScalingLetterbox))
+	assert.True(t, isValidMirrorScaling(mirrorScalingStretch))
+	assert.False(t, isValidMirrorScaling(""))
+	assert.False(t, isValidMirrorScaling("zoom"))
+}
@@ -18,6 +18,8 @@ import (
 	"github.com/linuxdeepin/go-lib/strv"
 	x "github.com/linuxdeepin/go-x11-client"
 	"github.com/linuxdeepin/go-x11-client/ext/randr"
+	"github.com/linuxdeepin/startdde/display/ddcci"
+	"github.com/linuxdeepin/startdde/display/edid"
 )
 
 const (
@@ -45,6 +47,17 @@ type Monitor struct {
 	realConnected bool
 	Manufacturer  string
 	Model         string
+	// 以下几个属性从 EDID 中解析
+	SerialNumber    string
+	ManufactureDate string
+	HDRCapable      bool
+	// 可变刷新率的范围，[最小值, 最大值]，不支持时为空
+	// dbusutil-gen: equal=uint32SliceEqual
+	VRRRange []uint32
+	// 显卡驱动和显示器是否支持可变刷新率
+	VrrCapable bool
+	// 可变刷新率策略，never、always 或 fullscreen-only
+	VrrPolicy string
 	// dbusutil-gen: equal=uint16SliceEqual
 	Rotations []uint16
 	// dbusutil-gen: equal=uint16SliceEqual
@@ -67,6 +80,10 @@ type Monitor struct {
 	RefreshRate       float64
 	Brightness        float64
 	CurrentRotateMode uint8
+	// 缩放比例
+	Scale float64
+	// crtc 的变换，用于不同缩放比例的显示器混合使用和复制模式下分辨率不同的显示器
+	transform crtcTransform
 
 	oldRotation uint16
 
@@ -74,6 +91,27 @@ type Monitor struct {
 	CurrentFillMode string `prop:"access:rw"`
 	// dbusutil-gen: equal=method:Equal
 	AvailableFillModes strv.Strv
+	// RGB 范围，Automatic、Full 或 Limited 16:235，显卡驱动不支持时为空
+	BroadcastRGB string
+	// dbusutil-gen: equal=method:Equal
+	AvailableBroadcastRGB strv.Strv
+	// 每个颜色通道的最大位数，不支持时为 0
+	MaxBpc uint32
+	// max bpc 的范围，[最小值, 最大值]，不支持时为空
+	// dbusutil-gen: equal=uint32SliceEqual
+	MaxBpcRange []uint32
+	// 输出的颜色空间
+	Colorspace string
+	// dbusutil-gen: equal=method:Equal
+	AvailableColorspaces strv.Strv
+	// ICC 配置文件的路径，为空时没有设置
+	IccProfile string
+	// 可以通过 DDC/CI 控制的功能，比如 brightness、contrast、input-source
+	// dbusutil-gen: equal=method:Equal
+	DdcciControls strv.Strv
+	// 直接通过 /dev/i2c 访问的 DDC/CI 设备和能力，不支持时为 nil
+	ddcciDisplay *ddcci.Display
+	ddcciCaps    *ddcci.Capabilities
 
 	backup *MonitorBackup
 	// changes 记录 DBus 接口对显示器对象做的设置，也用 PropsMu 保护。
@@ -92,6 +130,7 @@ const (
 	monitorPropHeight      = "Height"
 	monitorPropRotation    = "Rotation"
 	monitorPropRefreshRate = "RefreshRate"
+	monitorPropVrrPolicy   = "VrrPolicy"
 )
 
 func (changes monitorChanges) clone() monitorChanges {
@@ -133,40 +172,56 @@ func (m *Monitor) clone() *Monitor {
 	defer m.PropsMu.RUnlock()
 
 	monitorCp := Monitor{
-		m:                  m.m,
-		service:            m.service,
-		uuid:               m.uuid,
-		edid:               m.edid,
-		uuidV0:             m.uuidV0,
-		ID:                 m.ID,
-		Name:               m.Name,
-		Connected:          m.Connected,
-		realConnected:      m.realConnected,
-		Manufacturer:       m.Manufacturer,
-		Model:              m.Model,
-		Rotations:          m.Rotations,
-		Reflects:           m.Reflects,
-		BestMode:           m.BestMode,
-		Modes:              m.Modes,
-		PreferredModes:     m.PreferredModes,
-		MmWidth:            m.MmWidth,
-		MmHeight:           m.MmHeight,
-		Enabled:            m.Enabled,
-		X:                  m.X,
-		Y:                  m.Y,
-		Width:              m.Width,
-		Height:             m.Height,
-		Rotation:           m.Rotation,
-		Reflect:            m.Reflect,
-		RefreshRate:        m.RefreshRate,
-		Brightness:         m.Brightness,
-		CurrentRotateMode:  m.CurrentRotateMode,
-		oldRotation:        m.oldRotation,
-		CurrentMode:        m.CurrentMode,
-		CurrentFillMode:    m.CurrentFillMode,
-		AvailableFillModes: m.AvailableFillModes,
-		backup:             nil,
-		changes:            m.changes.clone(),
+		m:                     m.m,
+		service:               m.service,
+		uuid:                  m.uuid,
+		edid:                  m.edid,
+		uuidV0:                m.uuidV0,
+		ID:                    m.ID,
+		Name:                  m.Name,
+		Connected:             m.Connected,
+		realConnected:         m.realConnected,
+		Manufacturer:          m.Manufacturer,
+		Model:                 m.Model,
+		SerialNumber:          m.SerialNumber,
+		ManufactureDate:       m.ManufactureDate,
+		HDRCapable:            m.HDRCapable,
+		VRRRange:              m.VRRRange,
+		VrrCapable:            m.VrrCapable,
+		VrrPolicy:             m.VrrPolicy,
+		Rotations:             m.Rotations,
+		Reflects:              m.Reflects,
+		BestMode:              m.BestMode,
+		Modes:                 m.Modes,
+		PreferredModes:        m.PreferredModes,
+		MmWidth:               m.MmWidth,
+		MmHeight:              m.MmHeight,
+		Enabled:               m.Enabled,
+		X:                     m.X,
+		Y:                     m.Y,
+		Width:                 m.Width,
+		Height:                m.Height,
+		Rotation:              m.Rotation,
+		Reflect:               m.Reflect,
+		RefreshRate:           m.RefreshRate,
+		Brightness:            m.Brightness,
+		CurrentRotateMode:     m.CurrentRotateMode,
+		Scale:                 m.Scale,
+		transform:             m.transform,
+		oldRotation:           m.oldRotation,
+		CurrentMode:           m.CurrentMode,
+		CurrentFillMode:       m.CurrentFillMode,
+		AvailableFillModes:    m.AvailableFillModes,
+		BroadcastRGB:          m.BroadcastRGB,
+		AvailableBroadcastRGB: m.AvailableBroadcastRGB,
+		MaxBpc:                m.MaxBpc,
+		MaxBpcRange:           m.MaxBpcRange,
+		Colorspace:            m.Colorspace,
+		AvailableColorspaces:  m.AvailableColorspaces,
+		IccProfile:            m.IccProfile,
+		DdcciControls:         m.DdcciControls,
+		backup:                nil,
+		changes:               m.changes.clone(),
 	}
 
 	return &monitorCp
@@ -183,6 +238,7 @@ type MonitorBackup struct {
 	Reflect    uint16
 	Rotation   uint16
 	Brightness float64
+	VrrPolicy  string
 }
 
 func (m *Monitor) markChanged() {
@@ -197,6 +253,7 @@ func (m *Monitor) markChanged() {
 			Reflect:    m.Reflect,
 			Rotation:   m.Rotation,
 			Brightness: m.Brightness,
+			VrrPolicy:  m.VrrPolicy,
 		}
 	}
 }
@@ -341,14 +398,23 @@ func (m *Monitor) SetPosition(X, y int16) *dbus.Error {
 		return nil
 	}
 
+	m.changePosition(X, y)
+	return nil
+}
+
+// changePosition 改变显示器的位置，需要应用改变才能生效
+func (m *Monitor) changePosition(x, y int16) {
+	// NOTE: 不用加锁
+	if m.X == x && m.Y == y {
+		return
+	}
 	m.markChanged()
-	m.setPropX(X)
+	m.setPropX(x)
 	m.setPropY(y)
 	m.mergeChanges(monitorChanges{
-		monitorPropX: X,
+		monitorPropX: x,
 		monitorPropY: y,
 	})
-	return nil
 }
 
 func (m *Monitor) SetReflect(value uint16) *dbus.Error {
@@ -384,6 +450,99 @@ func (m *Monitor) SetRotation(value uint16) *dbus.Error {
 	return nil
 }
 
+func (m *Monitor) SetVrrPolicy(policy string) *dbus.Error {
+	m.PropsMu.Lock()
+	defer m.PropsMu.Unlock()
+
+	logger.Debugf("monitor %v %v dbus call SetVrrPolicy %v", m.ID, m.Name, policy)
+	if !isValidVrrPolicy(policy) {
+		return dbusutil.ToError(fmt.Errorf("invalid vrr policy %q", policy))
+	}
+	if !m.VrrCapable && policy != vrrPolicyNever {
+		return dbusutil.ToError(errors.New("monitor is not vrr capable"))
+	}
+	if policy != vrrPolicyNever && !m.m.mm.supportVrrPolicy() {
+		return dbusutil.ToError(errVrrUnsupported)
+	}
+	if m.VrrPolicy == policy {
+		return nil
+	}
+	m.markChanged()
+	m.setPropVrrPolicy(policy)
+	m.mergeChanges(monitorChanges{
+		monitorPropVrrPolicy: policy,
+	})
+	return nil
+}
+
+func (m *Monitor) SetBroadcastRGB(value string) *dbus.Error {
+	logger.Debugf("monitor %v %v dbus call SetBroadcastRGB %v", m.ID, m.Name, value)
+	if value == "" {
+		return dbusutil.ToError(errors.New("empty broadcast rgb"))
+	}
+	err := m.m.setMonitorOutputColor(m, SysOutputColorConfig{BroadcastRGB: value})
+	return dbusutil.ToError(err)
+}
+
+func (m *Monitor) SetMaxBpc(value uint32) *dbus.Error {
+	logger.Debugf("monitor %v %v dbus call SetMaxBpc %v", m.ID, m.Name, value)
+	if value == 0 {
+		return dbusutil.ToError(errors.New("invalid max bpc 0"))
+	}
+	err := m.m.setMonitorOutputColor(m, SysOutputColorConfig{MaxBpc: value})
+	return dbusutil.ToError(err)
+}
+
+func (m *Monitor) SetColorspace(value string) *dbus.Error {
+	logger.Debugf("monitor %v %v dbus call SetColorspace %v", m.ID, m.Name, value)
+	if value == "" {
+		return dbusutil.ToError(errors.New("empty colorspace"))
+	}
+	err := m.m.setMonitorOutputColor(m, SysOutputColorConfig{Colorspace: value})
+	return dbusutil.ToError(err)
+}
+
+// SetIccProfile 设置显示器的 ICC 配置文件，filename 为空时清除
+func (m *Monitor) SetIccProfile(filename string) *dbus.Error {
+	logger.Debugf("monitor %v %v dbus call SetIccProfile %v", m.ID, m.Name, filename)
+	err := m.m.setMonitorIccProfile(m, filename)
+	return dbusutil.ToError(err)
+}
+
+func (m *Monitor) GetIccProfile() (string, *dbus.Error) {
+	m.PropsMu.RLock()
+	defer m.PropsMu.RUnlock()
+	return m.IccProfile, nil
+}
+
+// ListIccProfiles 列出可以给显示器使用的 ICC 配置文件
+func (m *Monitor) ListIccProfiles() ([]string, *dbus.Error) {
+	return listIccProfiles(getIccProfileDirs()), nil
+}
+
+// GetDdcciControl 通过 DDC/CI 获取功能 name 的当前值和最大值，name 是 DdcciControls 中的值
+func (m *Monitor) GetDdcciControl(name string) (value, max uint32, busErr *dbus.Error) {
+	logger.Debugf("monitor %v %v dbus call GetDdcciControl %v", m.ID, m.Name, name)
+	current, maxValue, err := m.getDdcciControlValue(name)
+	if err != nil {
+		return 0, 0, dbusutil.ToError(err)
+	}
+	return uint32(current), uint32(maxValue), nil
+}
+
+// SetDdcciControl 通过 DDC/CI 设置功能 name 的值
+func (m *Monitor) SetDdcciControl(name string, value uint32) *dbus.Error {
+	logger.Debugf("monitor %v %v dbus call SetDdcciControl %v %v", m.ID, m.Name, name, value)
+	err := m.setDdcciControlValue(name, value)
+	return dbusutil.ToError(err)
+}
+
+// GetDdcciControlValues 获取功能 name 可以设置的值，比如 input-source 的输入源，显示器没有列出时为空
+func (m *Monitor) GetDdcciControlValues(name string) ([]uint32, *dbus.Error) {
+	values, err := m.getDdcciControlValues(name)
+	return values, dbusutil.ToError(err)
+}
+
 func (m *Monitor) setRotation(value uint16) {
 	width := m.CurrentMode.Width
 	height := m.CurrentMode.Height
@@ -405,6 +564,7 @@ func (m *Monitor) resetChanges() {
 	m.PropsMu.Lock()
 	defer m.PropsMu.Unlock()
 
+	m.changes = nil
 	if m.backup == nil {
 		return
 	}
@@ -422,6 +582,7 @@ func (m *Monitor) resetChanges() {
 	m.setPropHeight(b.Mode.Height)
 	m.setPropRefreshRate(b.Mode.Rate)
 	m.setPropBrightness(b.Brightness)
+	m.setPropVrrPolicy(b.VrrPolicy)
 
 	m.backup = nil
 }
@@ -457,8 +618,9 @@ func toSysMonitorConfigs(monitors []*Monitor, primary string) SysMonitorConfigs
 
 func (m *Monitor) toBasicSysConfig() *SysMonitorConfig {
 	return &SysMonitorConfig{
-		UUID: m.uuid,
-		Name: m.Name,
+		UUID:      m.uuid,
+		Name:      m.Name,
+		VrrPolicy: m.VrrPolicy,
 	}
 }
 
@@ -475,6 +637,7 @@ func (m *Monitor) toSysConfig() *SysMonitorConfig {
 		Reflect:     m.Reflect,
 		RefreshRate: m.RefreshRate,
 		Brightness:  m.Brightness,
+		VrrPolicy:   m.VrrPolicy,
 	}
 }
 
@@ -607,8 +770,38 @@ type MonitorInfo struct {
 	EDID               []byte
 	Manufacturer       string
 	Model              string
+	SerialNumber       string
+	ManufactureDate    string
+	HDRCapable         bool
+	VRRRange           []uint32
+	VrrCapable         bool
 	CurrentFillMode    string
 	AvailableFillModes []string
+	// 颜色输出相关的 output 属性，驱动不支持时为空
+	BroadcastRGB          string
+	AvailableBroadcastRGB []string
+	MaxBpc                uint32
+	MaxBpcRange           []uint32
+	Colorspace            string
+	AvailableColorspaces  []string
+}
+
+// parseEdidInfo 从 EDID 中解析序列号、生产日期、HDR 和 VRR 信息
+func (m *MonitorInfo) parseEdidInfo() {
+	if len(m.EDID) == 0 {
+		return
+	}
+	info, err := edid.Parse(m.EDID)
+	if err != nil {
+		logger.Debugf("parse monitor %v edid failed: %v", m.Name, err)
+		return
+	}
+	m.SerialNumber = info.Serial()
+	m.ManufactureDate = info.ManufactureDate()
+	m.HDRCapable = info.HDRCapable()
+	if vrrMin, vrrMax, ok := info.VRRRange(); ok {
+		m.VRRRange = []uint32{vrrMin, vrrMax}
+	}
 }
 
 func (m *MonitorInfo) dumpForDebug() {
@@ -0,0 +1,532 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package display
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/godbus/dbus"
+	geoclue2 "github.com/linuxdeepin/go-dbus-factory/org.freedesktop.geoclue2"
+	"github.com/linuxdeepin/go-lib/dbusutil"
+)
+
+// 自动调节色温时白天和夜晚的色温
+const (
+	nightLightDayTemperature   = 6500
+	nightLightNightTemperature = 3500
+)
+
+// 自动调节色温的时间表
+const (
+	// 根据日出日落调节
+	nightLightScheduleSun = "sun"
+	// 在固定的时间段内调节
+	nightLightScheduleCustom = "custom"
+)
+
+const (
+	// 自定义时间表开始和结束时的过渡时间
+	nightLightCustomTransition = 30 * time.Minute
+	// 色温变化比较大时，比如开始调节时，用渐变过渡的时间和步数
+	nightLightFadeDuration   = 2 * time.Second
+	nightLightFadeSteps      = 20
+	nightLightFadeThreshold  = 200
+	nightLightUpdateInterval = time.Minute
+)
+
+// NightLightConfig 自动调节色温的配置，保存在用户配置中
+type NightLightConfig struct {
+	// sun 或 custom，为空时是 sun
+	Schedule string `json:",omitempty"`
+	// 自定义时间表的开始和结束时间，格式是 HH:MM
+	From string `json:",omitempty"`
+	To   string `json:",omitempty"`
+	// 手动设置的位置，为空时通过 GeoClue 获取，获取不到时根据时区估计
+	Location *NightLightLocation `json:",omitempty"`
+}
+
+type NightLightLocation struct {
+	Latitude  float64
+	Longitude float64
+}
+
+func (c *NightLightConfig) clone() *NightLightConfig {
+	if c == nil {
+		return nil
+	}
+	cfg := *c
+	if c.Location != nil {
+		location := *c.Location
+		cfg.Location = &location
+	}
+	return &cfg
+}
+
+func (c *NightLightConfig) getSchedule() string {
+	if c == nil || c.Schedule == "" {
+		return nightLightScheduleSun
+	}
+	return c.Schedule
+}
+
+func isValidNightLightLocation(latitude, longitude float64) bool {
+	return latitude >= -90 && latitude <= 90 && longitude >= -180 && longitude <= 180
+}
+
+// parseClockTime 解析 HH:MM 格式的时间，返回从 0 点开始的分钟数
+func parseClockTime(s string) (int, error) {
+	var hour, minute int
+	_, err := fmt.Sscanf(s, "%d:%d", &hour, &minute)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q", s)
+	}
+	if hour < 0 || hour > 23 || minute < 0 || minute > 59 {
+		return 0, fmt.Errorf("invalid time %q", s)
+	}
+	return hour*60 + minute, nil
+}
+
+// scheduleNightRatio 计算自定义时间表中 t 时刻夜晚的程度，0 表示白天，1 表示夜晚。
+// 在 from 之后和 to 之前各有 transition 的过渡时间，from 和 to 可以跨过 0 点。
+func scheduleNightRatio(t time.Time, from, to int, transition time.Duration) float64 {
+	const minutesPerDay = 24 * 60
+	length := float64((to - from + minutesPerDay) % minutesPerDay)
+	if length == 0 {
+		return 0
+	}
+	now := float64(t.Hour()*60+t.Minute()) + float64(t.Second())/60
+	elapsed := math.Mod(now-float64(from)+minutesPerDay, minutesPerDay)
+	if elapsed >= length {
+		return 0
+	}
+	trans := transition.Minutes()
+	if trans <= 0 {
+		return 1
+	}
+	ratio := math.Min(elapsed/trans, (length-elapsed)/trans)
+	return math.Min(ratio, 1)
+}
+
+// getNightLightTemperature 根据夜晚的程度计算色温
+func getNightLightTemperature(nightRatio float64) int {
+	temp := nightLightDayTemperature - nightRatio*(nightLightDayTemperature-nightLightNightTemperature)
+	return int(math.Round(temp))
+}
+
+// nightLightEngine 在进程内根据太阳高度角或者自定义时间表自动调节色温，
+// 计算出的色温通过 cb 设置到 gamma 上。
+type nightLightEngine struct {
+	mu       sync.Mutex
+	running  bool
+	stopCh   chan struct{}
+	updateCh chan struct{}
+	value    int
+	cb       func(value int)
+	cfg      *NightLightConfig
+	// 通过 GeoClue 获取到的位置
+	geoLocation *NightLightLocation
+
+	sysService *dbusutil.Service
+	// 保护下面 GeoClue 相关的字段，启动 GeoClue 客户端时有阻塞的 dbus 调用，不能持有 mu
+	geoClueMu          sync.Mutex
+	sysSigLoop         *dbusutil.SignalLoop
+	geoAgentRegistered bool
+	// GeoClue 客户端启动成功后不再启动
+	geoClueStarted bool
+}
+
+func newNightLightEngine() *nightLightEngine {
+	sysService, err := dbusutil.NewSystemService()
+	if err != nil {
+		logger.Warning("new sys service failed:", err)
+	}
+	return &nightLightEngine{
+		sysService: sysService,
+		updateCh:   make(chan struct{}, 1),
+	}
+}
+
+func (e *nightLightEngine) start() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	logger.Debug("nightLightEngine.start")
+
+	if e.running {
+		return
+	}
+	e.running = true
+	e.value = 0
+	e.stopCh = make(chan struct{})
+	go e.run(e.stopCh)
+}
+
+func (e *nightLightEngine) stop() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if !e.running {
+		return
+	}
+	logger.Debug("nightLightEngine.stop")
+	e.running = false
+	close(e.stopCh)
+	e.stopCh = nil
+	e.value = 0
+}
+
+// setConfig 更新配置，正在运行时立即重新计算色温
+func (e *nightLightEngine) setConfig(cfg *NightLightConfig) {
+	e.mu.Lock()
+	e.cfg = cfg.clone()
+	e.mu.Unlock()
+	e.notifyUpdate()
+}
+
+func (e *nightLightEngine) notifyUpdate() {
+	select {
+	case e.updateCh <- struct{}{}:
+	default:
+	}
+}
+
+func (e *nightLightEngine) run(stopCh chan struct{}) {
+	ticker := time.NewTicker(nightLightUpdateInterval)
+	defer ticker.Stop()
+	for {
+		e.mu.Lock()
+		cfg := e.cfg.clone()
+		e.mu.Unlock()
+		if cfg.getSchedule() == nightLightScheduleSun && (cfg == nil || cfg.Location == nil) {
+			err := e.startGeoClue()
+			if err != nil {
+				logger.Warning("start geoClue failed:", err)
+			}
+		}
+
+		temp, err := e.getTargetTemperature(time.Now())
+		if err != nil {
+			logger.Warning("get night light temperature failed:", err)
+		} else if !e.fadeTo(temp, stopCh) {
+			return
+		}
+
+		select {
+		case <-stopCh:
+			return
+		case <-ticker.C:
+		case <-e.updateCh:
+		}
+	}
+}
+
+// getTargetTemperature 计算 t 时刻应该设置的色温
+func (e *nightLightEngine) getTargetTemperature(t time.Time) (int, error) {
+	e.mu.Lock()
+	cfg := e.cfg.clone()
+	geoLocation := e.geoLocation
+	e.mu.Unlock()
+
+	if cfg.getSchedule() == nightLightScheduleCustom {
+		from, err := parseClockTime(cfg.From)
+		if err != nil {
+			return 0, err
+		}
+		to, err := parseClockTime(cfg.To)
+		if err != nil {
+			return 0, err
+		}
+		return getNightLightTemperature(scheduleNightRatio(t, from, to, nightLightCustomTransition)), nil
+	}
+
+	var lat, lon float64
+	if cfg != nil && cfg.Location != nil {
+		lat, lon = cfg.Location.Latitude, cfg.Location.Longitude
+	} else if geoLocation != nil {
+		lat, lon = geoLocation.Latitude, geoLocation.Longitude
+	} else {
+		tzName, err := getTimezoneName()
+		if err != nil {
+			return 0, err
+		}
+		lat, lon, err = getTimezoneLocation(tzName)
+		if err != nil {
+			return 0, err
+		}
+	}
+	elevation := solarElevation(t, lat, lon)
+	logger.Debugf("solar elevation at (%.2f, %.2f): %.2f", lat, lon, elevation)
+	return getNightLightTemperature(solarNightRatio(elevation)), nil
+}
+
+// fadeTo 把色温设置为 temp，变化较大时渐变过渡，被停止时返回 false
+func (e *nightLightEngine) fadeTo(temp int, stopCh chan struct{}) bool {
+	current := e.getValue()
+	if current == 0 {
+		current = nightLightDayTemperature
+	}
+	diff := temp - current
+	if diff > -nightLightFadeThreshold && diff < nightLightFadeThreshold {
+		e.updateValue(temp, stopCh)
+		return true
+	}
+
+	interval := nightLightFadeDuration / nightLightFadeSteps
+	for i := 1; i <= nightLightFadeSteps; i++ {
+		e.updateValue(current+diff*i/nightLightFadeSteps, stopCh)
+		if i == nightLightFadeSteps {
+			break
+		}
+		select {
+		case <-stopCh:
+			return false
+		case <-time.After(interval):
+		}
+	}
+	return true
+}
+
+// updateValue 更新色温，stopCh 用于忽略已经停止的 run 设置的值
+func (e *nightLightEngine) updateValue(value int, stopCh chan struct{}) {
+	e.mu.Lock()
+	if e.value == value || e.stopCh != stopCh {
+		e.mu.Unlock()
+		return
+	}
+	e.value = value
+	e.mu.Unlock()
+
+	if e.cb != nil {
+		e.cb(value)
+	}
+}
+
+func (e *nightLightEngine) getValue() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.value
+}
+
+// startGeoClue 通过 GeoClue 获取位置，位置改变后重新计算色温
+func (e *nightLightEngine) startGeoClue() error {
+	e.geoClueMu.Lock()
+	defer e.geoClueMu.Unlock()
+	if e.geoClueStarted {
+		return nil
+	}
+	if e.sysService == nil {
+		return errors.New("sys service is nil")
+	}
+
+	err := e.registerGeoClueAgent()
+	if err != nil {
+		logger.Warning("register geoClue agent failed:", err)
+	}
+
+	sysBus := e.sysService.Conn()
+	geoManager := geoclue2.NewManager(sysBus)
+	clientPath, err := geoManager.GetClient(0)
+	if err != nil {
+		return err
+	}
+	client, err := geoclue2.NewClient(sysBus, clientPath)
+	if err != nil {
+		return err
+	}
+	err = client.DesktopId().Set(0, "dde-display")
+	if err != nil {
+		return err
+	}
+	err = client.RequestedAccuracyLevel().Set(0, AccuracyLevelCity)
+	if err != nil {
+		return err
+	}
+
+	if e.sysSigLoop == nil {
+		e.sysSigLoop = dbusutil.NewSignalLoop(sysBus, 10)
+		e.sysSigLoop.Start()
+	}
+	client.InitSignalExt(e.sysSigLoop, true)
+	_, err = client.ConnectLocationUpdated(func(oldPath, newPath dbus.ObjectPath) {
+		e.updateGeoLocation(newPath)
+	})
+	if err != nil {
+		return err
+	}
+
+	err = client.Start(0)
+	if err != nil {
+		// 下次重新获取客户端
+		client.RemoveAllHandlers()
+		return err
+	}
+	e.geoClueStarted = true
+	return nil
+}
+
+func (e *nightLightEngine) updateGeoLocation(path dbus.ObjectPath) {
+	location, err := geoclue2.NewLocation(e.sysService.Conn(), path)
+	if err != nil {
+		logger.Warning(err)
+		return
+	}
+	latitude, err := location.Latitude().Get(0)
+	if err != nil {
+		logger.Warning(err)
+		return
+	}
+	longitude, err := location.Longitude().Get(0)
+	if err != nil {
+		logger.Warning(err)
+		return
+	}
+	logger.Debugf("geoClue location updated: %v, %v", latitude, longitude)
+
+	e.mu.Lock()
+	e.geoLocation = &NightLightLocation{Latitude: latitude, Longitude: longitude}
+	e.mu.Unlock()
+	e.notifyUpdate()
+}
+
+const (
+	dbusIfcGeoClueAgent  = "org.freedesktop.GeoClue2.Agent"
+	dbusPathGeoClueAgent = "/org/freedesktop/GeoClue2/Agent"
+)
+
+type geoClueAgent struct {
+	MaxAccuracyLevel uint32
+}
+
+const (
+	AccuracyLevelNone         = 0
+	AccuracyLevelCountry      = 1
+	AccuracyLevelCity         = 4
+	AccuracyLevelNeighborhood = 5
+	AccuracyLevelStreet       = 6
+	AccuracyLevelExact        = 8
+)
+
+func (a *geoClueAgent) GetInterfaceName() string {
+	return dbusIfcGeoClueAgent
+}
+
+func (a *geoClueAgent) AuthorizeApp(desktopId string, reqAccuracyLevel uint32) (authorized bool, allowedAccuracyLevel uint32, busErr *dbus.Error) {
+	// 目前发现这个方法不会被调用。
+	logger.Debugf("AuthorizeApp desktopId: %q, reqAccuracyLevel: %v", desktopId, reqAccuracyLevel)
+	return true, reqAccuracyLevel, nil
+}
+
+func (a *geoClueAgent) GetExportedMethods() dbusutil.ExportedMethods {
+	return dbusutil.ExportedMethods{
+		{
+			Name:    "AuthorizeApp",
+			Fn:      a.AuthorizeApp,
+			InArgs:  []string{"desktopId", "reqAccuracyLevel"},
+			OutArgs: []string{"authorized", "allowedAccuracyLevel"},
+		},
+	}
+}
+
+func (e *nightLightEngine) registerGeoClueAgent() error {
+	if e.geoAgentRegistered {
+		return nil
+	}
+	if e.sysService == nil {
+		return errors.New("sys service is nil")
+	}
+
+	sysBus := e.sysService.Conn()
+	agent := &geoClueAgent{
+		MaxAccuracyLevel: AccuracyLevelStreet,
+	}
+	err := e.sysService.Export(dbusPathGeoClueAgent, agent)
+	if err != nil {
+		return err
+	}
+
+	geoManager := geoclue2.NewManager(sysBus)
+	err = geoManager.AddAgent(0, "geoclue-demo-agent")
+	if err != nil {
+		return err
+	}
+
+	e.geoAgentRegistered = true
+	return nil
+}
+
+// dbus 上导出的方法
+func (m *Manager) setNightLightSchedule(schedule, from, to string) error {
+	switch schedule {
+	case nightLightScheduleSun:
+	case nightLightScheduleCustom:
+		fromMin, err := parseClockTime(from)
+		if err != nil {
+			return err
+		}
+		toMin, err := parseClockTime(to)
+		if err != nil {
+			return err
+		}
+		if fromMin == toMin {
+			return errors.New("from and to are the same")
+		}
+	default:
+		return fmt.Errorf("invalid schedule %q", schedule)
+	}
+
+	cfg, err := m.modifyNightLightConfig(func(cfg *NightLightConfig) {
+		cfg.Schedule = schedule
+		if schedule == nightLightScheduleCustom {
+			cfg.From, cfg.To = from, to
+		}
+	})
+	m.nightLight.setConfig(cfg)
+	m.PropsMu.Lock()
+	m.setPropNightLightSchedule(cfg.getSchedule())
+	m.setPropNightLightFrom(cfg.From)
+	m.setPropNightLightTo(cfg.To)
+	m.PropsMu.Unlock()
+	return err
+}
+
+// dbus 上导出的方法，location 为 nil 时清除手动设置的位置
+func (m *Manager) setNightLightLocation(location *NightLightLocation) error {
+	if location != nil && !isValidNightLightLocation(location.Latitude, location.Longitude) {
+		return errors.New("location out of range")
+	}
+	cfg, err := m.modifyNightLightConfig(func(cfg *NightLightConfig) {
+		cfg.Location = location
+	})
+	m.nightLight.setConfig(cfg)
+	return err
+}
+
+// modifyNightLightConfig 修改用户配置中的自动调节色温配置并保存，返回修改后的配置
+func (m *Manager) modifyNightLightConfig(fn func(cfg *NightLightConfig)) (*NightLightConfig, error) {
+	m.userCfgMu.Lock()
+	defer m.userCfgMu.Unlock()
+	if m.userConfig.NightLight == nil {
+		m.userConfig.NightLight = &NightLightConfig{}
+	}
+	fn(m.userConfig.NightLight)
+	return m.userConfig.NightLight.clone(), m.saveUserConfigNoLock()
+}
+
+// initNightLight 从用户配置中初始化自动调节色温的配置和属性
+func (m *Manager) initNightLight() {
+	m.userCfgMu.Lock()
+	cfg := m.userConfig.NightLight.clone()
+	m.userCfgMu.Unlock()
+
+	m.nightLight.setConfig(cfg)
+	m.NightLightSchedule = cfg.getSchedule()
+	if cfg != nil {
+		m.NightLightFrom = cfg.From
+		m.NightLightTo = cfg.To
+	}
+}
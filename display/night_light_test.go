@@ -0,0 +1,73 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package display
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_parseClockTime(t *testing.T) {
+	v, err := parseClockTime("21:30")
+	assert.Nil(t, err)
+	assert.Equal(t, 21*60+30, v)
+
+	_, err = parseClockTime("24:00")
+	assert.NotNil(t, err)
+	_, err = parseClockTime("abc")
+	assert.NotNil(t, err)
+}
+
+func Test_scheduleNightRatio(t *testing.T) {
+	at := func(hour, minute int) time.Time {
+		return time.Date(2021, 1, 1, hour, minute, 0, 0, time.Local)
+	}
+	// 20:00 到第二天 07:00，跨过 0 点
+	from, to := 20*60, 7*60
+	tests := []struct {
+		t    time.Time
+		want float64
+	}{
+		{at(12, 0), 0},
+		{at(20, 0), 0},
+		{at(20, 15), 0.5},
+		{at(23, 0), 1},
+		{at(3, 0), 1},
+		{at(6, 45), 0.5},
+		{at(7, 0), 0},
+	}
+	for _, test := range tests {
+		assert.InDelta(t, test.want, scheduleNightRatio(test.t, from, to, 30*time.Minute), 0.001, test.t.String())
+	}
+
+	// 开始和结束时间相同时不调节
+	assert.Equal(t, 0.0, scheduleNightRatio(at(3, 0), from, from, 30*time.Minute))
+}
+
+func Test_getNightLightTemperature(t *testing.T) {
+	assert.Equal(t, nightLightDayTemperature, getNightLightTemperature(0))
+	assert.Equal(t, nightLightNightTemperature, getNightLightTemperature(1))
+	assert.Equal(t, 5000, getNightLightTemperature(0.5))
+
+	var cfg *NightLightConfig
+	assert.Equal(t, nightLightScheduleSun, cfg.getSchedule())
+	cfg = &NightLightConfig{Schedule: nightLightScheduleCustom, Location: &NightLightLocation{Latitude: 1}}
+	cfgCp := cfg.clone()
+	cfgCp.Location.Latitude = 2
+	assert.Equal(t, 1.0, cfg.Location.Latitude)
+}
+
+func Test_nightLightEngine_startGeoClue(t *testing.T) {
+	e := &nightLightEngine{}
+	// 启动失败时下次重试
+	assert.Error(t, e.startGeoClue())
+	assert.False(t, e.geoClueStarted)
+	assert.Error(t, e.startGeoClue())
+
+	e.geoClueStarted = true
+	assert.NoError(t, e.startGeoClue())
+}
@@ -0,0 +1,258 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package display
+
+import (
+	"errors"
+	"fmt"
+
+	x "github.com/linuxdeepin/go-x11-client"
+	"github.com/linuxdeepin/go-x11-client/ext/randr"
+)
+
+// output 的颜色相关属性，只有部分显卡驱动提供
+const (
+	outputPropBroadcastRGB = "Broadcast RGB"
+	outputPropMaxBpc       = "max bpc"
+	outputPropColorspace   = "Colorspace"
+)
+
+// Broadcast RGB 属性的值
+const (
+	broadcastRGBAutomatic = "Automatic"
+	broadcastRGBFull      = "Full"
+	broadcastRGBLimited   = "Limited 16:235"
+)
+
+// Colorspace 属性的值，只列出常用的，可选值以驱动提供的为准
+const (
+	colorspaceDefault   = "Default"
+	colorspaceBT2020RGB = "BT2020_RGB"
+)
+
+// errOutputColorUnsupported 显示服务不支持设置颜色输出
+var errOutputColorUnsupported = errors.New("setting output color is not supported")
+
+// SysOutputColorConfig 显示器的颜色输出设置，为空的字段表示不设置，保持驱动默认值
+type SysOutputColorConfig struct {
+	BroadcastRGB string `json:",omitempty"`
+	MaxBpc       uint32 `json:",omitempty"`
+	Colorspace   string `json:",omitempty"`
+}
+
+func (c SysOutputColorConfig) isEmpty() bool {
+	return c == SysOutputColorConfig{}
+}
+
+// merge 用 other 中不为空的字段覆盖 c 中的字段
+func (c SysOutputColorConfig) merge(other SysOutputColorConfig) SysOutputColorConfig {
+	if other.BroadcastRGB != "" {
+		c.BroadcastRGB = other.BroadcastRGB
+	}
+	if other.MaxBpc != 0 {
+		c.MaxBpc = other.MaxBpc
+	}
+	if other.Colorspace != "" {
+		c.Colorspace = other.Colorspace
+	}
+	return c
+}
+
+// outputColorPropValue 要设置的颜色相关的 output 属性，Broadcast RGB 和 Colorspace 是 ATOM 类型，
+// max bpc 是 INTEGER 类型
+type outputColorPropValue struct {
+	name     string
+	propType x.Atom
+	value    uint32
+}
+
+// getOutputColorPropValues 把 cfg 转换成要设置的 output 属性的值，cfg 中为空的字段不设置
+func getOutputColorPropValues(cfg SysOutputColorConfig, getAtom func(name string) (x.Atom, error)) ([]outputColorPropValue, error) {
+	var result []outputColorPropValue
+	appendAtomValue := func(name, value string) error {
+		atom, err := getAtom(value)
+		if err != nil {
+			return err
+		}
+		result = append(result, outputColorPropValue{name: name, propType: x.AtomAtom, value: uint32(atom)})
+		return nil
+	}
+
+	if cfg.BroadcastRGB != "" {
+		err := appendAtomValue(outputPropBroadcastRGB, cfg.BroadcastRGB)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if cfg.MaxBpc != 0 {
+		result = append(result, outputColorPropValue{name: outputPropMaxBpc, propType: x.AtomInteger, value: cfg.MaxBpc})
+	}
+	if cfg.Colorspace != "" {
+		err := appendAtomValue(outputPropColorspace, cfg.Colorspace)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// setOutputColorProp 按 output 属性的可选值和当前值设置颜色相关的属性
+func (m *MonitorInfo) setOutputColorProp(name string, propInfo *randr.QueryOutputPropertyReply, value uint32,
+	getAtomName func(atom x.Atom) (string, error)) {
+	switch name {
+	case outputPropMaxBpc:
+		if !propInfo.Range || len(propInfo.ValidValues) != 2 {
+			return
+		}
+		m.MaxBpcRange = []uint32{uint32(propInfo.ValidValues[0]), uint32(propInfo.ValidValues[1])}
+		m.MaxBpc = value
+	case outputPropBroadcastRGB, outputPropColorspace:
+		var validValues []string
+		for _, v := range propInfo.ValidValues {
+			valueName, err := getAtomName(x.Atom(v))
+			if err == nil {
+				validValues = append(validValues, valueName)
+			}
+		}
+		current, _ := getAtomName(x.Atom(value))
+		if name == outputPropBroadcastRGB {
+			m.AvailableBroadcastRGB = validValues
+			m.BroadcastRGB = current
+		} else {
+			m.AvailableColorspaces = validValues
+			m.Colorspace = current
+		}
+	}
+}
+
+// checkOutputColor 检查显示器是否支持 cfg 中的设置，需要持有 PropsMu 的读锁
+func (m *Monitor) checkOutputColor(cfg SysOutputColorConfig) error {
+	if cfg.BroadcastRGB != "" && !m.AvailableBroadcastRGB.Contains(cfg.BroadcastRGB) {
+		return fmt.Errorf("unsupported broadcast rgb %q", cfg.BroadcastRGB)
+	}
+	if cfg.MaxBpc != 0 {
+		if len(m.MaxBpcRange) != 2 {
+			return errors.New("monitor do not support set max bpc")
+		}
+		if cfg.MaxBpc < m.MaxBpcRange[0] || cfg.MaxBpc > m.MaxBpcRange[1] {
+			return fmt.Errorf("max bpc %d out of range %v", cfg.MaxBpc, m.MaxBpcRange)
+		}
+	}
+	if cfg.Colorspace != "" && !m.AvailableColorspaces.Contains(cfg.Colorspace) {
+		return fmt.Errorf("unsupported colorspace %q", cfg.Colorspace)
+	}
+	return nil
+}
+
+// filterOutputColor 去掉 cfg 中显示器不支持的设置，用于应用保存的配置
+func (m *Monitor) filterOutputColor(cfg SysOutputColorConfig) SysOutputColorConfig {
+	if cfg.BroadcastRGB != "" && m.checkOutputColor(SysOutputColorConfig{BroadcastRGB: cfg.BroadcastRGB}) != nil {
+		cfg.BroadcastRGB = ""
+	}
+	if cfg.MaxBpc != 0 && m.checkOutputColor(SysOutputColorConfig{MaxBpc: cfg.MaxBpc}) != nil {
+		cfg.MaxBpc = 0
+	}
+	if cfg.Colorspace != "" && m.checkOutputColor(SysOutputColorConfig{Colorspace: cfg.Colorspace}) != nil {
+		cfg.Colorspace = ""
+	}
+	return cfg
+}
+
+// setPropOutputColor 更新显示器的颜色输出属性，需要持有 PropsMu 的写锁
+func (m *Monitor) setPropOutputColor(cfg SysOutputColorConfig) {
+	if cfg.BroadcastRGB != "" {
+		m.setPropBroadcastRGB(cfg.BroadcastRGB)
+	}
+	if cfg.MaxBpc != 0 {
+		m.setPropMaxBpc(cfg.MaxBpc)
+	}
+	if cfg.Colorspace != "" {
+		m.setPropColorspace(cfg.Colorspace)
+	}
+}
+
+// setMonitorOutputColor 立即设置显示器的颜色输出，并按显示器 uuid 保存到配置中
+func (m *Manager) setMonitorOutputColor(monitor *Monitor, cfg SysOutputColorConfig) error {
+	m.setOutputColorMu.Lock()
+	defer m.setOutputColorMu.Unlock()
+
+	monitor.PropsMu.RLock()
+	err := monitor.checkOutputColor(cfg)
+	uuid := monitor.uuid
+	monitor.PropsMu.RUnlock()
+	if err != nil {
+		return err
+	}
+
+	logger.Debugf("%v set output color %+v", monitor, cfg)
+	err = m.mm.setMonitorOutputColor(monitor, cfg)
+	if err != nil {
+		return err
+	}
+
+	monitor.PropsMu.Lock()
+	monitor.setPropOutputColor(cfg)
+	monitor.PropsMu.Unlock()
+
+	m.sysConfig.mu.Lock()
+	defer m.sysConfig.mu.Unlock()
+	c := &m.sysConfig.Config
+	if c.OutputColors == nil {
+		c.OutputColors = make(map[string]SysOutputColorConfig)
+	}
+	c.OutputColors[uuid] = c.OutputColors[uuid].merge(cfg)
+	return m.saveSysConfigNoLock("output color changed")
+}
+
+// applyOutputColors 在应用显示器配置之后，给启用的显示器应用保存的颜色输出设置，
+// 显示器插拔后也通过这里恢复设置。
+func (m *Manager) applyOutputColors(configs SysMonitorConfigs) {
+	m.setOutputColorMu.Lock()
+	defer m.setOutputColorMu.Unlock()
+
+	m.sysConfig.mu.Lock()
+	outputColors := make(map[string]SysOutputColorConfig, len(m.sysConfig.Config.OutputColors))
+	for uuid, cfg := range m.sysConfig.Config.OutputColors {
+		outputColors[uuid] = cfg
+	}
+	m.sysConfig.mu.Unlock()
+	if len(outputColors) == 0 {
+		return
+	}
+
+	monitors := m.getConnectedMonitors()
+	for _, config := range configs {
+		if !config.Enabled {
+			continue
+		}
+		cfg, ok := outputColors[config.UUID]
+		if !ok {
+			continue
+		}
+		monitor := monitors.GetByUuidAndName(config.UUID, config.Name)
+		if monitor == nil {
+			monitor = monitors.GetByUuid(config.UUID)
+		}
+		if monitor == nil {
+			continue
+		}
+
+		monitor.PropsMu.RLock()
+		cfg = monitor.filterOutputColor(cfg)
+		monitor.PropsMu.RUnlock()
+		if cfg.isEmpty() {
+			continue
+		}
+
+		err := m.mm.setMonitorOutputColor(monitor, cfg)
+		if err != nil {
+			logger.Warningf("set monitor %v output color %+v failed: %v", monitor.Name, cfg, err)
+			continue
+		}
+		monitor.PropsMu.Lock()
+		monitor.setPropOutputColor(cfg)
+		monitor.PropsMu.Unlock()
+	}
+}
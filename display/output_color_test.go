@@ -0,0 +1,135 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package display
+
+import (
+	"fmt"
+	"testing"
+
+	x "github.com/linuxdeepin/go-x11-client"
+	"github.com/linuxdeepin/go-x11-client/ext/randr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSysOutputColorConfig_merge(t *testing.T) {
+	cfg := SysOutputColorConfig{BroadcastRGB: broadcastRGBAutomatic, MaxBpc: 8}
+	cfg = cfg.merge(SysOutputColorConfig{BroadcastRGB: broadcastRGBFull})
+	assert.Equal(t, SysOutputColorConfig{BroadcastRGB: broadcastRGBFull, MaxBpc: 8}, cfg)
+
+	cfg = cfg.merge(SysOutputColorConfig{MaxBpc: 10, Colorspace: colorspaceBT2020RGB})
+	assert.Equal(t, SysOutputColorConfig{
+		BroadcastRGB: broadcastRGBFull,
+		MaxBpc:       10,
+		Colorspace:   colorspaceBT2020RGB,
+	}, cfg)
+	assert.True(t, SysOutputColorConfig{}.isEmpty())
+	assert.False(t, cfg.isEmpty())
+}
+
+func TestMonitor_checkOutputColor(t *testing.T) {
+	m := &Monitor{
+		AvailableBroadcastRGB: []string{broadcastRGBAutomatic, broadcastRGBFull, broadcastRGBLimited},
+		MaxBpcRange:           []uint32{6, 12},
+	}
+	assert.Nil(t, m.checkOutputColor(SysOutputColorConfig{BroadcastRGB: broadcastRGBFull, MaxBpc: 10}))
+	assert.NotNil(t, m.checkOutputColor(SysOutputColorConfig{BroadcastRGB: "Limited"}))
+	assert.NotNil(t, m.checkOutputColor(SysOutputColorConfig{MaxBpc: 16}))
+	assert.NotNil(t, m.checkOutputColor(SysOutputColorConfig{Colorspace: colorspaceBT2020RGB}))
+
+	// 应用保存的配置时去掉不支持的设置
+	cfg := m.filterOutputColor(SysOutputColorConfig{
+		BroadcastRGB: broadcastRGBLimited,
+		MaxBpc:       16,
+		Colorspace:   colorspaceBT2020RGB,
+	})
+	assert.Equal(t, SysOutputColorConfig{BroadcastRGB: broadcastRGBLimited}, cfg)
+}
+
+// testAtoms 模拟 X 的 atom 表
+type testAtoms struct {
+	names []string
+}
+
+func (a *testAtoms) getAtom(name string) (x.Atom, error) {
+	for i, n := range a.names {
+		if n == name {
+			return x.Atom(i + 1), nil
+		}
+	}
+	a.names = append(a.names, name)
+	return x.Atom(len(a.names)), nil
+}
+
+func (a *testAtoms) getAtomName(atom x.Atom) (string, error) {
+	if atom == 0 || int(atom) > len(a.names) {
+		return "", fmt.Errorf("bad atom %d", atom)
+	}
+	return a.names[atom-1], nil
+}
+
+func (a *testAtoms) getAtoms(t *testing.T, names ...string) []int32 {
+	var result []int32
+	for _, name := range names {
+		atom, err := a.getAtom(name)
+		require.NoError(t, err)
+		result = append(result, int32(atom))
+	}
+	return result
+}
+
+func Test_outputColorPropsRoundTrip(t *testing.T) {
+	atoms := &testAtoms{}
+	propInfos := map[string]*randr.QueryOutputPropertyReply{
+		outputPropBroadcastRGB: {
+			ValidValues: atoms.getAtoms(t, broadcastRGBAutomatic, broadcastRGBFull, broadcastRGBLimited),
+		},
+		outputPropMaxBpc: {Range: true, ValidValues: []int32{6, 12}},
+		outputPropColorspace: {
+			ValidValues: atoms.getAtoms(t, colorspaceDefault, colorspaceBT2020RGB, "BT2020_YCC"),
+		},
+	}
+
+	cfg := SysOutputColorConfig{
+		BroadcastRGB: broadcastRGBLimited,
+		MaxBpc:       10,
+		Colorspace:   "BT2020_YCC",
+	}
+	values, err := getOutputColorPropValues(cfg, atoms.getAtom)
+	require.NoError(t, err)
+	require.Len(t, values, 3)
+
+	var monitor MonitorInfo
+	for _, v := range values {
+		if v.name == outputPropMaxBpc {
+			assert.Equal(t, x.AtomInteger, v.propType)
+		} else {
+			assert.Equal(t, x.AtomAtom, v.propType)
+		}
+		monitor.setOutputColorProp(v.name, propInfos[v.name], v.value, atoms.getAtomName)
+	}
+	// 读回来的值与设置的相同，Colorspace 保留原来的值
+	assert.Equal(t, broadcastRGBLimited, monitor.BroadcastRGB)
+	assert.Equal(t, []string{broadcastRGBAutomatic, broadcastRGBFull, broadcastRGBLimited},
+		[]string(monitor.AvailableBroadcastRGB))
+	assert.Equal(t, uint32(10), monitor.MaxBpc)
+	assert.Equal(t, []uint32{6, 12}, monitor.MaxBpcRange)
+	assert.Equal(t, "BT2020_YCC", monitor.Colorspace)
+	assert.Equal(t, []string{colorspaceDefault, colorspaceBT2020RGB, "BT2020_YCC"},
+		[]string(monitor.AvailableColorspaces))
+
+	// 为空的字段不设置
+	values, err = getOutputColorPropValues(SysOutputColorConfig{MaxBpc: 8}, atoms.getAtom)
+	require.NoError(t, err)
+	assert.Equal(t, []outputColorPropValue{
+		{name: outputPropMaxBpc, propType: x.AtomInteger, value: 8},
+	}, values)
+
+	// max bpc 不是范围类型的属性时忽略
+	monitor = MonitorInfo{}
+	monitor.setOutputColorProp(outputPropMaxBpc, &randr.QueryOutputPropertyReply{ValidValues: []int32{8}}, 8,
+		atoms.getAtomName)
+	assert.Nil(t, monitor.MaxBpcRange)
+}
@@ -0,0 +1,535 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package display
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/godbus/dbus"
+	"github.com/linuxdeepin/go-lib/utils"
+)
+
+const (
+	login1Service          = "org.freedesktop.login1"
+	login1Path             = "/org/freedesktop/login1"
+	login1PropLidClosed    = "org.freedesktop.login1.Manager.LidClosed"
+	login1PropDocked       = "org.freedesktop.login1.Manager.Docked"
+	profileTimeLayout      = "15:04"
+	maxProfileNameLength   = 64
+	profileNameInvalidChar = "/\\"
+)
+
+// Profile 命名的显示配置，保存了所有显示器的模式、位置、旋转、主屏、亮度，以及缩放和色温。
+type Profile struct {
+	DisplayMode            byte
+	Monitors               SysMonitorConfigs
+	ScaleFactors           map[string]float64 `json:",omitempty"`
+	ColorTemperatureMode   int32
+	ColorTemperatureManual int32
+	Rules                  *ProfileRules `json:",omitempty"`
+	UpdateAt               string
+}
+
+// ProfileRules 自动启用配置的条件，设置了的条件都满足时才启用，没有设置任何条件则不会自动启用。
+type ProfileRules struct {
+	// 已连接显示器的 EDID 标识集合，即 EDID 前 128 字节的 md5
+	Edids     []string `json:",omitempty"`
+	LidClosed *bool    `json:",omitempty"`
+	Docked    *bool    `json:",omitempty"`
+	// 时间段，格式 15:04，TimeStart 晚于 TimeEnd 表示跨过午夜
+	TimeStart string `json:",omitempty"`
+	TimeEnd   string `json:",omitempty"`
+}
+
+// profileEnv 评估启用条件时的环境
+type profileEnv struct {
+	edids     []string
+	lidClosed bool
+	docked    bool
+	now       time.Time
+}
+
+func (p *Profile) clone() *Profile {
+	if p == nil {
+		return nil
+	}
+	result := *p
+	result.Monitors = p.Monitors.clone()
+	if p.ScaleFactors != nil {
+		result.ScaleFactors = make(map[string]float64, len(p.ScaleFactors))
+		for name, factor := range p.ScaleFactors {
+			result.ScaleFactors[name] = factor
+		}
+	}
+	result.Rules = p.Rules.clone()
+	return &result
+}
+
+func (r *ProfileRules) clone() *ProfileRules {
+	if r == nil {
+		return nil
+	}
+	result := *r
+	result.Edids = append([]string(nil), r.Edids...)
+	if r.LidClosed != nil {
+		v := *r.LidClosed
+		result.LidClosed = &v
+	}
+	if r.Docked != nil {
+		v := *r.Docked
+		result.Docked = &v
+	}
+	return &result
+}
+
+func (r *ProfileRules) check() error {
+	if r.TimeStart == "" && r.TimeEnd == "" {
+		return nil
+	}
+	if r.TimeStart == "" || r.TimeEnd == "" {
+		return errors.New("both TimeStart and TimeEnd are required")
+	}
+	_, err := time.Parse(profileTimeLayout, r.TimeStart)
+	if err != nil {
+		return err
+	}
+	_, err = time.Parse(profileTimeLayout, r.TimeEnd)
+	return err
+}
+
+// numConditions 设置了的条件个数，用于在多个配置同时满足条件时选择更具体的一个。
+func (r *ProfileRules) numConditions() int {
+	if r == nil {
+		return 0
+	}
+	n := 0
+	if len(r.Edids) > 0 {
+		n++
+	}
+	if r.LidClosed != nil {
+		n++
+	}
+	if r.Docked != nil {
+		n++
+	}
+	if r.TimeStart != "" {
+		n++
+	}
+	return n
+}
+
+func (r *ProfileRules) match(env *profileEnv) bool {
+	if r.numConditions() == 0 {
+		return false
+	}
+	if len(r.Edids) > 0 && !edidsEqual(r.Edids, env.edids) {
+		return false
+	}
+	if r.LidClosed != nil && *r.LidClosed != env.lidClosed {
+		return false
+	}
+	if r.Docked != nil && *r.Docked != env.docked {
+		return false
+	}
+	if r.TimeStart != "" && !inTimeRange(r.TimeStart, r.TimeEnd, env.now) {
+		return false
+	}
+	return true
+}
+
+// edidsEqual 作为集合比较
+func edidsEqual(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	a = append([]string(nil), a...)
+	b = append([]string(nil), b...)
+	sort.Strings(a)
+	sort.Strings(b)
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func inTimeRange(start, end string, now time.Time) bool {
+	startTime, err := time.Parse(profileTimeLayout, start)
+	if err != nil {
+		return false
+	}
+	endTime, err := time.Parse(profileTimeLayout, end)
+	if err != nil {
+		return false
+	}
+	startMin := startTime.Hour()*60 + startTime.Minute()
+	endMin := endTime.Hour()*60 + endTime.Minute()
+	nowMin := now.Hour()*60 + now.Minute()
+	if startMin <= endMin {
+		return nowMin >= startMin && nowMin < endMin
+	}
+	// 跨过午夜
+	return nowMin >= startMin || nowMin < endMin
+}
+
+// matchProfile 返回满足启用条件的配置名，多个配置满足时选择条件最多的，条件数相同时按名字排序选第一个。
+func matchProfile(profiles map[string]*Profile, env *profileEnv) string {
+	var names []string
+	for name := range profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var result string
+	var maxConditions int
+	for _, name := range names {
+		rules := profiles[name].Rules
+		if rules == nil || !rules.match(env) {
+			continue
+		}
+		n := rules.numConditions()
+		if n > maxConditions {
+			result = name
+			maxConditions = n
+		}
+	}
+	return result
+}
+
+// getEdidId 获取 EDID 的标识，与 uuid 中的 md5 一致
+func getEdidId(edid []byte) string {
+	if len(edid) < 128 {
+		return ""
+	}
+	id, _ := utils.SumStrMd5(string(edid[:128]))
+	return id
+}
+
+func checkProfileName(name string) error {
+	if name == "" {
+		return errors.New("profile name is empty")
+	}
+	if len(name) > maxProfileNameLength {
+		return errors.New("profile name is too long")
+	}
+	if strings.ContainsAny(name, profileNameInvalidChar) {
+		return fmt.Errorf("invalid profile name %q", name)
+	}
+	return nil
+}
+
+func (cfg *UserConfig) getProfileNames() []string {
+	names := make([]string, 0, len(cfg.Profiles))
+	for name := range cfg.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (m *Manager) getProfile(name string) *Profile {
+	m.userCfgMu.Lock()
+	defer m.userCfgMu.Unlock()
+	return m.userConfig.Profiles[name].clone()
+}
+
+func (m *Manager) getProfiles() map[string]*Profile {
+	m.userCfgMu.Lock()
+	defer m.userCfgMu.Unlock()
+	result := make(map[string]*Profile, len(m.userConfig.Profiles))
+	for name, profile := range m.userConfig.Profiles {
+		result[name] = profile.clone()
+	}
+	return result
+}
+
+// modifyProfiles 修改配置并保存，同步 CustomIdList 属性
+func (m *Manager) modifyProfiles(fn func(profiles map[string]*Profile) error) error {
+	m.userCfgMu.Lock()
+	if m.userConfig.Profiles == nil {
+		m.userConfig.Profiles = make(map[string]*Profile)
+	}
+	err := fn(m.userConfig.Profiles)
+	if err != nil {
+		m.userCfgMu.Unlock()
+		return err
+	}
+	err = m.saveUserConfigNoLock()
+	names := m.userConfig.getProfileNames()
+	m.userCfgMu.Unlock()
+
+	m.PropsMu.Lock()
+	m.setPropCustomIdList(names)
+	m.PropsMu.Unlock()
+	return err
+}
+
+func (m *Manager) setCurrentProfile(name string) {
+	monitorsId := m.getMonitorsId()
+	m.PropsMu.Lock()
+	changed := m.setPropCurrentCustomId(name)
+	m.currentProfileMonitorsId = monitorsId
+	m.PropsMu.Unlock()
+	if changed && !_greeterMode {
+		m.settings.SetString(gsKeyCustomMode, name)
+	}
+}
+
+// resetCurrentProfile 连接的显示器不同于应用命名配置时的显示器，清除当前配置
+func (m *Manager) resetCurrentProfile(monitorsId monitorsId) {
+	m.PropsMu.RLock()
+	same := m.currentProfileMonitorsId == monitorsId
+	m.PropsMu.RUnlock()
+	if !same {
+		m.setCurrentProfile("")
+	}
+}
+
+// saveProfile 以当前的显示状态保存配置，rulesJSON 为空时保留同名配置原有的启用条件。
+func (m *Manager) saveProfile(name, rulesJSON string) error {
+	err := checkProfileName(name)
+	if err != nil {
+		return err
+	}
+
+	var rules *ProfileRules
+	if rulesJSON != "" {
+		rules = &ProfileRules{}
+		err = json.Unmarshal([]byte(rulesJSON), rules)
+		if err != nil {
+			return err
+		}
+		err = rules.check()
+		if err != nil {
+			return err
+		}
+	}
+
+	monitors := m.getConnectedMonitors()
+	if len(monitors) == 0 {
+		return errors.New("no monitor connected")
+	}
+
+	m.PropsMu.RLock()
+	profile := &Profile{
+		DisplayMode:            m.DisplayMode,
+		Monitors:               toSysMonitorConfigs(monitors, m.Primary),
+		ColorTemperatureMode:   m.ColorTemperatureMode,
+		ColorTemperatureManual: m.ColorTemperatureManual,
+		Rules:                  rules,
+		UpdateAt:               time.Now().Format(time.RFC3339Nano),
+	}
+	m.PropsMu.RUnlock()
+
+	m.sysConfig.mu.Lock()
+	if len(m.sysConfig.Config.ScaleFactors) > 0 {
+		profile.ScaleFactors = make(map[string]float64, len(m.sysConfig.Config.ScaleFactors))
+		for key, value := range m.sysConfig.Config.ScaleFactors {
+			profile.ScaleFactors[key] = value
+		}
+	}
+	m.sysConfig.mu.Unlock()
+
+	err = m.modifyProfiles(func(profiles map[string]*Profile) error {
+		if rulesJSON == "" && profiles[name] != nil {
+			profile.Rules = profiles[name].Rules
+		}
+		profiles[name] = profile
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	m.setCurrentProfile(name)
+	return nil
+}
+
+func (m *Manager) deleteProfile(name string) error {
+	err := m.modifyProfiles(func(profiles map[string]*Profile) error {
+		if profiles[name] == nil {
+			return fmt.Errorf("profile %q not found", name)
+		}
+		delete(profiles, name)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	m.PropsMu.RLock()
+	current := m.CurrentCustomId
+	m.PropsMu.RUnlock()
+	if current == name {
+		m.setCurrentProfile("")
+	}
+	return nil
+}
+
+func (m *Manager) renameProfile(name, newName string) error {
+	err := checkProfileName(newName)
+	if err != nil {
+		return err
+	}
+	err = m.modifyProfiles(func(profiles map[string]*Profile) error {
+		if profiles[name] == nil {
+			return fmt.Errorf("profile %q not found", name)
+		}
+		if profiles[newName] != nil {
+			return fmt.Errorf("profile %q already exists", newName)
+		}
+		profiles[newName] = profiles[name]
+		delete(profiles, name)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	m.PropsMu.RLock()
+	current := m.CurrentCustomId
+	m.PropsMu.RUnlock()
+	if current == name {
+		m.setCurrentProfile(newName)
+	}
+	return nil
+}
+
+// applyProfile 应用配置，配置中没有的显示器会被禁用，应用成功后保存为当前连接显示器的系统配置。
+func (m *Manager) applyProfile(name string) error {
+	profile := m.getProfile(name)
+	if profile == nil {
+		return fmt.Errorf("profile %q not found", name)
+	}
+
+	monitorMap := m.cloneMonitorMap()
+	monitors := getConnectedMonitors(monitorMap)
+	if len(monitors) == 0 {
+		return errors.New("no monitor connected")
+	}
+	monitorsId := monitors.getMonitorsId()
+
+	var configs SysMonitorConfigs
+	for _, config := range profile.Monitors {
+		if monitors.GetByUuid(config.UUID) != nil {
+			configs = append(configs, config)
+		}
+	}
+	if len(configs) == 0 {
+		return fmt.Errorf("no monitor of profile %q connected", name)
+	}
+	updateSysMonitorConfigsName(configs, monitorMap)
+
+	displayMode := profile.DisplayMode
+	if len(monitors) == 1 {
+		displayMode = DisplayModeInvalid
+	}
+
+	m.applySaveMu.Lock()
+	err := m.applySysMonitorConfigs(displayMode, monitorsId, monitorMap, configs, nil)
+	if err != nil {
+		m.applySaveMu.Unlock()
+		return err
+	}
+
+	screenCfg := m.getSysScreenConfig(monitorsId)
+	if len(monitors) == 1 {
+		screenCfg.setSingleMonitorConfigs(configs)
+	} else {
+		uuid := getOnlyOneMonitorUuid(profile.DisplayMode, monitors)
+		screenCfg.setMonitorConfigs(profile.DisplayMode, uuid, configs)
+	}
+	m.setSysScreenConfig(monitorsId, screenCfg)
+	m.sysConfig.mu.Lock()
+	if len(monitors) > 1 {
+		m.sysConfig.Config.DisplayMode = profile.DisplayMode
+	}
+	err = m.saveSysConfigNoLock("apply profile")
+	m.sysConfig.mu.Unlock()
+	m.applySaveMu.Unlock()
+	if err != nil {
+		logger.Warning(err)
+	}
+
+	if len(profile.ScaleFactors) > 0 {
+		err = m.setScaleFactors(profile.ScaleFactors)
+		if err != nil {
+			logger.Warning(err)
+		} else if ScaleFactorsHelper.changedCb != nil {
+			err = ScaleFactorsHelper.changedCb(profile.ScaleFactors)
+			if err != nil {
+				logger.Warning("scale factors changed cb err:", err)
+			}
+		}
+	}
+
+	if isValidColorTempMode(profile.ColorTemperatureMode) {
+		err = m.setColorTempMode(profile.ColorTemperatureMode)
+		if err == nil && profile.ColorTemperatureMode == ColorTemperatureModeManual {
+			err = m.setColorTempValue(profile.ColorTemperatureManual)
+		}
+		if err != nil {
+			logger.Warning(err)
+		}
+	}
+
+	m.setCurrentProfile(name)
+	return nil
+}
+
+func (m *Manager) getProfileEnv() *profileEnv {
+	env := &profileEnv{
+		now: time.Now(),
+	}
+	for _, monitor := range m.getConnectedMonitors() {
+		if id := getEdidId(monitor.edid); id != "" {
+			env.edids = append(env.edids, id)
+		}
+	}
+
+	if m.sysBus != nil {
+		obj := m.sysBus.Object(login1Service, login1Path)
+		env.lidClosed = getBoolProperty(obj, login1PropLidClosed)
+		env.docked = getBoolProperty(obj, login1PropDocked)
+	}
+	return env
+}
+
+func getBoolProperty(obj dbus.BusObject, name string) bool {
+	variant, err := obj.GetProperty(name)
+	if err != nil {
+		logger.Warning(err)
+		return false
+	}
+	value, _ := variant.Value().(bool)
+	return value
+}
+
+// evalProfileRules 在显示器连接或断开时评估启用条件，满足条件的配置在 delayApplyConfig 中应用。
+func (m *Manager) evalProfileRules() {
+	profiles := m.getProfiles()
+	if len(profiles) == 0 {
+		return
+	}
+	name := matchProfile(profiles, m.getProfileEnv())
+	logger.Debug("matched profile:", name)
+
+	m.PropsMu.Lock()
+	m.pendingProfile = name
+	m.PropsMu.Unlock()
+}
+
+func (m *Manager) takePendingProfile() string {
+	m.PropsMu.Lock()
+	name := m.pendingProfile
+	m.pendingProfile = ""
+	m.PropsMu.Unlock()
+	return name
+}
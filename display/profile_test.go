@@ -0,0 +1,112 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package display
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_inTimeRange(t *testing.T) {
+	at := func(hour, min int) time.Time {
+		return time.Date(2022, 1, 1, hour, min, 0, 0, time.Local)
+	}
+	assert.True(t, inTimeRange("09:00", "18:00", at(9, 0)))
+	assert.True(t, inTimeRange("09:00", "18:00", at(17, 59)))
+	assert.False(t, inTimeRange("09:00", "18:00", at(18, 0)))
+	assert.False(t, inTimeRange("09:00", "18:00", at(8, 59)))
+
+	// 跨过午夜
+	assert.True(t, inTimeRange("22:00", "06:00", at(23, 30)))
+	assert.True(t, inTimeRange("22:00", "06:00", at(5, 0)))
+	assert.False(t, inTimeRange("22:00", "06:00", at(12, 0)))
+
+	assert.False(t, inTimeRange("25:00", "06:00", at(1, 0)))
+}
+
+func Test_ProfileRules_check(t *testing.T) {
+	assert.NoError(t, (&ProfileRules{}).check())
+	assert.NoError(t, (&ProfileRules{TimeStart: "22:00", TimeEnd: "06:00"}).check())
+	assert.Error(t, (&ProfileRules{TimeStart: "22:00"}).check())
+	assert.Error(t, (&ProfileRules{TimeStart: "22:00", TimeEnd: "6am"}).check())
+}
+
+func Test_matchProfile(t *testing.T) {
+	var rulesHome, rulesOffice, rulesNight, rulesLid ProfileRules
+	assert.NoError(t, json.Unmarshal([]byte(`{"Edids":["a","b"]}`), &rulesHome))
+	assert.NoError(t, json.Unmarshal([]byte(`{"Edids":["b","c"],"Docked":true}`), &rulesOffice))
+	assert.NoError(t, json.Unmarshal([]byte(`{"TimeStart":"22:00","TimeEnd":"06:00"}`), &rulesNight))
+	assert.NoError(t, json.Unmarshal([]byte(`{"Edids":["a","b"],"LidClosed":true}`), &rulesLid))
+
+	profiles := map[string]*Profile{
+		"home":   {Rules: &rulesHome},
+		"office": {Rules: &rulesOffice},
+		"night":  {Rules: &rulesNight},
+		"lid":    {Rules: &rulesLid},
+		"manual": {},
+	}
+	noon := time.Date(2022, 1, 1, 12, 0, 0, 0, time.Local)
+	midnight := time.Date(2022, 1, 1, 0, 0, 0, 0, time.Local)
+
+	assert.Equal(t, "home", matchProfile(profiles, &profileEnv{
+		edids: []string{"b", "a"},
+		now:   noon,
+	}))
+	// 条件更多的优先
+	assert.Equal(t, "lid", matchProfile(profiles, &profileEnv{
+		edids:     []string{"a", "b"},
+		lidClosed: true,
+		now:       noon,
+	}))
+	assert.Equal(t, "", matchProfile(profiles, &profileEnv{
+		edids: []string{"b", "c"},
+		now:   noon,
+	}))
+	assert.Equal(t, "office", matchProfile(profiles, &profileEnv{
+		edids:  []string{"b", "c"},
+		docked: true,
+		now:    noon,
+	}))
+	// 条件数相同时按名字排序
+	assert.Equal(t, "home", matchProfile(profiles, &profileEnv{
+		edids: []string{"a", "b"},
+		now:   midnight,
+	}))
+	assert.Equal(t, "night", matchProfile(profiles, &profileEnv{
+		edids: []string{"c"},
+		now:   midnight,
+	}))
+}
+
+func Test_checkProfileName(t *testing.T) {
+	assert.NoError(t, checkProfileName("Home"))
+	assert.Error(t, checkProfileName(""))
+	assert.Error(t, checkProfileName("a/b"))
+}
+
+func TestManager_keepCurrentProfile(t *testing.T) {
+	m := newTestHeadlessManager(t)
+	mm := m.mm.(*headlessMonitorManager)
+	require.NoError(t, m.saveProfile("work", ""))
+	require.NoError(t, m.applyProfile("work"))
+	require.Equal(t, "work", m.CurrentCustomId)
+
+	// 保存对当前显示器的修改不清除当前配置
+	require.NoError(t, m.save())
+	assert.Equal(t, "work", m.CurrentCustomId)
+
+	// 显示器没有改变
+	m.resetCurrentProfile(m.getMonitorsId())
+	assert.Equal(t, "work", m.CurrentCustomId)
+
+	require.NoError(t, mm.hotplug("HDMI-1", false, ""))
+	m.delayApplyTimer.Stop()
+	m.delayApplyConfig()
+	assert.Equal(t, "", m.CurrentCustomId)
+}
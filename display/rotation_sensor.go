@@ -0,0 +1,270 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package display
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/godbus/dbus"
+	ofdbus "github.com/linuxdeepin/go-dbus-factory/org.freedesktop.dbus"
+	"github.com/linuxdeepin/go-lib/dbusutil"
+	"github.com/linuxdeepin/go-lib/strv"
+	"github.com/linuxdeepin/go-x11-client/ext/randr"
+)
+
+// rotationSensor 重力传感器，用于根据设备的方向自动旋转内置显示器
+type rotationSensor interface {
+	// getRotation 获取设备当前的方向对应的旋转值
+	getRotation() (uint16, error)
+	// listen 开始监听方向改变，方向改变时调用 cb
+	listen(cb func(rotation uint16)) error
+	// release 释放传感器，锁定旋转或者退出时调用，再次 getRotation 时重新申请
+	release()
+}
+
+// iio-sensor-proxy 的 AccelerometerOrientation 属性值对应的旋转值，
+// 比如 left-up 表示屏幕的左边朝上，需要逆时针旋转 90 度。
+var hadessOrientationRotation = map[string]uint16{
+	"normal":    randr.RotationRotate0,
+	"left-up":   randr.RotationRotate90,
+	"bottom-up": randr.RotationRotate180,
+	"right-up":  randr.RotationRotate270,
+}
+
+func isValidAutoRotation(rotation uint16) bool {
+	switch rotation {
+	case randr.RotationRotate0, randr.RotationRotate90, randr.RotationRotate180, randr.RotationRotate270:
+		return true
+	}
+	return false
+}
+
+// dbusNameAvailable 判断服务在总线上是否存在或者可以被激活
+func dbusNameAvailable(conn *dbus.Conn, name string) bool {
+	var hasOwner bool
+	err := conn.BusObject().Call("org.freedesktop.DBus.NameHasOwner", 0, name).Store(&hasOwner)
+	if err == nil && hasOwner {
+		return true
+	}
+	var names []string
+	err = conn.BusObject().Call("org.freedesktop.DBus.ListActivatableNames", 0).Store(&names)
+	if err != nil {
+		logger.Warning(err)
+		return false
+	}
+	return strv.Strv(names).Contains(name)
+}
+
+// watchNameOwner 监听服务的 NameOwnerChanged 信号，服务重启或者退出时调用 cb
+func watchNameOwner(conn *dbus.Conn, sigLoop *dbusutil.SignalLoop, name string, cb func(newOwner string)) error {
+	dbusDaemon := ofdbus.NewDBus(conn)
+	dbusDaemon.InitSignalExt(sigLoop, true)
+	_, err := dbusDaemon.ConnectNameOwnerChanged(func(sigName, oldOwner, newOwner string) {
+		if sigName == name {
+			cb(newOwner)
+		}
+	})
+	return err
+}
+
+// newRotationSensor 优先使用厂商的 com.deepin.SensorProxy 服务，不存在时使用 iio-sensor-proxy
+func (m *Manager) newRotationSensor() rotationSensor {
+	if m.debugOpts.sensorProxyOnSessionBus {
+		return newHadessRotationSensor(m.service.Conn(), m.sessionSigLoop)
+	}
+	if dbusNameAvailable(m.sysBus, sensorProxyInterface) {
+		logger.Debug("use rotation sensor", sensorProxyInterface)
+		return &deepinRotationSensor{conn: m.sysBus}
+	}
+	logger.Debug("use rotation sensor", hadessSensorProxyService)
+	return newHadessRotationSensor(m.sysBus, m.sysSigLoop)
+}
+
+// deepinRotationSensor 厂商提供的 com.deepin.SensorProxy 服务
+type deepinRotationSensor struct {
+	conn *dbus.Conn
+}
+
+func (s *deepinRotationSensor) getRotation() (uint16, error) {
+	screenStatus := "normal"
+	err := s.conn.Object(sensorProxyInterface, sensorProxyPath).Call(sensorProxyGetScreenStatus, 0).Store(&screenStatus)
+	if err != nil {
+		return 0, err
+	}
+	rotation, ok := rotationScreenValue[strings.TrimSpace(screenStatus)]
+	if !ok {
+		return 0, fmt.Errorf("unknown screen status %q", screenStatus)
+	}
+	return rotation, nil
+}
+
+func (s *deepinRotationSensor) listen(cb func(rotation uint16)) error {
+	err := s.conn.BusObject().AddMatchSignal(sensorProxyInterface, sensorProxySignalName,
+		dbus.WithMatchObjectPath(sensorProxyPath), dbus.WithMatchSender(sensorProxyInterface)).Err
+	if err != nil {
+		return err
+	}
+
+	signalCh := make(chan *dbus.Signal, 10)
+	s.conn.Signal(signalCh)
+	go func() {
+		for sig := range signalCh {
+			if sig.Path != sensorProxyPath || sig.Name != sensorProxySignal {
+				continue
+			}
+
+			var screenStatus string
+			err := dbus.Store(sig.Body, &screenStatus)
+			if err != nil {
+				logger.Warning("call dbus.Store err:", err)
+				continue
+			}
+			rotation, ok := rotationScreenValue[strings.TrimSpace(screenStatus)]
+			if ok {
+				cb(rotation)
+			}
+		}
+	}()
+	return nil
+}
+
+func (s *deepinRotationSensor) release() {}
+
+// hadessRotationSensor iio-sensor-proxy 提供的 net.hadess.SensorProxy 服务，
+// 需要先调用 ClaimAccelerometer，AccelerometerOrientation 属性才会更新。
+type hadessRotationSensor struct {
+	conn    *dbus.Conn
+	sigLoop *dbusutil.SignalLoop
+	mu      sync.Mutex
+	claimed bool
+	// iio-sensor-proxy 重启后是否需要重新申请，release 后为 false
+	wanted bool
+}
+
+func newHadessRotationSensor(conn *dbus.Conn, sigLoop *dbusutil.SignalLoop) *hadessRotationSensor {
+	return &hadessRotationSensor{
+		conn:    conn,
+		sigLoop: sigLoop,
+	}
+}
+
+func (s *hadessRotationSensor) sensor() dbus.BusObject {
+	return s.conn.Object(hadessSensorProxyService, hadessSensorProxyPath)
+}
+
+func (s *hadessRotationSensor) claim() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.wanted = true
+	if s.claimed {
+		return nil
+	}
+
+	hasAccel, err := s.sensor().GetProperty(hadessSensorProxyInterface + ".HasAccelerometer")
+	if err != nil {
+		return err
+	}
+	if v, _ := hasAccel.Value().(bool); !v {
+		return errors.New("no accelerometer")
+	}
+	err = s.sensor().Call(hadessSensorProxyInterface+".ClaimAccelerometer", 0).Err
+	if err != nil {
+		return err
+	}
+	s.claimed = true
+	return nil
+}
+
+func (s *hadessRotationSensor) release() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.wanted = false
+	if !s.claimed {
+		return
+	}
+	s.claimed = false
+	err := s.sensor().Call(hadessSensorProxyInterface+".ReleaseAccelerometer", 0).Err
+	if err != nil {
+		logger.Warning("release accelerometer failed:", err)
+	}
+}
+
+// handleOwnerChanged iio-sensor-proxy 退出时申请随之失效，重新启动后需要重新申请
+func (s *hadessRotationSensor) handleOwnerChanged(newOwner string, cb func(rotation uint16)) {
+	s.mu.Lock()
+	s.claimed = false
+	wanted := s.wanted
+	s.mu.Unlock()
+	if newOwner == "" || !wanted {
+		return
+	}
+
+	logger.Debug("iio-sensor-proxy restarted, claim accelerometer again")
+	rotation, err := s.getRotation()
+	if err != nil {
+		logger.Warning("failed to get rotation:", err)
+		return
+	}
+	cb(rotation)
+}
+
+func (s *hadessRotationSensor) getRotation() (uint16, error) {
+	err := s.claim()
+	if err != nil {
+		return 0, err
+	}
+	orientation, err := s.sensor().GetProperty(hadessSensorProxyInterface + ".AccelerometerOrientation")
+	if err != nil {
+		return 0, err
+	}
+	value, _ := orientation.Value().(string)
+	rotation, ok := hadessOrientationRotation[value]
+	if !ok {
+		return 0, fmt.Errorf("unknown orientation %q", value)
+	}
+	return rotation, nil
+}
+
+func (s *hadessRotationSensor) listen(cb func(rotation uint16)) error {
+	err := s.conn.BusObject().AddMatchSignal(dbusPropsInterface, dbusPropsChangedSignalMember,
+		dbus.WithMatchObjectPath(hadessSensorProxyPath), dbus.WithMatchSender(hadessSensorProxyService)).Err
+	if err != nil {
+		return err
+	}
+	s.sigLoop.AddHandler(&dbusutil.SignalRule{
+		Path: hadessSensorProxyPath,
+		Name: dbusPropsInterface + "." + dbusPropsChangedSignalMember,
+	}, func(sig *dbus.Signal) {
+		var iface string
+		var changed map[string]dbus.Variant
+		var invalidated []string
+		err := dbus.Store(sig.Body, &iface, &changed, &invalidated)
+		if err != nil || iface != hadessSensorProxyInterface {
+			return
+		}
+		orientation, ok := changed["AccelerometerOrientation"]
+		if !ok {
+			return
+		}
+		value, _ := orientation.Value().(string)
+		rotation, ok := hadessOrientationRotation[value]
+		if !ok {
+			// undefined 表示设备平放等无法判断方向的情况，保持不变
+			logger.Debug("ignore orientation", value)
+			return
+		}
+		cb(rotation)
+	})
+	err = watchNameOwner(s.conn, s.sigLoop, hadessSensorProxyService, func(newOwner string) {
+		s.handleOwnerChanged(newOwner, cb)
+	})
+	if err != nil {
+		logger.Warning("failed to watch iio-sensor-proxy:", err)
+	}
+	return s.claim()
+}
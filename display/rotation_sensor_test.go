@@ -0,0 +1,134 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package display
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/linuxdeepin/go-x11-client/ext/randr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHadessOrientationRotation(t *testing.T) {
+	for _, rotation := range hadessOrientationRotation {
+		assert.True(t, isValidAutoRotation(rotation))
+	}
+	assert.Len(t, hadessOrientationRotation, 4)
+	_, ok := hadessOrientationRotation["undefined"]
+	assert.False(t, ok)
+	assert.False(t, isValidAutoRotation(randr.RotationReflectX))
+}
+
+func TestManager_keepAutoRotation(t *testing.T) {
+	builtin := &Monitor{ID: 1}
+	m := &Manager{builtinMonitor: builtin}
+
+	config := &SysMonitorConfig{Width: 1920, Height: 1080, Rotation: randr.RotationRotate0}
+	m.keepAutoRotation(config, builtin)
+	assert.Equal(t, uint16(randr.RotationRotate0), config.Rotation)
+
+	m.setAutoRotation(randr.RotationRotate90)
+	// 不是内置显示器
+	m.keepAutoRotation(config, &Monitor{ID: 2})
+	assert.Equal(t, uint16(randr.RotationRotate0), config.Rotation)
+
+	m.keepAutoRotation(config, builtin)
+	assert.Equal(t, &SysMonitorConfig{Width: 1080, Height: 1920, Rotation: randr.RotationRotate90}, config)
+
+	m.setAutoRotation(randr.RotationRotate270)
+	m.keepAutoRotation(config, builtin)
+	assert.Equal(t, &SysMonitorConfig{Width: 1080, Height: 1920, Rotation: randr.RotationRotate270}, config)
+
+	// 手动旋转后不再保持
+	builtin.changes = monitorChanges{monitorPropRotation: uint16(randr.RotationRotate0)}
+	config = &SysMonitorConfig{Width: 1920, Height: 1080, Rotation: randr.RotationRotate0}
+	m.keepAutoRotation(config, builtin)
+	assert.Equal(t, uint16(randr.RotationRotate0), config.Rotation)
+	assert.Equal(t, uint16(0), m.getAutoRotation())
+}
+
+func TestManager_saveAfterAutoRotation(t *testing.T) {
+	m := newTestHeadlessManager(t)
+	builtin := m.getBuiltinMonitor()
+	require.NotNil(t, builtin)
+	require.Equal(t, "eDP-1", builtin.Name)
+	monitorsId := m.getMonitorsId()
+
+	m.startBuildInScreenRotation(randr.RotationRotate90)
+	assert.Equal(t, uint16(randr.RotationRotate90), builtin.Rotation)
+	assert.Equal(t, uint16(randr.RotationRotate90), m.getAutoRotation())
+
+	// 自动旋转后修改其他显示器并保存，配置中仍然是原来的方向
+	hdmi := m.getConnectedMonitors().GetByName("HDMI-1")
+	require.NotNil(t, hdmi)
+	require.Nil(t, hdmi.SetPosition(builtin.X+int16(builtin.Width)+30, 0))
+	require.NoError(t, m.applyChanges())
+	assert.Equal(t, uint16(randr.RotationRotate90), builtin.Rotation)
+	require.NoError(t, m.save())
+	// 系统级配置通过 com.deepin.system.Display 保存
+	assert.Contains(t, _testSysDisplay.getConfig(), builtin.uuid)
+
+	configs := m.getSuitableSysMonitorConfigs(m.DisplayMode, monitorsId, m.getConnectedMonitors())
+	config := configs.getByUuidAndName(builtin.uuid, builtin.Name)
+	require.NotNil(t, config)
+	assert.Equal(t, uint16(randr.RotationRotate0), config.Rotation)
+	assert.Equal(t, uint16(1920), config.Width)
+	assert.Equal(t, uint16(1080), config.Height)
+}
+
+type testRotationSensor struct {
+	mu       sync.Mutex
+	claimed  bool
+	released int
+}
+
+func (s *testRotationSensor) getRotation() (uint16, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.claimed = true
+	return randr.RotationRotate0, nil
+}
+
+func (s *testRotationSensor) listen(cb func(rotation uint16)) error {
+	return nil
+}
+
+func (s *testRotationSensor) release() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.claimed = false
+	s.released++
+}
+
+func (s *testRotationSensor) isClaimed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.claimed
+}
+
+func TestManager_setRotationLockedReleaseSensor(t *testing.T) {
+	m := newTestHeadlessManager(t)
+	require.NotNil(t, m.getBuiltinMonitor())
+	sensor := &testRotationSensor{claimed: true}
+	m.rotationSensor = sensor
+
+	require.NoError(t, m.setRotationLocked(true))
+	assert.False(t, sensor.isClaimed())
+	assert.Equal(t, 1, sensor.released)
+
+	// 锁定时不重新申请
+	m.initScreenRotation()
+	assert.False(t, sensor.isClaimed())
+
+	require.NoError(t, m.setRotationLocked(false))
+	assert.Eventually(t, sensor.isClaimed, time.Second, 10*time.Millisecond)
+
+	m.destroy()
+	assert.False(t, sensor.isClaimed())
+	assert.Equal(t, 2, sensor.released)
+}
@@ -0,0 +1,286 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package display
+
+import (
+	"math"
+)
+
+const (
+	scaleFactorsKeyAll = "ALL"
+
+	// 变换比例的精度，避免浮点误差导致不必要的变换
+	transformScalePrecision = 1000
+
+	filterNearest  = "nearest"
+	filterBilinear = "bilinear"
+)
+
+// getMonitorScaleFactor 获取显示器的缩放比例，没有单独设置的使用 ALL 的值，都没有则为 1。
+func getMonitorScaleFactor(factors map[string]float64, name string) float64 {
+	if factor, ok := factors[name]; ok && factor > 0 {
+		return factor
+	}
+	if factor, ok := factors[scaleFactorsKeyAll]; ok && factor > 0 {
+		return factor
+	}
+	return 1
+}
+
+// getScreenScaleFactor 获取整个 X 屏幕渲染时使用的缩放比例，取显示器中最大的，
+// 缩放比例较小的显示器通过 crtc 变换缩小显示。
+func getScreenScaleFactor(factors map[string]float64, names []string) float64 {
+	screenScale := 0.0
+	for _, name := range names {
+		factor := getMonitorScaleFactor(factors, name)
+		if factor > screenScale {
+			screenScale = factor
+		}
+	}
+	if screenScale == 0 {
+		return 1
+	}
+	return screenScale
+}
+
+// crtcTransform crtc 的缩放变换，比例大于 1 表示以更大的尺寸渲染然后缩小到显示器上，效果同 xrandr --scale。
+// 零值表示不变换。
+type crtcTransform struct {
+	scaleX float64
+	scaleY float64
+	// 缩放后超出屏幕的宽和高，画面向右下平移一半，超出的部分显示为黑边，用于复制模式下保持宽高比
+	padWidth  uint16
+	padHeight uint16
+}
+
+var identityTransform = crtcTransform{scaleX: 1, scaleY: 1}
+
+func newScaleTransform(scale float64) crtcTransform {
+	return crtcTransform{scaleX: scale, scaleY: scale}
+}
+
+// normalize 把无效的比例改为 1
+func (t crtcTransform) normalize() crtcTransform {
+	if t.scaleX <= 0 {
+		t.scaleX = 1
+	}
+	if t.scaleY <= 0 {
+		t.scaleY = 1
+	}
+	return t
+}
+
+func (t crtcTransform) isIdentity() bool {
+	t = t.normalize()
+	return t.scaleX == 1 && t.scaleY == 1 && t.padWidth == 0 && t.padHeight == 0
+}
+
+// roundTransformScale 按 transformScalePrecision 的精度取整
+func roundTransformScale(scale float64) float64 {
+	scale = math.Round(scale*transformScalePrecision) / transformScalePrecision
+	if scale <= 0 {
+		return 1
+	}
+	return scale
+}
+
+// getTransformScale 获取显示器 crtc 变换的比例，大于 1 表示以更大的尺寸渲染然后缩小到显示器上，
+// 效果同 xrandr --scale。
+func getTransformScale(screenScale, monitorScale float64) float64 {
+	if screenScale <= 0 || monitorScale <= 0 {
+		return 1
+	}
+	return roundTransformScale(screenScale / monitorScale)
+}
+
+// getScaledSize 获取经过变换后显示器在屏幕上占据的尺寸，不包括黑边
+func getScaledSize(width, height uint16, transform crtcTransform) (uint16, uint16) {
+	if transform.isIdentity() {
+		return width, height
+	}
+	transform = transform.normalize()
+	return subUint16(scaleUint16(width, transform.scaleX), transform.padWidth),
+		subUint16(scaleUint16(height, transform.scaleY), transform.padHeight)
+}
+
+// getUnscaledSize 根据 crtc 变换后的扫描尺寸获取变换之前的尺寸，扫描尺寸包括黑边
+func getUnscaledSize(width, height uint16, transform crtcTransform) (uint16, uint16) {
+	if transform.isIdentity() {
+		return width, height
+	}
+	transform = transform.normalize()
+	return scaleUint16(width, 1/transform.scaleX), scaleUint16(height, 1/transform.scaleY)
+}
+
+func subUint16(a, b uint16) uint16 {
+	if a < b {
+		return 0
+	}
+	return a - b
+}
+
+
+func scaleUint16(v uint16, scale float64) uint16 {
+	result := math.Round(float64(v) * scale)
+	if result > math.MaxUint16 {
+		return math.MaxUint16
+	}
+	return uint16(result)
+}
+
+// toFixed 转换为 render 扩展的 16.16 定点数
+func toFixed(v float64) int32 {
+	return int32(math.Round(v * 65536))
+}
+
+// getScaleMatrix 获取缩放变换的矩阵，元素为 16.16 定点数。有黑边时通过平移使画面居中。
+func getScaleMatrix(transform crtcTransform) [3][3]int32 {
+	transform = transform.normalize()
+	one := toFixed(1)
+	return [3][3]int32{
+		{toFixed(transform.scaleX), 0, -toFixed(float64(transform.padWidth / 2))},
+		{0, toFixed(transform.scaleY), -toFixed(float64(transform.padHeight / 2))},
+		{0, 0, one},
+	}
+}
+
+// getScaleFilter 获取变换使用的过滤器，非整数倍缩放时使用 bilinear 使画面更平滑。
+func getScaleFilter(transform crtcTransform) string {
+	transform = transform.normalize()
+	if transform.scaleX == math.Trunc(transform.scaleX) && transform.scaleY == math.Trunc(transform.scaleY) {
+		return filterNearest
+	}
+	return filterBilinear
+}
+
+// getTransformScales 根据缩放比例配置计算启用的显示器 monitors 的缩放比例和变换比例，键是显示器 id。
+// 在 wayland 下和复制模式下不使用这种变换，变换比例都为 1。
+func getTransformScales(factors map[string]float64, monitors Monitors, displayMode byte) (scales map[uint32]float64, transforms map[uint32]crtcTransform) {
+	names := make([]string, len(monitors))
+	for i, monitor := range monitors {
+		names[i] = monitor.Name
+	}
+	screenScale := getScreenScaleFactor(factors, names)
+
+	scales = make(map[uint32]float64, len(monitors))
+	transforms = make(map[uint32]crtcTransform, len(monitors))
+	for _, monitor := range monitors {
+		scale := getMonitorScaleFactor(factors, monitor.Name)
+		scales[monitor.ID] = scale
+		if _useWayland || displayMode == DisplayModeMirror {
+			transforms[monitor.ID] = identityTransform
+		} else {
+			transforms[monitor.ID] = newScaleTransform(getTransformScale(screenScale, scale))
+		}
+	}
+	return
+}
+
+// getScaledSize 获取显示器经过旋转和变换后在屏幕上占据的尺寸
+func (m *Monitor) getScaledSize() (uint16, uint16) {
+	width := m.CurrentMode.Width
+	height := m.CurrentMode.Height
+	swapWidthHeightWithRotation(m.Rotation, &width, &height)
+	return getScaledSize(width, height, m.getTransform())
+}
+
+func (m *Monitor) getTransform() crtcTransform {
+	return m.transform.normalize()
+}
+
+// updateMonitorsScale 在应用显示器配置之前更新 monitorMap 中显示器的缩放比例和变换，
+// 复制模式下根据复制的源显示器计算变换。
+func (m *Manager) updateMonitorsScale(monitorMap map[uint32]*Monitor, displayMode byte) (changed bool) {
+	m.sysConfig.mu.Lock()
+	factors := m.sysConfig.Config.ScaleFactors
+	m.sysConfig.mu.Unlock()
+
+	var enabledMonitors Monitors
+	for _, monitor := range getConnectedMonitors(monitorMap) {
+		if monitor.Enabled {
+			enabledMonitors = append(enabledMonitors, monitor)
+		}
+	}
+	scales, transforms := getTransformScales(factors, enabledMonitors, displayMode)
+	if displayMode == DisplayModeMirror && !_useWayland {
+		transforms = m.updateMirrorTransforms(enabledMonitors)
+	}
+	for _, monitor := range monitorMap {
+		scale, ok := scales[monitor.ID]
+		if !ok {
+			// 禁用的显示器
+			scale = getMonitorScaleFactor(factors, monitor.Name)
+		}
+		transform, ok := transforms[monitor.ID]
+		if !ok {
+			transform = identityTransform
+		}
+		transform = transform.normalize()
+		if monitor.getTransform() != transform {
+			changed = true
+		}
+		monitor.Scale = scale
+		monitor.transform = transform
+	}
+	return
+}
+
+// syncMonitorsScale 在应用显示器配置之后，把缩放比例同步到 Manager 中的显示器对象
+func (m *Manager) syncMonitorsScale(monitorMap map[uint32]*Monitor) {
+	m.monitorMapMu.Lock()
+	defer m.monitorMapMu.Unlock()
+	for id, monitorCp := range monitorMap {
+		monitor, ok := m.monitorMap[id]
+		if !ok || monitor == monitorCp {
+			continue
+		}
+		monitor.PropsMu.Lock()
+		monitor.transform = monitorCp.transform
+		monitor.setPropScale(monitorCp.Scale)
+		monitor.PropsMu.Unlock()
+	}
+}
+
+// applyMonitorsScale 缩放比例改变后，如果显示器的变换需要改变，重新应用当前的显示器配置。
+func (m *Manager) applyMonitorsScale() error {
+	if _useWayland {
+		return nil
+	}
+	m.PropsMu.RLock()
+	displayMode := m.DisplayMode
+	primary := m.Primary
+	m.PropsMu.RUnlock()
+
+	monitorMap := m.cloneMonitorMap()
+	if len(monitorMap) == 0 {
+		return nil
+	}
+	if !m.updateMonitorsScale(monitorMap, displayMode) {
+		m.syncMonitorsScale(monitorMap)
+		return nil
+	}
+
+	logger.Debug("monitors transform scale changed, reapply")
+	monitors := getConnectedMonitors(monitorMap)
+	if displayMode != DisplayModeMirror {
+		// 显示器占据的尺寸改变了，需要重新摆放
+		configs := toSysMonitorConfigs(monitors, primary)
+		err := m.packConfigsLayout(configs, monitors)
+		if err != nil {
+			return err
+		}
+		for _, config := range configs {
+			monitor := monitors.GetByUuidAndName(config.UUID, config.Name)
+			if monitor != nil {
+				monitor.X = config.X
+				monitor.Y = config.Y
+			}
+		}
+	}
+	monitorsId := monitors.getMonitorsId()
+	m.applySaveMu.Lock()
+	defer m.applySaveMu.Unlock()
+	return m.apply(monitorsId, monitorMap, nil, 0, displayMode)
+}
@@ -0,0 +1,114 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package display
+
+import (
+	"testing"
+
+	"github.com/linuxdeepin/go-x11-client/ext/randr"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_getMonitorScaleFactor(t *testing.T) {
+	factors := map[string]float64{"eDP-1": 2, "ALL": 1.25, "DP-1": 0}
+	assert.Equal(t, 2.0, getMonitorScaleFactor(factors, "eDP-1"))
+	assert.Equal(t, 1.25, getMonitorScaleFactor(factors, "HDMI-1"))
+	assert.Equal(t, 1.25, getMonitorScaleFactor(factors, "DP-1"))
+	assert.Equal(t, 1.0, getMonitorScaleFactor(nil, "eDP-1"))
+}
+
+func Test_getScreenScaleFactor(t *testing.T) {
+	factors := map[string]float64{"eDP-1": 2, "HDMI-1": 1}
+	assert.Equal(t, 2.0, getScreenScaleFactor(factors, []string{"eDP-1", "HDMI-1"}))
+	assert.Equal(t, 1.0, getScreenScaleFactor(factors, []string{"HDMI-1"}))
+	assert.Equal(t, 1.0, getScreenScaleFactor(factors, nil))
+}
+
+func Test_getTransformScale(t *testing.T) {
+	assert.Equal(t, 1.0, getTransformScale(2, 2))
+	assert.Equal(t, 2.0, getTransformScale(2, 1))
+	assert.Equal(t, 1.333, getTransformScale(2, 1.5))
+	assert.Equal(t, 1.0, getTransformScale(2, 0))
+}
+
+func Test_getScaledSize(t *testing.T) {
+	w, h := getScaledSize(1920, 1080, identityTransform)
+	assert.Equal(t, uint16(1920), w)
+	assert.Equal(t, uint16(1080), h)
+
+	w, h = getScaledSize(1920, 1080, newScaleTransform(2))
+	assert.Equal(t, uint16(3840), w)
+	assert.Equal(t, uint16(2160), h)
+
+	w, h = getScaledSize(1920, 1080, newScaleTransform(1.333))
+	assert.Equal(t, uint16(2559), w)
+	assert.Equal(t, uint16(1440), h)
+
+	w, _ = getScaledSize(40000, 1080, newScaleTransform(2))
+	assert.Equal(t, uint16(65535), w)
+
+	w, h = getUnscaledSize(2559, 1440, newScaleTransform(1.333))
+	assert.Equal(t, uint16(1920), w)
+	assert.Equal(t, uint16(1080), h)
+
+	w, h = getScaledSize(1280, 1024, crtcTransform{scaleX: 1.5, scaleY: 1.055})
+	assert.Equal(t, uint16(1920), w)
+	assert.Equal(t, uint16(1080), h)
+
+	w, h = getScaledSize(1280, 1024, crtcTransform{})
+	assert.Equal(t, uint16(1280), w)
+	assert.Equal(t, uint16(1024), h)
+
+	// 黑边不占据屏幕
+	w, h = getScaledSize(1024, 768, crtcTransform{scaleX: 1.875, scaleY: 1.875, padHeight: 360})
+	assert.Equal(t, uint16(1920), w)
+	assert.Equal(t, uint16(1080), h)
+	w, h = getUnscaledSize(1920, 1440, crtcTransform{scaleX: 1.875, scaleY: 1.875, padHeight: 360})
+	assert.Equal(t, uint16(1024), w)
+	assert.Equal(t, uint16(768), h)
+}
+
+func Test_getScaleMatrix(t *testing.T) {
+	assert.Equal(t, [3][3]int32{
+		{0x20000, 0, 0},
+		{0, 0x20000, 0},
+		{0, 0, 0x10000},
+	}, getScaleMatrix(newScaleTransform(2)))
+	matrix := getScaleMatrix(crtcTransform{scaleX: 1.5, scaleY: 2})
+	assert.Equal(t, int32(0x18000), matrix[0][0])
+	assert.Equal(t, int32(0x20000), matrix[1][1])
+	// 有黑边时平移一半
+	matrix = getScaleMatrix(crtcTransform{scaleX: 1.875, scaleY: 1.875, padHeight: 360})
+	assert.Equal(t, int32(0), matrix[0][2])
+	assert.Equal(t, int32(-180*0x10000), matrix[1][2])
+
+	assert.Equal(t, filterNearest, getScaleFilter(newScaleTransform(2)))
+	assert.Equal(t, filterNearest, getScaleFilter(crtcTransform{}))
+	assert.Equal(t, filterBilinear, getScaleFilter(crtcTransform{scaleX: 2, scaleY: 1.5}))
+}
+
+func Test_getTransformScales(t *testing.T) {
+	monitors := Monitors{
+		{ID: 1, Name: "eDP-1"},
+		{ID: 2, Name: "HDMI-1"},
+	}
+	factors := map[string]float64{"eDP-1": 2, "HDMI-1": 1}
+
+	scales, transforms := getTransformScales(factors, monitors, DisplayModeExtend)
+	assert.Equal(t, map[uint32]float64{1: 2, 2: 1}, scales)
+	assert.Equal(t, map[uint32]crtcTransform{1: identityTransform, 2: newScaleTransform(2)}, transforms)
+
+	_, transforms = getTransformScales(factors, monitors, DisplayModeMirror)
+	assert.Equal(t, map[uint32]crtcTransform{1: identityTransform, 2: identityTransform}, transforms)
+
+	monitor := &Monitor{
+		CurrentMode: ModeInfo{Width: 1920, Height: 1080},
+		Rotation:    randr.RotationRotate90,
+		transform:   newScaleTransform(2),
+	}
+	w, h := monitor.getScaledSize()
+	assert.Equal(t, uint16(2160), w)
+	assert.Equal(t, uint16(3840), h)
+}
@@ -0,0 +1,179 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package display
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 太阳高度角在 [solarElevationNight, solarElevationDay] 之间时色温逐渐过渡，与 redshift 的默认值相同
+const (
+	solarElevationDay   = 3.0
+	solarElevationNight = -6.0
+)
+
+func degToRad(deg float64) float64 {
+	return deg * math.Pi / 180
+}
+
+func radToDeg(rad float64) float64 {
+	return rad * 180 / math.Pi
+}
+
+// solarElevation 计算 t 时刻在纬度 lat、经度 lon 处的太阳高度角，单位是度。
+// 使用简化的太阳位置算法，误差在 1 度以内，对于调节色温足够了。
+func solarElevation(t time.Time, lat, lon float64) float64 {
+	// 从 J2000.0 起的天数
+	jd := float64(t.UTC().UnixNano())/float64(24*time.Hour) + 2440587.5
+	n := jd - 2451545.0
+
+	// 平近点角和平黄经
+	g := degToRad(math.Mod(357.529+0.98560028*n, 360))
+	q := math.Mod(280.459+0.98564736*n, 360)
+	// 黄经和黄赤交角
+	l := degToRad(q + 1.915*math.Sin(g) + 0.020*math.Sin(2*g))
+	e := degToRad(23.439 - 0.00000036*n)
+
+	ra := math.Atan2(math.Cos(e)*math.Sin(l), math.Cos(l))
+	decl := math.Asin(math.Sin(e) * math.Sin(l))
+
+	// 格林尼治平恒星时换算成当地时角
+	gmst := math.Mod(18.697374558+24.06570982441908*n, 24)
+	hourAngle := degToRad(gmst*15+lon) - ra
+
+	latRad := degToRad(lat)
+	sinElev := math.Sin(latRad)*math.Sin(decl) + math.Cos(latRad)*math.Cos(decl)*math.Cos(hourAngle)
+	return radToDeg(math.Asin(sinElev))
+}
+
+// solarNightRatio 根据太阳高度角计算夜晚的程度，0 表示白天，1 表示夜晚
+func solarNightRatio(elevation float64) float64 {
+	if elevation >= solarElevationDay {
+		return 0
+	}
+	if elevation <= solarElevationNight {
+		return 1
+	}
+	return (solarElevationDay - elevation) / (solarElevationDay - solarElevationNight)
+}
+
+// getTimezoneName 获取本地时区的名称，比如 Asia/Shanghai
+func getTimezoneName() (string, error) {
+	if tz := os.Getenv("TZ"); tz != "" {
+		return strings.TrimPrefix(tz, ":"), nil
+	}
+	content, err := ioutil.ReadFile("/etc/timezone")
+	if err == nil {
+		name := strings.TrimSpace(string(content))
+		if name != "" {
+			return name, nil
+		}
+	}
+	target, err := filepath.EvalSymlinks("/etc/localtime")
+	if err != nil {
+		return "", err
+	}
+	const zoneInfoDir = "/zoneinfo/"
+	idx := strings.LastIndex(target, zoneInfoDir)
+	if idx == -1 {
+		return "", fmt.Errorf("unknown timezone file %q", target)
+	}
+	return target[idx+len(zoneInfoDir):], nil
+}
+
+// getTimezoneLocation 从 zone1970.tab 或 zone.tab 中查找时区代表城市的经纬度
+func getTimezoneLocation(name string) (lat, lon float64, err error) {
+	for _, filename := range []string{"/usr/share/zoneinfo/zone1970.tab", "/usr/share/zoneinfo/zone.tab"} {
+		f, err := os.Open(filename)
+		if err != nil {
+			continue
+		}
+		lat, lon, err = findZoneTabLocation(f, name)
+		_ = f.Close()
+		if err == nil {
+			return lat, lon, nil
+		}
+	}
+	return 0, 0, fmt.Errorf("not found location of timezone %q", name)
+}
+
+// findZoneTabLocation 在 zone.tab 格式的内容中查找时区的经纬度
+func findZoneTabLocation(r io.Reader, name string) (lat, lon float64, err error) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Split(line, "\t")
+		if len(fields) < 3 || fields[2] != name {
+			continue
+		}
+		return parseIso6709(fields[1])
+	}
+	err = scanner.Err()
+	if err == nil {
+		err = fmt.Errorf("not found timezone %q", name)
+	}
+	return 0, 0, err
+}
+
+// parseIso6709 解析 zone.tab 中的坐标，格式是 ±DDMM±DDDMM 或 ±DDMMSS±DDDMMSS
+func parseIso6709(s string) (lat, lon float64, err error) {
+	if len(s) < 2 {
+		return 0, 0, fmt.Errorf("invalid coordinates %q", s)
+	}
+	idx := strings.IndexAny(s[1:], "+-")
+	if idx == -1 {
+		return 0, 0, fmt.Errorf("invalid coordinates %q", s)
+	}
+	lat, err = parseIso6709Part(s[:idx+1], 2)
+	if err != nil {
+		return 0, 0, err
+	}
+	lon, err = parseIso6709Part(s[idx+1:], 3)
+	if err != nil {
+		return 0, 0, err
+	}
+	return lat, lon, nil
+}
+
+func parseIso6709Part(s string, degDigits int) (float64, error) {
+	if len(s) != 1+degDigits+2 && len(s) != 1+degDigits+4 {
+		return 0, errors.New("invalid coordinate " + s)
+	}
+	sign := 1.0
+	switch s[0] {
+	case '-':
+		sign = -1
+	case '+':
+	default:
+		return 0, errors.New("invalid coordinate " + s)
+	}
+	digits := s[1:]
+	var parts []float64
+	for _, part := range []string{digits[:degDigits], digits[degDigits : degDigits+2], digits[degDigits+2:]} {
+		if part == "" {
+			parts = append(parts, 0)
+			continue
+		}
+		v, err := strconv.Atoi(part)
+		if err != nil {
+			return 0, err
+		}
+		parts = append(parts, float64(v))
+	}
+	return sign * (parts[0] + parts[1]/60 + parts[2]/3600), nil
+}
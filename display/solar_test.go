@@ -0,0 +1,64 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package display
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_solarElevation(t *testing.T) {
+	tests := []struct {
+		time     string
+		lat, lon float64
+		want     float64
+	}{
+		// 北京夏至正午
+		{"2021-06-21T04:12:00Z", 39.9, 116.4, 73.5},
+		// 北京夏至午夜
+		{"2021-06-21T16:00:00Z", 39.9, 116.4, -26.6},
+		// 春分时赤道上的正午
+		{"2021-03-20T12:07:00Z", 0, 0, 89.9},
+		// 柏林冬至正午
+		{"2021-12-21T11:00:00Z", 52.5, 13.4, 14.1},
+	}
+	for _, test := range tests {
+		tm, err := time.Parse(time.RFC3339, test.time)
+		require.NoError(t, err)
+		assert.InDelta(t, test.want, solarElevation(tm, test.lat, test.lon), 0.5, test.time)
+	}
+}
+
+func Test_solarNightRatio(t *testing.T) {
+	assert.Equal(t, 0.0, solarNightRatio(10))
+	assert.Equal(t, 1.0, solarNightRatio(-10))
+	assert.InDelta(t, 1.0/3, solarNightRatio(0), 0.001)
+}
+
+func Test_findZoneTabLocation(t *testing.T) {
+	const zoneTab = `# tzdb timezone descriptions
+CN	+3114+12128	Asia/Shanghai	Beijing Time
+US	+404251-0740023	America/New_York	Eastern (most areas)
+`
+	lat, lon, err := findZoneTabLocation(strings.NewReader(zoneTab), "Asia/Shanghai")
+	require.NoError(t, err)
+	assert.InDelta(t, 31.233, lat, 0.001)
+	assert.InDelta(t, 121.467, lon, 0.001)
+
+	lat, lon, err = findZoneTabLocation(strings.NewReader(zoneTab), "America/New_York")
+	require.NoError(t, err)
+	assert.InDelta(t, 40.714, lat, 0.001)
+	assert.InDelta(t, -74.006, lon, 0.001)
+
+	_, _, err = findZoneTabLocation(strings.NewReader(zoneTab), "Europe/Berlin")
+	assert.NotNil(t, err)
+
+	_, _, err = parseIso6709("+31-121")
+	assert.NotNil(t, err)
+}
@@ -99,6 +99,27 @@ func uint16SliceEqual(v1, v2 []uint16) bool {
 	return true
 }
 
+func uint16SliceContains(slice []uint16, value uint16) bool {
+	for _, v := range slice {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func uint32SliceEqual(v1, v2 []uint32) bool {
+	if len(v1) != len(v2) {
+		return false
+	}
+	for i, e1 := range v1 {
+		if e1 != v2[i] {
+			return false
+		}
+	}
+	return true
+}
+
 func objPathsEqual(v1, v2 []dbus.ObjectPath) bool {
 	if len(v1) != len(v2) {
 		return false
@@ -0,0 +1,91 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package display
+
+import "errors"
+
+// 可变刷新率（Adaptive Sync）策略
+const (
+	vrrPolicyNever          = "never"
+	vrrPolicyAlways         = "always"
+	vrrPolicyFullscreenOnly = "fullscreen-only"
+)
+
+// errVrrUnsupported 显示服务不支持设置 VRR 策略
+var errVrrUnsupported = errors.New("setting vrr policy is not supported")
+
+func isValidVrrPolicy(policy string) bool {
+	switch policy {
+	case vrrPolicyNever, vrrPolicyAlways, vrrPolicyFullscreenOnly:
+		return true
+	}
+	return false
+}
+
+// normalizeVrrPolicy 配置中没有设置或者无效的策略都当作 never
+func normalizeVrrPolicy(policy string) string {
+	if !isValidVrrPolicy(policy) {
+		return vrrPolicyNever
+	}
+	return policy
+}
+
+// applyVrrPolicies 在应用显示器配置之后，按配置设置启用的显示器的 VRR 策略。
+// 不支持 VRR 的显示器或者显示服务不支持设置时策略总是 never。
+func (m *Manager) applyVrrPolicies(configs SysMonitorConfigs) {
+	supported := m.mm.supportVrrPolicy()
+	monitors := m.getConnectedMonitors()
+	for _, config := range configs {
+		if !config.Enabled {
+			continue
+		}
+		monitor := monitors.GetByUuidAndName(config.UUID, config.Name)
+		if monitor == nil {
+			monitor = monitors.GetByUuid(config.UUID)
+		}
+		if monitor == nil {
+			continue
+		}
+
+		monitor.PropsMu.RLock()
+		capable := monitor.VrrCapable
+		monitor.PropsMu.RUnlock()
+
+		policy := normalizeVrrPolicy(config.VrrPolicy)
+		if !capable || !supported {
+			policy = vrrPolicyNever
+		} else {
+			err := m.mm.setMonitorVrrPolicy(monitor, policy)
+			if err != nil {
+				logger.Warningf("set monitor %v vrr policy %q failed: %v", monitor.Name, policy, err)
+				continue
+			}
+		}
+
+		monitor.PropsMu.Lock()
+		monitor.setPropVrrPolicy(policy)
+		monitor.PropsMu.Unlock()
+	}
+}
+
+// updateMonitorVrrCapable 显示器是否支持 VRR 改变了，不再支持时策略改为 never
+func (m *Manager) updateMonitorVrrCapable(id uint32, capable bool) {
+	m.monitorMapMu.Lock()
+	monitor, ok := m.monitorMap[id]
+	m.monitorMapMu.Unlock()
+	if !ok {
+		return
+	}
+
+	monitor.PropsMu.Lock()
+	defer monitor.PropsMu.Unlock()
+	if !monitor.setPropVrrCapable(capable) {
+		return
+	}
+	logger.Debugf("monitor %v vrr capable changed: %v", monitor.Name, capable)
+	if !capable {
+		monitor.setPropVrrPolicy(vrrPolicyNever)
+	}
+}
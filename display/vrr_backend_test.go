@@ -0,0 +1,22 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package display
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_setMonitorVrrPolicyUnsupported(t *testing.T) {
+	monitor := &Monitor{ID: 1, Name: "DP-1"}
+	for _, mm := range []monitorManager{&xMonitorManager{}, &kMonitorManager{}} {
+		assert.False(t, mm.supportVrrPolicy())
+		assert.Equal(t, errVrrUnsupported, mm.setMonitorVrrPolicy(monitor, vrrPolicyAlways))
+		assert.Equal(t, errVrrUnsupported, mm.setMonitorVrrPolicy(monitor, vrrPolicyFullscreenOnly))
+		// 关闭 VRR 不需要做任何事情
+		assert.Nil(t, mm.setMonitorVrrPolicy(monitor, vrrPolicyNever))
+	}
+}
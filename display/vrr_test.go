@@ -0,0 +1,63 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package display
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_normalizeVrrPolicy(t *testing.T) {
+	assert.True(t, isValidVrrPolicy(vrrPolicyAlways))
+	assert.True(t, isValidVrrPolicy(vrrPolicyFullscreenOnly))
+	assert.False(t, isValidVrrPolicy(""))
+	assert.False(t, isValidVrrPolicy("auto"))
+
+	assert.Equal(t, vrrPolicyNever, normalizeVrrPolicy(""))
+	assert.Equal(t, vrrPolicyNever, normalizeVrrPolicy("auto"))
+	assert.Equal(t, vrrPolicyFullscreenOnly, normalizeVrrPolicy(vrrPolicyFullscreenOnly))
+}
+
+func TestSysMonitorConfig_VrrPolicy(t *testing.T) {
+	cfg := &SysMonitorConfig{Brightness: 1}
+	cfg.modify(monitorChanges{monitorPropVrrPolicy: vrrPolicyAlways})
+	assert.Equal(t, vrrPolicyAlways, cfg.VrrPolicy)
+
+	cfg.VrrPolicy = "auto"
+	cfg.fix()
+	assert.Equal(t, "", cfg.VrrPolicy)
+}
+
+func TestManager_headlessVrrPolicy(t *testing.T) {
+	m := newTestHeadlessManager(t)
+	mm := m.mm.(*headlessMonitorManager)
+	monitors := m.getConnectedMonitors()
+	edp := monitors.GetByName("eDP-1")
+	hdmi := monitors.GetByName("HDMI-1")
+	require.NotNil(t, edp)
+	require.NotNil(t, hdmi)
+	assert.True(t, hdmi.VrrCapable)
+	assert.Equal(t, vrrPolicyNever, hdmi.VrrPolicy)
+
+	// 不支持 VRR 的显示器只能设置为 never
+	assert.NotNil(t, edp.SetVrrPolicy(vrrPolicyAlways))
+	assert.NotNil(t, hdmi.SetVrrPolicy("auto"))
+	assert.False(t, m.HasChanged)
+
+	require.Nil(t, hdmi.SetVrrPolicy(vrrPolicyFullscreenOnly))
+	assert.True(t, m.HasChanged)
+	// 应用之前不设置
+	assert.Equal(t, vrrPolicyNever, mm.getVrrPolicy(hdmi.ID))
+
+	require.Nil(t, m.ApplyChanges())
+	assert.Equal(t, vrrPolicyFullscreenOnly, mm.getVrrPolicy(hdmi.ID))
+	assert.Equal(t, vrrPolicyFullscreenOnly, hdmi.VrrPolicy)
+
+	// 不再支持 VRR 时策略改为 never
+	m.updateMonitorVrrCapable(hdmi.ID, false)
+	assert.Equal(t, vrrPolicyNever, hdmi.VrrPolicy)
+}
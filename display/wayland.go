@@ -221,6 +221,7 @@ func (oi *KOutputInfo) toMonitorInfo(mm *kMonitorManager) *MonitorInfo {
 		logger.Warningf("decode monitor %v %v edid failed: %v", mi.ID, mi.Name, err)
 	} else {
 		mi.EDID = edid
+		mi.parseEdidInfo()
 	}
 	if logger.GetLogLevel() == log.LevelDebug {
 		logger.Debugf("monitor %v %v edid: %v", mi.ID, mi.Name, spew.Sdump(mi.EDID))
@@ -469,6 +470,23 @@ func (mm *kMonitorManager) setMonitorFillMode(monitor *Monitor, fillMode string)
 	return nil
 }
 
+// supportVrrPolicy KWayland 的 Output 接口没有提供设置 VRR 策略的方法
+func (mm *kMonitorManager) supportVrrPolicy() bool {
+	return false
+}
+
+func (mm *kMonitorManager) setMonitorVrrPolicy(monitor *Monitor, policy string) error {
+	if policy == vrrPolicyNever {
+		return nil
+	}
+	return errVrrUnsupported
+}
+
+// setMonitorOutputColor KWayland 的 Output 接口没有提供设置颜色输出的方法
+func (mm *kMonitorManager) setMonitorOutputColor(monitor *Monitor, cfg SysOutputColorConfig) error {
+	return errOutputColorUnsupported
+}
+
 func (mm *kMonitorManager) getMonitors() []*MonitorInfo {
 	mm.mu.Lock()
 	defer mm.mu.Unlock()
@@ -5,6 +5,7 @@
 package display
 
 import (
+	"encoding/binary"
 	"errors"
 	"fmt"
 	"math"
@@ -16,6 +17,7 @@ import (
 	x "github.com/linuxdeepin/go-x11-client"
 	"github.com/linuxdeepin/go-x11-client/ext/input"
 	"github.com/linuxdeepin/go-x11-client/ext/randr"
+	"github.com/linuxdeepin/go-x11-client/ext/render"
 	"github.com/linuxdeepin/go-x11-client/ext/xfixes"
 )
 
@@ -121,7 +123,8 @@ func getScreenResources(xConn *x.Conn) (*randr.GetScreenResourcesReply, error) {
 const evMaskForHideCursor uint32 = input.XIEventMaskRawMotion | input.XIEventMaskRawTouchBegin
 
 func (m *Manager) listenXEvents() {
-	if _useWayland {
+	// 单元测试中使用虚拟显示器时没有 X 连接
+	if _useWayland || m.xConn == nil {
 		return
 	}
 	eventChan := m.xConn.MakeAndAddEventChan(50)
@@ -208,6 +211,10 @@ type monitorManager interface {
 	apply(monitorsId monitorsId, monitorMap map[uint32]*Monitor, prevScreenSize screenSize, options applyOptions, fillModes map[string]string, primaryMonitorID uint32, displayMode byte) error
 	setMonitorPrimary(monitorId uint32) error
 	setMonitorFillMode(monitor *Monitor, fillMode string) error
+	// 显示服务是否支持设置 VRR 策略
+	supportVrrPolicy() bool
+	setMonitorVrrPolicy(monitor *Monitor, policy string) error
+	setMonitorOutputColor(monitor *Monitor, cfg SysOutputColorConfig) error
 	showCursor(show bool) error
 	HandleEvent(ev interface{})
 	HandleScreenChanged(e *randr.ScreenChangeNotifyEvent) (cfgTsChanged bool)
@@ -227,6 +234,8 @@ type xMonitorManager struct {
 	monitorChangedCbEnabled bool
 	// 键是 x 的 output 名称，值是标准名。
 	stdNamesCache map[string]string
+	// 设置过的 crtc 变换，没有的为不变换
+	crtcTransforms map[randr.Crtc]crtcTransform
 }
 
 func newXMonitorManager(xConn *x.Conn, hasRandr1d2 bool) *xMonitorManager {
@@ -236,6 +245,7 @@ func newXMonitorManager(xConn *x.Conn, hasRandr1d2 bool) *xMonitorManager {
 		crtcs:                   make(map[randr.Crtc]*CrtcInfo),
 		outputs:                 make(map[randr.Output]*OutputInfo),
 		stdNamesCache:           make(map[string]string),
+		crtcTransforms:          make(map[randr.Crtc]crtcTransform),
 		monitorChangedCbEnabled: true,
 	}
 	err := xmm.init()
@@ -541,12 +551,18 @@ func (mm *xMonitorManager) refreshMonitorsCache() {
 		monitor.UUID = getOutputUuid(monitor.Name, stdName, monitor.EDID)
 		monitor.UuidV0 = getOutputUuidV0(monitor.Name, monitor.EDID)
 		monitor.Manufacturer, monitor.Model = parseEdid(monitor.EDID)
+		monitor.parseEdidInfo()
 
 		availFillModes, err := mm.getOutputAvailableFillModes(outputId)
 		if err != nil {
 			logger.Warningf("get output %d available fill modes failed: %v", outputId, err)
 		}
 		monitor.AvailableFillModes = availFillModes
+		monitor.VrrCapable = getOutputVrrCapable(mm.xConn, outputId)
+		err = mm.getOutputColorProps(outputId, monitor)
+		if err != nil {
+			logger.Warningf("get output %d color props failed: %v", outputId, err)
+		}
 
 		// TODO 获取显示器当前的 fill mode
 
@@ -557,6 +573,10 @@ func (mm *xMonitorManager) refreshMonitorsCache() {
 				monitor.Y = crtcInfo.Y
 				monitor.Rotation = crtcInfo.Rotation
 				monitor.Width, monitor.Height = crtcInfo.Width, crtcInfo.Height
+				// 去除 crtc 变换的影响
+				if transform, ok := mm.crtcTransforms[monitor.crtc]; ok {
+					monitor.Width, monitor.Height = getUnscaledSize(monitor.Width, monitor.Height, transform)
+				}
 				swapWidthHeightWithRotation(crtcInfo.Rotation, &monitor.Width, &monitor.Height)
 				monitor.Rotations = crtcInfo.Rotations
 				monitor.CurrentMode = findModeInfo(mm.modes, crtcInfo.Mode)
@@ -621,6 +641,8 @@ type crtcConfig struct {
 	y        int16
 	rotation uint16
 	mode     randr.Mode
+	// 变换，为零值时不设置变换
+	transform crtcTransform
 }
 
 func findOutputInCrtcCfgs(crtcCfgs map[randr.Crtc]crtcConfig, crtc randr.Crtc) randr.Output {
@@ -681,12 +703,13 @@ func (mm *xMonitorManager) apply(monitorsId monitorsId, monitorMap map[uint32]*M
 				}
 			}
 			crtcCfgs[crtc] = crtcConfig{
-				crtc:     crtc,
-				x:        monitor.X,
-				y:        monitor.Y,
-				mode:     randr.Mode(monitor.CurrentMode.Id),
-				rotation: monitor.Rotation | monitor.Reflect,
-				outputs:  []randr.Output{randr.Output(output)},
+				crtc:      crtc,
+				x:         monitor.X,
+				y:         monitor.Y,
+				mode:      randr.Mode(monitor.CurrentMode.Id),
+				rotation:  monitor.Rotation | monitor.Reflect,
+				outputs:   []randr.Output{randr.Output(output)},
+				transform: monitor.getTransform(),
 			}
 		}
 	}
@@ -737,7 +760,7 @@ func (mm *xMonitorManager) apply(monitorsId monitorsId, monitorMap map[uint32]*M
 
 	var disableCrtcs []randr.Crtc
 	for crtc, crtcInfo := range mm.getCrtcs() {
-		rect := crtcInfo.getRect()
+		rect := mm.getCrtcRect(crtc, crtcInfo)
 		logger.Debugf("crtc %v, crtcInfo: %+v", crtc, crtcInfo)
 
 		// 是否考虑临时禁用 crtc
@@ -761,8 +784,9 @@ func (mm *xMonitorManager) apply(monitorsId monitorsId, monitorMap map[uint32]*M
 				monitor := monitors.GetById(uint32(output))
 				// 根据 crtc 找到对应的 monitor
 				if monitor != nil && monitor.Enabled {
+					width, height := monitor.getScaledSize()
 					if rect.X != monitor.X || rect.Y != monitor.Y ||
-						rect.Width != monitor.Width || rect.Height != monitor.Height ||
+						rect.Width != width || rect.Height != height ||
 						crtcInfo.Rotation != monitor.Rotation|monitor.Reflect {
 						// crtc 的参数将发生改变, 这里的 monitor 包含了 crtc 未来的状态。
 						logger.Debugf("should disable crtc %v because of the parameters of crtc changed", crtc)
@@ -959,10 +983,8 @@ func getScreenWidthHeight(monitorMap map[uint32]*Monitor) (sw, sh uint16) {
 			continue
 		}
 
-		width := monitor.CurrentMode.Width
-		height := monitor.CurrentMode.Height
-
-		swapWidthHeightWithRotation(monitor.Rotation, &width, &height)
+		// 考虑旋转和变换
+		width, height := monitor.getScaledSize()
 
 		w1 := int(monitor.X) + int(width)
 		h1 := int(monitor.Y) + int(height)
@@ -1007,8 +1029,14 @@ func (mm *xMonitorManager) setCrtcConfig(cfg crtcConfig) error {
 	mm.mu.Unlock()
 
 	logger.Debugf("setCrtcConfig crtc: %v, cfgTs: %v, x: %v, y: %v,"+
-		" mode: %v, rotation|reflect: %v, outputs: %v",
-		cfg.crtc, cfgTs, cfg.x, cfg.y, cfg.mode, cfg.rotation, cfg.outputs)
+		" mode: %v, rotation|reflect: %v, outputs: %v, transform: %+v",
+		cfg.crtc, cfgTs, cfg.x, cfg.y, cfg.mode, cfg.rotation, cfg.outputs, cfg.transform)
+	if len(cfg.outputs) > 0 && cfg.transform != (crtcTransform{}) {
+		err := mm.setCrtcTransform(cfg.crtc, cfg.transform)
+		if err != nil {
+			return err
+		}
+	}
 	setCfg, err := randr.SetCrtcConfig(mm.xConn, cfg.crtc, 0, cfgTs,
 		cfg.x, cfg.y, cfg.mode, cfg.rotation,
 		cfg.outputs).Reply(mm.xConn)
@@ -1020,6 +1048,59 @@ func (mm *xMonitorManager) setCrtcConfig(cfg crtcConfig) error {
 			cfg.crtc, getRandrStatusStr(setCfg.Status))
 		return err
 	}
+	if len(cfg.outputs) == 0 {
+		// crtc 已经关闭，缓存的变换不再对应它的显示内容
+		mm.mu.Lock()
+		delete(mm.crtcTransforms, cfg.crtc)
+		mm.mu.Unlock()
+	}
+	return nil
+}
+
+// getCrtcRect 获取 crtc 在屏幕上占据的区域，X 返回的 crtc 尺寸包括复制模式下的黑边，需要去除
+func (mm *xMonitorManager) getCrtcRect(crtc randr.Crtc, crtcInfo *CrtcInfo) x.Rectangle {
+	rect := crtcInfo.getRect()
+	mm.mu.Lock()
+	transform := mm.crtcTransforms[crtc]
+	mm.mu.Unlock()
+	rect.Width = subUint16(rect.Width, transform.padWidth)
+	rect.Height = subUint16(rect.Height, transform.padHeight)
+	return rect
+}
+
+// setCrtcTransform 设置 crtc 的缩放变换，在下一次设置 crtc 配置时生效。
+// 和服务器上 crtc 当前的变换比较，其他程序比如 xrandr 也可能修改变换。
+func (mm *xMonitorManager) setCrtcTransform(crtc randr.Crtc, transform crtcTransform) error {
+	transform = transform.normalize()
+	matrix := getScaleMatrix(transform)
+	renderTransform := render.Transform{
+		Matrix11: render.Fixed(matrix[0][0]),
+		Matrix12: render.Fixed(matrix[0][1]),
+		Matrix13: render.Fixed(matrix[0][2]),
+		Matrix21: render.Fixed(matrix[1][0]),
+		Matrix22: render.Fixed(matrix[1][1]),
+		Matrix23: render.Fixed(matrix[1][2]),
+		Matrix31: render.Fixed(matrix[2][0]),
+		Matrix32: render.Fixed(matrix[2][1]),
+		Matrix33: render.Fixed(matrix[2][2]),
+	}
+	filter := getScaleFilter(transform)
+
+	reply, err := randr.GetCrtcTransform(mm.xConn, crtc).Reply(mm.xConn)
+	if err != nil {
+		logger.Warningf("get crtc %v transform failed: %v", crtc, err)
+	}
+	if err != nil || reply.CurrentTransform != renderTransform || reply.CurrentFilterName != filter {
+		logger.Debugf("set crtc %v transform %+v, filter: %v", crtc, transform, filter)
+		err = randr.SetCrtcTransformChecked(mm.xConn, crtc, renderTransform, filter, nil).Check(mm.xConn)
+		if err != nil {
+			return err
+		}
+	}
+
+	mm.mu.Lock()
+	mm.crtcTransforms[crtc] = transform
+	mm.mu.Unlock()
 	return nil
 }
 
@@ -1087,6 +1168,105 @@ func (mm *xMonitorManager) setOutputScalingMode(output randr.Output, fillMode st
 	return nil
 }
 
+// output 的 vrr_capable 属性，表示显示器是否支持可变刷新率
+const outputPropVrrCapable = "vrr_capable"
+
+// getOutputVrrCapable 读取 output 的 vrr_capable 属性，没有这个属性时表示不支持
+func getOutputVrrCapable(xConn *x.Conn, output randr.Output) bool {
+	atom, err := xConn.GetAtom(outputPropVrrCapable)
+	if err != nil {
+		return false
+	}
+	reply, err := randr.GetOutputProperty(xConn, output, atom, x.AtomInteger,
+		0, 1, false, false).Reply(xConn)
+	if err != nil || reply.Format != 32 || len(reply.Value) < 4 {
+		return false
+	}
+	for _, b := range reply.Value[:4] {
+		if b != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// supportVrrPolicy VRR_ENABLED 是 DRM 的 crtc 属性，randr 没有提供，X 下无法设置 VRR 策略，
+// 由驱动根据全屏窗口的 _VARIABLE_REFRESH 属性启用。
+func (mm *xMonitorManager) supportVrrPolicy() bool {
+	return false
+}
+
+func (mm *xMonitorManager) setMonitorVrrPolicy(monitor *Monitor, policy string) error {
+	if policy == vrrPolicyNever {
+		return nil
+	}
+	return errVrrUnsupported
+}
+
+// getOutputColorProps 读取 output 的 Broadcast RGB、max bpc 和 Colorspace 属性的当前值和可选值
+func (mm *xMonitorManager) getOutputColorProps(output randr.Output, monitor *MonitorInfo) error {
+	xConn := mm.xConn
+	lsPropsReply, err := randr.ListOutputProperties(xConn, output).Reply(xConn)
+	if err != nil {
+		return err
+	}
+
+	for _, atom := range lsPropsReply.Atoms {
+		name, err := xConn.GetAtomName(atom)
+		if err != nil {
+			continue
+		}
+		if name != outputPropBroadcastRGB && name != outputPropMaxBpc && name != outputPropColorspace {
+			continue
+		}
+
+		propInfo, err := randr.QueryOutputProperty(xConn, output, atom).Reply(xConn)
+		if err != nil {
+			logger.Warningf("query output %d property %q failed: %v", output, name, err)
+			continue
+		}
+		value, ok := mm.getOutputPropUint32(output, atom)
+		if !ok {
+			continue
+		}
+
+		monitor.setOutputColorProp(name, propInfo, value, xConn.GetAtomName)
+	}
+	return nil
+}
+
+// getOutputPropUint32 读取 output 的 32 位属性值，包括 INTEGER 和 ATOM 类型
+func (mm *xMonitorManager) getOutputPropUint32(output randr.Output, atom x.Atom) (uint32, bool) {
+	reply, err := randr.GetOutputProperty(mm.xConn, output, atom, x.GetPropertyTypeAny,
+		0, 1, false, false).Reply(mm.xConn)
+	if err != nil || reply.Format != 32 || len(reply.Value) < 4 {
+		return 0, false
+	}
+	return binary.LittleEndian.Uint32(reply.Value), true
+}
+
+// setMonitorOutputColor 设置 output 的颜色相关属性，cfg 中为空的字段不设置
+func (mm *xMonitorManager) setMonitorOutputColor(monitor *Monitor, cfg SysOutputColorConfig) error {
+	values, err := getOutputColorPropValues(cfg, mm.xConn.GetAtom)
+	if err != nil {
+		return err
+	}
+	for _, v := range values {
+		prop, err := mm.xConn.GetAtom(v.name)
+		if err != nil {
+			return err
+		}
+		w := x.NewWriter()
+		w.Write4b(v.value)
+		err = randr.ChangeOutputPropertyChecked(mm.xConn, randr.Output(monitor.ID), prop,
+			v.propType, 32, x.PropModeReplace, w.Bytes()).Check(mm.xConn)
+		if err != nil {
+			return fmt.Errorf("set output property %q failed: %v", v.name, err)
+		}
+	}
+	return nil
+}
+
 func (mm *xMonitorManager) setMonitorPrimary(monitorId uint32) error {
 	logger.Debug("mm.setMonitorPrimary", monitorId)
 	err := mm.setOutputPrimary(randr.Output(monitorId))
@@ -171,6 +171,16 @@ func (v *StartManager) GetExportedMethods() dbusutil.ExportedMethods {
 			Fn:      v.AutostartList,
 			OutArgs: []string{"outArg0"},
 		},
+		{
+			Name:   "CancelPendingLaunch",
+			Fn:     v.CancelPendingLaunch,
+			InArgs: []string{"id"},
+		},
+		{
+			Name:   "CloseApp",
+			Fn:     v.CloseApp,
+			InArgs: []string{"id"},
+		},
 		{
 			Name:    "DumpMemRecord",
 			Fn:      v.DumpMemRecord,
@@ -181,6 +191,11 @@ func (v *StartManager) GetExportedMethods() dbusutil.ExportedMethods {
 			Fn:      v.GetApps,
 			OutArgs: []string{"outArg0"},
 		},
+		{
+			Name:    "GetMemPressure",
+			Fn:      v.GetMemPressure,
+			OutArgs: []string{"outArg0"},
+		},
 		{
 			Name:    "IsAutostart",
 			Fn:      v.IsAutostart,
@@ -219,6 +234,17 @@ func (v *StartManager) GetExportedMethods() dbusutil.ExportedMethods {
 			InArgs:  []string{"desktopFile", "timestamp"},
 			OutArgs: []string{"outArg0"},
 		},
+		{
+			Name:    "ListPendingLaunches",
+			Fn:      v.ListPendingLaunches,
+			OutArgs: []string{"outArg0"},
+		},
+		{
+			Name:    "ListReclaimableApps",
+			Fn:      v.ListReclaimableApps,
+			InArgs:  []string{"n"},
+			OutArgs: []string{"outArg0"},
+		},
 		{
 			Name:    "RemoveAutostart",
 			Fn:      v.RemoveAutostart,
@@ -240,5 +266,10 @@ func (v *StartManager) GetExportedMethods() dbusutil.ExportedMethods {
 			Fn:     v.TryAgain,
 			InArgs: []string{"launch"},
 		},
+		{
+			Name:   "TryAgainPendingLaunch",
+			Fn:     v.TryAgainPendingLaunch,
+			InArgs: []string{"id"},
+		},
 	}
 }
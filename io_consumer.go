@@ -0,0 +1,74 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+
+	"github.com/linuxdeepin/startdde/iowait"
+	"github.com/linuxdeepin/startdde/memanalyzer"
+)
+
+// the max depth of the process ancestors searched for the launched app
+const maxAncestorDepth = 32
+
+// getAppInfoByPid find the app of the process by the launched record of the
+// process or its ancestors, then by the app unit it runs in
+func (m *StartManager) getAppInfoByPid(pid uint32) *iowait.AppInfo {
+	launchedApps := m.getLaunchedApps()
+	var desktopFile string
+	for i, p := 0, pid; i < maxAncestorDepth && p > 1; i++ {
+		if app, ok := launchedApps[p]; ok {
+			desktopFile = app.desktopFile
+			break
+		}
+		p = getParentPid(p)
+	}
+	if desktopFile == "" {
+		if unitName := memanalyzer.GetAppUnitByPid(pid); unitName != "" {
+			desktopFile = getDesktopFileByUnit(unitName)
+		}
+	}
+	if desktopFile == "" {
+		return nil
+	}
+
+	dai, err := newDesktopAppInfoFromFile(desktopFile)
+	if err != nil {
+		return nil
+	}
+	return &iowait.AppInfo{
+		Id:   dai.GetId(),
+		Name: dai.GetName(),
+		Icon: dai.GetIcon(),
+	}
+}
+
+// getParentPid returns 0 if the process exited
+func getParentPid(pid uint32) uint32 {
+	contents, err := ioutil.ReadFile(fmt.Sprintf("/proc/%v/stat", pid))
+	if err != nil {
+		return 0
+	}
+	return parseParentPid(string(contents))
+}
+
+// parseParentPid the format is 'pid (comm) state ppid ...', the comm may
+// contain spaces and parentheses
+func parseParentPid(stat string) uint32 {
+	idx := strings.LastIndex(stat, ")")
+	if idx < 0 {
+		return 0
+	}
+	list := strings.Fields(stat[idx+1:])
+	if len(list) < 2 {
+		return 0
+	}
+	v, _ := strconv.ParseUint(list[1], 10, 32)
+	return uint32(v)
+}
@@ -0,0 +1,18 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_parseParentPid(t *testing.T) {
+	assert.Equal(t, uint32(1024), parseParentPid("2048 (deepin-editor) S 1024 2048 2048 0 -1"))
+	assert.Equal(t, uint32(1), parseParentPid("2048 (a) b (c)) R 1 2048 2048 0 -1"))
+	assert.Equal(t, uint32(0), parseParentPid("2048 (deepin-editor"))
+	assert.Equal(t, uint32(0), parseParentPid(""))
+}
@@ -0,0 +1,138 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package iowait
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/linuxdeepin/go-lib/xdg/basedir"
+)
+
+const (
+	sysConfigFile = "/usr/share/startdde/iowait.json"
+
+	envMaxIOWait = "DDE_MAX_IOWAIT"
+
+	defaultInterval = 4 // 4s
+
+	// the same as before the thresholds configurable, the other indicators
+	// and the hysteresis are disabled unless configured
+	defaultCPUHigh = 65
+	defaultCPULow  = 65
+
+	defaultPerCPUIOWaitHigh = 0 // disabled
+	defaultPerCPUIOWaitLow  = 0
+
+	defaultIOSomeAvg10High = 0 // disabled
+	defaultIOSomeAvg10Low  = 0
+
+	defaultCPUSomeAvg10High = 0 // disabled
+	defaultCPUSomeAvg10Low  = 0
+)
+
+// the thresholds are percentages, the system becomes busy when any value
+// reaches the high threshold, and becomes idle again only after all values
+// drop below the low thresholds. The high threshold 0 disables the value,
+// the low threshold equal to the high one disables the hysteresis.
+type configInfo struct {
+	// the seconds between the samples
+	Interval uint32 `json:"interval"`
+
+	// the user, system and iowait time of all the CPUs
+	CPUHigh float64 `json:"cpu-high"`
+	CPULow  float64 `json:"cpu-low"`
+
+	// the iowait time of the busiest CPU, a single task blocked on the disk
+	// hardly shows in the summary of many CPUs
+	PerCPUIOWaitHigh float64 `json:"per-cpu-iowait-high"`
+	PerCPUIOWaitLow  float64 `json:"per-cpu-iowait-low"`
+
+	// the avg10 of /proc/pressure/io and /proc/pressure/cpu
+	IOSomeAvg10High  float64 `json:"io-some-avg10-high"`
+	IOSomeAvg10Low   float64 `json:"io-some-avg10-low"`
+	CPUSomeAvg10High float64 `json:"cpu-some-avg10-high"`
+	CPUSomeAvg10Low  float64 `json:"cpu-some-avg10-low"`
+}
+
+func newDefaultConfig() *configInfo {
+	return &configInfo{
+		Interval:         defaultInterval,
+		CPUHigh:          defaultCPUHigh,
+		CPULow:           defaultCPULow,
+		PerCPUIOWaitHigh: defaultPerCPUIOWaitHigh,
+		PerCPUIOWaitLow:  defaultPerCPUIOWaitLow,
+		IOSomeAvg10High:  defaultIOSomeAvg10High,
+		IOSomeAvg10Low:   defaultIOSomeAvg10Low,
+		CPUSomeAvg10High: defaultCPUSomeAvg10High,
+		CPUSomeAvg10Low:  defaultCPUSomeAvg10Low,
+	}
+}
+
+func loadConfig(filename string) (*configInfo, error) {
+	content, err := ioutil.ReadFile(filename)
+	if err != nil {
+		content, err = ioutil.ReadFile(sysConfigFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var info = newDefaultConfig()
+	err = json.Unmarshal(content, info)
+	if err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+func getConfigPath() string {
+	return filepath.Join(basedir.GetUserConfigDir(),
+		"deepin", "startdde", "iowait.json")
+}
+
+// getConfig load the config, DDE_MAX_IOWAIT still overrides the CPU
+// threshold as before
+func getConfig() *configInfo {
+	cfg, err := loadConfig(getConfigPath())
+	if err != nil {
+		if !os.IsNotExist(err) {
+			_logger.Warning("Failed to load config:", err)
+		}
+		cfg = newDefaultConfig()
+	}
+
+	v := stof(os.Getenv(envMaxIOWait))
+	if v > 0 {
+		cfg.CPUHigh = v
+	}
+	correctConfig(cfg)
+	return cfg
+}
+
+func correctConfig(cfg *configInfo) {
+	if cfg.Interval == 0 {
+		cfg.Interval = defaultInterval
+	}
+	correctThreshold(&cfg.CPUHigh, &cfg.CPULow)
+	correctThreshold(&cfg.PerCPUIOWaitHigh, &cfg.PerCPUIOWaitLow)
+	correctThreshold(&cfg.IOSomeAvg10High, &cfg.IOSomeAvg10Low)
+	correctThreshold(&cfg.CPUSomeAvg10High, &cfg.CPUSomeAvg10Low)
+}
+
+// correctThreshold the low threshold must not be higher than the high one,
+// otherwise the state flaps between the samples
+func correctThreshold(high, low *float64) {
+	if *high <= 0 {
+		*high = 0
+		*low = 0
+		return
+	}
+	if *low <= 0 || *low > *high {
+		*low = *high * 0.75
+	}
+}
@@ -0,0 +1,125 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package iowait
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+const procStatFile = "/proc/stat"
+
+// CPUStat store the cpu stat
+type CPUStat struct {
+	User   float64
+	System float64
+	Idle   float64
+	IOWait float64
+	Count  float64
+}
+
+// CPULoad the percentages of the cpu time between two samples
+type CPULoad struct {
+	User   float64
+	System float64
+	IOWait float64
+}
+
+// cpuSampler compute the load of all the CPUs and of each CPU from the
+// deltas of /proc/stat
+type cpuSampler struct {
+	filename string
+	prev     map[string]CPUStat
+}
+
+func newCPUSampler() *cpuSampler {
+	return &cpuSampler{
+		filename: procStatFile,
+	}
+}
+
+// sample returns the load of all the CPUs and the max iowait of the CPUs,
+// ok is false at the first sample
+func (s *cpuSampler) sample() (total CPULoad, maxIOWait float64, ok bool, err error) {
+	stats, err := readCPUStats(s.filename)
+	if err != nil {
+		return
+	}
+
+	prev := s.prev
+	s.prev = stats
+	if prev == nil {
+		return
+	}
+
+	for name, cur := range stats {
+		old, exist := prev[name]
+		if !exist {
+			// the CPU went online
+			continue
+		}
+		load := getCPULoad(old, cur)
+		if name == "cpu" {
+			total = load
+			ok = true
+		} else if load.IOWait > maxIOWait {
+			maxIOWait = load.IOWait
+		}
+	}
+	return
+}
+
+func getCPULoad(old, cur CPUStat) CPULoad {
+	count := cur.Count - old.Count
+	if count <= 0 {
+		return CPULoad{}
+	}
+	return CPULoad{
+		User:   100.0 * (cur.User - old.User) / count,
+		System: 100.0 * (cur.System - old.System) / count,
+		IOWait: 100.0 * (cur.IOWait - old.IOWait) / count,
+	}
+}
+
+// readCPUStats read the summary line 'cpu' and the lines 'cpuN'
+func readCPUStats(filename string) (map[string]CPUStat, error) {
+	fr, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer fr.Close()
+
+	var ret = make(map[string]CPUStat)
+	var scanner = bufio.NewScanner(fr)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "cpu") {
+			continue
+		}
+
+		// cpu  user nice system idle iowait irq softirq steal ...
+		list := strings.Fields(line)
+		if len(list) < 6 {
+			return nil, fmt.Errorf("invalid format: %s", line)
+		}
+
+		var stat CPUStat
+		stat.User = stof(list[1])
+		stat.System = stof(list[3])
+		stat.Idle = stof(list[4])
+		stat.IOWait = stof(list[5])
+		stat.Count = stat.User + stat.System + stat.Idle + stat.IOWait
+		ret[list[0]] = stat
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if _, ok := ret["cpu"]; !ok {
+		return nil, fmt.Errorf("invalid format: %s", filename)
+	}
+	return ret, nil
+}
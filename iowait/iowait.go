@@ -5,131 +5,250 @@
 package iowait
 
 import (
-	"bufio"
-	"fmt"
-	"os"
 	"strconv"
-	"strings"
+	"sync"
 	"time"
 
+	dbus "github.com/godbus/dbus"
+	"github.com/linuxdeepin/go-lib/dbusutil"
 	"github.com/linuxdeepin/go-lib/log"
-	"github.com/linuxdeepin/go-lib/strv"
 )
 
-// #cgo pkg-config: x11 xcursor xfixes gio-2.0
-// #cgo LDFLAGS: -lm
-// #cgo CFLAGS: -W -Wall -fstack-protector-all -fPIC
-// #include "xcursor_remap.h"
-import "C"
+//go:generate dbusutil-gen -type LoadMonitor iowait.go
+//go:generate dbusutil-gen em -type LoadMonitor
 
 const (
-	ddeMaxIOWait = "DDE_MAX_IOWAIT"
+	dbusPath      = "/com/deepin/SessionManager/LoadMonitor"
+	dbusInterface = "com.deepin.SessionManager.LoadMonitor"
 )
 
 var (
-	_logger  *log.Logger
-	cpuState CPUStat
-	isWatch  = false
+	_logger *log.Logger
 )
 
-var _max = 65.0
+// LoadMonitor watch the load of the system, the consumers show the busy
+// state to the user
+type LoadMonitor struct {
+	service *dbusutil.Service
+	cfg     *configInfo
+	cpu     *cpuSampler
+	io      *ioSampler
+
+	ioPressureSupported  bool
+	cpuPressureSupported bool
+
+	loadMu sync.Mutex
+	load   Load
+
+	consumersMu sync.Mutex
+	consumers   []Consumer
+	stopped     bool
+	quit        chan struct{}
+
+	resolverMu sync.Mutex
+	resolver   AppResolver
+
+	PropsMu    sync.RWMutex
+	SystemBusy bool
+	// the indicator which keeps the system busy, empty if not busy
+	BusyReason string
+
+	//nolint
+	signals *struct {
+		SystemBusyChanged struct {
+			busy   bool
+			reason string
+		}
+	}
+}
 
-func init() {
-	s := os.Getenv(ddeMaxIOWait)
-	if s == "" {
-		return
+// Start join the iowait module, the cursor shows watch when the system is
+// busy, by remapping the X cursor or by the KWin cursor theme under Wayland
+func Start(logger *log.Logger, service *dbusutil.Service, useWayland bool) (*LoadMonitor, error) {
+	_logger = logger
+
+	m := newLoadMonitor(service, getConfig())
+	if useWayland {
+		c := newKWinCursorConsumer(service.Conn())
+		// restore the origin theme in case the last session exited in the
+		// busy state
+		err := c.SetBusy(false)
+		if err != nil {
+			logger.Warning("Failed to restore the cursor theme:", err)
+		}
+		m.AddConsumer(c)
+	} else {
+		m.AddConsumer(newXCursorConsumer())
 	}
 
-	v := stof(s)
-	if v > 0 {
-		_max = float64(v)
+	err := service.Export(dbusPath, m)
+	if err != nil {
+		return nil, err
 	}
-}
 
-// CPUStat store the cpu stat
-type CPUStat struct {
-	User   float64
-	System float64
-	Idle   float64
-	IOWait float64
-	Count  float64
+	go m.loop()
+	return m, nil
 }
 
-// Start join the iowait module
-func Start(logger *log.Logger) {
-	_logger = logger
-	for {
-		time.Sleep(time.Second * 4)
-		showIOWait()
+func newLoadMonitor(service *dbusutil.Service, cfg *configInfo) *LoadMonitor {
+	m := &LoadMonitor{
+		service: service,
+		cfg:     cfg,
+		cpu:     newCPUSampler(),
+		io:      newIOSampler(),
+		quit:    make(chan struct{}),
 	}
-}
 
-func showIOWait() {
-	fr, err := os.Open("/proc/stat")
-	if err != nil {
-		_logger.Warning("Failed to open:", err)
-		return
+	_, err := getSomeAvg10(ioPressureFile)
+	m.ioPressureSupported = err == nil
+	_, err = getSomeAvg10(cpuPressureFile)
+	m.cpuPressureSupported = err == nil
+	if !m.ioPressureSupported || !m.cpuPressureSupported {
+		_logger.Info("PSI not supported, use /proc/stat only")
 	}
+	return m
+}
 
-	var scanner = bufio.NewScanner(fr)
-	scanner.Scan()
-	line := scanner.Text()
-	fr.Close()
-	list := strings.Split(line, " ")
-	list = strv.Strv(list).FilterEmpty()
-	if len(list) < 6 {
-		_logger.Warning("INvalid format:", line, len(list))
-		return
-	}
+func (*LoadMonitor) GetInterfaceName() string {
+	return dbusInterface
+}
 
-	var TEMP CPUStat
-	TEMP.User = stof(list[1])
-	TEMP.System = stof(list[3])
-	TEMP.Idle = stof(list[4])
-	TEMP.IOWait = stof(list[5])
-	TEMP.Count = (TEMP.User + TEMP.System + TEMP.Idle + TEMP.IOWait)
+// AddConsumer add the consumer notified when the busy state changed
+func (m *LoadMonitor) AddConsumer(c Consumer) {
+	m.consumersMu.Lock()
+	m.consumers = append(m.consumers, c)
+	m.consumersMu.Unlock()
+}
 
-	if cpuState.Count == 0 {
-		cpuState = TEMP
+// Stop stop watching the load and restore the idle state of the consumers,
+// called before the session exits
+func (m *LoadMonitor) Stop() {
+	m.consumersMu.Lock()
+	defer m.consumersMu.Unlock()
+	if m.stopped {
 		return
 	}
+	m.stopped = true
+	close(m.quit)
+
+	for _, c := range m.consumers {
+		err := c.SetBusy(false)
+		if err != nil {
+			_logger.Warning("Failed to restore the idle state:", err)
+		}
+	}
+}
 
-	count := TEMP.Count - cpuState.Count
-	userStep := 100.0 * (TEMP.User - cpuState.User) / count
-	sysStep := 100.0 * (TEMP.System - cpuState.System) / count
-	iowaitStep := 100.0 * (TEMP.IOWait - cpuState.IOWait) / count
+// SetAppResolver set the function finding the app of the IO consumers
+func (m *LoadMonitor) SetAppResolver(resolver AppResolver) {
+	m.resolverMu.Lock()
+	m.resolver = resolver
+	m.resolverMu.Unlock()
+}
 
-	_logger.Debug("current info: ", TEMP, userStep, sysStep, iowaitStep)
-	xcLeftPtrToWatch(canShowWatch(userStep, sysStep, iowaitStep))
-	cpuState = TEMP
+// ListIOConsumers list the top n apps or processes of the session which use
+// the disk in the last interval, all of them if n <= 0
+func (m *LoadMonitor) ListIOConsumers(n int32) ([]IOConsumer, *dbus.Error) {
+	m.resolverMu.Lock()
+	resolver := m.resolver
+	m.resolverMu.Unlock()
+	return getIOConsumers(m.io.getRates(), resolver, int(n)), nil
 }
 
-func canShowWatch(user, sys, wait float64) bool {
-	if user >= _max || sys >= _max || wait >= _max {
-		return true
-	}
-	return false
+// GetLoad get the current values of the load indicators in percentage
+func (m *LoadMonitor) GetLoad() (map[string]float64, *dbus.Error) {
+	m.loadMu.Lock()
+	load := m.load
+	m.loadMu.Unlock()
+	return load.toMap(), nil
 }
 
-func stof(v string) float64 {
-	r, _ := strconv.ParseFloat(v, 64)
-	return r
+func (m *LoadMonitor) loop() {
+	ticker := time.NewTicker(time.Duration(m.cfg.Interval) * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			m.update()
+		case <-m.quit:
+			return
+		}
+	}
 }
 
-func xcLeftPtrToWatch(enabled bool) {
-	if isWatch == enabled {
+func (m *LoadMonitor) update() {
+	err := m.io.sample(time.Now())
+	if err != nil {
+		_logger.Warning("Failed to sample process io:", err)
+	}
+
+	load, ok := m.sample()
+	if !ok {
+		return
+	}
+	m.loadMu.Lock()
+	m.load = load
+	m.loadMu.Unlock()
+
+	m.PropsMu.Lock()
+	busy, reason := evalBusy(m.SystemBusy, &load, m.cfg)
+	changed := m.setPropSystemBusy(busy)
+	m.setPropBusyReason(reason)
+	m.PropsMu.Unlock()
+
+	_logger.Debug("current load:", load, busy, reason)
+	if !changed {
 		return
 	}
 
-	var v C.int = 1
-	if !enabled {
-		v = 0
+	_logger.Info("System busy changed:", busy, reason)
+	err = m.service.Emit(m, "SystemBusyChanged", busy, reason)
+	if err != nil {
+		_logger.Warning(err)
 	}
 
-	ret := C.xc_left_ptr_to_watch(v)
-	if ret != 0 {
-		fmt.Printf("Failed to map(%v) left_ptr/left_ptr_watch", enabled)
+	m.consumersMu.Lock()
+	defer m.consumersMu.Unlock()
+	if m.stopped {
 		return
 	}
-	isWatch = enabled
+	for _, c := range m.consumers {
+		err := c.SetBusy(busy)
+		if err != nil {
+			_logger.Warning("Failed to show the busy state:", err)
+		}
+	}
+}
+
+func (m *LoadMonitor) sample() (Load, bool) {
+	var load Load
+	cpuLoad, maxIOWait, ok, err := m.cpu.sample()
+	if err != nil {
+		_logger.Warning("Failed to sample cpu:", err)
+		return load, false
+	}
+	if !ok {
+		return load, false
+	}
+	load.CPULoad = cpuLoad
+	load.MaxCPUIOWait = maxIOWait
+
+	if m.ioPressureSupported {
+		load.IOSomeAvg10, err = getSomeAvg10(ioPressureFile)
+		if err != nil {
+			_logger.Warning("Failed to get io pressure:", err)
+		}
+	}
+	if m.cpuPressureSupported {
+		load.CPUSomeAvg10, err = getSomeAvg10(cpuPressureFile)
+		if err != nil {
+			_logger.Warning("Failed to get cpu pressure:", err)
+		}
+	}
+	return load, true
+}
+
+func stof(v string) float64 {
+	r, _ := strconv.ParseFloat(v, 64)
+	return r
 }
@@ -5,15 +5,277 @@
 package iowait
 
 import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
+	dbus "github.com/godbus/dbus"
 	"github.com/stretchr/testify/assert"
-	"github.com/linuxdeepin/go-lib/log"
 )
 
-func Test_showIOWait(t *testing.T) {
-	_logger = log.NewLogger("startdde")
-	assert.NotPanics(t, func() {
-		showIOWait()
-	})
+func Test_cpuSampler(t *testing.T) {
+	s := &cpuSampler{filename: "./testdata/proc_stat_0"}
+	_, _, ok, err := s.sample()
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	s.filename = "./testdata/proc_stat_1"
+	load, maxIOWait, ok, err := s.sample()
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.InDelta(t, 10.0, load.User, 0.01)
+	assert.InDelta(t, 10.0, load.System, 0.01)
+	assert.InDelta(t, 40.0, load.IOWait, 0.01)
+	// cpu1 spent 400 of 500 on iowait
+	assert.InDelta(t, 80.0, maxIOWait, 0.01)
+
+	s.filename = "./testdata/not-exist"
+	_, _, _, err = s.sample()
+	assert.Error(t, err)
+}
+
+func Test_getSomeAvg10(t *testing.T) {
+	v, err := getSomeAvg10("./testdata/pressure_io")
+	assert.NoError(t, err)
+	assert.Equal(t, 42.5, v)
+
+	_, err = getSomeAvg10("./testdata/proc_stat_0")
+	assert.Error(t, err)
+}
+
+func Test_evalBusy(t *testing.T) {
+	cfg := &configInfo{
+		CPUHigh:          65,
+		CPULow:           50,
+		PerCPUIOWaitHigh: 90,
+		PerCPUIOWaitLow:  70,
+		IOSomeAvg10High:  60,
+		IOSomeAvg10Low:   30,
+	}
+	correctConfig(cfg)
+
+	var load Load
+	busy, reason := evalBusy(false, &load, cfg)
+	assert.False(t, busy)
+	assert.Equal(t, "", reason)
+
+	load.IOWait = 70
+	busy, reason = evalBusy(false, &load, cfg)
+	assert.True(t, busy)
+	assert.Equal(t, "iowait", reason)
+
+	// keep busy between the low and high thresholds
+	load.IOWait = 55
+	busy, _ = evalBusy(false, &load, cfg)
+	assert.False(t, busy)
+	busy, reason = evalBusy(true, &load, cfg)
+	assert.True(t, busy)
+	assert.Equal(t, "iowait", reason)
+
+	load.IOWait = 45
+	busy, _ = evalBusy(true, &load, cfg)
+	assert.False(t, busy)
+
+	load.IOSomeAvg10 = 60
+	busy, reason = evalBusy(false, &load, cfg)
+	assert.True(t, busy)
+	assert.Equal(t, "io-pressure", reason)
+
+	// the disabled indicator
+	load = Load{CPUSomeAvg10: 100}
+	busy, _ = evalBusy(false, &load, cfg)
+	assert.False(t, busy)
+}
+
+func Test_evalBusy_default(t *testing.T) {
+	cfg := newDefaultConfig()
+	correctConfig(cfg)
+
+	// only the CPU summary without hysteresis as before
+	load := Load{CPULoad: CPULoad{System: 65}}
+	busy, reason := evalBusy(false, &load, cfg)
+	assert.True(t, busy)
+	assert.Equal(t, "system", reason)
+
+	load.System = 64
+	busy, _ = evalBusy(true, &load, cfg)
+	assert.False(t, busy)
+
+	load = Load{MaxCPUIOWait: 100, IOSomeAvg10: 100, CPUSomeAvg10: 100}
+	busy, _ = evalBusy(false, &load, cfg)
+	assert.False(t, busy)
+}
+
+func Test_correctConfig(t *testing.T) {
+	cfg := &configInfo{
+		CPUHigh:          80,
+		CPULow:           90,
+		PerCPUIOWaitHigh: -1,
+		PerCPUIOWaitLow:  10,
+		IOSomeAvg10High:  60,
+		IOSomeAvg10Low:   30,
+	}
+	correctConfig(cfg)
+	assert.Equal(t, uint32(defaultInterval), cfg.Interval)
+	assert.Equal(t, 60.0, cfg.CPULow)
+	assert.Equal(t, 0.0, cfg.PerCPUIOWaitHigh)
+	assert.Equal(t, 0.0, cfg.PerCPUIOWaitLow)
+	assert.Equal(t, 30.0, cfg.IOSomeAvg10Low)
+
+	cfg = &configInfo{CPUHigh: 65, CPULow: 65}
+	correctConfig(cfg)
+	assert.Equal(t, 65.0, cfg.CPULow)
+}
+
+func Test_readProcIO(t *testing.T) {
+	stat, err := readProcIO("./testdata/proc/2048/io")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(409600), stat.readBytes)
+	assert.Equal(t, uint64(8192), stat.writeBytes)
+
+	_, err = readProcIO("./testdata/pressure_io")
+	assert.Error(t, err)
+
+	assert.Equal(t, "deepin-editor", readProcComm("./testdata/proc/2048/comm"))
+}
+
+func Test_ioSampler(t *testing.T) {
+	dir := t.TempDir()
+	writeIO := func(pid string, read, write int) {
+		err := os.MkdirAll(filepath.Join(dir, pid), 0755)
+		assert.NoError(t, err)
+		content := fmt.Sprintf("read_bytes: %d\nwrite_bytes: %d\n", read, write)
+		err = ioutil.WriteFile(filepath.Join(dir, pid, "io"), []byte(content), 0644)
+		assert.NoError(t, err)
+		err = ioutil.WriteFile(filepath.Join(dir, pid, "comm"), []byte("comm-"+pid+"\n"), 0644)
+		assert.NoError(t, err)
+	}
+
+	s := newIOSampler()
+	s.procDir = dir
+	now := time.Now()
+	writeIO("100", 0, 0)
+	writeIO("200", 1000, 1000)
+	assert.NoError(t, s.sample(now))
+	assert.Empty(t, s.getRates())
+
+	writeIO("100", 4000, 0)
+	writeIO("200", 1000, 1000)
+	writeIO("300", 4000, 4000)
+	assert.NoError(t, s.sample(now.Add(2*time.Second)))
+	assert.Equal(t, map[uint32]procIORate{
+		100: {comm: "comm-100", readRate: 2000},
+	}, s.getRates())
+}
+
+func Test_getIOConsumers(t *testing.T) {
+	rates := map[uint32]procIORate{
+		100: {comm: "tracker-miner", readRate: 1000},
+		101: {comm: "tracker-extract", writeRate: 500},
+		200: {comm: "cp", readRate: 200, writeRate: 200},
+		300: {comm: "", readRate: 100},
+	}
+	resolver := func(pid uint32) *AppInfo {
+		if pid == 100 || pid == 101 {
+			return &AppInfo{Id: "indexer", Name: "Indexer", Icon: "indexer"}
+		}
+		return nil
+	}
+
+	consumers := getIOConsumers(rates, resolver, 0)
+	assert.Equal(t, []IOConsumer{
+		{Id: "indexer", DesktopId: "indexer", Name: "Indexer", Icon: "indexer",
+			Pids: []uint32{100, 101}, ReadRate: 1000, WriteRate: 500},
+		{Id: "cp", Name: "cp", Pids: []uint32{200}, ReadRate: 200, WriteRate: 200},
+		{Id: "300", Pids: []uint32{300}, ReadRate: 100},
+	}, consumers)
+
+	consumers = getIOConsumers(rates, nil, 1)
+	assert.Len(t, consumers, 1)
+	assert.Equal(t, "tracker-miner", consumers[0].Id)
+}
+
+type testConsumer struct {
+	busy bool
+}
+
+func (c *testConsumer) SetBusy(busy bool) error {
+	c.busy = busy
+	return nil
+}
+
+func Test_LoadMonitorStop(t *testing.T) {
+	c := &testConsumer{busy: true}
+	m := &LoadMonitor{quit: make(chan struct{})}
+	m.AddConsumer(c)
+
+	m.Stop()
+	assert.False(t, c.busy)
+	assert.True(t, m.stopped)
+	select {
+	case <-m.quit:
+	default:
+		t.Error("quit not closed")
+	}
+	// stop again does nothing
+	c.busy = true
+	m.Stop()
+	assert.True(t, c.busy)
+}
+
+type testCursorTheme struct {
+	value string
+	sets  int
+}
+
+func (p *testCursorTheme) Get(flags dbus.Flags) (string, error) {
+	return p.value, nil
+}
+
+func (p *testCursorTheme) Set(flags dbus.Flags, value string) error {
+	p.value = value
+	p.sets++
+	return nil
+}
+
+func Test_kwinCursorConsumer(t *testing.T) {
+	dataDir := t.TempDir()
+	oldDataDir, ok := os.LookupEnv("XDG_DATA_HOME")
+	os.Setenv("XDG_DATA_HOME", dataDir)
+	defer func() {
+		if ok {
+			os.Setenv("XDG_DATA_HOME", oldDataDir)
+		} else {
+			os.Unsetenv("XDG_DATA_HOME")
+		}
+	}()
+
+	watch := filepath.Join(dataDir, "icons", "base", "cursors", "watch")
+	assert.NoError(t, os.MkdirAll(filepath.Dir(watch), 0755))
+	assert.NoError(t, ioutil.WriteFile(watch, nil, 0644))
+
+	theme := &testCursorTheme{value: "base"}
+	c := &kwinCursorConsumer{theme: theme}
+	assert.NoError(t, c.SetBusy(true))
+	assert.Equal(t, busyCursorTheme, theme.value)
+	link, err := os.Readlink(filepath.Join(getBusyCursorThemeDir(), "cursors", "left_ptr"))
+	assert.NoError(t, err)
+	assert.Equal(t, watch, link)
+
+	// already busy
+	assert.NoError(t, c.SetBusy(true))
+	assert.Equal(t, 1, theme.sets)
+
+	assert.NoError(t, c.SetBusy(false))
+	assert.Equal(t, "base", theme.value)
+	assert.NoError(t, c.SetBusy(false))
+	assert.Equal(t, 2, theme.sets)
+
+	// the theme without the watch cursor
+	theme.value = "no-watch"
+	assert.Error(t, c.SetBusy(true))
+	assert.Equal(t, "no-watch", theme.value)
 }
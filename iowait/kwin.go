@@ -0,0 +1,164 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package iowait
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	dbus "github.com/godbus/dbus"
+	wm "github.com/linuxdeepin/go-dbus-factory/com.deepin.wm"
+	"github.com/linuxdeepin/go-lib/keyfile"
+	"github.com/linuxdeepin/go-lib/xdg/basedir"
+)
+
+const (
+	busyCursorTheme  = "dde-busy-cursor"
+	defaultThemeName = "default"
+
+	iconThemeSection  = "Icon Theme"
+	iconThemeInherits = "Inherits"
+
+	maxInheritsDepth = 3
+)
+
+// cursorThemeProp the cursor theme property of the window manager
+type cursorThemeProp interface {
+	Get(flags dbus.Flags) (string, error)
+	Set(flags dbus.Flags, value string) error
+}
+
+// kwinCursorConsumer switch the cursor theme of KWin to a theme which inherits
+// the current theme and replaces left_ptr with the watch cursor, since the
+// cursor can not be remapped by the X server under Wayland. The theme is
+// changed by the property of the window manager at runtime, the cursor theme
+// in the config files of the user is left untouched.
+type kwinCursorConsumer struct {
+	theme cursorThemeProp
+}
+
+func newKWinCursorConsumer(conn *dbus.Conn) *kwinCursorConsumer {
+	return &kwinCursorConsumer{
+		theme: wm.NewWm(conn).CursorTheme(),
+	}
+}
+
+func (c *kwinCursorConsumer) SetBusy(busy bool) error {
+	cur, err := c.theme.Get(0)
+	if err != nil {
+		return err
+	}
+	if cur == "" {
+		cur = defaultThemeName
+	}
+
+	if busy {
+		if cur == busyCursorTheme {
+			return nil
+		}
+		err = makeBusyCursorTheme(cur)
+		if err != nil {
+			return err
+		}
+		return c.theme.Set(0, busyCursorTheme)
+	}
+
+	if cur != busyCursorTheme {
+		return nil
+	}
+	// read the origin theme back from the busy theme, so it can be
+	// restored even if startdde restarted in the busy state
+	theme := getThemeInherits(getBusyCursorThemeDir())
+	if theme == "" {
+		theme = defaultThemeName
+	}
+	return c.theme.Set(0, theme)
+}
+
+func getBusyCursorThemeDir() string {
+	return filepath.Join(basedir.GetUserDataDir(), "icons", busyCursorTheme)
+}
+
+// makeBusyCursorTheme create the busy theme inherits from the base theme,
+// the left_ptr of it links to the watch cursor of the base theme
+func makeBusyCursorTheme(base string) error {
+	watch := findThemeCursor(base, []string{"watch", "wait", "left_ptr_watch"}, 0)
+	if watch == "" {
+		return fmt.Errorf("no watch cursor found in the theme %s", base)
+	}
+
+	dir := getBusyCursorThemeDir()
+	err := os.MkdirAll(filepath.Join(dir, "cursors"), 0755)
+	if err != nil {
+		return err
+	}
+
+	content := fmt.Sprintf("[%s]\nName=%s\n%s=%s\n",
+		iconThemeSection, busyCursorTheme, iconThemeInherits, base)
+	err = ioutil.WriteFile(filepath.Join(dir, "index.theme"), []byte(content), 0644)
+	if err != nil {
+		return err
+	}
+
+	link := filepath.Join(dir, "cursors", "left_ptr")
+	err = os.Remove(link)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return os.Symlink(watch, link)
+}
+
+// getIconDirs the directories searched for the cursor themes
+func getIconDirs() []string {
+	dirs := []string{
+		filepath.Join(basedir.GetUserDataDir(), "icons"),
+		filepath.Join(basedir.GetUserHomeDir(), ".icons"),
+	}
+	for _, dir := range basedir.GetSystemDataDirs() {
+		dirs = append(dirs, filepath.Join(dir, "icons"))
+	}
+	return append(dirs, "/usr/share/pixmaps")
+}
+
+func findThemeCursor(theme string, names []string, depth int) string {
+	if theme == busyCursorTheme || depth > maxInheritsDepth {
+		return ""
+	}
+
+	var parents []string
+	for _, dir := range getIconDirs() {
+		themeDir := filepath.Join(dir, theme)
+		for _, name := range names {
+			file := filepath.Join(themeDir, "cursors", name)
+			if _, err := os.Stat(file); err == nil {
+				return file
+			}
+		}
+		if v := getThemeInherits(themeDir); v != "" {
+			parents = append(parents, strings.Split(v, ",")...)
+		}
+	}
+
+	for _, parent := range parents {
+		file := findThemeCursor(strings.TrimSpace(parent), names, depth+1)
+		if file != "" {
+			return file
+		}
+	}
+	return ""
+}
+
+func getThemeInherits(themeDir string) string {
+	kf := keyfile.NewKeyFile()
+	err := kf.LoadFromFile(filepath.Join(themeDir, "index.theme"))
+	if err != nil {
+		return ""
+	}
+	v, _ := kf.GetString(iconThemeSection, iconThemeInherits)
+	return v
+}
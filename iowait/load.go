@@ -0,0 +1,68 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package iowait
+
+// Load the load of the system in percentage
+type Load struct {
+	CPULoad
+	// the max iowait of the CPUs
+	MaxCPUIOWait float64
+	// the some avg10 of the PSI, 0 if PSI not supported
+	IOSomeAvg10  float64
+	CPUSomeAvg10 float64
+}
+
+type indicator struct {
+	name  string
+	value float64
+	high  float64
+	low   float64
+}
+
+func getIndicators(load *Load, cfg *configInfo) []indicator {
+	return []indicator{
+		{"user", load.User, cfg.CPUHigh, cfg.CPULow},
+		{"system", load.System, cfg.CPUHigh, cfg.CPULow},
+		{"iowait", load.IOWait, cfg.CPUHigh, cfg.CPULow},
+		{"per-cpu-iowait", load.MaxCPUIOWait, cfg.PerCPUIOWaitHigh, cfg.PerCPUIOWaitLow},
+		{"io-pressure", load.IOSomeAvg10, cfg.IOSomeAvg10High, cfg.IOSomeAvg10Low},
+		{"cpu-pressure", load.CPUSomeAvg10, cfg.CPUSomeAvg10High, cfg.CPUSomeAvg10Low},
+	}
+}
+
+// toMap the values of the indicators by name
+func (load *Load) toMap() map[string]float64 {
+	var ret = make(map[string]float64)
+	for _, v := range getIndicators(load, &configInfo{}) {
+		ret[v.name] = v.value
+	}
+	return ret
+}
+
+// evalBusy returns the new state and the name of the indicator which keeps
+// the system busy. The idle system becomes busy when any indicator reaches
+// its high threshold, the busy system becomes idle only after all the
+// indicators drop below their low thresholds.
+func evalBusy(busy bool, load *Load, cfg *configInfo) (bool, string) {
+	for _, v := range getIndicators(load, cfg) {
+		if v.high <= 0 {
+			continue
+		}
+
+		var threshold = v.high
+		if busy {
+			threshold = v.low
+		}
+		if v.value >= threshold {
+			return true, v.name
+		}
+	}
+	return false, ""
+}
+
+// Consumer present the busy state to the user, such as by the cursor
+type Consumer interface {
+	SetBusy(busy bool) error
+}
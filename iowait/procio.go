@@ -0,0 +1,236 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package iowait
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const procDir = "/proc"
+
+// IOConsumer the app or the process which uses the disk
+type IOConsumer struct {
+	// the desktop id, or the command name if the app unknown
+	Id        string
+	DesktopId string
+	Name      string
+	Icon      string
+	Pids      []uint32
+	// bytes per second
+	ReadRate  uint64
+	WriteRate uint64
+}
+
+// AppInfo the desktop app which the process belongs to
+type AppInfo struct {
+	Id   string
+	Name string
+	Icon string
+}
+
+// AppResolver find the app of the process, returns nil if not found
+type AppResolver func(pid uint32) *AppInfo
+
+type procIOStat struct {
+	readBytes  uint64
+	writeBytes uint64
+}
+
+type procIORate struct {
+	comm      string
+	readRate  uint64
+	writeRate uint64
+}
+
+// ioSampler compute the IO rates of the processes of the session user from
+// the deltas of /proc/<pid>/io
+type ioSampler struct {
+	procDir string
+	uid     uint32
+
+	mu       sync.Mutex
+	prev     map[uint32]procIOStat
+	prevTime time.Time
+	rates    map[uint32]procIORate
+}
+
+func newIOSampler() *ioSampler {
+	return &ioSampler{
+		procDir: procDir,
+		uid:     uint32(os.Getuid()),
+	}
+}
+
+func (s *ioSampler) sample(now time.Time) error {
+	fileInfoList, err := ioutil.ReadDir(s.procDir)
+	if err != nil {
+		return err
+	}
+
+	var stats = make(map[uint32]procIOStat)
+	for _, info := range fileInfoList {
+		pid, err := strconv.ParseUint(info.Name(), 10, 32)
+		if err != nil || !info.IsDir() {
+			continue
+		}
+		if st, ok := info.Sys().(*syscall.Stat_t); ok && st.Uid != s.uid {
+			continue
+		}
+
+		stat, err := readProcIO(filepath.Join(s.procDir, info.Name(), "io"))
+		if err != nil {
+			// exited, or not permitted
+			continue
+		}
+		stats[uint32(pid)] = stat
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	elapsed := now.Sub(s.prevTime).Seconds()
+	var rates = make(map[uint32]procIORate)
+	if s.prev != nil && elapsed > 0 {
+		for pid, cur := range stats {
+			old, ok := s.prev[pid]
+			if !ok || cur.readBytes < old.readBytes || cur.writeBytes < old.writeBytes {
+				// new process, or the pid reused
+				continue
+			}
+			rate := procIORate{
+				readRate:  uint64(float64(cur.readBytes-old.readBytes) / elapsed),
+				writeRate: uint64(float64(cur.writeBytes-old.writeBytes) / elapsed),
+			}
+			if rate.readRate == 0 && rate.writeRate == 0 {
+				continue
+			}
+			rate.comm = readProcComm(filepath.Join(s.procDir,
+				strconv.FormatUint(uint64(pid), 10), "comm"))
+			rates[pid] = rate
+		}
+	}
+	s.prev = stats
+	s.prevTime = now
+	s.rates = rates
+	return nil
+}
+
+func (s *ioSampler) getRates() map[uint32]procIORate {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.rates
+}
+
+// getIOConsumers group the processes by app, sorted by the IO rate in
+// descending order, all of them if n <= 0
+func getIOConsumers(rates map[uint32]procIORate, resolver AppResolver, n int) []IOConsumer {
+	var groups = make(map[string]*IOConsumer)
+	for pid, rate := range rates {
+		var c = IOConsumer{
+			Id:   rate.comm,
+			Name: rate.comm,
+		}
+		if c.Id == "" {
+			c.Id = strconv.FormatUint(uint64(pid), 10)
+		}
+		if resolver != nil {
+			if app := resolver(pid); app != nil {
+				c.Id = app.Id
+				c.DesktopId = app.Id
+				c.Name = app.Name
+				c.Icon = app.Icon
+			}
+		}
+
+		group, ok := groups[c.Id]
+		if !ok {
+			group = &c
+			groups[c.Id] = group
+		}
+		group.Pids = append(group.Pids, pid)
+		group.ReadRate += rate.readRate
+		group.WriteRate += rate.writeRate
+	}
+
+	var ret = make([]IOConsumer, 0, len(groups))
+	for _, group := range groups {
+		sort.Slice(group.Pids, func(i, j int) bool {
+			return group.Pids[i] < group.Pids[j]
+		})
+		ret = append(ret, *group)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		ri := ret[i].ReadRate + ret[i].WriteRate
+		rj := ret[j].ReadRate + ret[j].WriteRate
+		if ri != rj {
+			return ri > rj
+		}
+		return ret[i].Id < ret[j].Id
+	})
+	if n > 0 && len(ret) > n {
+		ret = ret[:n]
+	}
+	return ret
+}
+
+// readProcIO read the bytes really fetched from and sent to the storage
+func readProcIO(filename string) (procIOStat, error) {
+	var stat procIOStat
+	fr, err := os.Open(filename)
+	if err != nil {
+		return stat, err
+	}
+	defer fr.Close()
+
+	var found int
+	var scanner = bufio.NewScanner(fr)
+	for scanner.Scan() {
+		// read_bytes: 4096
+		list := strings.Fields(scanner.Text())
+		if len(list) != 2 {
+			continue
+		}
+
+		var p *uint64
+		switch list[0] {
+		case "read_bytes:":
+			p = &stat.readBytes
+		case "write_bytes:":
+			p = &stat.writeBytes
+		default:
+			continue
+		}
+		*p, err = strconv.ParseUint(list[1], 10, 64)
+		if err != nil {
+			return stat, err
+		}
+		found++
+	}
+	if err := scanner.Err(); err != nil {
+		return stat, err
+	}
+	if found != 2 {
+		return stat, fmt.Errorf("bad format: %s", filename)
+	}
+	return stat, nil
+}
+
+func readProcComm(filename string) string {
+	contents, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(contents))
+}
@@ -0,0 +1,47 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package iowait
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const (
+	ioPressureFile  = "/proc/pressure/io"
+	cpuPressureFile = "/proc/pressure/cpu"
+)
+
+// getSomeAvg10 get the percentage of the time in the last 10s in which some
+// tasks stalled on the resource, the format is
+// 'some avg10=0.00 avg60=0.00 avg300=0.00 total=0'
+func getSomeAvg10(filename string) (float64, error) {
+	fr, err := os.Open(filename)
+	if err != nil {
+		return 0, err
+	}
+	defer fr.Close()
+
+	var scanner = bufio.NewScanner(fr)
+	for scanner.Scan() {
+		list := strings.Fields(scanner.Text())
+		if len(list) < 2 || list[0] != "some" {
+			continue
+		}
+
+		for _, field := range list[1:] {
+			if strings.HasPrefix(field, "avg10=") {
+				return strconv.ParseFloat(strings.TrimPrefix(field, "avg10="), 64)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, err
+	}
+	return 0, fmt.Errorf("bad format: %s", filename)
+}
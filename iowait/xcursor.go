@@ -0,0 +1,42 @@
+// SPDX-FileCopyrightText: 2018 - 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package iowait
+
+// #cgo pkg-config: x11 xcursor xfixes gio-2.0
+// #cgo LDFLAGS: -lm
+// #cgo CFLAGS: -W -Wall -fstack-protector-all -fPIC
+// #include "xcursor_remap.h"
+import "C"
+
+import (
+	"fmt"
+)
+
+// xcursorConsumer map the left_ptr cursor to the watch cursor, X11 only
+type xcursorConsumer struct {
+	isWatch bool
+}
+
+func newXCursorConsumer() *xcursorConsumer {
+	return &xcursorConsumer{}
+}
+
+func (c *xcursorConsumer) SetBusy(busy bool) error {
+	if c.isWatch == busy {
+		return nil
+	}
+
+	var v C.int = 1
+	if !busy {
+		v = 0
+	}
+
+	ret := C.xc_left_ptr_to_watch(v)
+	if ret != 0 {
+		return fmt.Errorf("failed to map(%v) left_ptr/left_ptr_watch", busy)
+	}
+	c.isWatch = busy
+	return nil
+}
@@ -0,0 +1,290 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	dbus "github.com/godbus/dbus"
+	"github.com/linuxdeepin/go-lib/dbusutil"
+	"github.com/linuxdeepin/startdde/memchecker"
+)
+
+const (
+	pendingKindLaunchApp       = "LaunchApp"
+	pendingKindLaunchAppAction = "LaunchAppAction"
+	pendingKindRunCommand      = "RunCommand"
+
+	defaultPendingLaunchExpiry = 5 * time.Minute
+
+	envPendingLaunchExpiry = "DDE_PENDING_LAUNCH_EXPIRY"
+)
+
+var (
+	_launchQueue         = newLaunchQueue()
+	_pendingLaunchExpiry = defaultPendingLaunchExpiry
+)
+
+func init() {
+	s := os.Getenv(envPendingLaunchExpiry)
+	if s == "" {
+		return
+	}
+
+	v, _ := strconv.ParseInt(s, 10, 32)
+	if v > 0 {
+		_pendingLaunchExpiry = time.Duration(v) * time.Second
+	}
+}
+
+// pendingLaunch the launch deferred by the memory insufficient
+type pendingLaunch struct {
+	id        uint32
+	kind      string
+	name      string
+	requester string
+	created   time.Time
+	expiry    time.Time
+	neededMem uint64
+
+	// LaunchApp and LaunchAppAction
+	desktop   string
+	action    string
+	timestamp uint32
+	files     []string
+	// LaunchApp and RunCommand
+	options map[string]dbus.Variant
+	// RunCommand
+	exe  string
+	args []string
+}
+
+// PendingLaunch the pending launch exported over D-Bus
+type PendingLaunch struct {
+	Id   uint32
+	Kind string
+	// the desktop file, or the command line
+	Name string
+	// the D-Bus unique name of the caller, empty if launched by startdde
+	Requester string
+	// unix timestamps, in seconds
+	Created int64
+	Expiry  int64
+	// the memory the launch needed, in kB
+	NeededMemory uint64
+}
+
+type launchQueue struct {
+	mu            sync.Mutex
+	items         []*pendingLaunch
+	nextId        uint32
+	tickerRunning bool
+}
+
+func newLaunchQueue() *launchQueue {
+	return &launchQueue{
+		nextId: 1,
+	}
+}
+
+// add append the item to the queue, returns true if the memory ticker
+// should be started
+func (q *launchQueue) add(item *pendingLaunch) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	item.id = q.nextId
+	q.nextId++
+	item.created = time.Now()
+	item.expiry = item.created.Add(_pendingLaunchExpiry)
+	q.items = append(q.items, item)
+
+	if q.tickerRunning {
+		return false
+	}
+	q.tickerRunning = true
+	return true
+}
+
+func (q *launchQueue) remove(id uint32) *pendingLaunch {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for i, item := range q.items {
+		if item.id == id {
+			q.items = append(q.items[:i], q.items[i+1:]...)
+			return item
+		}
+	}
+	return nil
+}
+
+func (q *launchQueue) get(id uint32) *pendingLaunch {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for _, item := range q.items {
+		if item.id == id {
+			return item
+		}
+	}
+	return nil
+}
+
+func (q *launchQueue) removeExpired(now time.Time) []*pendingLaunch {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	var expired []*pendingLaunch
+	var items = q.items[:0]
+	for _, item := range q.items {
+		if now.After(item.expiry) {
+			expired = append(expired, item)
+			continue
+		}
+		items = append(items, item)
+	}
+	q.items = items
+	return expired
+}
+
+// clear remove all the items, returns them in the order they were added
+func (q *launchQueue) clear() []*pendingLaunch {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	items := q.items
+	q.items = nil
+	return items
+}
+
+func (q *launchQueue) list() []*pendingLaunch {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	items := make([]*pendingLaunch, len(q.items))
+	copy(items, q.items)
+	return items
+}
+
+func (q *launchQueue) len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.items)
+}
+
+func (q *launchQueue) neededMemory() uint64 {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	var sum uint64
+	for _, item := range q.items {
+		sum += item.neededMem
+	}
+	return sum
+}
+
+// stopTickerIfEmpty mark the ticker stopped if no item left, the check and
+// the mark must be atomic, otherwise the item added between them is lost
+func (q *launchQueue) stopTickerIfEmpty() bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if len(q.items) != 0 {
+		return false
+	}
+	q.tickerRunning = false
+	return true
+}
+
+func (item *pendingLaunch) toPendingLaunch() PendingLaunch {
+	return PendingLaunch{
+		Id:           item.id,
+		Kind:         item.kind,
+		Name:         item.name,
+		Requester:    item.requester,
+		Created:      item.created.Unix(),
+		Expiry:       item.expiry.Unix(),
+		NeededMemory: item.neededMem,
+	}
+}
+
+func (m *StartManager) launchPending(item *pendingLaunch) error {
+	switch item.kind {
+	case pendingKindLaunchApp:
+		return m.doLaunchAppWithOptions(item.desktop, item.timestamp,
+			item.files, item.options)
+	case pendingKindLaunchAppAction:
+		return m.doLaunchAppAction(item.desktop, item.action, item.timestamp)
+	case pendingKindRunCommand:
+		return m.doRunCommandWithOptions(item.exe, item.args, item.options)
+	}
+	return fmt.Errorf("unknown pending launch kind: %s", item.kind)
+}
+
+// tryPendingLaunch launch the item if the memory is sufficient now,
+// otherwise keep it in the queue and show the warning dialog again
+func (m *StartManager) tryPendingLaunch(id uint32) error {
+	item := _launchQueue.get(id)
+	if item == nil {
+		return fmt.Errorf("not found the pending launch: %d", id)
+	}
+
+	if _gSettingsConfig.memcheckerEnabled && !memchecker.IsSufficient() {
+		logger.Info("The memory still insufficient:", item.id, item.name)
+		updateNeededMemory()
+		showWarningDialog(item.name)
+		return nil
+	}
+
+	if _launchQueue.remove(id) == nil {
+		// launched or canceled by others
+		return nil
+	}
+	updateNeededMemory()
+	return m.launchPending(item)
+}
+
+// ListPendingLaunches list the launches deferred by the memory insufficient
+func (m *StartManager) ListPendingLaunches() ([]PendingLaunch, *dbus.Error) {
+	items := _launchQueue.list()
+	ret := make([]PendingLaunch, 0, len(items))
+	for _, item := range items {
+		ret = append(ret, item.toPendingLaunch())
+	}
+	return ret, nil
+}
+
+// CancelPendingLaunch remove the pending launch from the queue
+func (m *StartManager) CancelPendingLaunch(sender dbus.Sender, id uint32) *dbus.Error {
+	err := checkDMsgUid(m.service, sender)
+	if err != nil {
+		return dbusutil.ToError(err)
+	}
+
+	item := _launchQueue.remove(id)
+	if item == nil {
+		return dbusutil.ToError(fmt.Errorf("not found the pending launch: %d", id))
+	}
+	logger.Info("Cancel the pending launch:", item.id, item.name)
+	updateNeededMemory()
+	return nil
+}
+
+// TryAgainPendingLaunch launch the pending launch if the memory is
+// sufficient now
+func (m *StartManager) TryAgainPendingLaunch(sender dbus.Sender, id uint32) *dbus.Error {
+	err := checkDMsgUid(m.service, sender)
+	if err != nil {
+		return dbusutil.ToError(err)
+	}
+
+	err = m.tryPendingLaunch(id)
+	return dbusutil.ToError(err)
+}
@@ -0,0 +1,42 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_launchQueue(t *testing.T) {
+	q := newLaunchQueue()
+
+	// the first item starts the ticker
+	assert.True(t, q.add(&pendingLaunch{name: "a", neededMem: 100}))
+	assert.False(t, q.add(&pendingLaunch{name: "b", neededMem: 200}))
+	assert.False(t, q.add(&pendingLaunch{name: "c", neededMem: 300}))
+	assert.Equal(t, 3, q.len())
+	assert.Equal(t, uint64(600), q.neededMemory())
+
+	items := q.list()
+	assert.Equal(t, uint32(1), items[0].id)
+	assert.Equal(t, uint32(3), items[2].id)
+
+	item := q.remove(2)
+	assert.Equal(t, "b", item.name)
+	assert.Nil(t, q.remove(2))
+	assert.Equal(t, "c", q.get(3).name)
+
+	expired := q.removeExpired(time.Now().Add(_pendingLaunchExpiry + time.Second))
+	assert.Len(t, expired, 2)
+	assert.Equal(t, 0, q.len())
+
+	assert.True(t, q.stopTickerIfEmpty())
+	// the ticker should be started again
+	assert.True(t, q.add(&pendingLaunch{name: "d"}))
+	assert.False(t, q.stopTickerIfEmpty())
+	assert.Len(t, q.clear(), 1)
+}
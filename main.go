@@ -57,6 +57,8 @@ var _useKWin bool
 
 var _homeDir string
 
+var _loadMonitor *iowait.LoadMonitor
+
 func init() {
 	flag.BoolVar(&_options.noXSessionScripts, "no-xsession-scripts", false, "")
 }
@@ -352,7 +354,7 @@ func main() {
 		os.Exit(1)
 	}
 
-	xsManager, err := xsettings.Start(xConn, recommendedScaleFactor, service, &display.ScaleFactorsHelper)
+	xsManager, err := xsettings.Start(xConn, _useWayland, recommendedScaleFactor, service, &display.ScaleFactorsHelper)
 	if err != nil {
 		logger.Warning(err)
 	} else {
@@ -421,7 +423,13 @@ func main() {
 	watchdog.Start(sessionManager.getLocked, _useKWin)
 
 	if _gSettingsConfig.iowaitEnabled {
-		go iowait.Start(logger)
+		loadMonitor, err := iowait.Start(logger, service, _useWayland)
+		if err != nil {
+			logger.Warning("failed to start iowait:", err)
+		} else {
+			loadMonitor.SetAppResolver(_startManager.getAppInfoByPid)
+			_loadMonitor = loadMonitor
+		}
 	} else {
 		logger.Info("iowait disabled")
 	}
@@ -447,9 +455,18 @@ loop:
 	}
 
 	logger.Info("received unexpected signal, force logout")
+	stopLoadMonitor()
+	display.Stop()
 	m.doLogout(true)
 }
 
+// stopLoadMonitor 会话退出前恢复忙碌时修改的光标
+func stopLoadMonitor() {
+	if _loadMonitor != nil {
+		_loadMonitor.Stop()
+	}
+}
+
 func doSetLogLevel(level log.Priority) {
 	logger.SetLogLevel(level)
 	display.SetLogLevel(level)
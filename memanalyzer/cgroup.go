@@ -18,13 +18,17 @@ const (
 	login1Dest       = "org.freedesktop.login1"
 	login1SelfPath   = "/org/freedesktop/login1/session/self"
 	login1SessionIFC = login1Dest + ".Session"
+
+	cgroupRoot      = "/sys/fs/cgroup"
+	cgroupV1MemRoot = cgroupRoot + "/memory"
+	cgroupProcsFile = "cgroup.procs"
 )
 
 var (
 	_sessionID = ""
 )
 
-func getProcessList(pid uint16) ([]uint16, error) {
+func getProcessList(pid uint32) ([]uint32, error) {
 	dir, err := getCGroupDDEPath()
 	if err != nil {
 		return nil, err
@@ -40,8 +44,8 @@ func getProcessList(pid uint16) ([]uint16, error) {
 			continue
 		}
 
-		found, ret := isPidFound(pid, fmt.Sprintf("%s/%s/cgroup.procs",
-			dir, fileInfo.Name()))
+		found, ret := isPidFound(pid, filepath.Join(dir, fileInfo.Name(),
+			cgroupProcsFile))
 		if found {
 			return ret, nil
 		}
@@ -49,9 +53,102 @@ func getProcessList(pid uint16) ([]uint16, error) {
 	return nil, fmt.Errorf("no group found for %v", pid)
 }
 
-func getPidsInCGroup(cgroupName string) ([]uint16, error) {
-	cgroupProcsFile := filepath.Join("/sys/fs/cgroup/memory", cgroupName, "cgroup.procs")
-	contents, err := ioutil.ReadFile(cgroupProcsFile)
+// listAppCGroupsV1 list the app cgroups under the uiapps of the session
+func listAppCGroupsV1(dir string) ([]*AppCGroup, error) {
+	fileInfoList, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var ret []*AppCGroup
+	for _, fileInfo := range fileInfoList {
+		if !fileInfo.IsDir() {
+			continue
+		}
+
+		path := filepath.Join(dir, fileInfo.Name())
+		pids, err := readCGroupProcs(path)
+		if err != nil || len(pids) == 0 {
+			continue
+		}
+		ret = append(ret, &AppCGroup{
+			Name: fileInfo.Name(),
+			Dir:  path,
+			Pids: pids,
+		})
+	}
+	return ret, nil
+}
+
+// getPidsInCGroup get the processes in the cgroup of the memory hierarchy,
+// the apps are in the uiapps of the session rather than the hierarchy root.
+func getPidsInCGroup(cgroupName string) ([]uint32, error) {
+	uiappsDir, err := getCGroupDDEPath()
+	if err != nil {
+		return nil, err
+	}
+
+	dir, err := getCGroupV1Dir(uiappsDir, cgroupName)
+	if err != nil {
+		return nil, err
+	}
+	return readCGroupProcs(dir)
+}
+
+// getCGroupV1Dir find the cgroup directory of the memory hierarchy.
+// The name can be an absolute path, a path relative to the memory hierarchy
+// root or the name of the app cgroup under the uiapps.
+func getCGroupV1Dir(uiappsDir, name string) (string, error) {
+	if filepath.IsAbs(name) && strings.HasPrefix(name, cgroupRoot) {
+		return name, nil
+	}
+
+	if strings.Contains(name, "/") {
+		return filepath.Join(cgroupV1MemRoot, name), nil
+	}
+
+	return findCGroupDir(uiappsDir, name)
+}
+
+// getAppCGroupV1Dir get the cgroup directory of the memory hierarchy which
+// the pid belongs to, the processes not in any cgroup are refused.
+func getAppCGroupV1Dir(pid uint32) (string, error) {
+	path, err := parseProcCGroupV1File(fmt.Sprintf("/proc/%v/cgroup", pid), "memory")
+	if err != nil {
+		return "", err
+	}
+
+	if path == "/" {
+		return "", fmt.Errorf("the process %v not in a memory cgroup", pid)
+	}
+	return filepath.Join(cgroupV1MemRoot, path), nil
+}
+
+// parseProcCGroupV1File returns the path of the hierarchy which the
+// controller attached to, the format is '4:memory:/1@dde/uiapps/xxx'
+func parseProcCGroupV1File(filename, controller string) (string, error) {
+	contents, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return "", err
+	}
+
+	lines := strings.Split(string(contents), "\n")
+	for _, line := range lines {
+		fields := strings.SplitN(line, ":", 3)
+		if len(fields) != 3 {
+			continue
+		}
+		for _, v := range strings.Split(fields[1], ",") {
+			if v == controller {
+				return fields[2], nil
+			}
+		}
+	}
+	return "", fmt.Errorf("no %s hierarchy found in %s", controller, filename)
+}
+
+func readCGroupProcs(dir string) ([]uint32, error) {
+	contents, err := ioutil.ReadFile(filepath.Join(dir, cgroupProcsFile))
 	if err != nil {
 		return nil, err
 	}
@@ -64,10 +161,10 @@ func getPidsInCGroup(cgroupName string) ([]uint16, error) {
 		}
 		ret = append(ret, line)
 	}
-	return strvToUint16(ret), nil
+	return strvToPids(ret), nil
 }
 
-func isPidFound(pid uint16, filename string) (bool, []uint16) {
+func isPidFound(pid uint32, filename string) (bool, []uint32) {
 	contents, err := ioutil.ReadFile(filename)
 	if err != nil {
 		return false, nil
@@ -92,7 +189,7 @@ func isPidFound(pid uint16, filename string) (bool, []uint16) {
 		return false, nil
 	}
 
-	return true, strvToUint16(ret)
+	return true, strvToPids(ret)
 }
 
 func getCGroupDDEPath() (string, error) {
@@ -101,7 +198,7 @@ func getCGroupDDEPath() (string, error) {
 		return "", err
 	}
 
-	return fmt.Sprintf("/sys/fs/cgroup/memory/%s@dde/uiapps/", id), nil
+	return fmt.Sprintf("%s/%s@dde/uiapps/", cgroupV1MemRoot, id), nil
 }
 
 func getSessionID() (string, error) {
@@ -124,11 +221,13 @@ func getSessionID() (string, error) {
 	return _sessionID, nil
 }
 
-func strvToUint16(list []string) []uint16 {
-	var ret []uint16
+// strvToPids converts the lines of cgroup.procs to pids, pid_max may be
+// up to 2^22, so the pids must not be truncated to 16 bits.
+func strvToPids(list []string) []uint32 {
+	var ret []uint32
 	for _, s := range list {
-		v, _ := strconv.ParseUint(s, 10, 64)
-		ret = append(ret, uint16(v))
+		v, _ := strconv.ParseUint(strings.TrimSpace(s), 10, 32)
+		ret = append(ret, uint32(v))
 	}
 	return ret
 }
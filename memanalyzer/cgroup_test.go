@@ -10,24 +10,66 @@ import (
 	"github.com/stretchr/testify/assert"
 )
 
-func Test_strvToUint16(t *testing.T) {
+func Test_strvToPids(t *testing.T) {
 	testdata := struct {
 		from []string
-		to   []uint16
+		to   []uint32
 	}{
 		from: []string{
 			"12",
 			"99",
 			"ac",
+			"4194304",
 		},
 
-		to: []uint16{
-			uint16(12),
-			uint16(99),
-			uint16(0),
+		to: []uint32{
+			uint32(12),
+			uint32(99),
+			uint32(0),
+			uint32(4194304),
 		},
 	}
 
-	value := strvToUint16(testdata.from)
+	value := strvToPids(testdata.from)
 	assert.ElementsMatch(t, testdata.to, value)
 }
+
+func Test_getCGroupV1Dir(t *testing.T) {
+	const uiappsDir = "./testdata/cgroup_v1/2@dde/uiapps"
+	dir, err := getCGroupV1Dir(uiappsDir, "app-dde-deepin\\x2deditor-4096.scope")
+	assert.NoError(t, err)
+	assert.Equal(t, "testdata/cgroup_v1/2@dde/uiapps/app-dde-deepin\\x2deditor-4096.scope", dir)
+	pids, err := readCGroupProcs(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, []uint32{4096, 4100}, pids)
+
+	_, err = getCGroupV1Dir(uiappsDir, "app-not-exist-1.scope")
+	assert.Error(t, err)
+
+	dir, err = getCGroupV1Dir(uiappsDir, "2@dde/uiapps/app-dde-deepin\\x2dmusic-5120.scope")
+	assert.NoError(t, err)
+	assert.Equal(t, cgroupV1MemRoot+"/2@dde/uiapps/app-dde-deepin\\x2dmusic-5120.scope", dir)
+}
+
+func Test_listAppCGroupsV1(t *testing.T) {
+	list, err := listAppCGroupsV1("./testdata/cgroup_v1/2@dde/uiapps")
+	assert.NoError(t, err)
+	assert.Len(t, list, 2)
+	assert.Equal(t, "app-dde-deepin\\x2deditor-4096.scope", list[0].Name)
+	assert.Equal(t, []uint32{4096, 4100}, list[0].Pids)
+	assert.Equal(t, "app-dde-deepin\\x2dmusic-5120.scope", list[1].Name)
+	assert.Equal(t, []uint32{5120}, list[1].Pids)
+}
+
+func Test_parseProcCGroupV1File(t *testing.T) {
+	path, err := parseProcCGroupV1File("./testdata/proc_pid_cgroup_v1", "memory")
+	assert.NoError(t, err)
+	assert.Equal(t, "/2@dde/uiapps/app-dde-deepin\\x2deditor-4096.scope", path)
+
+	path, err = parseProcCGroupV1File("./testdata/proc_pid_cgroup_v1", "cpuacct")
+	assert.NoError(t, err)
+	assert.Equal(t, "/", path)
+
+	_, err = parseProcCGroupV1File("./testdata/proc_pid_cgroup", "memory")
+	assert.Error(t, err)
+}
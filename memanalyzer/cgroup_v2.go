@@ -0,0 +1,314 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package memanalyzer
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	cgroupV2ControllersFile = "cgroup.controllers"
+	cgroupV2MemStatFile     = "memory.stat"
+	cgroupV2MemCurrentFile  = "memory.current"
+
+	// the prefix of the scopes and services which systemd creates for apps,
+	// such as 'app-dde-deepin-editor-1234.scope'
+	appUnitPrefix = "app-"
+)
+
+var (
+	_isCGroupV2     bool
+	_isCGroupV2Once sync.Once
+)
+
+// the keys of memory.stat which can not be reclaimed by the kernel, the page
+// cache is left out, since it has been counted in the MemAvailable
+var cgroupV2UnreclaimableKeys = []string{
+	"anon",
+	"shmem",
+	"kernel_stack",
+	"pagetables",
+	"percpu",
+	"sock",
+}
+
+func isCGroupV2() bool {
+	_isCGroupV2Once.Do(func() {
+		_, err := os.Stat(filepath.Join(cgroupRoot, cgroupV2ControllersFile))
+		_isCGroupV2 = err == nil
+	})
+	return _isCGroupV2
+}
+
+// getCGroupV2Dir find the cgroup directory of the unified hierarchy.
+// The name can be an absolute path, a path relative to the cgroup root or
+// a systemd unit name, such as 'app-dde-deepin-editor-1234.scope'.
+func getCGroupV2Dir(name string) (string, error) {
+	if filepath.IsAbs(name) && strings.HasPrefix(name, cgroupRoot) {
+		return name, nil
+	}
+
+	if strings.Contains(name, "/") {
+		return filepath.Join(cgroupRoot, name), nil
+	}
+
+	return findCGroupDir(getUserCGroupV2Dir(), name)
+}
+
+// getUserCGroupV2Dir the cgroup of the user manager, the apps launched in
+// the session are in it
+func getUserCGroupV2Dir() string {
+	uid := os.Getuid()
+	return filepath.Join(cgroupRoot, "user.slice",
+		fmt.Sprintf("user-%d.slice", uid),
+		fmt.Sprintf("user@%d.service", uid))
+}
+
+// listAppCGroupsV2 list the app units which have processes under the root
+func listAppCGroupsV2(root string) ([]*AppCGroup, error) {
+	var ret []*AppCGroup
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() || !isAppUnit(info.Name()) {
+			return nil
+		}
+
+		pids, err := readCGroupProcs(path)
+		if err != nil || len(pids) == 0 {
+			return nil
+		}
+		ret = append(ret, &AppCGroup{
+			Name: info.Name(),
+			Dir:  path,
+			Pids: pids,
+		})
+		return filepath.SkipDir
+	})
+	return ret, err
+}
+
+func isAppUnit(name string) bool {
+	return strings.HasPrefix(name, appUnitPrefix) &&
+		(strings.HasSuffix(name, ".scope") || strings.HasSuffix(name, ".service"))
+}
+
+// GetAppUnitByPid get the app unit of the process from /proc/<pid>/cgroup,
+// returns "" if the process not in an app unit
+func GetAppUnitByPid(pid uint32) string {
+	contents, err := ioutil.ReadFile(fmt.Sprintf("/proc/%v/cgroup", pid))
+	if err != nil {
+		return ""
+	}
+	return parseAppUnit(string(contents))
+}
+
+// parseAppUnit find the app unit in the lines such as
+// '0::/user.slice/user-1000.slice/user@1000.service/app.slice/app-dde-xxx.scope'
+func parseAppUnit(contents string) string {
+	for _, line := range strings.Split(contents, "\n") {
+		fields := strings.SplitN(line, ":", 3)
+		if len(fields) != 3 {
+			continue
+		}
+		for _, name := range strings.Split(fields[2], "/") {
+			if isAppUnit(name) {
+				return name
+			}
+		}
+	}
+	return ""
+}
+
+// GetAppIdCandidatesByUnit parse the app id from the unit name, the format is
+// 'app[-<launcher>]-<ApplicationID>-<RANDOM>.scope' or
+// 'app[-<launcher>]-<ApplicationID>[@<RANDOM>].service', the launcher is
+// optional, so both with and without it are returned.
+func GetAppIdCandidatesByUnit(unitName string) []string {
+	name := unescapeUnitName(unitName)
+	switch {
+	case strings.HasSuffix(name, ".scope"):
+		name = strings.TrimSuffix(name, ".scope")
+		idx := strings.LastIndex(name, "-")
+		if idx < 0 {
+			return nil
+		}
+		name = name[:idx]
+	case strings.HasSuffix(name, ".service"):
+		name = strings.TrimSuffix(name, ".service")
+		if idx := strings.Index(name, "@"); idx >= 0 {
+			name = name[:idx]
+		}
+	default:
+		return nil
+	}
+
+	if !strings.HasPrefix(name, appUnitPrefix) {
+		return nil
+	}
+	name = strings.TrimPrefix(name, appUnitPrefix)
+	if name == "" {
+		return nil
+	}
+
+	ret := []string{name}
+	if idx := strings.Index(name, "-"); idx > 0 && idx < len(name)-1 {
+		ret = append(ret, name[idx+1:])
+	}
+	return ret
+}
+
+// unescapeUnitName unescape the '\xNN' sequences of the systemd unit name
+func unescapeUnitName(name string) string {
+	var sb strings.Builder
+	for i := 0; i < len(name); i++ {
+		if name[i] == '\\' && i+3 < len(name) && name[i+1] == 'x' {
+			v, err := strconv.ParseUint(name[i+2:i+4], 16, 8)
+			if err == nil {
+				sb.WriteByte(byte(v))
+				i += 3
+				continue
+			}
+		}
+		sb.WriteByte(name[i])
+	}
+	return sb.String()
+}
+
+// errCGroupFound stops walking the cgroup tree once the cgroup found
+var errCGroupFound = errors.New("cgroup found")
+
+// findCGroupDir walk the tree under root for the cgroup of the name, used
+// only if the cgroup can not be resolved by a pid, see GetAppCGroupDir.
+func findCGroupDir(root, name string) (string, error) {
+	var ret string
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if path == root {
+				return err
+			}
+			// the cgroup removed while walking
+			return nil
+		}
+		if !info.IsDir() {
+			return nil
+		}
+		if info.Name() == name {
+			ret = path
+			return errCGroupFound
+		}
+		return nil
+	})
+	if err != nil && err != errCGroupFound {
+		return "", err
+	}
+	if ret == "" {
+		return "", fmt.Errorf("no cgroup found for %s", name)
+	}
+	return ret, nil
+}
+
+// getAppCGroupV2Dir get the cgroup directory of the app which the pid
+// belongs to, only the units created for apps are accepted, otherwise the
+// memory of the whole session will be counted.
+func getAppCGroupV2Dir(pid uint32) (string, error) {
+	path, err := parseProcCGroupFile(fmt.Sprintf("/proc/%v/cgroup", pid))
+	if err != nil {
+		return "", err
+	}
+
+	if !strings.HasPrefix(filepath.Base(path), appUnitPrefix) {
+		return "", fmt.Errorf("the process %v not in an app unit: %s", pid, path)
+	}
+	return filepath.Join(cgroupRoot, path), nil
+}
+
+// parseProcCGroupFile returns the path of the unified hierarchy entry,
+// the format is '0::/user.slice/.../app-dde-xxx.scope'
+func parseProcCGroupFile(filename string) (string, error) {
+	contents, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return "", err
+	}
+
+	lines := strings.Split(string(contents), "\n")
+	for _, line := range lines {
+		if strings.HasPrefix(line, "0::") {
+			return strings.TrimPrefix(line, "0::"), nil
+		}
+	}
+	return "", fmt.Errorf("no unified hierarchy found in %s", filename)
+}
+
+// getCGroupV2Memory returns the unreclaimable memory of the cgroup in kB,
+// falls back to memory.current if memory.stat not available.
+func getCGroupV2Memory(dir string) (uint64, error) {
+	v, err := sumMemByStatFile(filepath.Join(dir, cgroupV2MemStatFile))
+	if err == nil {
+		return v, nil
+	}
+
+	contents, err := ioutil.ReadFile(filepath.Join(dir, cgroupV2MemCurrentFile))
+	if err != nil {
+		return 0, err
+	}
+	v, err = strconv.ParseUint(strings.TrimSpace(string(contents)), 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	return v / 1024, nil
+}
+
+func sumMemByStatFile(filename string) (uint64, error) {
+	fr, err := os.Open(filename)
+	if err != nil {
+		return 0, err
+	}
+	defer fr.Close()
+
+	var found = false
+	var memSize uint64
+	var scanner = bufio.NewScanner(fr)
+	for scanner.Scan() {
+		list := strings.Fields(scanner.Text())
+		if len(list) != 2 || !isUnreclaimableKey(list[0]) {
+			continue
+		}
+
+		v, err := strconv.ParseUint(list[1], 10, 64)
+		if err != nil {
+			return 0, err
+		}
+		memSize += v
+		found = true
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, err
+	}
+	if !found {
+		return 0, fmt.Errorf("bad format: %s", filename)
+	}
+
+	// memory.stat in bytes
+	return memSize / 1024, nil
+}
+
+func isUnreclaimableKey(key string) bool {
+	for _, v := range cgroupV2UnreclaimableKeys {
+		if v == key {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,117 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package memanalyzer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_parseProcCGroupFile(t *testing.T) {
+	path, err := parseProcCGroupFile("./testdata/proc_pid_cgroup")
+	assert.NoError(t, err)
+	assert.Equal(t, "/user.slice/user-1000.slice/user@1000.service/app.slice/app-dde-deepin\\x2deditor-4096.scope", path)
+
+	_, err = parseProcCGroupFile("./testdata/proc_pid_status")
+	assert.Error(t, err)
+}
+
+func Test_sumMemByStatFile(t *testing.T) {
+	// anon + shmem + kernel_stack + pagetables + percpu + sock
+	sum, err := sumMemByStatFile("./testdata/memory.stat")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64((52428800+1048576+294912+1310720+0+4096)/1024), sum)
+
+	_, err = sumMemByStatFile("./testdata/proc_pid_status")
+	assert.Error(t, err)
+}
+
+func Test_getCGroupV2Memory(t *testing.T) {
+	sum, err := getCGroupV2Memory("./testdata/cgroup_v2")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(81920), sum)
+}
+
+func Test_listAppCGroupsV2(t *testing.T) {
+	list, err := listAppCGroupsV2("./testdata/user@1000.service")
+	assert.NoError(t, err)
+	assert.Len(t, list, 2)
+	assert.Equal(t, "app-dde-deepin\\x2deditor-4096.scope", list[0].Name)
+	assert.Equal(t, []uint32{4096, 4100}, list[0].Pids)
+	assert.Equal(t, "app-dde-deepin\\x2dmusic-5120.scope", list[1].Name)
+	assert.Equal(t, []uint32{5120}, list[1].Pids)
+}
+
+func Test_findCGroupDir(t *testing.T) {
+	const root = "./testdata/user@1000.service"
+	dir, err := findCGroupDir(root, "app-dde-deepin\\x2deditor-4096.scope")
+	assert.NoError(t, err)
+	assert.Equal(t, "testdata/user@1000.service/app.slice/app-dde-deepin\\x2deditor-4096.scope", dir)
+
+	dir, err = findCGroupDir(root, "dbus.service")
+	assert.NoError(t, err)
+	assert.Equal(t, "testdata/user@1000.service/session.slice/dbus.service", dir)
+
+	_, err = findCGroupDir(root, "app-not-exist-1.scope")
+	assert.Error(t, err)
+
+	_, err = findCGroupDir("./testdata/not-exist", "dbus.service")
+	assert.Error(t, err)
+}
+
+func Test_parseAppUnit(t *testing.T) {
+	v2 := "0::/user.slice/user-1000.slice/user@1000.service/app.slice/app-dde-deepin-editor-4096.scope\n"
+	assert.Equal(t, "app-dde-deepin-editor-4096.scope", parseAppUnit(v2))
+
+	v1 := "12:memory:/\n1:name=systemd:/user.slice/user-1000.slice/user@1000.service/app-dde-tracker@a1.service\n0::/\n"
+	assert.Equal(t, "app-dde-tracker@a1.service", parseAppUnit(v1))
+
+	assert.Equal(t, "", parseAppUnit("0::/user.slice/user-1000.slice/session-2.scope\n"))
+}
+
+func Test_GetAppIdCandidatesByUnit(t *testing.T) {
+	tests := []struct {
+		unit string
+		want []string
+	}{
+		{
+			unit: "app-dde-deepin-editor-4096.scope",
+			want: []string{"dde-deepin-editor", "deepin-editor"},
+		},
+		{
+			unit: "app-dde-deepin\\x2deditor-4096.scope",
+			want: []string{"dde-deepin-editor", "deepin-editor"},
+		},
+		{
+			unit: "app-gnome-org.gnome.Terminal@a1b2c3.service",
+			want: []string{"gnome-org.gnome.Terminal", "org.gnome.Terminal"},
+		},
+		{
+			unit: "app-firefox.service",
+			want: []string{"firefox"},
+		},
+		{
+			unit: "dbus.service",
+			want: nil,
+		},
+		{
+			unit: "session-2.scope",
+			want: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.unit, func(t *testing.T) {
+			assert.Equal(t, tt.want, GetAppIdCandidatesByUnit(tt.unit))
+		})
+	}
+}
+
+func Test_unescapeUnitName(t *testing.T) {
+	assert.Equal(t, "deepin-editor", unescapeUnitName("deepin\\x2deditor"))
+	assert.Equal(t, "a\\xzz", unescapeUnitName("a\\xzz"))
+	assert.Equal(t, "a\\x2", unescapeUnitName("a\\x2"))
+}
@@ -7,31 +7,60 @@ package memanalyzer
 import (
 	"bytes"
 	"encoding/gob"
-	"fmt"
+	"encoding/json"
 	"io/ioutil"
 	"os"
 	"path/filepath"
-	"github.com/linuxdeepin/go-lib/xdg/basedir"
 	"sync"
+	"time"
+
+	"github.com/linuxdeepin/go-lib/xdg/basedir"
 )
 
 var (
-	_memDB     map[string]uint64
-	_memLocker sync.Mutex
+	_memDB      map[string]*Record
+	_percentile float64       = defaultPercentile
+	_halfLife   time.Duration = defaultHalfLife
+	_memLocker  sync.Mutex
 )
 
 func init() {
 	_memLocker.Lock()
 	db, err := loadConfig(getConfigPath())
 	if err != nil {
-		_memDB = make(map[string]uint64)
+		_memDB = make(map[string]*Record)
 	} else {
 		_memDB = db
 	}
 	_memLocker.Unlock()
 }
 
-// DumpDB dump config contents
+type dumpRecord struct {
+	Estimate uint64   `json:"estimate"`
+	Steady   uint64   `json:"steady"`
+	Samples  []Sample `json:"samples"`
+}
+
+type dumpInfo struct {
+	Percentile float64                `json:"percentile"`
+	HalfLife   int64                  `json:"half-life"`
+	Records    map[string]*dumpRecord `json:"records"`
+}
+
+// SetModel set the percentile used to estimate the needed memory, and the
+// half life in which the weight of a sample decays by half
+func SetModel(percentile float64, halfLife time.Duration) {
+	_memLocker.Lock()
+	defer _memLocker.Unlock()
+	if percentile > 0 && percentile <= 100 {
+		_percentile = percentile
+	}
+	if halfLife > 0 {
+		_halfLife = halfLife
+	}
+}
+
+// DumpDB dump config contents as json, including the samples of every app
 func DumpDB() string {
 	_memLocker.Lock()
 	defer _memLocker.Unlock()
@@ -39,29 +68,45 @@ func DumpDB() string {
 		return ""
 	}
 
-	var ret = "{"
+	var now = time.Now()
+	var info = dumpInfo{
+		Percentile: _percentile,
+		HalfLife:   int64(_halfLife / time.Second),
+		Records:    make(map[string]*dumpRecord),
+	}
 	for k, v := range _memDB {
-		ret += fmt.Sprintf("\"%s\": %v,", k, v)
+		info.Records[k] = &dumpRecord{
+			Estimate: v.estimate(_percentile, _halfLife, now),
+			Steady:   v.steady(_percentile, _halfLife, now),
+			Samples:  v.Samples,
+		}
+	}
+	data, err := json.Marshal(&info)
+	if err != nil {
+		return ""
 	}
-	data := []byte(ret)
-	data[len(data)-1] = '}'
 	return string(data)
 }
 
-func setDB(k string, v uint64) {
+func addSampleDB(k string, s Sample) {
 	_memLocker.Lock()
 	defer _memLocker.Unlock()
-	tmp := _memDB[k]
-	if tmp == v {
-		return
+	record := _memDB[k]
+	if record == nil {
+		record = new(Record)
+		_memDB[k] = record
 	}
-	_memDB[k] = v
+	record.addSample(s)
 }
 
 func getDB(k string) uint64 {
 	_memLocker.Lock()
 	defer _memLocker.Unlock()
-	return _memDB[k]
+	record := _memDB[k]
+	if record == nil {
+		return 0
+	}
+	return record.estimate(_percentile, _halfLife, time.Now())
 }
 
 func doSaveDB(filename string) error {
@@ -79,18 +124,30 @@ func doSaveDB(filename string) error {
 	return ioutil.WriteFile(filename, w.Bytes(), 0644)
 }
 
-func loadConfig(filename string) (map[string]uint64, error) {
+func loadConfig(filename string) (map[string]*Record, error) {
 	contents, err := ioutil.ReadFile(filename)
 	if err != nil {
 		return nil, err
 	}
 
-	var db = make(map[string]uint64)
-	r := bytes.NewReader(contents)
-	err = gob.NewDecoder(r).Decode(&db)
+	var db = make(map[string]*Record)
+	err = gob.NewDecoder(bytes.NewReader(contents)).Decode(&db)
+	if err == nil {
+		return db, nil
+	}
+
+	// the old db only has one value per app
+	var oldDB = make(map[string]uint64)
+	err = gob.NewDecoder(bytes.NewReader(contents)).Decode(&oldDB)
 	if err != nil {
 		return nil, err
 	}
+
+	var now = time.Now().Unix()
+	db = make(map[string]*Record)
+	for k, v := range oldDB {
+		db[k] = &Record{Samples: []Sample{{Peak: v, Steady: v, Timestamp: now}}}
+	}
 	return db, nil
 }
 
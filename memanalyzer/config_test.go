@@ -5,6 +5,8 @@
 package memanalyzer
 
 import (
+	"encoding/json"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -16,16 +18,38 @@ const (
 
 func Test_config(t *testing.T) {
 	assert.NotPanics(t, func() {
-		_memDB, err := loadConfig(dbpath)
-		assert.NotEqual(t, len(_memDB), 0)
+		// the old format db, one value per app
+		db, err := loadConfig(dbpath)
+		assert.NotEqual(t, len(db), 0)
 		assert.NoError(t, err)
+		for _, v := range db {
+			assert.Len(t, v.Samples, 1)
+		}
 
-		setDB("/usr/share/applications/deepin-editor.desktop", 1024)
+		addSampleDB("/usr/share/applications/deepin-editor.desktop", Sample{Peak: 1024})
 		value := getDB("/usr/share/applications/deepin-editor.desktop")
 		assert.Equal(t, value, uint64(1024))
 
-		setDB("/usr/share/applications/deepin-editor.desktop", 18668)
-		err = doSaveDB(dbpath)
+		addSampleDB("/usr/share/applications/deepin-editor.desktop", Sample{Peak: 18668})
+		filename := filepath.Join(t.TempDir(), "memanalyzer.db")
+		err = doSaveDB(filename)
 		assert.NoError(t, err)
+
+		db, err = loadConfig(filename)
+		assert.NoError(t, err)
+		assert.Len(t, db["/usr/share/applications/deepin-editor.desktop"].Samples, 2)
 	})
 }
+
+func Test_DumpDB(t *testing.T) {
+	addSampleDB("/usr/share/applications/deepin-music.desktop", Sample{Peak: 2048, Steady: 1024})
+
+	var info dumpInfo
+	err := json.Unmarshal([]byte(DumpDB()), &info)
+	assert.NoError(t, err)
+	record := info.Records["/usr/share/applications/deepin-music.desktop"]
+	assert.NotNil(t, record)
+	assert.Equal(t, uint64(2048), record.Estimate)
+	assert.Equal(t, uint64(1024), record.Steady)
+	assert.Len(t, record.Samples, 1)
+}
@@ -10,6 +10,7 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/linuxdeepin/go-lib/strv"
 )
@@ -23,8 +24,66 @@ func GetProcessMemory(name string) (uint64, error) {
 	return v, nil
 }
 
-// GetCGroupMemory get these process in cgroup used memory
+// AppCGroup the cgroup of an app, the Pss is the sum of the processes PSS in kB
+type AppCGroup struct {
+	// the unit name in cgroup v2, or the directory name in the uiapps
+	Name string
+	Dir  string
+	Pids []uint32
+	Pss  uint64
+}
+
+// ListAppCGroups list the cgroups of the apps in the session and the memory
+// they used
+func ListAppCGroups() ([]*AppCGroup, error) {
+	var (
+		list []*AppCGroup
+		err  error
+	)
+	if isCGroupV2() {
+		list, err = listAppCGroupsV2(getUserCGroupV2Dir())
+	} else {
+		var dir string
+		dir, err = getCGroupDDEPath()
+		if err == nil {
+			list, err = listAppCGroupsV1(dir)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for _, v := range list {
+		v.Pss = sumPidsMemory(v.Pids)
+	}
+	return list, nil
+}
+
+// GetPidsMemory get the memory used by the processes, in kB
+func GetPidsMemory(pids []uint32) uint64 {
+	return sumPidsMemory(pids)
+}
+
+// GetAppCGroupDir get the cgroup directory of the app which the pid belongs
+// to from /proc/<pid>/cgroup, the directory can be passed to GetCGroupMemory.
+func GetAppCGroupDir(pid uint32) (string, error) {
+	if isCGroupV2() {
+		return getAppCGroupV2Dir(pid)
+	}
+	return getAppCGroupV1Dir(pid)
+}
+
+// GetCGroupMemory get these process in cgroup used memory, the cgroupName
+// can be the directory of the cgroup or the name to be found
 func GetCGroupMemory(cgroupName string) (uint64, error) {
+	if isCGroupV2() {
+		dir, err := getCGroupV2Dir(cgroupName)
+		if err != nil {
+			return 0, err
+		}
+		return sumCGroupV2Memory(dir)
+	}
+
 	list, err := getPidsInCGroup(cgroupName)
 	if err != nil {
 		return 0, err
@@ -33,7 +92,16 @@ func GetCGroupMemory(cgroupName string) (uint64, error) {
 }
 
 // GetPidMemory get the process used memory
-func GetPidMemory(pid uint16) (uint64, error) {
+func GetPidMemory(pid uint32) (uint64, error) {
+	if isCGroupV2() {
+		dir, err := getAppCGroupV2Dir(pid)
+		if err != nil {
+			fmt.Println("Failed to get app cgroup:", err)
+			return sumMemByPid(pid)
+		}
+		return sumCGroupV2Memory(dir)
+	}
+
 	list, err := getProcessList(pid)
 	if err != nil {
 		fmt.Println("Failed to get process list from cgroup:", err)
@@ -44,11 +112,34 @@ func GetPidMemory(pid uint16) (uint64, error) {
 
 //SaveProcessMemory save process memory used info
 func SaveProcessMemory(name string, mem uint64) error {
-	setDB(name, mem)
+	return SaveProcessSample(name, mem, mem)
+}
+
+// SaveProcessSample save the memory used in the startup and the steady state
+func SaveProcessSample(name string, peak, steady uint64) error {
+	addSampleDB(name, Sample{
+		Peak:      peak,
+		Steady:    steady,
+		Timestamp: time.Now().Unix(),
+	})
 	return doSaveDB(getConfigPath())
 }
 
-func sumPidsMemory(pids []uint16) uint64 {
+func sumCGroupV2Memory(dir string) (uint64, error) {
+	v, err := getCGroupV2Memory(dir)
+	if err == nil {
+		return v, nil
+	}
+
+	fmt.Println("Failed to get cgroup memory, try to sum the processes:", err)
+	list, err := readCGroupProcs(dir)
+	if err != nil {
+		return 0, err
+	}
+	return sumPidsMemory(list), nil
+}
+
+func sumPidsMemory(pids []uint32) uint64 {
 	var memSize uint64
 	for _, v := range pids {
 		s, err := sumMemByPid(v)
@@ -61,10 +152,36 @@ func sumPidsMemory(pids []uint16) uint64 {
 	return memSize
 }
 
-func sumMemByPid(pid uint16) (uint64, error) {
+// sumMemByPid prefer the PSS, the shared pages will not be counted repeatedly
+func sumMemByPid(pid uint32) (uint64, error) {
+	v, err := getPssByFile(fmt.Sprintf("/proc/%v/smaps_rollup", pid))
+	if err == nil {
+		return v, nil
+	}
 	return sumMemByFile(fmt.Sprintf("/proc/%v/status", pid))
 }
 
+func getPssByFile(filename string) (uint64, error) {
+	fr, err := os.Open(filename)
+	if err != nil {
+		return 0, err
+	}
+	defer fr.Close()
+
+	var scanner = bufio.NewScanner(fr)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "Pss:") {
+			continue
+		}
+		return getInteger(line)
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, err
+	}
+	return 0, fmt.Errorf("no pss found in %s", filename)
+}
+
 func sumMemByFile(filename string) (uint64, error) {
 	fr, err := os.Open(filename)
 	if err != nil {
@@ -15,3 +15,12 @@ func Test_sumMemByFile(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, uint64(0x7080), sum)
 }
+
+func Test_getPssByFile(t *testing.T) {
+	pss, err := getPssByFile("./testdata/proc_pid_smaps_rollup")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(31442), pss)
+
+	_, err = getPssByFile("./testdata/proc_pid_status")
+	assert.Error(t, err)
+}
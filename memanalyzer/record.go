@@ -0,0 +1,112 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package memanalyzer
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+const (
+	maxSamples = 20
+
+	defaultPercentile = 90
+	defaultHalfLife   = 14 * 24 * time.Hour
+)
+
+// Sample the memory used by an app at one launch, in kB
+type Sample struct {
+	// the max memory used in the startup
+	Peak uint64 `json:"peak"`
+	// the memory used after startup, 0 if the app exited before that
+	Steady    uint64 `json:"steady"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+// Record the memory history of an app, the oldest sample first
+type Record struct {
+	Samples []Sample `json:"samples"`
+}
+
+func (r *Record) addSample(s Sample) {
+	r.Samples = append(r.Samples, s)
+	if len(r.Samples) > maxSamples {
+		r.Samples = r.Samples[len(r.Samples)-maxSamples:]
+	}
+}
+
+// estimate returns the weighted percentile of the startup peaks, the
+// weight of a sample halves every halfLife, so the new versions of the app
+// take over quickly.
+func (r *Record) estimate(percentile float64, halfLife time.Duration, now time.Time) uint64 {
+	return weightedPercentile(r.Samples, func(s Sample) uint64 {
+		return s.Peak
+	}, percentile, halfLife, now)
+}
+
+// steady returns the weighted percentile of the steady state samples
+func (r *Record) steady(percentile float64, halfLife time.Duration, now time.Time) uint64 {
+	return weightedPercentile(r.Samples, func(s Sample) uint64 {
+		return s.Steady
+	}, percentile, halfLife, now)
+}
+
+type weightedValue struct {
+	value  uint64
+	weight float64
+}
+
+func weightedPercentile(samples []Sample, fn func(Sample) uint64, percentile float64,
+	halfLife time.Duration, now time.Time) uint64 {
+
+	var list []weightedValue
+	var total float64
+	for _, s := range samples {
+		v := fn(s)
+		if v == 0 {
+			continue
+		}
+		w := decayWeight(time.Unix(s.Timestamp, 0), halfLife, now)
+		list = append(list, weightedValue{value: v, weight: w})
+		total += w
+	}
+	if len(list) == 0 {
+		return 0
+	}
+
+	sort.SliceStable(list, func(i, j int) bool {
+		return list[i].value < list[j].value
+	})
+
+	if percentile <= 0 {
+		return list[0].value
+	}
+	if percentile >= 100 || total == 0 {
+		return list[len(list)-1].value
+	}
+
+	// tolerate the rounding errors of the float weights
+	var limit = total*percentile/100 - total*1e-9
+	var sum float64
+	for _, v := range list {
+		sum += v.weight
+		if sum >= limit {
+			return v.value
+		}
+	}
+	return list[len(list)-1].value
+}
+
+func decayWeight(t time.Time, halfLife time.Duration, now time.Time) float64 {
+	if halfLife <= 0 {
+		return 1
+	}
+	age := now.Sub(t)
+	if age < 0 {
+		age = 0
+	}
+	return math.Pow(0.5, float64(age)/float64(halfLife))
+}
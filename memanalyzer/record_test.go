@@ -0,0 +1,49 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package memanalyzer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Record_addSample(t *testing.T) {
+	var r Record
+	for i := 0; i < maxSamples+5; i++ {
+		r.addSample(Sample{Peak: uint64(i)})
+	}
+	assert.Len(t, r.Samples, maxSamples)
+	assert.Equal(t, uint64(5), r.Samples[0].Peak)
+}
+
+func Test_Record_estimate(t *testing.T) {
+	now := time.Now()
+	var r Record
+	for i := 1; i <= 10; i++ {
+		r.addSample(Sample{
+			Peak:      uint64(i * 100),
+			Steady:    uint64(i * 10),
+			Timestamp: now.Unix(),
+		})
+	}
+
+	assert.Equal(t, uint64(900), r.estimate(90, defaultHalfLife, now))
+	assert.Equal(t, uint64(500), r.estimate(50, defaultHalfLife, now))
+	assert.Equal(t, uint64(1000), r.estimate(100, defaultHalfLife, now))
+	assert.Equal(t, uint64(100), r.estimate(0, defaultHalfLife, now))
+	assert.Equal(t, uint64(90), r.steady(90, defaultHalfLife, now))
+
+	// the old samples decay, the new small one takes over
+	var r2 Record
+	r2.addSample(Sample{Peak: 5000, Timestamp: now.Add(-10 * defaultHalfLife).Unix()})
+	r2.addSample(Sample{Peak: 1000, Timestamp: now.Unix()})
+	assert.Equal(t, uint64(1000), r2.estimate(90, defaultHalfLife, now))
+	assert.Equal(t, uint64(5000), r2.estimate(100, defaultHalfLife, now))
+
+	var empty Record
+	assert.Equal(t, uint64(0), empty.estimate(90, defaultHalfLife, now))
+}
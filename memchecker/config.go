@@ -16,11 +16,38 @@ const (
 
 	defaultMinMemAvail = 300  // 300M
 	defaultMaxSwapUsed = 1200 // 1200M
+
+	defaultNeededMemPercentile = 90
+	defaultSampleHalfLife      = 14 // 14 days
+
+	defaultMaxSomeAvg10 = 10 // 10%
+	defaultMaxFullAvg10 = 5  // 5%
+)
+
+const (
+	// ModeMemAvailable check the MemAvailable and swap used in /proc/meminfo
+	ModeMemAvailable = "mem-available"
+	// ModePressure check the memory pressure stall in /proc/pressure/memory
+	ModePressure = "pressure"
 )
 
 type configInfo struct {
 	MinMemAvail uint64 `json:"min-mem-available"`
 	MaxSwapUsed uint64 `json:"max-swap-used"`
+
+	// the percentile of the app memory samples used as the needed memory
+	NeededMemPercentile float64 `json:"needed-memory-percentile"`
+	// the days in which the weight of the app memory sample decays by half
+	SampleHalfLife uint32 `json:"sample-half-life"`
+
+	// 'mem-available' or 'pressure', default 'mem-available'
+	Mode string `json:"mode"`
+	// the thresholds of the avg10 in percentage
+	MaxSomeAvg10 float64 `json:"max-some-avg10"`
+	MaxFullAvg10 float64 `json:"max-full-avg10"`
+	// the PSI trigger, in us
+	TriggerStall  uint32 `json:"trigger-stall"`
+	TriggerWindow uint32 `json:"trigger-window"`
 }
 
 func loadConfig(filename string) (*configInfo, error) {
@@ -20,15 +20,41 @@ func init() {
 			MaxSwapUsed: defaultMaxSwapUsed,
 		}
 	}
+	if _config.NeededMemPercentile <= 0 || _config.NeededMemPercentile > 100 {
+		_config.NeededMemPercentile = defaultNeededMemPercentile
+	}
+	if _config.SampleHalfLife == 0 {
+		_config.SampleHalfLife = defaultSampleHalfLife
+	}
 	correctConfig()
+	correctPressureConfig()
 }
 
 func GetConfig() *configInfo {
 	return _config
 }
 
+// GetMode returns the active mode, the pressure mode falls back to the
+// mem-available mode if the kernel not supports PSI
+func GetMode() string {
+	if _config.Mode == ModePressure && IsPressureSupported() {
+		return ModePressure
+	}
+	return ModeMemAvailable
+}
+
 // IsSufficient check the memory whether reaches the qualified value
 func IsSufficient() bool {
+	if GetMode() == ModePressure {
+		info, err := GetPressure()
+		if err != nil {
+			return true
+		}
+		fmt.Printf("Pressure some: %v(%v), full: %v(%v)\n", info.SomeAvg10,
+			_config.MaxSomeAvg10, info.FullAvg10, _config.MaxFullAvg10)
+		return isPressureSufficient(info)
+	}
+
 	if _config.MinMemAvail == 0 {
 		return true
 	}
@@ -77,3 +103,23 @@ func correctConfig() {
 		_config.MinMemAvail = uint64(float64(info.MemTotal) * 0.15)
 	}
 }
+
+func correctPressureConfig() {
+	if _config.Mode != ModePressure {
+		_config.Mode = ModeMemAvailable
+	}
+
+	if _config.MaxSomeAvg10 <= 0 || _config.MaxSomeAvg10 > 100 {
+		_config.MaxSomeAvg10 = defaultMaxSomeAvg10
+	}
+	if _config.MaxFullAvg10 <= 0 || _config.MaxFullAvg10 > 100 {
+		_config.MaxFullAvg10 = defaultMaxFullAvg10
+	}
+
+	if _config.TriggerWindow == 0 {
+		_config.TriggerWindow = defaultTriggerWindow
+	}
+	if _config.TriggerStall == 0 || _config.TriggerStall > _config.TriggerWindow {
+		_config.TriggerStall = defaultTriggerStall
+	}
+}
@@ -20,3 +20,28 @@ func Test_IsSufficient(t *testing.T) {
 		IsSufficient()
 	})
 }
+
+func Test_IsSufficient_pressure(t *testing.T) {
+	oldConfig := *_config
+	oldPressureFile := memPressureFile
+	defer func() {
+		*_config = oldConfig
+		memPressureFile = oldPressureFile
+	}()
+	memPressureFile = "./testdata/pressure_memory"
+	_config.Mode = ModePressure
+	assert.Equal(t, ModePressure, GetMode())
+
+	// some avg10=12.50, full avg10=4.00
+	_config.MaxSomeAvg10 = 10
+	_config.MaxFullAvg10 = 5
+	assert.False(t, IsSufficient())
+	_config.MaxSomeAvg10 = 20
+	assert.True(t, IsSufficient())
+	_config.MaxFullAvg10 = 4
+	assert.False(t, IsSufficient())
+
+	// the kernel not supports PSI
+	memPressureFile = "./testdata/not-exist"
+	assert.Equal(t, ModeMemAvailable, GetMode())
+}
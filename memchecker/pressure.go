@@ -0,0 +1,213 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package memchecker
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// memPressureFile the PSI file of the memory, the tests replace it with a
+// fixture
+var memPressureFile = "/proc/pressure/memory"
+
+const (
+	// the unprivileged triggers require the window to be a multiple of 2s
+	defaultTriggerStall  = 150000  // 150ms
+	defaultTriggerWindow = 2000000 // 2s
+)
+
+// PressureInfo the memory pressure stall information, the avg values are
+// the percentages of the time some or all tasks stalled on memory.
+type PressureInfo struct {
+	SomeAvg10  float64
+	SomeAvg60  float64
+	SomeAvg300 float64
+	SomeTotal  uint64
+	FullAvg10  float64
+	FullAvg60  float64
+	FullAvg300 float64
+	FullTotal  uint64
+}
+
+// IsPressureSupported check whether the kernel supports PSI
+func IsPressureSupported() bool {
+	_, err := os.Stat(memPressureFile)
+	return err == nil
+}
+
+// GetPressure get the memory pressure from /proc/pressure/memory
+func GetPressure() (*PressureInfo, error) {
+	return doGetPressure(memPressureFile)
+}
+
+func doGetPressure(filename string) (*PressureInfo, error) {
+	fr, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer fr.Close()
+
+	var found = false
+	var info = new(PressureInfo)
+	var scanner = bufio.NewScanner(fr)
+	for scanner.Scan() {
+		// some avg10=0.00 avg60=0.00 avg300=0.00 total=0
+		list := strings.Fields(scanner.Text())
+		if len(list) != 5 {
+			continue
+		}
+
+		var avg10, avg60, avg300 *float64
+		var total *uint64
+		switch list[0] {
+		case "some":
+			avg10, avg60, avg300, total = &info.SomeAvg10, &info.SomeAvg60,
+				&info.SomeAvg300, &info.SomeTotal
+		case "full":
+			avg10, avg60, avg300, total = &info.FullAvg10, &info.FullAvg60,
+				&info.FullAvg300, &info.FullTotal
+		default:
+			continue
+		}
+
+		for _, field := range list[1:] {
+			kv := strings.SplitN(field, "=", 2)
+			if len(kv) != 2 {
+				return nil, fmt.Errorf("bad format: %s", field)
+			}
+			switch kv[0] {
+			case "avg10":
+				*avg10, err = strconv.ParseFloat(kv[1], 64)
+			case "avg60":
+				*avg60, err = strconv.ParseFloat(kv[1], 64)
+			case "avg300":
+				*avg300, err = strconv.ParseFloat(kv[1], 64)
+			case "total":
+				*total, err = strconv.ParseUint(kv[1], 10, 64)
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+		found = true
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, fmt.Errorf("bad format: %s", filename)
+	}
+	return info, nil
+}
+
+// isPressureSufficient the memory is sufficient if the stalls lower than
+// the thresholds
+func isPressureSufficient(info *PressureInfo) bool {
+	if _config.MaxSomeAvg10 != 0 && info.SomeAvg10 >= _config.MaxSomeAvg10 {
+		return false
+	}
+	if _config.MaxFullAvg10 != 0 && info.FullAvg10 >= _config.MaxFullAvg10 {
+		return false
+	}
+	return true
+}
+
+// PressureWatcher notify when the memory stall exceeds the trigger
+type PressureWatcher struct {
+	file   *os.File
+	epfd   int
+	events chan struct{}
+}
+
+// WatchPressure register a PSI trigger, the channel of the watcher receives
+// an event when some tasks stalled for more than the trigger stall in
+// the trigger window.
+func WatchPressure() (*PressureWatcher, error) {
+	fw, err := os.OpenFile(memPressureFile, os.O_RDWR|syscall.O_NONBLOCK, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	// the kernel replaces the last byte with the null terminator
+	_, err = fw.WriteString(fmt.Sprintf("some %d %d\n",
+		_config.TriggerStall, _config.TriggerWindow))
+	if err != nil {
+		fw.Close()
+		return nil, err
+	}
+
+	epfd, err := syscall.EpollCreate1(syscall.EPOLL_CLOEXEC)
+	if err != nil {
+		fw.Close()
+		return nil, err
+	}
+
+	fd := int(fw.Fd())
+	err = syscall.EpollCtl(epfd, syscall.EPOLL_CTL_ADD, fd, &syscall.EpollEvent{
+		Events: syscall.EPOLLPRI,
+		Fd:     int32(fd),
+	})
+	if err != nil {
+		syscall.Close(epfd)
+		fw.Close()
+		return nil, err
+	}
+
+	w := &PressureWatcher{
+		file:   fw,
+		epfd:   epfd,
+		events: make(chan struct{}, 1),
+	}
+	go w.loop()
+	return w, nil
+}
+
+// Events the channel will be closed after the watcher stopped
+func (w *PressureWatcher) Events() <-chan struct{} {
+	return w.events
+}
+
+// Stop unregister the trigger
+func (w *PressureWatcher) Stop() {
+	// the trigger is destroyed when the file closed, the closed file is
+	// removed from the epoll set, so the loop exits at the next timeout
+	w.file.Close()
+}
+
+func (w *PressureWatcher) loop() {
+	defer close(w.events)
+	defer syscall.Close(w.epfd)
+
+	var events [1]syscall.EpollEvent
+	for {
+		// wake up periodically to check whether the file has been closed
+		n, err := syscall.EpollWait(w.epfd, events[:], 1000)
+		if err == syscall.EINTR {
+			continue
+		}
+		if err != nil {
+			return
+		}
+		if n == 0 {
+			if _, err := w.file.Stat(); err != nil {
+				return
+			}
+			continue
+		}
+
+		if events[0].Events&syscall.EPOLLERR != 0 {
+			return
+		}
+		select {
+		case w.events <- struct{}{}:
+		default:
+		}
+	}
+}
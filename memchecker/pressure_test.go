@@ -0,0 +1,42 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package memchecker
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_doGetPressure(t *testing.T) {
+	info, err := doGetPressure("./testdata/pressure_memory")
+	assert.NoError(t, err)
+	assert.Equal(t, &PressureInfo{
+		SomeAvg10:  12.5,
+		SomeAvg60:  3.21,
+		SomeAvg300: 0.8,
+		SomeTotal:  123456789,
+		FullAvg10:  4,
+		FullAvg60:  1.02,
+		FullAvg300: 0.2,
+		FullTotal:  45678901,
+	}, info)
+
+	_, err = doGetPressure("./testdata/meminfo")
+	assert.Error(t, err)
+}
+
+func Test_isPressureSufficient(t *testing.T) {
+	old := *_config
+	defer func() {
+		*_config = old
+	}()
+	_config.MaxSomeAvg10 = 10
+	_config.MaxFullAvg10 = 5
+
+	assert.True(t, isPressureSufficient(&PressureInfo{SomeAvg10: 9.9, FullAvg10: 4.9}))
+	assert.False(t, isPressureSufficient(&PressureInfo{SomeAvg10: 12.5, FullAvg10: 4}))
+	assert.False(t, isPressureSufficient(&PressureInfo{SomeAvg10: 1, FullAvg10: 5}))
+}
@@ -0,0 +1,278 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"syscall"
+	"time"
+
+	dbus "github.com/godbus/dbus"
+	"github.com/linuxdeepin/go-lib/appinfo/desktopappinfo"
+	"github.com/linuxdeepin/go-lib/dbusutil"
+	"github.com/linuxdeepin/go-x11-client/util/wm/ewmh"
+	"github.com/linuxdeepin/startdde/memanalyzer"
+)
+
+const (
+	appCloseTimeout       = 10 * time.Second
+	appCloseCheckInterval = 500 * time.Millisecond
+)
+
+// ReclaimableApp the app in the session which can be closed to free memory
+type ReclaimableApp struct {
+	// the desktop id, or the cgroup name if the app unknown
+	Id        string
+	DesktopId string
+	Name      string
+	Icon      string
+	// the PSS of the processes, in kB
+	Pss  uint64
+	Pids []uint32
+}
+
+// ListReclaimableApps list the top n memory consumers in the session, all
+// of them if n <= 0
+func (m *StartManager) ListReclaimableApps(n int32) ([]ReclaimableApp, *dbus.Error) {
+	return topReclaimableApps(m.getReclaimableApps(), n), nil
+}
+
+func topReclaimableApps(apps []ReclaimableApp, n int32) []ReclaimableApp {
+	if n > 0 && len(apps) > int(n) {
+		apps = apps[:n]
+	}
+	return apps
+}
+
+// CloseApp close the app politely, the windows of the app receive the close
+// request, the processes without windows receive SIGTERM. The pending
+// launches will be launched after the app exited if the memory is sufficient.
+func (m *StartManager) CloseApp(sender dbus.Sender, id string) *dbus.Error {
+	err := checkDMsgUid(m.service, sender)
+	if err != nil {
+		return dbusutil.ToError(err)
+	}
+
+	err = m.closeApp(id)
+	return dbusutil.ToError(err)
+}
+
+func (m *StartManager) closeApp(id string) error {
+	app, err := findReclaimableApp(m.getReclaimableApps(), id)
+	if err != nil {
+		return err
+	}
+
+	logger.Info("Close app:", app.Id, app.Pids)
+	m.closePids(app.Pids)
+	go func() {
+		if !waitPidsExited(app.Pids, appCloseTimeout) {
+			logger.Warning("The app not exited in time:", app.Id)
+		}
+		checkPendingLaunches()
+	}()
+	return nil
+}
+
+func findReclaimableApp(apps []ReclaimableApp, id string) (*ReclaimableApp, error) {
+	for i := range apps {
+		if apps[i].Id == id {
+			return &apps[i], nil
+		}
+	}
+	return nil, fmt.Errorf("not found the app: %s", id)
+}
+
+// closePids request to close the windows of the processes, the processes
+// without a closed window receive SIGTERM.
+func (m *StartManager) closePids(pids []uint32) {
+	var windowPids = make(map[uint32]bool)
+	if m.xConn != nil {
+		windows, err := ewmh.GetClientList(m.xConn).Reply(m.xConn)
+		if err != nil {
+			logger.Warning("Failed to get client list:", err)
+		}
+
+		for _, win := range windows {
+			pid, err := ewmh.GetWMPid(m.xConn, win).Reply(m.xConn)
+			if err != nil || !isPidInList(pid, pids) {
+				continue
+			}
+
+			// the window manager sends WM_DELETE_WINDOW to the client
+			err = ewmh.RequestCloseWindowChecked(m.xConn, win, 0, 2).Check(m.xConn)
+			if err != nil {
+				logger.Warning("Failed to close window:", win, err)
+				continue
+			}
+			windowPids[pid] = true
+		}
+	}
+
+	for _, pid := range getPidsWithoutWindow(pids, windowPids) {
+		err := syscall.Kill(int(pid), syscall.SIGTERM)
+		if err != nil {
+			logger.Warning("Failed to terminate process:", pid, err)
+		}
+	}
+}
+
+func getPidsWithoutWindow(pids []uint32, windowPids map[uint32]bool) []uint32 {
+	var ret []uint32
+	for _, pid := range pids {
+		if !windowPids[pid] {
+			ret = append(ret, pid)
+		}
+	}
+	return ret
+}
+
+func waitPidsExited(pids []uint32, timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for {
+		alive := false
+		for _, pid := range pids {
+			if isPidAlive(pid) {
+				alive = true
+				break
+			}
+		}
+		if !alive {
+			return true
+		}
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(appCloseCheckInterval)
+	}
+}
+
+func isPidAlive(pid uint32) bool {
+	_, err := os.Stat(filepath.Join("/proc", strconv.FormatUint(uint64(pid), 10)))
+	return err == nil
+}
+
+func isPidInList(pid uint32, pids []uint32) bool {
+	for _, v := range pids {
+		if v == pid {
+			return true
+		}
+	}
+	return false
+}
+
+// getReclaimableApps group the app cgroups and the launched processes by app,
+// sorted by PSS in descending order
+func (m *StartManager) getReclaimableApps() []ReclaimableApp {
+	cgroups, err := memanalyzer.ListAppCGroups()
+	if err != nil {
+		logger.Warning("Failed to list app cgroups:", err)
+	}
+	return groupReclaimableApps(cgroups, m.getLaunchedApps(), getDesktopFileByUnit)
+}
+
+// groupReclaimableApps the app of a cgroup is found by the launched record of
+// the unit, then of the processes, then by the unit name.
+func groupReclaimableApps(cgroups []*memanalyzer.AppCGroup, launchedApps map[uint32]launchedApp,
+	getDesktopFileByUnit func(unitName string) string) []ReclaimableApp {
+	unitDesktopMap := make(map[string]string)
+	for _, app := range launchedApps {
+		if app.unitName != "" {
+			unitDesktopMap[app.unitName] = app.desktopFile
+		}
+	}
+
+	var groups = make(map[string]*ReclaimableApp)
+	var ids []string
+	var seenPids = make(map[uint32]bool)
+	addApp := func(desktopFile, fallbackId string, pids []uint32, pss uint64) {
+		var app = ReclaimableApp{Id: fallbackId}
+		if desktopFile != "" {
+			dai, err := newDesktopAppInfoFromFile(desktopFile)
+			if err == nil {
+				app.Id = dai.GetId()
+				app.DesktopId = dai.GetId()
+				app.Name = dai.GetName()
+				app.Icon = dai.GetIcon()
+			}
+		}
+
+		group, ok := groups[app.Id]
+		if !ok {
+			group = &app
+			groups[app.Id] = group
+			ids = append(ids, app.Id)
+		}
+		group.Pids = append(group.Pids, pids...)
+		group.Pss += pss
+		for _, pid := range pids {
+			seenPids[pid] = true
+		}
+	}
+
+	for _, cg := range cgroups {
+		desktopFile, ok := unitDesktopMap[cg.Name]
+		if !ok {
+			desktopFile = getDesktopFileByPids(cg.Pids, launchedApps)
+		}
+		if desktopFile == "" {
+			desktopFile = getDesktopFileByUnit(cg.Name)
+		}
+		addApp(desktopFile, cg.Name, cg.Pids, cg.Pss)
+	}
+
+	// the apps not in the app cgroups
+	for pid, app := range launchedApps {
+		if seenPids[pid] || !isPidAlive(pid) {
+			continue
+		}
+		pids := []uint32{pid}
+		addApp(app.desktopFile, strconv.FormatUint(uint64(pid), 10), pids,
+			memanalyzer.GetPidsMemory(pids))
+	}
+
+	var ret = make([]ReclaimableApp, 0, len(ids))
+	for _, id := range ids {
+		ret = append(ret, *groups[id])
+	}
+	sort.SliceStable(ret, func(i, j int) bool {
+		return ret[i].Pss > ret[j].Pss
+	})
+	return ret
+}
+
+func getDesktopFileByPids(pids []uint32, launchedApps map[uint32]launchedApp) string {
+	for _, pid := range pids {
+		if app, ok := launchedApps[pid]; ok {
+			return app.desktopFile
+		}
+	}
+	return ""
+}
+
+func getDesktopFileByUnit(unitName string) string {
+	return findDesktopFileByUnit(unitName, func(id string) string {
+		dai := desktopappinfo.NewDesktopAppInfo(id)
+		if dai == nil {
+			return ""
+		}
+		return dai.GetFileName()
+	})
+}
+
+// findDesktopFileByUnit try the app ids parsed from the unit name in order,
+// the lookup returns "" if the app not installed
+func findDesktopFileByUnit(unitName string, lookup func(id string) string) string {
+	for _, id := range memanalyzer.GetAppIdCandidatesByUnit(unitName) {
+		if desktopFile := lookup(id); desktopFile != "" {
+			return desktopFile
+		}
+	}
+	return ""
+}
@@ -0,0 +1,141 @@
+// SPDX-FileCopyrightText: 2022 UnionTech Software Technology Co., Ltd.
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package main
+
+import (
+	"os"
+	"testing"
+
+	"github.com/linuxdeepin/startdde/memanalyzer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_getPidsWithoutWindow(t *testing.T) {
+	pids := []uint32{100, 101, 102}
+	assert.Equal(t, pids, getPidsWithoutWindow(pids, nil))
+	assert.Equal(t, []uint32{101, 102},
+		getPidsWithoutWindow(pids, map[uint32]bool{100: true}))
+	assert.Nil(t, getPidsWithoutWindow(pids, map[uint32]bool{100: true, 101: true, 102: true}))
+}
+
+func Test_topReclaimableApps(t *testing.T) {
+	apps := []ReclaimableApp{{Id: "a", Pss: 300}, {Id: "b", Pss: 200}, {Id: "c", Pss: 100}}
+	assert.Equal(t, apps, topReclaimableApps(apps, 0))
+	assert.Equal(t, apps, topReclaimableApps(apps, -1))
+	assert.Equal(t, apps, topReclaimableApps(apps, 3))
+	assert.Equal(t, apps, topReclaimableApps(apps, 10))
+	assert.Equal(t, apps[:2], topReclaimableApps(apps, 2))
+	assert.Empty(t, topReclaimableApps(nil, 2))
+}
+
+func Test_findReclaimableApp(t *testing.T) {
+	apps := []ReclaimableApp{
+		{Id: "a", Pids: []uint32{100}},
+		{Id: "b", Pids: []uint32{200, 201}},
+	}
+	app, err := findReclaimableApp(apps, "b")
+	assert.NoError(t, err)
+	assert.Equal(t, []uint32{200, 201}, app.Pids)
+
+	_, err = findReclaimableApp(apps, "c")
+	assert.Error(t, err)
+	_, err = findReclaimableApp(nil, "a")
+	assert.Error(t, err)
+}
+
+func Test_findDesktopFileByUnit(t *testing.T) {
+	var tried []string
+	newLookup := func(installed map[string]string) func(string) string {
+		tried = nil
+		return func(id string) string {
+			tried = append(tried, id)
+			return installed[id]
+		}
+	}
+
+	// without the launcher in the unit name
+	assert.Equal(t, "/usr/share/applications/org.gnome.Terminal.desktop",
+		findDesktopFileByUnit("app-gnome-org.gnome.Terminal@a1b2c3.service", newLookup(map[string]string{
+			"org.gnome.Terminal": "/usr/share/applications/org.gnome.Terminal.desktop",
+		})))
+	assert.Equal(t, []string{"gnome-org.gnome.Terminal", "org.gnome.Terminal"}, tried)
+
+	// the escaped app id, found at the first candidate
+	assert.Equal(t, "/usr/share/applications/dde-deepin-editor.desktop",
+		findDesktopFileByUnit("app-dde-deepin\\x2deditor-4096.scope", newLookup(map[string]string{
+			"dde-deepin-editor": "/usr/share/applications/dde-deepin-editor.desktop",
+			"deepin-editor":     "/usr/share/applications/deepin-editor.desktop",
+		})))
+	assert.Equal(t, []string{"dde-deepin-editor"}, tried)
+
+	// not installed
+	assert.Equal(t, "", findDesktopFileByUnit("app-dde-deepin\\x2deditor-4096.scope", newLookup(nil)))
+	assert.Len(t, tried, 2)
+
+	// not an app unit
+	assert.Equal(t, "", findDesktopFileByUnit("session-2.scope", newLookup(nil)))
+	assert.Empty(t, tried)
+}
+
+func Test_groupReclaimableApps(t *testing.T) {
+	const fileManagerDesktop = "testdata/desktop/dde-file-manager.desktop"
+	dai, err := newDesktopAppInfoFromFile(fileManagerDesktop)
+	require.NoError(t, err)
+	fileManagerId := dai.GetId()
+
+	getDesktopFileByUnit := func(unitName string) string {
+		return findDesktopFileByUnit(unitName, func(id string) string {
+			if id == "dde-file-manager" {
+				return fileManagerDesktop
+			}
+			return ""
+		})
+	}
+
+	cgroups := []*memanalyzer.AppCGroup{
+		// found by the unit name
+		{Name: "app-dde-dde\\x2dfile\\x2dmanager-4096.scope", Pids: []uint32{4096, 4100}, Pss: 300},
+		// unknown app
+		{Name: "app-unknown-5120.scope", Pids: []uint32{5120}, Pss: 500},
+		// the desktop file of the launched record fails to load
+		{Name: "app-dde-broken-6000.scope", Pids: []uint32{6000}, Pss: 100},
+		// found by the launched record of the unit
+		{Name: "app-dde-fm-7000.scope", Pids: []uint32{7000}, Pss: 50},
+	}
+	launchedApps := map[uint32]launchedApp{
+		6000: {desktopFile: "testdata/desktop/not-exist.desktop"},
+		7000: {desktopFile: fileManagerDesktop, unitName: "app-dde-fm-7000.scope"},
+		// exited
+		0x7ffffffe: {desktopFile: fileManagerDesktop},
+	}
+
+	apps := groupReclaimableApps(cgroups, launchedApps, getDesktopFileByUnit)
+	require.Len(t, apps, 3)
+
+	assert.Equal(t, "app-unknown-5120.scope", apps[0].Id)
+	assert.Equal(t, "", apps[0].DesktopId)
+	assert.Equal(t, uint64(500), apps[0].Pss)
+
+	assert.Equal(t, fileManagerId, apps[1].Id)
+	assert.Equal(t, fileManagerId, apps[1].DesktopId)
+	assert.Equal(t, uint64(350), apps[1].Pss)
+	assert.Equal(t, []uint32{4096, 4100, 7000}, apps[1].Pids)
+
+	assert.Equal(t, "app-dde-broken-6000.scope", apps[2].Id)
+	assert.Equal(t, []uint32{6000}, apps[2].Pids)
+
+	// the launched app not in any app cgroup
+	pid := uint32(os.Getpid())
+	apps = groupReclaimableApps(nil, map[uint32]launchedApp{
+		pid: {desktopFile: fileManagerDesktop},
+	}, getDesktopFileByUnit)
+	require.Len(t, apps, 1)
+	assert.Equal(t, fileManagerId, apps[0].Id)
+	assert.Equal(t, []uint32{pid}, apps[0].Pids)
+	assert.True(t, apps[0].Pss > 0)
+
+	assert.Empty(t, groupReclaimableApps(nil, nil, getDesktopFileByUnit))
+}
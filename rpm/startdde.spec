@@ -76,11 +76,13 @@ fi
 %{_sysconfdir}/X11/xinit/xinitrc.d/01deepin-profile
 %{_sysconfdir}/profile.d/deepin-xdg-dir.sh
 %{_bindir}/%{name}
+%{_bindir}/dde-display-ctl
 %{_sbindir}/deepin-fix-xauthority-perm
 %{_datadir}/xsessions/deepin.desktop
 %{_datadir}/lightdm/lightdm.conf.d/60-deepin.conf
 %{_datadir}/%{name}/auto_launch.json
 %{_datadir}/%{name}/memchecker.json
+%{_datadir}/%{name}/iowait.json
 /usr/lib/deepin-daemon/greeter-display-daemon
 
 %changelog
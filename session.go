@@ -37,6 +37,7 @@ import (
 	x "github.com/linuxdeepin/go-x11-client"
 	"github.com/linuxdeepin/go-x11-client/ext/dpms"
 	"github.com/linuxdeepin/startdde/autostop"
+	"github.com/linuxdeepin/startdde/display"
 	"github.com/linuxdeepin/startdde/keyring"
 	"github.com/linuxdeepin/startdde/watchdog"
 	"github.com/linuxdeepin/startdde/wm_kwin"
@@ -154,6 +155,8 @@ func stopRedshift() {
 }
 
 func (m *SessionManager) prepareLogout(force bool) {
+	stopLoadMonitor()
+	display.Stop()
 	if !force {
 		err := autostop.LaunchAutostopScripts(logger)
 		if err != nil {
@@ -246,6 +249,8 @@ func (m *SessionManager) Shutdown() *dbus.Error {
 }
 
 func (m *SessionManager) prepareShutdown(force bool) {
+	stopLoadMonitor()
+	display.Stop()
 	killSogouImeWatchdog()
 	stopBAMFDaemon()
 	if !force {
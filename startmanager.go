@@ -34,6 +34,7 @@ import (
 	"github.com/linuxdeepin/go-lib/strv"
 	"github.com/linuxdeepin/go-lib/xdg/basedir"
 	x "github.com/linuxdeepin/go-x11-client"
+	"github.com/linuxdeepin/startdde/memchecker"
 )
 
 //go:generate dbusutil-gen em -type StartManager,SessionManager,Inhibitor
@@ -83,6 +84,7 @@ type StartManager struct {
 	appProxy            proxy.App
 
 	NeededMemory     uint64
+	MemCheckerMode   string // 'mem-available' or 'pressure'
 	systemPower      systemPower.Power
 	cpuFreqAdjustMap map[string]int32
 
@@ -90,6 +92,9 @@ type StartManager struct {
 
 	enableSystemdApplicationUnit bool
 
+	launchedApps   map[uint32]*launchedApp // key is pid
+	launchedAppsMu sync.Mutex
+
 	//nolint
 	signals *struct {
 		AutostartChanged struct {
@@ -99,6 +104,13 @@ type StartManager struct {
 	}
 }
 
+// launchedApp the record of the app launched by StartManager
+type launchedApp struct {
+	desktopFile string
+	// the systemd scope, empty if the systemd application units disabled
+	unitName string
+}
+
 func getLaunchedHooks(dir string) (ret []string) {
 	fileInfoList, err := ioutil.ReadDir(dir)
 	if err != nil {
@@ -164,8 +176,9 @@ func (m *StartManager) enableCpuFreqLock(desktopFile string) error {
 
 func newStartManager(xConn *x.Conn, service *dbusutil.Service) *StartManager {
 	m := &StartManager{
-		service: service,
-		xConn:   xConn,
+		service:        service,
+		xConn:          xConn,
+		MemCheckerMode: memchecker.GetMode(),
 	}
 
 	m.appsDir = getAppDirs()
@@ -195,6 +208,7 @@ func newStartManager(xConn *x.Conn, service *dbusutil.Service) *StartManager {
 	logger.Debugf("startManager proxychain confFile %q, bin: %q", m.proxyChainsConfFile, m.proxyChainsBin)
 
 	m.restartTimeMap = make(map[string]time.Time)
+	m.launchedApps = make(map[uint32]*launchedApp)
 	m.delayHandler = newMapDelayHandler(100*time.Millisecond,
 		m.emitSignalAutostartChanged)
 	sysBus, err := dbus.SystemBus()
@@ -282,7 +296,7 @@ func (m *StartManager) Launch(sender dbus.Sender, desktopFile string) (bool, *db
 	if err != nil {
 		return false, dbusutil.ToError(err)
 	}
-	err = m.launchAppWithOptions(desktopFile, 0, nil, nil)
+	err = m.launchAppWithOptions(string(sender), desktopFile, 0, nil, nil)
 	return err == nil, dbusutil.ToError(err)
 }
 
@@ -294,7 +308,7 @@ func (m *StartManager) LaunchWithTimestamp(sender dbus.Sender, desktopFile strin
 	if err != nil {
 		return false, dbusutil.ToError(err)
 	}
-	err = m.launchAppWithOptions(desktopFile, timestamp, nil, nil)
+	err = m.launchAppWithOptions(string(sender), desktopFile, timestamp, nil, nil)
 	return err == nil, dbusutil.ToError(err)
 }
 
@@ -305,7 +319,7 @@ func (m *StartManager) LaunchApp(sender dbus.Sender, desktopFile string,
 	if err != nil {
 		return dbusutil.ToError(err)
 	}
-	err = m.launchAppWithOptions(desktopFile, timestamp, files, nil)
+	err = m.launchAppWithOptions(string(sender), desktopFile, timestamp, files, nil)
 	return dbusutil.ToError(err)
 }
 
@@ -316,27 +330,32 @@ func (m *StartManager) LaunchAppWithOptions(sender dbus.Sender, desktopFile stri
 	if err != nil {
 		return dbusutil.ToError(err)
 	}
-	err = m.launchAppWithOptions(desktopFile, timestamp, files, options)
+	err = m.launchAppWithOptions(string(sender), desktopFile, timestamp, files, options)
 	return dbusutil.ToError(err)
 }
 
-func (m *StartManager) launchAppWithOptions(desktopFile string, timestamp uint32,
+func (m *StartManager) launchAppWithOptions(requester, desktopFile string, timestamp uint32,
 	files []string, options map[string]dbus.Variant) error {
 
-	err := handleMemInsufficient(desktopFile)
-	if err != nil {
-		if getCurAction() != "" {
-			return nil
-		}
-		_app.desktop = desktopFile
-		_app.timestamp = timestamp
-		_app.files = files
-		_app.options = options
-		setCurAction("LaunchApp")
+	queued := deferLaunchIfMemInsufficient(&pendingLaunch{
+		kind:      pendingKindLaunchApp,
+		name:      desktopFile,
+		requester: requester,
+		desktop:   desktopFile,
+		timestamp: timestamp,
+		files:     files,
+		options:   options,
+	})
+	if queued {
 		return nil
 	}
+	return m.doLaunchAppWithOptions(desktopFile, timestamp, files, options)
+}
+
+func (m *StartManager) doLaunchAppWithOptions(desktopFile string, timestamp uint32,
+	files []string, options map[string]dbus.Variant) error {
 
-	err = m.launchApp(desktopFile, timestamp, files, options)
+	err := m.launchApp(desktopFile, timestamp, files, options)
 	if err != nil {
 		logger.Warning("launch failed:", err)
 	}
@@ -359,24 +378,27 @@ func (m *StartManager) LaunchAppAction(sender dbus.Sender, desktopFile, action s
 		return dbusutil.ToError(err)
 	}
 
-	err = m.launchAppAction(desktopFile, action, timestamp)
+	err = m.launchAppAction(string(sender), desktopFile, action, timestamp)
 	return dbusutil.ToError(err)
 }
 
-func (m *StartManager) launchAppAction(desktopFile, action string, timestamp uint32) error {
-	err := handleMemInsufficient(desktopFile + action)
-	if err != nil {
-		if getCurAction() != "" {
-			return nil
-		}
-		_appAction.desktop = desktopFile
-		_appAction.action = action
-		_appAction.timestamp = timestamp
-		setCurAction("LaunchAppAction")
+func (m *StartManager) launchAppAction(requester, desktopFile, action string, timestamp uint32) error {
+	queued := deferLaunchIfMemInsufficient(&pendingLaunch{
+		kind:      pendingKindLaunchAppAction,
+		name:      desktopFile + action,
+		requester: requester,
+		desktop:   desktopFile,
+		action:    action,
+		timestamp: timestamp,
+	})
+	if queued {
 		return nil
 	}
+	return m.doLaunchAppAction(desktopFile, action, timestamp)
+}
 
-	err = m.launchAppActionAux(desktopFile, action, timestamp)
+func (m *StartManager) doLaunchAppAction(desktopFile, action string, timestamp uint32) error {
+	err := m.launchAppActionAux(desktopFile, action, timestamp)
 	if err != nil {
 		logger.Warning("launch failed:", err)
 	}
@@ -409,7 +431,7 @@ func (m *StartManager) RunCommand(sender dbus.Sender, exe string, args []string)
 	if err != nil {
 		return dbusutil.ToError(err)
 	}
-	err = m.runCommandWithOptions(exe, args, nil)
+	err = m.runCommandWithOptions(string(sender), exe, args, nil)
 	return dbusutil.ToError(err)
 }
 
@@ -420,7 +442,7 @@ func (m *StartManager) RunCommandWithOptions(sender dbus.Sender, exe string, arg
 	if err != nil {
 		return dbusutil.ToError(err)
 	}
-	err = m.runCommandWithOptions(exe, args, options)
+	err = m.runCommandWithOptions(string(sender), exe, args, options)
 	return dbusutil.ToError(err)
 }
 
@@ -435,24 +457,33 @@ func checkDMsgUid(service *dbusutil.Service, sender dbus.Sender) error {
 	return errors.New("permission denied")
 }
 
-func (m *StartManager) runCommandWithOptions(exe string, args []string,
+func (m *StartManager) runCommandWithOptions(requester, exe string, args []string,
 	options map[string]dbus.Variant) error {
 
-	var _name = exe
-	if len(args) != 0 {
-		_name += " " + strings.Join(args, " ")
-	}
-	err := handleMemInsufficient(_name)
-	if err != nil {
-		if getCurAction() != "" {
-			return nil
-		}
-		_cmd.exe = exe
-		_cmd.args = args
-		_cmd.options = options
-		setCurAction("RunCommand")
+	queued := deferLaunchIfMemInsufficient(&pendingLaunch{
+		kind:      pendingKindRunCommand,
+		name:      getCmdName(exe, args),
+		requester: requester,
+		exe:       exe,
+		args:      args,
+		options:   options,
+	})
+	if queued {
 		return nil
 	}
+	return m.doRunCommandWithOptions(exe, args, options)
+}
+
+func getCmdName(exe string, args []string) string {
+	var name = exe
+	if len(args) != 0 {
+		name += " " + strings.Join(args, " ")
+	}
+	return name
+}
+
+func (m *StartManager) doRunCommandWithOptions(exe string, args []string,
+	options map[string]dbus.Variant) error {
 
 	cmd := exec.Command(exe, args...)
 
@@ -464,8 +495,8 @@ func (m *StartManager) runCommandWithOptions(exe string, args []string,
 		}
 	}
 
-	err = cmd.Start()
-	return m.waitCmd(nil, cmd, err, _name)
+	err := cmd.Start()
+	return m.waitCmd(nil, cmd, err, getCmdName(exe, args))
 }
 
 func (m *StartManager) getAppIdByFilePath(file string) string {
@@ -509,7 +540,7 @@ type IStartCommand interface {
 	StartCommand(files []string, ctx *appinfo.AppLaunchContext) (*exec.Cmd, error)
 }
 
-func (m *StartManager) createSystemdUnitForPID(appID string, desktopFile string, pid uint) {
+func (m *StartManager) createSystemdUnitForPID(appID string, desktopFile string, pid uint) string {
 	if appID == "" {
 		appID = strings.TrimSuffix(filepath.Base(desktopFile), ".desktop")
 	}
@@ -532,6 +563,7 @@ func (m *StartManager) createSystemdUnitForPID(appID string, desktopFile string,
 	}
 
 	m.userSystemd.StartTransientUnit(0, unitName, "fail", properties, nil)
+	return unitName
 }
 
 func (m *StartManager) launch(appInfo *desktopappinfo.DesktopAppInfo, timestamp uint32,
@@ -583,13 +615,46 @@ func (m *StartManager) launch(appInfo *desktopappinfo.DesktopAppInfo, timestamp
 
 	cmd, err := iStartCmd.StartCommand(files, ctx)
 
-	if m.enableSystemdApplicationUnit {
-		m.createSystemdUnitForPID(appId, desktopFile, uint(cmd.Process.Pid))
+	if err == nil {
+		var unitName string
+		if m.enableSystemdApplicationUnit {
+			unitName = m.createSystemdUnitForPID(appId, desktopFile, uint(cmd.Process.Pid))
+			if _gSettingsConfig.memcheckerEnabled {
+				startSaveNeededMemory(cmdName, unitName, uint32(cmd.Process.Pid))
+			}
+		}
+		m.addLaunchedApp(uint32(cmd.Process.Pid), desktopFile, unitName)
 	}
 
 	return m.waitCmd(appInfo, cmd, err, cmdName)
 }
 
+func (m *StartManager) addLaunchedApp(pid uint32, desktopFile, unitName string) {
+	m.launchedAppsMu.Lock()
+	m.launchedApps[pid] = &launchedApp{
+		desktopFile: desktopFile,
+		unitName:    unitName,
+	}
+	m.launchedAppsMu.Unlock()
+}
+
+func (m *StartManager) removeLaunchedApp(pid uint32) {
+	m.launchedAppsMu.Lock()
+	delete(m.launchedApps, pid)
+	m.launchedAppsMu.Unlock()
+}
+
+// getLaunchedApps returns a copy of the launched app records
+func (m *StartManager) getLaunchedApps() map[uint32]launchedApp {
+	m.launchedAppsMu.Lock()
+	defer m.launchedAppsMu.Unlock()
+	ret := make(map[uint32]launchedApp, len(m.launchedApps))
+	for pid, app := range m.launchedApps {
+		ret[pid] = *app
+	}
+	return ret
+}
+
 func newDesktopAppInfoFromFile(filename string) (*desktopappinfo.DesktopAppInfo, error) {
 	dai, err := desktopappinfo.NewDesktopAppInfoFromFile(filename)
 	if err != nil {
@@ -676,6 +741,7 @@ func (m *StartManager) waitCmd(appInfo *desktopappinfo.DesktopAppInfo, cmd *exec
 			}
 		}
 		err := cmd.Wait()
+		m.removeLaunchedApp(uint32(cmd.Process.Pid))
 		if err != nil {
 			logger.Warningf("%v: %v", cmd.Args, err)
 
@@ -1062,7 +1128,7 @@ func startAutostartProgram() {
 			if delay != 0 {
 				time.Sleep(delay)
 			}
-			err = _startManager.launchAppWithOptions(desktopFile, 0, nil, nil)
+			err = _startManager.launchAppWithOptions("", desktopFile, 0, nil, nil)
 			if err != nil {
 				logger.Warning(err)
 			}
@@ -135,15 +135,9 @@ func (m *XSManager) setScreenScaleFactorsForQt(factors map[string]float64) error
 		logger.Warning("failed to load qt-theme.ini:", err)
 	}
 
-	var value string
-	switch len(factors) {
-	case 0:
-		return errors.New("factors is empty")
-	case 1:
-		value = strconv.FormatFloat(getMapFirstValueSF(factors), 'f', 2, 64)
-	default:
-		value = joinScreenScaleFactors(factors)
-		value = strconv.Quote(value)
+	value, err := getQtScreenScaleFactors(factors)
+	if err != nil {
+		return err
 	}
 	kf.SetValue(qtThemeSection, qtThemeKeyScreenScaleFactors, value)
 	kf.DeleteKey(qtThemeSection, qtThemeKeyScaleFactor)
@@ -163,6 +157,21 @@ func (m *XSManager) setScreenScaleFactorsForQt(factors map[string]float64) error
 	return err
 }
 
+// getQtScreenScaleFactors 获取 qt-theme.ini 中 ScreenScaleFactors 的值
+func getQtScreenScaleFactors(factors map[string]float64) (string, error) {
+	switch {
+	case len(factors) == 0:
+		return "", errors.New("factors is empty")
+	case len(factors) == 1 || !_useWayland:
+		// X11 下显示器缩放比例不同时，display 模块通过 crtc 变换缩小缩放比例较小的显示器，
+		// 整个屏幕按相同的缩放比例渲染，所以不再为每个屏幕单独设置。
+		return strconv.FormatFloat(getSingleScaleFactor(factors), 'f', 2, 64), nil
+	default:
+		// Wayland 下由合成器按显示器缩放，需要为每个屏幕单独设置
+		return strconv.Quote(joinScreenScaleFactors(factors)), nil
+	}
+}
+
 func getMapFirstValueSF(m map[string]float64) float64 {
 	for _, value := range m {
 		return value
@@ -225,11 +234,24 @@ func getSingleScaleFactor(factors map[string]float64) float64 {
 	if len(factors) == 1 {
 		return getMapFirstValueSF(factors)
 	}
-	v, ok := factors["ALL"]
-	if ok {
-		return v
+	if _useWayland {
+		v, ok := factors["ALL"]
+		if ok {
+			return v
+		}
+		return 1
+	}
+	// 取最大的，与 display 模块计算屏幕缩放比例的方式一致
+	var maxFactor float64
+	for _, v := range factors {
+		if v > maxFactor {
+			maxFactor = v
+		}
+	}
+	if maxFactor <= 0 {
+		return 1
 	}
-	return 1
+	return maxFactor
 }
 
 func singleToMapSF(value float64) map[string]float64 {
@@ -5,6 +5,7 @@
 package xsettings
 
 import (
+	"strconv"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -88,3 +89,36 @@ func Test_getPlymouthThemeScaleFactor(t *testing.T) {
 		})
 	}
 }
+
+func Test_getSingleScaleFactor(t *testing.T) {
+	assert.Equal(t, 1.0, getSingleScaleFactor(nil))
+	assert.Equal(t, 1.25, getSingleScaleFactor(map[string]float64{"ALL": 1.25}))
+	assert.Equal(t, 2.0, getSingleScaleFactor(map[string]float64{"eDP-1": 2, "HDMI-1": 1}))
+	assert.Equal(t, 1.5, getSingleScaleFactor(map[string]float64{"ALL": 1.5, "HDMI-1": 1}))
+}
+
+func Test_getQtScreenScaleFactors(t *testing.T) {
+	factors := map[string]float64{"eDP-1": 2, "HDMI-1": 1}
+	_, err := getQtScreenScaleFactors(nil)
+	assert.Error(t, err)
+
+	value, err := getQtScreenScaleFactors(factors)
+	assert.NoError(t, err)
+	assert.Equal(t, "2.00", value)
+
+	// Wayland 下保持每个屏幕的缩放比例
+	_useWayland = true
+	defer func() {
+		_useWayland = false
+	}()
+	value, err = getQtScreenScaleFactors(factors)
+	assert.NoError(t, err)
+	value, err = strconv.Unquote(value)
+	assert.NoError(t, err)
+	assert.Equal(t, factors, parseScreenFactors(value))
+	value, err = getQtScreenScaleFactors(map[string]float64{"ALL": 1.25})
+	assert.NoError(t, err)
+	assert.Equal(t, "1.25", value)
+	assert.Equal(t, 1.0, getSingleScaleFactor(factors))
+	assert.Equal(t, 1.5, getSingleScaleFactor(map[string]float64{"ALL": 1.5, "HDMI-1": 1}))
+}
@@ -113,6 +113,9 @@ func (m *XSManager) GetInterfaceName() string {
 
 var _gs *gio.Settings
 
+// 是否运行在 Wayland 会话中
+var _useWayland bool
+
 func GetScaleFactor() float64 {
 	return getScaleFactor()
 }
@@ -328,7 +331,8 @@ func (m *XSManager) handleGSettingsChanged() {
 }
 
 // Start load xsettings module
-func Start(conn *x.Conn, recommendedScaleFactor float64, service *dbusutil.Service, helper displayScaleFactorsHelper) (*XSManager, error) {
+func Start(conn *x.Conn, useWayland bool, recommendedScaleFactor float64, service *dbusutil.Service, helper displayScaleFactorsHelper) (*XSManager, error) {
+	_useWayland = useWayland
 	_gs = gio.NewSettings(xsSchema)
 	m, err := NewXSManager(conn, recommendedScaleFactor, service, helper)
 	if err != nil {